@@ -7,14 +7,13 @@ import (
 	"io"
 	"io/ioutil"
 
-	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/fundwit/go-commons/types"
 )
 
 func DetailAvatar(id types.ID, s *session.Session) ([]byte, error) {
 	r, err := s3.GetObjectFunc("avatars/"+id.String()+".png", s)
 	if err != nil {
-		if serErr, ok := err.(oss.ServiceError); ok && serErr.Code == "NoSuchKey" {
+		if err == s3.ErrObjectNotFound {
 			return nil, bizerror.ErrNotFound
 		}
 		return nil, err
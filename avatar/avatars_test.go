@@ -8,12 +8,10 @@ import (
 	"io"
 	"io/ioutil"
 	"testing"
-
-	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
 
 func TestDetailAvatar(t *testing.T) {
-	s3.GetObjectFunc = func(key string, s *session.Session, o ...oss.Option) (io.ReadCloser, error) {
+	s3.GetObjectFunc = func(key string, s *session.Session) (io.ReadCloser, error) {
 		return ioutil.NopCloser(bytes.NewReader([]byte(key + "=>hello world"))), nil
 	}
 
@@ -24,8 +22,8 @@ func TestDetailAvatar(t *testing.T) {
 		}
 	})
 
-	s3.GetObjectFunc = func(key string, s *session.Session, o ...oss.Option) (io.ReadCloser, error) {
-		return nil, oss.ServiceError{Code: "NoSuchKey"}
+	s3.GetObjectFunc = func(key string, s *session.Session) (io.ReadCloser, error) {
+		return nil, s3.ErrObjectNotFound
 	}
 	t.Run("Show not found error when avatar not found", func(t *testing.T) {
 		r, err := DetailAvatar(123456, &session.Session{Identity: session.Identity{ID: 123456}})
@@ -37,7 +35,7 @@ func TestDetailAvatar(t *testing.T) {
 
 func TestCreateAvatar(t *testing.T) {
 	var store string
-	s3.PutObjectFunc = func(k string, r io.Reader, s *session.Session, o ...oss.Option) error {
+	s3.PutObjectFunc = func(k string, r io.Reader, s *session.Session) error {
 		b, err := ioutil.ReadAll(r)
 		if err != nil {
 			return err
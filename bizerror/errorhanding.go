@@ -94,6 +94,11 @@ func HandleError(c *gin.Context, err error) {
 		c.Abort()
 		return
 	}
+	if errors.Is(genericErr, ErrWorkflowVersionInvalid) {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "workflow.version_invalid", Message: "workflow version is invalid"})
+		c.Abort()
+		return
+	}
 	if errors.Is(genericErr, gorm.ErrRecordNotFound) || errors.Is(genericErr, ErrNotFound) {
 		c.JSON(http.StatusNotFound, &misc.ErrorBody{Code: "common.record_not_found", Message: "record not found"})
 		c.Abort()
@@ -102,6 +102,15 @@ var _ = Describe("ErrorHandlingMiddleware", func() {
 			Expect(status).To(Equal(http.StatusForbidden))
 			Expect(body).To(MatchJSON(`{"code":"security.forbidden", "message":"access forbidden", "data": null}`))
 		})
+		It("should handle common.ErrWorkflowVersionInvalid", func() {
+			r.GET("/", func(c *gin.Context) {
+				_ = c.Error(bizerror.ErrWorkflowVersionInvalid)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			status, body, _ := testinfra.ExecuteRequest(req, r)
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(MatchJSON(`{"code":"workflow.version_invalid", "message":"workflow version is invalid", "data": null}`))
+		})
 		It("should handle gorm.ErrRecordNotFound", func() {
 			r.GET("/", func(c *gin.Context) {
 				_ = c.Error(gorm.ErrRecordNotFound)
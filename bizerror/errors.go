@@ -3,6 +3,8 @@ package bizerror
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 var ErrInvalidArguments = errors.New("invalid arguments")
@@ -16,6 +18,7 @@ var ErrStateInvalid = errors.New("state is invalid")
 var ErrStateCategoryInvalid = errors.New("state category is invalid")
 var ErrArchiveStatusInvalid = errors.New("archive status is invalid")
 var ErrWorkProcessStepStateInvalid = errors.New("state of work process step is invalid")
+var ErrWorkflowVersionInvalid = errors.New("workflow version is invalid")
 
 var ErrLabelNotFound = errors.New("label not found")
 var ErrLabelIsReferenced = errors.New("label is referenced")
@@ -64,3 +67,125 @@ func (e *ErrBadParam) Respond() *BizErrorDetail {
 	}
 	return &BizErrorDetail{Status: http.StatusBadRequest, Code: "common.bad_param", Message: message, Data: nil}
 }
+
+// ErrTransitionRefused is returned when some guards of the transition are not satisfied
+type ErrTransitionRefused struct {
+	Transition string
+	Reasons    []string
+}
+
+func (e *ErrTransitionRefused) Error() string {
+	return "transition " + e.Transition + " is refused: " + strings.Join(e.Reasons, "; ")
+}
+func (e *ErrTransitionRefused) Respond() *BizErrorDetail {
+	return &BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.transition_refused", Message: e.Error(), Data: e.Reasons}
+}
+
+// ErrStatesUnmapped is returned when works in some states can not be migrated to the target version of workflow
+type ErrStatesUnmapped struct {
+	States []string
+}
+
+func (e *ErrStatesUnmapped) Error() string {
+	return "states are not mapped: " + strings.Join(e.States, ", ")
+}
+func (e *ErrStatesUnmapped) Respond() *BizErrorDetail {
+	return &BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.states_unmapped", Message: e.Error(), Data: e.States}
+}
+
+// ErrChangesUnsupported is returned when a definition can not be applied because some of its changes are not supported
+type ErrChangesUnsupported struct {
+	Changes []string
+}
+
+func (e *ErrChangesUnsupported) Error() string {
+	return "changes are not supported: " + strings.Join(e.Changes, "; ")
+}
+func (e *ErrChangesUnsupported) Respond() *BizErrorDetail {
+	return &BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.changes_unsupported", Message: e.Error(), Data: e.Changes}
+}
+
+// ErrStateReferenced is returned when a state is going to be deleted but the history of works still refers to it
+type ErrStateReferenced struct {
+	State         string
+	ProcessSteps  int
+	ArchivedWorks int
+}
+
+func (e *ErrStateReferenced) Error() string {
+	return "state " + e.State + " is referenced by " + strconv.Itoa(e.ProcessSteps) + " process steps and " +
+		strconv.Itoa(e.ArchivedWorks) + " archived works"
+}
+func (e *ErrStateReferenced) Respond() *BizErrorDetail {
+	return &BizErrorDetail{Status: http.StatusConflict, Code: "workflow.state_referenced", Message: e.Error(),
+		Data: map[string]int{"processSteps": e.ProcessSteps, "archivedWorks": e.ArchivedWorks}}
+}
+
+// ErrPropertiesRequired is returned when a work enters a state but some properties required by the state have no value
+type ErrPropertiesRequired struct {
+	State      string
+	Properties []string
+}
+
+func (e *ErrPropertiesRequired) Error() string {
+	return "properties are required by state " + e.State + ": " + strings.Join(e.Properties, ", ")
+}
+func (e *ErrPropertiesRequired) Respond() *BizErrorDetail {
+	return &BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.properties_required", Message: e.Error(), Data: e.Properties}
+}
+
+// ErrWipLimitExceeded is returned when a work is going to enter a state which is full
+type ErrWipLimitExceeded struct {
+	State    string
+	WipLimit int
+	Works    int
+}
+
+func (e *ErrWipLimitExceeded) Error() string {
+	return "wip limit of state " + e.State + " is exceeded: " + strconv.Itoa(e.Works) + " works, limit " + strconv.Itoa(e.WipLimit)
+}
+func (e *ErrWipLimitExceeded) Respond() *BizErrorDetail {
+	return &BizErrorDetail{Status: http.StatusConflict, Code: "workflow.wip_limit_exceeded", Message: e.Error(),
+		Data: map[string]int{"works": e.Works, "wipLimit": e.WipLimit}}
+}
+
+// ErrHookUnsupported is returned when a transition is going to perform a hook of unknown type
+type ErrHookUnsupported struct {
+	Transition string
+	Hook       string
+}
+
+func (e *ErrHookUnsupported) Error() string {
+	return "hook " + e.Hook + " of transition " + e.Transition + " is not supported"
+}
+func (e *ErrHookUnsupported) Respond() *BizErrorDetail {
+	return &BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.hook_unsupported", Message: e.Error(), Data: e.Hook}
+}
+
+// ErrStateCategoryReferenced is returned when a custom state category is going to be deleted but states or works still refer to it
+type ErrStateCategoryReferenced struct {
+	Category string
+	States   int
+	Works    int
+}
+
+func (e *ErrStateCategoryReferenced) Error() string {
+	return "state category " + e.Category + " is referenced by " + strconv.Itoa(e.States) + " states and " +
+		strconv.Itoa(e.Works) + " works"
+}
+func (e *ErrStateCategoryReferenced) Respond() *BizErrorDetail {
+	return &BizErrorDetail{Status: http.StatusConflict, Code: "workflow.state_category_referenced", Message: e.Error(),
+		Data: map[string]int{"states": e.States, "works": e.Works}}
+}
+
+// ErrWorkParentInvalid is returned when a work can not be the parent of another work
+type ErrWorkParentInvalid struct {
+	Reason string
+}
+
+func (e *ErrWorkParentInvalid) Error() string {
+	return "parent of work is invalid: " + e.Reason
+}
+func (e *ErrWorkParentInvalid) Respond() *BizErrorDetail {
+	return &BizErrorDetail{Status: http.StatusBadRequest, Code: "work.parent_invalid", Message: e.Error(), Data: e.Reason}
+}
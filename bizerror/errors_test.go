@@ -2,6 +2,8 @@ package bizerror_test
 
 import (
 	"flywheel/bizerror"
+	"net/http"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -20,3 +22,75 @@ var _ = Describe("Errors", func() {
 		})
 	})
 })
+
+var _ = Describe("ErrTransitionRefused", func() {
+	It("should describe all reasons", func() {
+		err := &bizerror.ErrTransitionRefused{Transition: "finish", Reasons: []string{"2 check items are not done", "property resolution is not set"}}
+		Expect(err.Error()).To(Equal("transition finish is refused: 2 check items are not done; property resolution is not set"))
+		Expect(*err.Respond()).To(Equal(bizerror.BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.transition_refused",
+			Message: err.Error(), Data: err.Reasons}))
+	})
+})
+
+var _ = Describe("ErrStatesUnmapped", func() {
+	It("should describe all unmapped states", func() {
+		err := &bizerror.ErrStatesUnmapped{States: []string{"REVIEW", "TESTING"}}
+		Expect(err.Error()).To(Equal("states are not mapped: REVIEW, TESTING"))
+		Expect(*err.Respond()).To(Equal(bizerror.BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.states_unmapped",
+			Message: err.Error(), Data: err.States}))
+	})
+})
+
+var _ = Describe("ErrChangesUnsupported", func() {
+	It("should describe all unsupported changes", func() {
+		err := &bizerror.ErrChangesUnsupported{Changes: []string{"state REVIEW is removed", "category of state DONE is changed"}}
+		Expect(err.Error()).To(Equal("changes are not supported: state REVIEW is removed; category of state DONE is changed"))
+		Expect(*err.Respond()).To(Equal(bizerror.BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.changes_unsupported",
+			Message: err.Error(), Data: err.Changes}))
+	})
+})
+
+var _ = Describe("ErrStateReferenced", func() {
+	It("should describe references of state", func() {
+		err := &bizerror.ErrStateReferenced{State: "REVIEW", ProcessSteps: 3, ArchivedWorks: 1}
+		Expect(err.Error()).To(Equal("state REVIEW is referenced by 3 process steps and 1 archived works"))
+		Expect(*err.Respond()).To(Equal(bizerror.BizErrorDetail{Status: http.StatusConflict, Code: "workflow.state_referenced",
+			Message: err.Error(), Data: map[string]int{"processSteps": 3, "archivedWorks": 1}}))
+	})
+})
+
+var _ = Describe("ErrPropertiesRequired", func() {
+	It("should describe all missing properties", func() {
+		err := &bizerror.ErrPropertiesRequired{State: "DONE", Properties: []string{"resolution", "estimate"}}
+		Expect(err.Error()).To(Equal("properties are required by state DONE: resolution, estimate"))
+		Expect(*err.Respond()).To(Equal(bizerror.BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.properties_required",
+			Message: err.Error(), Data: err.Properties}))
+	})
+})
+
+var _ = Describe("ErrWipLimitExceeded", func() {
+	It("should describe load of state", func() {
+		err := &bizerror.ErrWipLimitExceeded{State: "DOING", WipLimit: 3, Works: 3}
+		Expect(err.Error()).To(Equal("wip limit of state DOING is exceeded: 3 works, limit 3"))
+		Expect(*err.Respond()).To(Equal(bizerror.BizErrorDetail{Status: http.StatusConflict, Code: "workflow.wip_limit_exceeded",
+			Message: err.Error(), Data: map[string]int{"works": 3, "wipLimit": 3}}))
+	})
+})
+
+var _ = Describe("ErrHookUnsupported", func() {
+	It("should name the hook", func() {
+		err := &bizerror.ErrHookUnsupported{Transition: "finish", Hook: "sendMail"}
+		Expect(err.Error()).To(Equal("hook sendMail of transition finish is not supported"))
+		Expect(*err.Respond()).To(Equal(bizerror.BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.hook_unsupported",
+			Message: err.Error(), Data: "sendMail"}))
+	})
+})
+
+var _ = Describe("ErrWorkParentInvalid", func() {
+	It("should describe the reason", func() {
+		err := &bizerror.ErrWorkParentInvalid{Reason: "work is an ancestor of its parent"}
+		Expect(err.Error()).To(Equal("parent of work is invalid: work is an ancestor of its parent"))
+		Expect(*err.Respond()).To(Equal(bizerror.BizErrorDetail{Status: http.StatusBadRequest, Code: "work.parent_invalid",
+			Message: err.Error(), Data: err.Reason}))
+	})
+})
@@ -0,0 +1,55 @@
+package s3
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestLocalStore(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should be able to put, get and delete object", func(t *testing.T) {
+		root, err := ioutil.TempDir("", "local_store_test")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(root)
+		store := &LocalStore{Root: root}
+
+		_, err = store.GetObject("attachments/1/2")
+		Expect(err).To(Equal(ErrObjectNotFound))
+
+		Expect(store.PutObject("attachments/1/2", bytes.NewReader([]byte("hello")))).To(BeNil())
+		Expect(store.PutObject("attachments/1/2", bytes.NewReader([]byte("hello world")))).To(BeNil())
+		r, err := store.GetObject("attachments/1/2")
+		Expect(err).To(BeNil())
+		content, err := ioutil.ReadAll(r)
+		Expect(r.Close()).To(BeNil())
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal("hello world"))
+
+		files, err := ioutil.ReadDir(root + "/attachments/1")
+		Expect(err).To(BeNil())
+		Expect(len(files)).To(Equal(1))
+
+		Expect(store.DeleteObject("attachments/1/2")).To(BeNil())
+		Expect(store.DeleteObject("attachments/1/2")).To(BeNil())
+		_, err = store.GetObject("attachments/1/2")
+		Expect(err).To(Equal(ErrObjectNotFound))
+	})
+
+	t.Run("should keep objects under root", func(t *testing.T) {
+		root, err := ioutil.TempDir("", "local_store_test")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(root)
+		store := &LocalStore{Root: root + "/objects"}
+
+		Expect(store.PutObject("../escaped", bytes.NewReader([]byte("hello")))).To(BeNil())
+		_, err = os.Stat(root + "/objects/escaped")
+		Expect(err).To(BeNil())
+
+		Expect(store.PutObject("dir/", bytes.NewReader([]byte("hello")))).ToNot(BeNil())
+	})
+}
@@ -0,0 +1,117 @@
+package s3
+
+import (
+	"errors"
+	"flywheel/session"
+	"io"
+	"os"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+const (
+	StoreTypeOSS   = "oss"
+	StoreTypeLocal = "local"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+
+	ActiveStore ObjectStore
+
+	GetObjectFunc    func(string, *session.Session) (io.ReadCloser, error)
+	PutObjectFunc    func(string, io.Reader, *session.Session) error
+	DeleteObjectFunc func(string, *session.Session) error
+)
+
+// ObjectStore is the backend which objects like avatars and attachments are saved in,
+// GetObject returns ErrObjectNotFound if the key does not exist
+type ObjectStore interface {
+	GetObject(key string) (io.ReadCloser, error)
+	PutObject(key string, r io.Reader) error
+	DeleteObject(key string) error
+}
+
+// Bootstrap activates the object store selected by env OBJECT_STORE ('oss' or 'local'),
+// the local store is used if neither OBJECT_STORE nor OSS_ENDPOINT is set
+func Bootstrap() {
+	store, err := BuildStoreFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	ActiveStore = store
+
+	GetObjectFunc = GetObject
+	PutObjectFunc = PutObject
+	DeleteObjectFunc = DeleteObject
+}
+
+func BuildStoreFromEnv() (ObjectStore, error) {
+	storeType := os.Getenv("OBJECT_STORE")
+	if storeType == "" {
+		storeType = StoreTypeLocal
+		if os.Getenv("OSS_ENDPOINT") != "" {
+			storeType = StoreTypeOSS
+		}
+	}
+
+	switch storeType {
+	case StoreTypeOSS:
+		bucket, err := BuildBucketFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return &OSSStore{Bucket: bucket}, nil
+	case StoreTypeLocal:
+		root := os.Getenv("LOCAL_STORE_ROOT")
+		if root == "" {
+			root = "data/objects"
+		}
+		return &LocalStore{Root: root}, nil
+	default:
+		return nil, errors.New("unknown object store " + storeType)
+	}
+}
+
+func GetObject(key string, s *session.Session) (io.ReadCloser, error) {
+	childSpan := startObjectSpan("get-object-async", key, s)
+	r, err := ActiveStore.GetObject(key)
+	finishObjectSpan(childSpan, err)
+	return r, err
+}
+
+func PutObject(key string, r io.Reader, s *session.Session) error {
+	childSpan := startObjectSpan("put-object-async", key, s)
+	err := ActiveStore.PutObject(key, r)
+	finishObjectSpan(childSpan, err)
+	return err
+}
+
+func DeleteObject(key string, s *session.Session) error {
+	childSpan := startObjectSpan("delete-object-async", key, s)
+	err := ActiveStore.DeleteObject(key)
+	finishObjectSpan(childSpan, err)
+	return err
+}
+
+func startObjectSpan(operation, key string, s *session.Session) opentracing.Span {
+	if s.Context == nil {
+		return nil
+	}
+	parentSpan := opentracing.SpanFromContext(s.Context)
+	if parentSpan == nil {
+		return nil
+	}
+	sp := parentSpan.Tracer().StartSpan(operation, opentracing.ChildOf(parentSpan.Context()))
+	sp.SetTag("object-key", key)
+	return sp
+}
+
+func finishObjectSpan(sp opentracing.Span, err error) {
+	if sp == nil {
+		return
+	}
+	ext.Error.Set(sp, err != nil)
+	sp.Finish()
+}
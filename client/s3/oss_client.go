@@ -1,31 +1,32 @@
 package s3
 
 import (
-	"flywheel/session"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
 )
 
-var (
-	AvatarBucket  *oss.Bucket
-	GetObjectFunc func(string, *session.Session, ...oss.Option) (io.ReadCloser, error)
-	PutObjectFunc func(string, io.Reader, *session.Session, ...oss.Option) error
-)
+// OSSStore saves objects in a bucket of Aliyun OSS
+type OSSStore struct {
+	Bucket *oss.Bucket
+}
 
-func Bootstrap() {
-	var err error
-	AvatarBucket, err = BuildBucketFromEnv()
-	if err != nil {
-		panic(err)
+func (o *OSSStore) GetObject(key string) (io.ReadCloser, error) {
+	r, err := o.Bucket.GetObject(key)
+	if serErr, ok := err.(oss.ServiceError); ok && serErr.Code == "NoSuchKey" {
+		return nil, ErrObjectNotFound
 	}
+	return r, err
+}
 
-	GetObjectFunc = GetObject
-	PutObjectFunc = PutObject
+func (o *OSSStore) PutObject(key string, r io.Reader) error {
+	return o.Bucket.PutObject(key, r)
+}
+
+func (o *OSSStore) DeleteObject(key string) error {
+	return o.Bucket.DeleteObject(key)
 }
 
 func BuildBucketFromEnv() (*oss.Bucket, error) {
@@ -56,46 +57,6 @@ func BuildBucket(endpoint, accesskey, secretKey, bucketName string) (*oss.Bucket
 	return bucket, nil
 }
 
-func GetObject(key string, s *session.Session, opts ...oss.Option) (io.ReadCloser, error) {
-	var childSpan *opentracing.Span
-	if s.Context != nil {
-		parentSpan := opentracing.SpanFromContext(s.Context)
-		if parentSpan != nil {
-			tracer := parentSpan.Tracer()
-			sp := tracer.StartSpan("get-object-async", opentracing.ChildOf(parentSpan.Context()))
-			sp.SetTag("object-key", key)
-			childSpan = &sp
-			defer sp.Finish()
-		}
-	}
-
-	r, err := AvatarBucket.GetObject(key, opts...)
-	if childSpan != nil {
-		ext.Error.Set(*childSpan, err != nil)
-	}
-	return r, err
-}
-
-func PutObject(key string, r io.Reader, s *session.Session, opts ...oss.Option) error {
-	var childSpan *opentracing.Span
-	if s.Context != nil {
-		parentSpan := opentracing.SpanFromContext(s.Context)
-		if parentSpan != nil {
-			tracer := parentSpan.Tracer()
-			sp := tracer.StartSpan("put-object-async", opentracing.ChildOf(parentSpan.Context()))
-			sp.SetTag("object-key", key)
-			childSpan = &sp
-			defer sp.Finish()
-		}
-	}
-
-	err := AvatarBucket.PutObject(key, r, opts...)
-	if childSpan != nil {
-		ext.Error.Set(*childSpan, err != nil)
-	}
-	return err
-}
-
 func ListObject(bucket *oss.Bucket, prefix string) error {
 	marker := oss.Marker("")
 	pre := oss.Prefix(prefix)
@@ -24,6 +24,6 @@ var GenericWorkflowTemplate = WorkflowTemplateDetail{
 		{Name: "reopen", From: StateDone.Name, To: StatePending.Name},
 	}),
 	PropertyDefinitions: []PropertyDefinition{
-		{Name: "description"}, {Name: "creatorId"},
+		{Name: "description", Type: PropTypeTextArea}, {Name: "creatorId", Type: PropTypeText},
 	},
 }
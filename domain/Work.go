@@ -13,7 +13,12 @@ type Work struct {
 	ProjectID  types.ID        `json:"projectId"`
 	CreateTime types.Timestamp `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
 
+	// ParentID is the work which this work is a child of, it is zero for the top level works
+	ParentID types.ID `json:"parentId" gorm:"index"`
+
 	FlowID types.ID `json:"flowId"`
+	// FlowVersion is the version of workflow which the work is running on, changed only by migration
+	FlowVersion int `json:"flowVersion"`
 
 	// bigger OrderInState means lower priority
 	// max integer number in javascript is:        9007199254740991 (2^53-1)
@@ -28,3 +33,22 @@ type Work struct {
 
 	ArchiveTime types.Timestamp `json:"archivedTime" sql:"type:DATETIME(6)"`
 }
+
+// EnterProcessState maintains the process begin time and the process end time of work which enters a state of the category
+// at the time, custom categories behave as their base categories. The changed columns are returned to be saved.
+func (w *Work) EnterProcessState(category state.Category, now types.Timestamp) map[string]interface{} {
+	updates := map[string]interface{}{}
+	baseCategory := state.BaseCategory(category)
+	if w.ProcessBeginTime.IsZero() && baseCategory != state.InBacklog {
+		w.ProcessBeginTime = now
+		updates["process_begin_time"] = now
+	}
+	if w.ProcessEndTime.IsZero() && baseCategory == state.Done {
+		w.ProcessEndTime = now
+		updates["process_end_time"] = now
+	} else if !w.ProcessEndTime.IsZero() && baseCategory != state.Done {
+		w.ProcessEndTime = types.Timestamp{}
+		updates["process_end_time"] = nil
+	}
+	return updates
+}
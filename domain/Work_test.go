@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"flywheel/domain/state"
+	"testing"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestWork_EnterProcessState(t *testing.T) {
+	RegisterTestingT(t)
+
+	begin := types.Timestamp(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	now := types.Timestamp(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	t.Run("should begin process when work leaves backlog", func(t *testing.T) {
+		w := Work{}
+		Expect(w.EnterProcessState(state.InBacklog, now)).To(BeEmpty())
+		Expect(w.ProcessBeginTime.IsZero()).To(BeTrue())
+
+		Expect(w.EnterProcessState(state.InProcess, now)).To(Equal(map[string]interface{}{"process_begin_time": now}))
+		Expect(w.ProcessBeginTime).To(Equal(now))
+		Expect(w.ProcessEndTime.IsZero()).To(BeTrue())
+	})
+
+	t.Run("should end process when work is done", func(t *testing.T) {
+		w := Work{ProcessBeginTime: begin}
+		Expect(w.EnterProcessState(state.Done, now)).To(Equal(map[string]interface{}{"process_end_time": now}))
+		Expect(w.ProcessBeginTime).To(Equal(begin))
+		Expect(w.ProcessEndTime).To(Equal(now))
+
+		Expect(w.EnterProcessState(state.Done, types.CurrentTimestamp())).To(BeEmpty())
+		Expect(w.ProcessEndTime).To(Equal(now))
+	})
+
+	t.Run("should reopen process when done work goes back", func(t *testing.T) {
+		w := Work{ProcessBeginTime: begin, ProcessEndTime: begin}
+		Expect(w.EnterProcessState(state.InBacklog, now)).To(Equal(map[string]interface{}{"process_end_time": nil}))
+		Expect(w.ProcessBeginTime).To(Equal(begin))
+		Expect(w.ProcessEndTime.IsZero()).To(BeTrue())
+	})
+}
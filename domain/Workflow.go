@@ -1,7 +1,10 @@
 package domain
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"flywheel/domain/state"
+	"fmt"
 	"time"
 
 	"github.com/fundwit/go-commons/types"
@@ -15,6 +18,9 @@ type Workflow struct {
 
 	ProjectID  types.ID  `json:"projectId"`
 	CreateTime time.Time `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
+
+	// Version is increased each time the states, transitions or property definitions are changed
+	Version int `json:"version"`
 }
 
 type WorkflowDetail struct {
@@ -22,6 +28,17 @@ type WorkflowDetail struct {
 
 	PropertyDefinitions []PropertyDefinition `json:"propertyDefinitions"`
 	StateMachine        state.StateMachine   `json:"stateMachine"`
+
+	// StateLoads are only appended in the detail api of workflow
+	StateLoads []StateLoad `json:"stateLoads,omitempty"`
+}
+
+// StateLoad is the number of unarchived works in the state against the wip limit of it
+type StateLoad struct {
+	State    string `json:"state"`
+	Works    int    `json:"works"`
+	WipLimit int    `json:"wipLimit"`
+	Exceeded bool   `json:"exceeded"`
 }
 
 type WorkflowState struct {
@@ -31,6 +48,12 @@ type WorkflowState struct {
 
 	Category   state.Category `json:"category"`
 	CreateTime time.Time      `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
+
+	WipLimit int  `json:"wipLimit"`
+	WipSoft  bool `json:"wipSoft"`
+
+	Reasons state.Reasons `json:"reasons" sql:"type:TEXT"`
+	Parent  string        `json:"parent"`
 }
 
 type WorkflowStateTransition struct {
@@ -40,6 +63,44 @@ type WorkflowStateTransition struct {
 
 	Name       string    `json:"name"`
 	CreateTime time.Time `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
+
+	Guards   state.Guards   `json:"guards" sql:"type:TEXT"`
+	Hooks    state.Hooks    `json:"hooks" sql:"type:TEXT"`
+	Triggers state.Triggers `json:"triggers" sql:"type:TEXT"`
+}
+
+// WorkflowVersion is the frozen definition of a superseded version of workflow,
+// the definition of current version is the live one in workflow_states, workflow_state_transitions and property definitions
+type WorkflowVersion struct {
+	WorkflowID types.ID           `json:"workflowId" gorm:"primary_key" sql:"type:BIGINT UNSIGNED NOT NULL"`
+	Version    int                `json:"version" gorm:"primary_key;auto_increment:false"`
+	Definition WorkflowDefinition `json:"definition" sql:"type:MEDIUMTEXT"`
+	CreateTime time.Time          `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
+}
+
+type WorkflowDefinition struct {
+	StateMachine        state.StateMachine   `json:"stateMachine"`
+	PropertyDefinitions []PropertyDefinition `json:"propertyDefinitions"`
+}
+
+func (d WorkflowDefinition) Value() (driver.Value, error) {
+	jsonBytes, err := json.Marshal(&d)
+	if err != nil {
+		return nil, err
+	}
+	return string(jsonBytes), nil
+}
+
+func (d *WorkflowDefinition) Scan(v interface{}) error {
+	jsonString, ok := v.(string)
+	if !ok {
+		jsonByte, ok := v.([]byte)
+		if !ok {
+			return fmt.Errorf("type is neither string nor []byte: %T %v", v, v)
+		}
+		jsonString = string(jsonByte)
+	}
+	return json.Unmarshal([]byte(jsonString), d)
 }
 
 func (f *WorkflowDetail) FindState(stateName string) (state.State, bool) {
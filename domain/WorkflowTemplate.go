@@ -14,10 +14,16 @@ type WorkflowTemplateDetail struct {
 	StateMachine        state.StateMachine   `json:"stateMachine"`
 }
 
+// WorkflowTemplate is shared system wide when ProjectID is 0, otherwise it is only visible in the project
 type WorkflowTemplate struct {
 	ID   types.ID `json:"id" gorm:"primary_key" sql:"type:BIGINT UNSIGNED NOT NULL"`
 	Name string   `json:"name"`
 
 	ProjectID  types.ID  `json:"projectId"`
+	CreatorID  types.ID  `json:"creatorId"`
 	CreateTime time.Time `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
 }
+
+func (t *WorkflowTemplate) IsSystemWide() bool {
+	return t.ProjectID == 0
+}
@@ -0,0 +1,287 @@
+package flow
+
+import (
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+	ChangeRenamed  = "renamed"
+
+	ChangeKindWorkflow   = "workflow"
+	ChangeKindState      = "state"
+	ChangeKindTransition = "transition"
+	ChangeKindProperty   = "property"
+)
+
+// DefinitionChange is a difference between two definitions of workflow, Old is nil for added item and New is nil for removed item
+type DefinitionChange struct {
+	Action string      `json:"action"`
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Old    interface{} `json:"old,omitempty"`
+	New    interface{} `json:"new,omitempty"`
+	// Fields are the changed fields of modified or renamed item, they are only reported by CompareWorkflowDefinitions
+	Fields []string `json:"fields,omitempty"`
+}
+
+// DiffWorkflowDefinition compares states, transitions and property definitions, states are identified by name,
+// transitions are identified by from state and to state, property definitions are identified by name.
+func DiffWorkflowDefinition(origin, target *domain.WorkflowDefinition) []DefinitionChange {
+	changes := []DefinitionChange{}
+
+	originStates := map[string]state.State{}
+	for _, s := range origin.StateMachine.States {
+		originStates[s.Name] = s
+	}
+	targetStates := map[string]bool{}
+	for _, s := range target.StateMachine.States {
+		targetStates[s.Name] = true
+		if o, found := originStates[s.Name]; !found {
+			changes = append(changes, DefinitionChange{Action: ChangeAdded, Kind: ChangeKindState, Name: s.Name, New: s})
+		} else if !isStateEqual(o, s) {
+			changes = append(changes, DefinitionChange{Action: ChangeModified, Kind: ChangeKindState, Name: s.Name, Old: o, New: s})
+		}
+	}
+	for _, s := range origin.StateMachine.States {
+		if !targetStates[s.Name] {
+			changes = append(changes, DefinitionChange{Action: ChangeRemoved, Kind: ChangeKindState, Name: s.Name, Old: s})
+		}
+	}
+
+	originTransitions := map[string]state.Transition{}
+	for _, t := range origin.StateMachine.Transitions {
+		originTransitions[t.From+"\n"+t.To] = t
+	}
+	targetTransitions := map[string]bool{}
+	for _, t := range target.StateMachine.Transitions {
+		targetTransitions[t.From+"\n"+t.To] = true
+		if o, found := originTransitions[t.From+"\n"+t.To]; !found {
+			changes = append(changes, DefinitionChange{Action: ChangeAdded, Kind: ChangeKindTransition, Name: t.Name, New: t})
+		} else if !isTransitionEqual(o, t) {
+			changes = append(changes, DefinitionChange{Action: ChangeModified, Kind: ChangeKindTransition, Name: t.Name, Old: o, New: t})
+		}
+	}
+	for _, t := range origin.StateMachine.Transitions {
+		if !targetTransitions[t.From+"\n"+t.To] {
+			changes = append(changes, DefinitionChange{Action: ChangeRemoved, Kind: ChangeKindTransition, Name: t.Name, Old: t})
+		}
+	}
+
+	originProperties := map[string]domain.PropertyDefinition{}
+	for _, p := range origin.PropertyDefinitions {
+		originProperties[p.Name] = p
+	}
+	targetProperties := map[string]bool{}
+	for _, p := range target.PropertyDefinitions {
+		targetProperties[p.Name] = true
+		if o, found := originProperties[p.Name]; !found {
+			changes = append(changes, DefinitionChange{Action: ChangeAdded, Kind: ChangeKindProperty, Name: p.Name, New: p})
+		} else if !isPropertyDefinitionEqual(o, p) {
+			changes = append(changes, DefinitionChange{Action: ChangeModified, Kind: ChangeKindProperty, Name: p.Name, Old: o, New: p})
+		}
+	}
+	for _, p := range origin.PropertyDefinitions {
+		if !targetProperties[p.Name] {
+			changes = append(changes, DefinitionChange{Action: ChangeRemoved, Kind: ChangeKindProperty, Name: p.Name, Old: p})
+		}
+	}
+
+	return changes
+}
+
+// CompareWorkflowDefinitions reports the differences of DiffWorkflowDefinition for review. A removed state and an added
+// state with the same category are reported as a renamed state when they have the same order or the same transitions
+// (by name and direction), the one nearest in order is taken when there are many of them, and the transitions and
+// required states of properties are compared with the new name of state. The other removed and added states are
+// reported as they are. The changed fields of modified and renamed items are listed.
+func CompareWorkflowDefinitions(origin, target *domain.WorkflowDefinition) []DefinitionChange {
+	originNames := map[string]bool{}
+	for _, s := range origin.StateMachine.States {
+		originNames[s.Name] = true
+	}
+	targetNames := map[string]bool{}
+	for _, s := range target.StateMachine.States {
+		targetNames[s.Name] = true
+	}
+
+	renames := map[string]string{}
+	changes := []DefinitionChange{}
+	for _, o := range origin.StateMachine.States {
+		if targetNames[o.Name] {
+			continue
+		}
+		matched := -1
+		signature := transitionSignature(&origin.StateMachine, o.Name)
+		for i, t := range target.StateMachine.States {
+			if originNames[t.Name] || t.Category != o.Category {
+				continue
+			}
+			if t.Order != o.Order && (signature == "" || signature != transitionSignature(&target.StateMachine, t.Name)) {
+				continue
+			}
+			if matched < 0 || orderDistance(o, t) < orderDistance(o, target.StateMachine.States[matched]) {
+				matched = i
+			}
+		}
+		if matched < 0 {
+			continue
+		}
+		t := target.StateMachine.States[matched]
+		renames[o.Name] = t.Name
+		originNames[t.Name] = true // claimed
+		changes = append(changes, DefinitionChange{Action: ChangeRenamed, Kind: ChangeKindState, Name: t.Name, Old: o, New: t,
+			Fields: stateChangedFields(o, t)})
+	}
+	rename := func(name string) string {
+		if n, found := renames[name]; found {
+			return n
+		}
+		return name
+	}
+
+	renamed := domain.WorkflowDefinition{}
+	renamedStates := map[string]bool{}
+	for _, s := range origin.StateMachine.States {
+		if n, found := renames[s.Name]; found {
+			renamedStates[n] = true
+			s.Name = n
+		}
+		renamed.StateMachine.States = append(renamed.StateMachine.States, s)
+	}
+	originTransitions := map[string]state.Transition{}
+	for _, t := range origin.StateMachine.Transitions {
+		r := t
+		r.From, r.To = rename(t.From), rename(t.To)
+		originTransitions[r.From+"\n"+r.To] = t
+		renamed.StateMachine.Transitions = append(renamed.StateMachine.Transitions, r)
+	}
+	originProperties := map[string]domain.PropertyDefinition{}
+	for _, p := range origin.PropertyDefinitions {
+		originProperties[p.Name] = p
+		if len(p.RequiredStates) > 0 {
+			requiredStates := domain.PropertyStates{}
+			for _, name := range p.RequiredStates {
+				requiredStates = append(requiredStates, rename(name))
+			}
+			p.RequiredStates = requiredStates
+		}
+		renamed.PropertyDefinitions = append(renamed.PropertyDefinitions, p)
+	}
+
+	for _, c := range DiffWorkflowDefinition(&renamed, target) {
+		switch c.Kind {
+		case ChangeKindState:
+			if renamedStates[c.Name] {
+				continue // reported as renamed
+			}
+			if c.Action == ChangeModified {
+				c.Fields = stateChangedFields(c.Old.(state.State), c.New.(state.State))
+			}
+		case ChangeKindTransition:
+			// fields are compared with the renamed one, but the original one is reported
+			if c.Action == ChangeModified {
+				c.Fields = transitionChangedFields(c.Old.(state.Transition), c.New.(state.Transition))
+			}
+			if c.Old != nil {
+				r := c.Old.(state.Transition)
+				c.Old = originTransitions[r.From+"\n"+r.To]
+			}
+		case ChangeKindProperty:
+			if c.Action == ChangeModified {
+				c.Fields = propertyChangedFields(c.Old.(domain.PropertyDefinition), c.New.(domain.PropertyDefinition))
+			}
+			if c.Old != nil {
+				c.Old = originProperties[c.Name]
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// transitionSignature lists the names and directions of the transitions of state, the names of other states are not
+// involved, so that it is kept when the states around are renamed too
+func transitionSignature(sm *state.StateMachine, stateName string) string {
+	var signature []string
+	for _, t := range sm.Transitions {
+		if t.From == stateName {
+			signature = append(signature, "out "+t.Name)
+		}
+		if t.To == stateName {
+			signature = append(signature, "in "+t.Name)
+		}
+	}
+	sort.Strings(signature)
+	return strings.Join(signature, "\n")
+}
+
+func orderDistance(a, b state.State) int {
+	if a.Order > b.Order {
+		return a.Order - b.Order
+	}
+	return b.Order - a.Order
+}
+
+func stateChangedFields(a, b state.State) []string {
+	var fields []string
+	fields = appendChangedField(fields, "name", a.Name == b.Name)
+	fields = appendChangedField(fields, "category", a.Category == b.Category)
+	fields = appendChangedField(fields, "order", a.Order == b.Order)
+	fields = appendChangedField(fields, "wipLimit", a.WipLimit == b.WipLimit)
+	fields = appendChangedField(fields, "wipSoft", a.WipSoft == b.WipSoft)
+	fields = appendChangedField(fields, "reasons", len(a.Reasons) == 0 && len(b.Reasons) == 0 || reflect.DeepEqual(a.Reasons, b.Reasons))
+	fields = appendChangedField(fields, "parent", a.Parent == b.Parent)
+	return fields
+}
+
+func transitionChangedFields(a, b state.Transition) []string {
+	var fields []string
+	fields = appendChangedField(fields, "name", a.Name == b.Name)
+	fields = appendChangedField(fields, "guards", len(a.Guards) == 0 && len(b.Guards) == 0 || reflect.DeepEqual(a.Guards, b.Guards))
+	fields = appendChangedField(fields, "hooks", len(a.Hooks) == 0 && len(b.Hooks) == 0 || reflect.DeepEqual(a.Hooks, b.Hooks))
+	fields = appendChangedField(fields, "triggers", len(a.Triggers) == 0 && len(b.Triggers) == 0 || reflect.DeepEqual(a.Triggers, b.Triggers))
+	return fields
+}
+
+func propertyChangedFields(a, b domain.PropertyDefinition) []string {
+	var fields []string
+	fields = appendChangedField(fields, "type", a.Type == b.Type)
+	fields = appendChangedField(fields, "title", a.Title == b.Title)
+	fields = appendChangedField(fields, "options", len(a.Options) == 0 && len(b.Options) == 0 || reflect.DeepEqual(a.Options, b.Options))
+	fields = appendChangedField(fields, "defaultValue", a.DefaultValue == b.DefaultValue)
+	fields = appendChangedField(fields, "requiredStates",
+		len(a.RequiredStates) == 0 && len(b.RequiredStates) == 0 || reflect.DeepEqual(a.RequiredStates, b.RequiredStates))
+	return fields
+}
+
+func appendChangedField(fields []string, name string, equal bool) []string {
+	if equal {
+		return fields
+	}
+	return append(fields, name)
+}
+
+func isStateEqual(a, b state.State) bool {
+	return a.Name == b.Name && a.Category == b.Category && a.Order == b.Order && a.WipLimit == b.WipLimit && a.WipSoft == b.WipSoft && a.Parent == b.Parent &&
+		(len(a.Reasons) == 0 && len(b.Reasons) == 0 || reflect.DeepEqual(a.Reasons, b.Reasons))
+}
+
+func isTransitionEqual(a, b state.Transition) bool {
+	return a.Name == b.Name && a.From == b.From && a.To == b.To &&
+		(len(a.Guards) == 0 && len(b.Guards) == 0 || reflect.DeepEqual(a.Guards, b.Guards)) &&
+		(len(a.Hooks) == 0 && len(b.Hooks) == 0 || reflect.DeepEqual(a.Hooks, b.Hooks)) &&
+		(len(a.Triggers) == 0 && len(b.Triggers) == 0 || reflect.DeepEqual(a.Triggers, b.Triggers))
+}
+
+func isPropertyDefinitionEqual(a, b domain.PropertyDefinition) bool {
+	return a.Name == b.Name && a.Type == b.Type && a.Title == b.Title && a.DefaultValue == b.DefaultValue &&
+		(len(a.RequiredStates) == 0 && len(b.RequiredStates) == 0 || reflect.DeepEqual(a.RequiredStates, b.RequiredStates)) &&
+		(len(a.Options) == 0 && len(b.Options) == 0 || reflect.DeepEqual(a.Options, b.Options))
+}
@@ -0,0 +1,150 @@
+package flow_test
+
+import (
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDiffWorkflowDefinition(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should report no change for same definitions", func(t *testing.T) {
+		origin := &domain.WorkflowDefinition{StateMachine: domain.GenericWorkflowTemplate.StateMachine,
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "a", Type: domain.PropTypeText, Options: domain.PropertyOptions{}}}}
+		target := &domain.WorkflowDefinition{StateMachine: domain.GenericWorkflowTemplate.StateMachine,
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "a", Type: domain.PropTypeText}}}
+		Expect(flow.DiffWorkflowDefinition(origin, target)).To(Equal([]flow.DefinitionChange{}))
+	})
+
+	t.Run("should report added, modified and removed items", func(t *testing.T) {
+		origin := &domain.WorkflowDefinition{
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1}, {Name: "CLOSED", Category: state.Done, Order: 2},
+					{Name: "REVIEW", Category: state.InProcess, Order: 3}},
+				Transitions: []state.Transition{{Name: "close", From: "OPEN", To: "CLOSED"}, {Name: "pass", From: "REVIEW", To: "CLOSED"}},
+			},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "a", Type: domain.PropTypeText}, {Name: "b", Type: domain.PropTypeText}},
+		}
+		target := &domain.WorkflowDefinition{
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1}, {Name: "CLOSED", Category: state.Done, Order: 3},
+					{Name: "DOING", Category: state.InProcess, Order: 2}},
+				Transitions: []state.Transition{{Name: "close", From: "OPEN", To: "CLOSED", Guards: state.Guards{{Type: state.GuardChecklistDone}}},
+					{Name: "begin", From: "OPEN", To: "DOING"}},
+			},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "a", Type: domain.PropTypeNumber}, {Name: "c", Type: domain.PropTypeText}},
+		}
+		Expect(flow.DiffWorkflowDefinition(origin, target)).To(Equal([]flow.DefinitionChange{
+			{Action: flow.ChangeModified, Kind: flow.ChangeKindState, Name: "CLOSED", Old: origin.StateMachine.States[1], New: target.StateMachine.States[1]},
+			{Action: flow.ChangeAdded, Kind: flow.ChangeKindState, Name: "DOING", New: target.StateMachine.States[2]},
+			{Action: flow.ChangeRemoved, Kind: flow.ChangeKindState, Name: "REVIEW", Old: origin.StateMachine.States[2]},
+			{Action: flow.ChangeModified, Kind: flow.ChangeKindTransition, Name: "close", Old: origin.StateMachine.Transitions[0], New: target.StateMachine.Transitions[0]},
+			{Action: flow.ChangeAdded, Kind: flow.ChangeKindTransition, Name: "begin", New: target.StateMachine.Transitions[1]},
+			{Action: flow.ChangeRemoved, Kind: flow.ChangeKindTransition, Name: "pass", Old: origin.StateMachine.Transitions[1]},
+			{Action: flow.ChangeModified, Kind: flow.ChangeKindProperty, Name: "a", Old: origin.PropertyDefinitions[0], New: target.PropertyDefinitions[0]},
+			{Action: flow.ChangeAdded, Kind: flow.ChangeKindProperty, Name: "c", New: target.PropertyDefinitions[1]},
+			{Action: flow.ChangeRemoved, Kind: flow.ChangeKindProperty, Name: "b", Old: origin.PropertyDefinitions[1]},
+		}))
+	})
+}
+
+func TestCompareWorkflowDefinitions(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should report renamed states and changed fields", func(t *testing.T) {
+		origin := &domain.WorkflowDefinition{
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1}, {Name: "CLOSED", Category: state.Done, Order: 2},
+					{Name: "REVIEW", Category: state.InProcess, Order: 3}},
+				Transitions: []state.Transition{{Name: "close", From: "OPEN", To: "CLOSED"}, {Name: "pass", From: "REVIEW", To: "CLOSED"}},
+			},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "a", Type: domain.PropTypeText, RequiredStates: domain.PropertyStates{"CLOSED"}},
+				{Name: "b", Type: domain.PropTypeText}},
+		}
+		target := &domain.WorkflowDefinition{
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InProcess, Order: 1}, {Name: "DONE", Category: state.Done, Order: 2, WipLimit: 3},
+					{Name: "TESTING", Category: state.Done, Order: 4}},
+				Transitions: []state.Transition{{Name: "finish", From: "OPEN", To: "DONE", Guards: state.Guards{{Type: state.GuardProjectRole, Name: domain.ProjectRoleManager}}},
+					{Name: "pass", From: "TESTING", To: "DONE"}},
+			},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "a", Type: domain.PropTypeText, RequiredStates: domain.PropertyStates{"DONE"}},
+				{Name: "b", Type: domain.PropTypeSelect, Options: domain.PropertyOptions{domain.OptionKeySelectEnum: []interface{}{"x"}}}},
+		}
+		Expect(flow.CompareWorkflowDefinitions(origin, target)).To(Equal([]flow.DefinitionChange{
+			{Action: flow.ChangeRenamed, Kind: flow.ChangeKindState, Name: "DONE", Old: origin.StateMachine.States[1], New: target.StateMachine.States[1],
+				Fields: []string{"name", "wipLimit"}},
+			{Action: flow.ChangeModified, Kind: flow.ChangeKindState, Name: "OPEN", Old: origin.StateMachine.States[0], New: target.StateMachine.States[0],
+				Fields: []string{"category"}},
+			{Action: flow.ChangeAdded, Kind: flow.ChangeKindState, Name: "TESTING", New: target.StateMachine.States[2]},
+			{Action: flow.ChangeRemoved, Kind: flow.ChangeKindState, Name: "REVIEW", Old: origin.StateMachine.States[2]},
+			{Action: flow.ChangeModified, Kind: flow.ChangeKindTransition, Name: "finish", Old: origin.StateMachine.Transitions[0], New: target.StateMachine.Transitions[0],
+				Fields: []string{"name", "guards"}},
+			{Action: flow.ChangeAdded, Kind: flow.ChangeKindTransition, Name: "pass", New: target.StateMachine.Transitions[1]},
+			{Action: flow.ChangeRemoved, Kind: flow.ChangeKindTransition, Name: "pass", Old: origin.StateMachine.Transitions[1]},
+			{Action: flow.ChangeModified, Kind: flow.ChangeKindProperty, Name: "b", Old: origin.PropertyDefinitions[1], New: target.PropertyDefinitions[1],
+				Fields: []string{"type", "options"}},
+		}))
+	})
+
+	t.Run("should report renamed states which are reordered", func(t *testing.T) {
+		origin := &domain.WorkflowDefinition{
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1}, {Name: "CODING", Category: state.InProcess, Order: 2},
+					{Name: "REVIEW", Category: state.InProcess, Order: 3}, {Name: "CLOSED", Category: state.Done, Order: 4}},
+				Transitions: []state.Transition{{Name: "begin", From: "OPEN", To: "CODING"}, {Name: "submit", From: "CODING", To: "REVIEW"},
+					{Name: "pass", From: "REVIEW", To: "CLOSED"}},
+			},
+		}
+		target := &domain.WorkflowDefinition{
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1}, {Name: "CLOSED", Category: state.Done, Order: 2},
+					{Name: "CHECKING", Category: state.InProcess, Order: 4}, {Name: "DEVELOPING", Category: state.InProcess, Order: 3}},
+				Transitions: []state.Transition{{Name: "begin", From: "OPEN", To: "DEVELOPING"}, {Name: "submit", From: "DEVELOPING", To: "CHECKING"},
+					{Name: "pass", From: "CHECKING", To: "CLOSED"}},
+			},
+		}
+		Expect(flow.CompareWorkflowDefinitions(origin, target)).To(Equal([]flow.DefinitionChange{
+			{Action: flow.ChangeRenamed, Kind: flow.ChangeKindState, Name: "DEVELOPING", Old: origin.StateMachine.States[1], New: target.StateMachine.States[3],
+				Fields: []string{"name", "order"}},
+			{Action: flow.ChangeRenamed, Kind: flow.ChangeKindState, Name: "CHECKING", Old: origin.StateMachine.States[2], New: target.StateMachine.States[2],
+				Fields: []string{"name", "order"}},
+			{Action: flow.ChangeModified, Kind: flow.ChangeKindState, Name: "CLOSED", Old: origin.StateMachine.States[3], New: target.StateMachine.States[1],
+				Fields: []string{"order"}},
+		}))
+	})
+
+	t.Run("should report removed and added states without order or transitions in common", func(t *testing.T) {
+		origin := &domain.WorkflowDefinition{
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1}, {Name: "REVIEW", Category: state.InProcess, Order: 2},
+					{Name: "CLOSED", Category: state.Done, Order: 3}},
+				Transitions: []state.Transition{{Name: "submit", From: "OPEN", To: "REVIEW"}, {Name: "pass", From: "REVIEW", To: "CLOSED"}},
+			},
+		}
+		target := &domain.WorkflowDefinition{
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1}, {Name: "CLOSED", Category: state.Done, Order: 3},
+					{Name: "BLOCKED", Category: state.InProcess, Order: 5}},
+				Transitions: []state.Transition{{Name: "block", From: "OPEN", To: "BLOCKED"}, {Name: "close", From: "OPEN", To: "CLOSED"}},
+			},
+		}
+		Expect(flow.CompareWorkflowDefinitions(origin, target)).To(Equal([]flow.DefinitionChange{
+			{Action: flow.ChangeAdded, Kind: flow.ChangeKindState, Name: "BLOCKED", New: target.StateMachine.States[2]},
+			{Action: flow.ChangeRemoved, Kind: flow.ChangeKindState, Name: "REVIEW", Old: origin.StateMachine.States[1]},
+			{Action: flow.ChangeAdded, Kind: flow.ChangeKindTransition, Name: "block", New: target.StateMachine.Transitions[0]},
+			{Action: flow.ChangeAdded, Kind: flow.ChangeKindTransition, Name: "close", New: target.StateMachine.Transitions[1]},
+			{Action: flow.ChangeRemoved, Kind: flow.ChangeKindTransition, Name: "submit", Old: origin.StateMachine.Transitions[0]},
+			{Action: flow.ChangeRemoved, Kind: flow.ChangeKindTransition, Name: "pass", Old: origin.StateMachine.Transitions[1]},
+		}))
+	})
+
+	t.Run("should report no change for same definitions", func(t *testing.T) {
+		origin := &domain.WorkflowDefinition{StateMachine: domain.GenericWorkflowTemplate.StateMachine}
+		Expect(flow.CompareWorkflowDefinitions(origin, origin)).To(Equal([]flow.DefinitionChange{}))
+	})
+}
@@ -1,6 +1,7 @@
 package flow
 
 import (
+	"flywheel/domain"
 	"flywheel/domain/state"
 
 	"github.com/fundwit/go-commons/types"
@@ -12,7 +13,8 @@ type WorkflowCreation struct {
 	ThemeColor string   `json:"themeColor" binding:"required"`
 	ThemeIcon  string   `json:"themeIcon"  binding:"required"`
 
-	StateMachine state.StateMachine `json:"stateMachine" binding:"dive"`
+	StateMachine        state.StateMachine          `json:"stateMachine"                  binding:"dive"`
+	PropertyDefinitions []domain.PropertyDefinition `json:"propertyDefinitions,omitempty" binding:"dive"`
 }
 
 type WorkflowBaseUpdation struct {
@@ -26,6 +28,13 @@ type WorkflowStateUpdating struct {
 
 	Name  string `json:"name"        binding:"required"`
 	Order int    `json:"order"`
+
+	// WipLimit, WipSoft and Reasons are kept unchanged when they are omitted
+	WipLimit *int  `json:"wipLimit"    binding:"omitempty,min=0"`
+	WipSoft  *bool `json:"wipSoft"`
+
+	Reasons *state.Reasons `json:"reasons"`
+	Parent  string         `json:"parent"`
 }
 
 type StateOrderRangeUpdating struct {
@@ -37,5 +46,104 @@ type StateCreating struct {
 	Name        string             `json:"name"         binding:"required"`
 	Category    state.Category     `json:"category"     binding:"required"`
 	Order       int                `json:"order"        binding:"required"`
+	WipLimit    int                `json:"wipLimit"     binding:"min=0"`
+	WipSoft     bool               `json:"wipSoft"`
+	Reasons     state.Reasons      `json:"reasons"`
+	Parent      string             `json:"parent"`
 	Transitions []state.Transition `json:"transitions"  binding:"dive"`
 }
+
+// WorkMigration moves works running on FromVersion of workflow to ToVersion,
+// a work in state X is moved to state StateMapping[X], or to state X of ToVersion if X is not in StateMapping
+type WorkMigration struct {
+	FromVersion  int               `json:"fromVersion"  validate:"min=0"`
+	ToVersion    int               `json:"toVersion"    validate:"gtfield=FromVersion"`
+	StateMapping map[string]string `json:"stateMapping"`
+}
+
+type WorkMigrationResult struct {
+	MigratedWorks int `json:"migratedWorks"`
+}
+
+type WorkflowTemplateCreation struct {
+	Name      string   `json:"name"      binding:"required"`
+	ProjectID types.ID `json:"projectId"`
+
+	StateMachine        state.StateMachine          `json:"stateMachine"        binding:"dive"`
+	PropertyDefinitions []domain.PropertyDefinition `json:"propertyDefinitions" binding:"dive"`
+}
+
+type WorkflowTemplateUpdating struct {
+	Name string `json:"name" binding:"required"`
+
+	StateMachine        state.StateMachine          `json:"stateMachine"        binding:"dive"`
+	PropertyDefinitions []domain.PropertyDefinition `json:"propertyDefinitions" binding:"dive"`
+}
+
+// WorkflowTemplateQuery returns the system wide templates, and the templates of ProjectID if it is specified
+type WorkflowTemplateQuery struct {
+	ProjectID types.ID `json:"projectId" form:"projectId"`
+}
+
+// WorkflowTemplateSaving saves the current definition of a workflow as template
+type WorkflowTemplateSaving struct {
+	Name      string   `json:"name"      binding:"required"`
+	ProjectID types.ID `json:"projectId"`
+}
+
+// WorkflowInstantiation creates a workflow in project ProjectID from a template
+type WorkflowInstantiation struct {
+	Name       string   `json:"name"       binding:"required"`
+	ProjectID  types.ID `json:"projectId"  binding:"required"`
+	ThemeColor string   `json:"themeColor" binding:"required"`
+	ThemeIcon  string   `json:"themeIcon"  binding:"required"`
+}
+
+// WorkflowCloning copies a workflow into project ProjectID with a new name
+type WorkflowCloning struct {
+	Name      string   `json:"name"      binding:"required"`
+	ProjectID types.ID `json:"projectId" binding:"required"`
+}
+
+// WorkflowComparisonQuery compares a workflow with workflow WorkflowID or template TemplateID, only one of them is given
+type WorkflowComparisonQuery struct {
+	WorkflowID types.ID `form:"workflowId"`
+	TemplateID types.ID `form:"templateId"`
+}
+
+// WorkflowDiagramQuery renders the workflow in Format, the number of works in each state is shown if WithCounts is true
+type WorkflowDiagramQuery struct {
+	Format     string `form:"format"     binding:"omitempty,oneof=plantuml dot mermaid"`
+	WithCounts bool   `form:"withCounts"`
+}
+
+// StateDeleting removes state Name from workflow, works in the state are moved to state Replacement.
+// The deletion is refused if the state is referenced by history unless Force is true.
+type StateDeleting struct {
+	Name        string `json:"name"        form:"-"`
+	Replacement string `json:"replacement" form:"replacement" binding:"required"`
+	Force       bool   `json:"force"       form:"force"`
+}
+
+// TransitionPermission tells whether the caller is permitted to perform the transition
+type TransitionPermission struct {
+	state.Transition
+	Permitted bool `json:"permitted"`
+}
+
+type StateDeletionResult struct {
+	MovedWorks int `json:"movedWorks"`
+}
+
+// StateCategoryCreation defines a custom category of states which behaves as category Base
+type StateCategoryCreation struct {
+	Name       string         `json:"name"       binding:"required"`
+	ThemeColor string         `json:"themeColor"`
+	Base       state.Category `json:"base"       binding:"required"`
+}
+
+// StateCategoryUpdating changes the display of custom category, the base of it can not be changed
+type StateCategoryUpdating struct {
+	Name       string `json:"name"       binding:"required"`
+	ThemeColor string `json:"themeColor"`
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"flywheel/bizerror"
 	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/event"
 	"flywheel/idgen"
 	"flywheel/persistence"
 	"flywheel/session"
@@ -42,16 +44,44 @@ func CreatePropertyDefinition(workflowId types.ID, p domain.PropertyDefinition,
 		return nil, bizerror.ErrForbidden
 	}
 
-	// save record
+	var r *WorkflowPropertyDefinition
+	var ev *event.EventRecord
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := nextWorkflowVersion(tx, workflowId); err != nil {
+			return err
+		}
+		var err error
+		if r, err = createPropertyDefinition(tx, workflowId, p); err != nil {
+			return err
+		}
+		ev, err = createWorkflowChangedEvent(&w, []DefinitionChange{{Action: ChangeAdded, Kind: ChangeKindProperty, Name: p.Name, New: p}}, s, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invokeEventHandlers(ev)
+	return r, nil
+}
+
+// createPropertyDefinition saves the property definition after the required states of it are checked
+func createPropertyDefinition(tx *gorm.DB, workflowId types.ID, p domain.PropertyDefinition) (*WorkflowPropertyDefinition, error) {
+	stateMachine, err := queryStateMachine(tx, workflowId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRequiredStates(p, stateMachine); err != nil {
+		return nil, err
+	}
 	r := WorkflowPropertyDefinition{
 		ID:                 idgen.NextID(propertyDefinitionIdWorker),
 		WorkflowID:         workflowId,
 		PropertyDefinition: p,
 	}
-	if err := db.Create(&r).Error; err != nil {
+	if err := tx.Create(&r).Error; err != nil {
 		return nil, err
 	}
-
 	return &r, nil
 }
 
@@ -98,16 +128,74 @@ func DeletePropertyDefinition(id types.ID, s *session.Session) error {
 		return bizerror.ErrForbidden
 	}
 
+	var ev *event.EventRecord
 	dbErr := db.Transaction(func(tx *gorm.DB) error {
-		for _, checkFunc := range PropertyDefinitionDeleteCheckFuncs {
-			err := checkFunc(p, tx)
-			if err != nil {
-				return err
-			}
+		if err := nextWorkflowVersion(tx, w.ID); err != nil {
+			return err
 		}
-
-		return db.Where("id = ?", id).Delete(&WorkflowPropertyDefinition{ID: id}).Error
+		if err := deletePropertyDefinition(tx, p); err != nil {
+			return err
+		}
+		var err error
+		ev, err = createWorkflowChangedEvent(&w, []DefinitionChange{{Action: ChangeRemoved, Kind: ChangeKindProperty, Name: p.Name,
+			Old: p.PropertyDefinition}}, s, tx)
+		return err
 	})
+	if dbErr != nil {
+		return dbErr
+	}
+
+	invokeEventHandlers(ev)
+	return nil
+}
+
+// deletePropertyDefinition deletes the property definition if all of PropertyDefinitionDeleteCheckFuncs are passed
+func deletePropertyDefinition(tx *gorm.DB, p WorkflowPropertyDefinition) error {
+	for _, checkFunc := range PropertyDefinitionDeleteCheckFuncs {
+		if err := checkFunc(p, tx); err != nil {
+			return err
+		}
+	}
+	return tx.Where("id = ?", p.ID).Delete(&WorkflowPropertyDefinition{ID: p.ID}).Error
+}
+
+// checkRequiredStates checks that the required states of property definition are defined in the state machine
+func checkRequiredStates(p domain.PropertyDefinition, stateMachine *state.StateMachine) error {
+	for _, name := range p.RequiredStates {
+		if _, found := stateMachine.FindState(name); !found {
+			return &bizerror.ErrBadParam{Cause: errors.New("required state " + name + " of property " + p.Name + " is unknown")}
+		}
+	}
+	return nil
+}
 
-	return dbErr
+// replaceRequiredState replaces state origin in the required states of property definitions of workflow with state
+// replacement, or removes it if replacement is empty. The changes of the property definitions are returned.
+func replaceRequiredState(tx *gorm.DB, workflowID types.ID, origin, replacement string) ([]DefinitionChange, error) {
+	var definitions []WorkflowPropertyDefinition
+	if err := tx.Where("workflow_id = ?", workflowID).Find(&definitions).Error; err != nil {
+		return nil, err
+	}
+	var changes []DefinitionChange
+	for _, d := range definitions {
+		if !d.IsRequiredBy(origin, nil) {
+			continue
+		}
+		updated := d.PropertyDefinition
+		updated.RequiredStates = domain.PropertyStates{}
+		for _, name := range d.RequiredStates {
+			if name != origin {
+				updated.RequiredStates = append(updated.RequiredStates, name)
+			} else if replacement != "" {
+				updated.RequiredStates = append(updated.RequiredStates, replacement)
+			}
+		}
+		if err := tx.Model(&WorkflowPropertyDefinition{}).Where("id = ?", d.ID).
+			Update("required_states", updated.RequiredStates).Error; err != nil {
+			return nil, err
+		}
+		changes = append(changes, DefinitionChange{Action: ChangeModified, Kind: ChangeKindProperty, Name: d.Name,
+			Old: d.PropertyDefinition, New: updated})
+	}
+	return changes, nil
 }
@@ -7,6 +7,8 @@ import (
 	"flywheel/bizerror"
 	"flywheel/domain"
 	"flywheel/domain/flow"
+	"flywheel/event"
+	"flywheel/indices/indexlog"
 	"flywheel/persistence"
 	"flywheel/testinfra"
 	"testing"
@@ -21,7 +23,8 @@ func propertyDefinitionTestSetup(t *testing.T, testDatabase **testinfra.TestData
 	db := testinfra.StartMysqlTestDatabase("flywheel")
 	err := db.DS.GormDB(context.Background()).AutoMigrate(
 		&flow.WorkflowPropertyDefinition{},
-		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}).Error
+		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &domain.WorkflowVersion{},
+		&event.EventRecord{}, &indexlog.IndexLogRecord{}).Error
 	Expect(err).To(BeNil())
 
 	*testDatabase = db
@@ -109,6 +112,25 @@ func TestCreatePropertyDefinition(t *testing.T) {
 		Expect(err.Error()).To(Equal(`Error 1062: Duplicate entry '` + workflow.ID.String() +
 			`-testProperty' for key 'workflow_property_definitions.uni_workflow_prop'`))
 	})
+
+	t.Run("required states must be defined in workflow", func(t *testing.T) {
+		defer propertyDefinitionTeardown(t, testDatabase)
+		propertyDefinitionTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow, err := flow.CreateWorkflow(creationDemo, sec)
+		Expect(err).To(BeNil())
+
+		pd, err := flow.CreatePropertyDefinition(workflow.ID,
+			domain.PropertyDefinition{Name: "estimation", Type: "number", RequiredStates: domain.PropertyStates{"CLOSED", "DONE"}}, sec)
+		Expect(pd).To(BeNil())
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("required state DONE of property estimation is unknown")}))
+
+		pd, err = flow.CreatePropertyDefinition(workflow.ID,
+			domain.PropertyDefinition{Name: "estimation", Type: "number", RequiredStates: domain.PropertyStates{"CLOSED"}}, sec)
+		Expect(err).To(BeNil())
+		Expect(pd.RequiredStates).To(Equal(domain.PropertyStates{"CLOSED"}))
+	})
 }
 
 func TestQueryPropertyDefinitions(t *testing.T) {
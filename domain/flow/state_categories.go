@@ -0,0 +1,181 @@
+package flow
+
+import (
+	"context"
+	"flywheel/account"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/persistence"
+	"flywheel/session"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	QueryStateCategoriesFunc = QueryStateCategories
+	CreateStateCategoryFunc  = CreateStateCategory
+	UpdateStateCategoryFunc  = UpdateStateCategory
+	DeleteStateCategoryFunc  = DeleteStateCategory
+
+	LoadStateCategoriesFunc        = LoadStateCategories
+	StateCategoriesRefreshInterval = time.Minute
+)
+
+// StateCategoryRecord is a system wide custom category of states
+type StateCategoryRecord struct {
+	Category   state.Category `gorm:"primary_key;auto_increment:false"`
+	Name       string
+	ThemeColor string
+	Base       state.Category
+	CreateTime time.Time `sql:"type:DATETIME(6) NOT NULL"`
+}
+
+func (r *StateCategoryRecord) TableName() string {
+	return "state_categories"
+}
+
+func (r *StateCategoryRecord) definition() state.CategoryDefinition {
+	return state.CategoryDefinition{Category: r.Category, Name: r.Name, ThemeColor: r.ThemeColor, Base: r.Base}
+}
+
+// LoadStateCategories makes the custom categories stored in database known by the state machines,
+// it is called on start up and each time the custom categories are changed
+func LoadStateCategories(db *gorm.DB) error {
+	definitions, err := queryCustomCategoryDefinitions(db)
+	if err != nil {
+		return err
+	}
+	state.SetCustomCategories(definitions)
+	return nil
+}
+
+// StartStateCategoriesRefresh reloads the custom categories periodically until the returned stop function is called,
+// so that the custom categories changed by other instances are known by the state machines of this instance
+func StartStateCategoriesRefresh() (stop func()) {
+	ticker := time.NewTicker(StateCategoriesRefreshInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := LoadStateCategoriesFunc(persistence.ActiveDataSourceManager.GormDB(context.Background())); err != nil {
+					logrus.Warnf("state categories refresh: %v", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+func queryCustomCategoryDefinitions(db *gorm.DB) ([]state.CategoryDefinition, error) {
+	var records []StateCategoryRecord
+	if err := db.Order("category ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	var definitions []state.CategoryDefinition
+	for _, r := range records {
+		definitions = append(definitions, r.definition())
+	}
+	return definitions, nil
+}
+
+// QueryStateCategories returns the base categories first, then the custom categories in the order of category value
+func QueryStateCategories(s *session.Session) ([]state.CategoryDefinition, error) {
+	var records []StateCategoryRecord
+	if err := persistence.ActiveDataSourceManager.GormDB(s.Context).Order("category ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	definitions := state.BaseCategories()
+	for _, r := range records {
+		definitions = append(definitions, r.definition())
+	}
+	return definitions, nil
+}
+
+// CreateStateCategory defines a custom category valued from state.CustomCategoryMin, only system administrators are permitted
+func CreateStateCategory(c *StateCategoryCreation, s *session.Session) (*state.CategoryDefinition, error) {
+	if !s.Perms.HasRole(account.SystemAdminPermission.ID) {
+		return nil, bizerror.ErrForbidden
+	}
+	if !state.IsBaseCategory(c.Base) {
+		return nil, bizerror.ErrStateCategoryInvalid
+	}
+
+	r := StateCategoryRecord{Name: c.Name, ThemeColor: c.ThemeColor, Base: c.Base, CreateTime: time.Now().Round(time.Millisecond)}
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		last := StateCategoryRecord{}
+		if err := tx.Order("category DESC").First(&last).Error; err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		r.Category = state.CustomCategoryMin
+		if last.Category >= state.CustomCategoryMin {
+			r.Category = last.Category + 1
+		}
+		return tx.Create(&r).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := LoadStateCategories(db); err != nil {
+		return nil, err
+	}
+	definition := r.definition()
+	return &definition, nil
+}
+
+func UpdateStateCategory(category state.Category, c *StateCategoryUpdating, s *session.Session) error {
+	if !s.Perms.HasRole(account.SystemAdminPermission.ID) {
+		return bizerror.ErrForbidden
+	}
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		r := StateCategoryRecord{}
+		if err := tx.Where("category = ?", category).First(&r).Error; err != nil {
+			return err
+		}
+		return tx.Model(&StateCategoryRecord{}).Where("category = ?", category).
+			Update(map[string]interface{}{"name": c.Name, "theme_color": c.ThemeColor}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return LoadStateCategories(db)
+}
+
+// DeleteStateCategory removes the custom category, it is refused when any state of workflow or work is in the category
+func DeleteStateCategory(category state.Category, s *session.Session) error {
+	if !s.Perms.HasRole(account.SystemAdminPermission.ID) {
+		return bizerror.ErrForbidden
+	}
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		r := StateCategoryRecord{}
+		if err := tx.Where("category = ?", category).First(&r).Error; err != nil {
+			return err
+		}
+		states, works := 0, 0
+		if err := tx.Model(&domain.WorkflowState{}).Where("category = ?", category).Count(&states).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.Work{}).Where("state_category = ?", category).Count(&works).Error; err != nil {
+			return err
+		}
+		if states > 0 || works > 0 {
+			return &bizerror.ErrStateCategoryReferenced{Category: r.Name, States: states, Works: works}
+		}
+		return tx.Where("category = ?", category).Delete(&StateCategoryRecord{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return LoadStateCategories(db)
+}
@@ -0,0 +1,97 @@
+package flow_test
+
+import (
+	"errors"
+	"flywheel/account"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/persistence"
+	"flywheel/testinfra"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+	. "github.com/onsi/gomega"
+)
+
+func TestStateCategories(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should only permit system administrators to manage categories", func(t *testing.T) {
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		_, err := flow.CreateStateCategory(&flow.StateCategoryCreation{Name: "Blocked", Base: state.InProcess}, sec)
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+		Expect(flow.UpdateStateCategory(state.CustomCategoryMin, &flow.StateCategoryUpdating{Name: "Blocked"}, sec)).To(Equal(bizerror.ErrForbidden))
+		Expect(flow.DeleteStateCategory(state.CustomCategoryMin, sec)).To(Equal(bizerror.ErrForbidden))
+
+		_, err = flow.CreateStateCategory(&flow.StateCategoryCreation{Name: "Blocked", Base: state.CustomCategoryMin},
+			testinfra.BuildSecCtx(100, account.SystemAdminPermission.ID))
+		Expect(err).To(Equal(bizerror.ErrStateCategoryInvalid))
+	})
+
+	t.Run("should create, query, update and delete categories", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		defer state.SetCustomCategories(nil)
+		setup(t, &testDatabase)
+
+		admin := testinfra.BuildSecCtx(1, account.SystemAdminPermission.ID)
+		blocked, err := flow.CreateStateCategory(&flow.StateCategoryCreation{Name: "Blocked", ThemeColor: "#FFCC80", Base: state.InProcess}, admin)
+		Expect(err).To(BeNil())
+		Expect(*blocked).To(Equal(state.CategoryDefinition{Category: 100, Name: "Blocked", ThemeColor: "#FFCC80", Base: state.InProcess}))
+		waiting, err := flow.CreateStateCategory(&flow.StateCategoryCreation{Name: "Waiting", Base: state.InProcess}, admin)
+		Expect(err).To(BeNil())
+		Expect(waiting.Category).To(Equal(state.Category(101)))
+		Expect(state.IsCategoryValid(waiting.Category)).To(BeTrue())
+		Expect(state.BaseCategory(waiting.Category)).To(Equal(state.InProcess))
+
+		Expect(flow.UpdateStateCategory(waiting.Category, &flow.StateCategoryUpdating{Name: "Waiting for customer", ThemeColor: "#FFF59D"}, admin)).To(BeNil())
+		categories, err := flow.QueryStateCategories(testinfra.BuildSecCtx(100))
+		Expect(err).To(BeNil())
+		Expect(categories).To(Equal(append(state.BaseCategories(), *blocked,
+			state.CategoryDefinition{Category: 101, Name: "Waiting for customer", ThemeColor: "#FFF59D", Base: state.InProcess})))
+
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test workflow", ProjectID: types.ID(1), StateMachine: state.StateMachine{
+			States:      []state.State{{Name: "OPEN", Category: state.InBacklog}, {Name: "BLOCKED", Category: blocked.Category}, {Name: "CLOSED", Category: state.Done}},
+			Transitions: []state.Transition{{Name: "block", From: "OPEN", To: "BLOCKED"}, {Name: "done", From: "BLOCKED", To: "CLOSED"}},
+		}}, testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1"))
+		Expect(err).To(BeNil())
+		Expect(flow.DeleteStateCategory(blocked.Category, admin)).To(Equal(&bizerror.ErrStateCategoryReferenced{Category: "Blocked", States: 1}))
+
+		Expect(flow.DeleteWorkflow(workflow.ID, testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1"))).To(BeNil())
+		Expect(flow.DeleteStateCategory(blocked.Category, admin)).To(BeNil())
+		Expect(state.IsCategoryValid(blocked.Category)).To(BeFalse())
+		Expect(state.CustomCategories()).To(Equal([]state.CategoryDefinition{
+			{Category: 101, Name: "Waiting for customer", ThemeColor: "#FFF59D", Base: state.InProcess}}))
+	})
+}
+
+func TestStartStateCategoriesRefresh(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should reload state categories periodically until stopped", func(t *testing.T) {
+		defer func() {
+			flow.LoadStateCategoriesFunc, flow.StateCategoriesRefreshInterval = flow.LoadStateCategories, time.Minute
+		}()
+		if persistence.ActiveDataSourceManager == nil {
+			persistence.ActiveDataSourceManager = &persistence.DataSourceManager{}
+			defer func() { persistence.ActiveDataSourceManager = nil }()
+		}
+		var loaded int32
+		flow.LoadStateCategoriesFunc = func(db *gorm.DB) error {
+			atomic.AddInt32(&loaded, 1)
+			return errors.New("some error")
+		}
+		flow.StateCategoriesRefreshInterval = 10 * time.Millisecond
+
+		stop := flow.StartStateCategoriesRefresh()
+		Eventually(func() int32 { return atomic.LoadInt32(&loaded) }).Should(BeNumerically(">=", 2))
+		stop()
+		count := atomic.LoadInt32(&loaded)
+		Consistently(func() int32 { return atomic.LoadInt32(&loaded) }, 50*time.Millisecond).Should(BeNumerically("<=", count+1))
+	})
+}
@@ -0,0 +1,40 @@
+package flow
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/session"
+
+	"github.com/fundwit/go-commons/types"
+)
+
+var CompareWorkflowFunc = CompareWorkflow
+
+// CompareWorkflow reports how workflow WorkflowID or template TemplateID differs from the workflow,
+// see CompareWorkflowDefinitions
+func CompareWorkflow(id types.ID, q *WorkflowComparisonQuery, s *session.Session) ([]DefinitionChange, error) {
+	if (q.WorkflowID == 0) == (q.TemplateID == 0) {
+		return nil, &bizerror.ErrBadParam{Cause: errors.New("one of workflowId and templateId is required")}
+	}
+
+	origin, err := ExportWorkflow(id, s)
+	if err != nil {
+		return nil, err
+	}
+	var target *domain.WorkflowDefinition
+	if q.WorkflowID != 0 {
+		doc, err := ExportWorkflow(q.WorkflowID, s)
+		if err != nil {
+			return nil, err
+		}
+		target = doc.definition()
+	} else {
+		t, err := DetailWorkflowTemplate(q.TemplateID, s)
+		if err != nil {
+			return nil, err
+		}
+		target = &domain.WorkflowDefinition{StateMachine: t.StateMachine, PropertyDefinitions: t.PropertyDefinitions}
+	}
+	return CompareWorkflowDefinitions(origin.definition(), target), nil
+}
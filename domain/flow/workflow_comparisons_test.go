@@ -0,0 +1,58 @@
+package flow_test
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/testinfra"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCompareWorkflow(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should require one of workflow and template", func(t *testing.T) {
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		_, err := flow.CompareWorkflow(10, &flow.WorkflowComparisonQuery{}, sec)
+		Expect(err).To(BeAssignableToTypeOf(&bizerror.ErrBadParam{}))
+		_, err = flow.CompareWorkflow(10, &flow.WorkflowComparisonQuery{WorkflowID: 20, TemplateID: 30}, sec)
+		Expect(err).To(BeAssignableToTypeOf(&bizerror.ErrBadParam{}))
+	})
+
+	t.Run("should compare workflow with workflow and template", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1", domain.ProjectRoleCommon+"_2")
+		origin, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "origin", ProjectID: 1, ThemeColor: "blue", ThemeIcon: "some-icon",
+			StateMachine: state.StateMachine{
+				States:      []state.State{{Name: "OPEN", Category: state.InBacklog}, {Name: "CLOSED", Category: state.Done}},
+				Transitions: []state.Transition{{Name: "close", From: "OPEN", To: "CLOSED"}},
+			}}, sec)
+		Expect(err).To(BeNil())
+		cloned, err := flow.CloneWorkflow(origin.ID, &flow.WorkflowCloning{Name: "cloned", ProjectID: 1}, sec)
+		Expect(err).To(BeNil())
+
+		changes, err := flow.CompareWorkflow(origin.ID, &flow.WorkflowComparisonQuery{WorkflowID: cloned.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(changes).To(BeEmpty())
+
+		Expect(flow.UpdateWorkflowState(cloned.ID, flow.WorkflowStateUpdating{OriginName: "CLOSED", Name: "DONE", Order: 10002}, sec)).To(BeNil())
+		changes, err = flow.CompareWorkflow(origin.ID, &flow.WorkflowComparisonQuery{WorkflowID: cloned.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(len(changes)).To(Equal(1))
+		Expect(changes[0].Action).To(Equal(flow.ChangeRenamed))
+		Expect(changes[0].Name).To(Equal("DONE"))
+
+		changes, err = flow.CompareWorkflow(origin.ID, &flow.WorkflowComparisonQuery{TemplateID: flow.BuiltinWorkflowTemplates[0].ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(changes).ToNot(BeEmpty())
+
+		_, err = flow.CompareWorkflow(origin.ID, &flow.WorkflowComparisonQuery{WorkflowID: cloned.ID}, testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_2"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+}
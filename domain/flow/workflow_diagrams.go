@@ -0,0 +1,56 @@
+package flow
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/persistence"
+	"flywheel/session"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+var RenderWorkflowDiagramFunc = RenderWorkflowDiagram
+
+type stateWorkCount struct {
+	StateName string
+	Count     int
+}
+
+// RenderWorkflowDiagram renders the current definition of workflow, archived works are not counted
+func RenderWorkflowDiagram(id types.ID, q *WorkflowDiagramQuery, s *session.Session) (string, error) {
+	format := q.Format
+	if format == "" {
+		format = state.DiagramPlantUML
+	}
+
+	var diagram string
+	err := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		wf := domain.Workflow{}
+		if err := tx.Where(&domain.Workflow{ID: id}).First(&wf).Error; err != nil {
+			return err
+		}
+		if !s.Perms.HasProjectViewPerm(wf.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+		stateMachine, err := loadStateMachine(tx, wf.ID)
+		if err != nil {
+			return err
+		}
+
+		var counts map[string]int
+		if q.WithCounts {
+			if counts, err = countStateWorks(tx, wf.ID); err != nil {
+				return err
+			}
+		}
+
+		diagram, err = stateMachine.Render(format, counts)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return diagram, nil
+}
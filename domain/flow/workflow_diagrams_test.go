@@ -0,0 +1,45 @@
+package flow_test
+
+import (
+	"context"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/testinfra"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestRenderWorkflowDiagram(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should render workflow with counts of works", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test work", ProjectID: types.ID(1),
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, sec)
+		Expect(err).To(BeNil())
+
+		now := types.CurrentTimestamp()
+		db := testDatabase.DS.GormDB(context.Background())
+		Expect(db.Create(domain.Work{ID: 1, Identifier: "W-1", Name: "w1", ProjectID: 1, CreateTime: now, FlowID: workflow.ID, FlowVersion: 1,
+			StateName: domain.StatePending.Name, StateCategory: domain.StatePending.Category, StateBeginTime: now}).Error).To(BeNil())
+		Expect(db.Create(domain.Work{ID: 2, Identifier: "W-2", Name: "w2", ProjectID: 1, CreateTime: now, FlowID: workflow.ID, FlowVersion: 1,
+			StateName: domain.StatePending.Name, StateCategory: domain.StatePending.Category, StateBeginTime: now, ArchiveTime: now}).Error).To(BeNil())
+
+		diagram, err := flow.RenderWorkflowDiagram(workflow.ID, &flow.WorkflowDiagramQuery{WithCounts: true}, sec)
+		Expect(err).To(BeNil())
+		expected, err := workflow.StateMachine.Render(state.DiagramPlantUML, map[string]int{domain.StatePending.Name: 1})
+		Expect(err).To(BeNil())
+		Expect(diagram).To(Equal(expected))
+
+		_, err = flow.RenderWorkflowDiagram(workflow.ID, &flow.WorkflowDiagramQuery{}, testinfra.BuildSecCtx(200, domain.ProjectRoleManager+"_2"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+}
@@ -0,0 +1,347 @@
+package flow
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/event"
+	"flywheel/persistence"
+	"flywheel/session"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+// WorkflowDocumentFormat is the format version of WorkflowDocument, it will be changed when the format becomes incompatible
+const WorkflowDocumentFormat = "flywheel.workflow/v1"
+
+var (
+	ExportWorkflowFunc = ExportWorkflow
+	ImportWorkflowFunc = ImportWorkflow
+)
+
+// WorkflowDocument is the portable definition of workflow, it carries no identity and can be saved as JSON or YAML:
+//
+//	format: flywheel.workflow/v1
+//	name: GenericTask
+//	themeColor: blue
+//	themeIcon: task
+//	states:
+//	  - {name: PENDING, category: 1, order: 1}
+//	  - {name: DONE, category: 3, order: 2}
+//	transitions:
+//	  - name: close
+//	    from: PENDING
+//	    to: DONE
+//	    guards: [{type: checklistDone}]
+//	propertyDefinitions:
+//	  - {name: priority, type: select, title: Priority, options: {selectEnums: [high, low]}}
+//
+// category of state: 1 InBacklog, 2 InProcess, 3 Done, 4 Rejected.
+type WorkflowDocument struct {
+	Format     string `json:"format"     yaml:"format"     binding:"required,eq=flywheel.workflow/v1"`
+	Name       string `json:"name"       yaml:"name"       binding:"required"`
+	ThemeColor string `json:"themeColor" yaml:"themeColor" binding:"required"`
+	ThemeIcon  string `json:"themeIcon"  yaml:"themeIcon"  binding:"required"`
+
+	States              []state.State               `json:"states"              yaml:"states"`
+	Transitions         []state.Transition          `json:"transitions"         yaml:"transitions"         binding:"dive"`
+	PropertyDefinitions []domain.PropertyDefinition `json:"propertyDefinitions" yaml:"propertyDefinitions" binding:"dive"`
+}
+
+func (d *WorkflowDocument) definition() *domain.WorkflowDefinition {
+	return &domain.WorkflowDefinition{
+		StateMachine:        state.StateMachine{States: d.States, Transitions: d.Transitions},
+		PropertyDefinitions: d.PropertyDefinitions,
+	}
+}
+
+// WorkflowImportQuery imports document as a new workflow of ProjectID when WorkflowID is 0, otherwise updates workflow WorkflowID
+type WorkflowImportQuery struct {
+	ProjectID  types.ID `form:"projectId"`
+	WorkflowID types.ID `form:"workflowId"`
+	DryRun     bool     `form:"dryRun"`
+}
+
+type WorkflowImportResult struct {
+	DryRun   bool                   `json:"dryRun"`
+	Changes  []DefinitionChange     `json:"changes"`
+	Workflow *domain.WorkflowDetail `json:"workflow,omitempty"`
+}
+
+func ExportWorkflow(id types.ID, s *session.Session) (*WorkflowDocument, error) {
+	doc := WorkflowDocument{Format: WorkflowDocumentFormat}
+	err := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		wf := domain.Workflow{}
+		if err := tx.Where(&domain.Workflow{ID: id}).First(&wf).Error; err != nil {
+			return err
+		}
+		if !s.Perms.HasProjectViewPerm(wf.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+		definition, err := queryWorkflowDefinition(tx, wf.ID)
+		if err != nil {
+			return err
+		}
+		doc.Name, doc.ThemeColor, doc.ThemeIcon = wf.Name, wf.ThemeColor, wf.ThemeIcon
+		doc.States = definition.StateMachine.States
+		doc.Transitions = definition.StateMachine.Transitions
+		doc.PropertyDefinitions = definition.PropertyDefinitions
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// ImportWorkflow creates or updates a workflow from document, nothing is saved in dry run mode but the changes are reported.
+// Removing states, changing category of states and changing property definitions are not supported when updating workflow.
+func ImportWorkflow(q *WorkflowImportQuery, doc *WorkflowDocument, s *session.Session) (*WorkflowImportResult, error) {
+	if q.WorkflowID == 0 {
+		return importNewWorkflow(q, doc, s)
+	}
+
+	result := WorkflowImportResult{DryRun: q.DryRun}
+	var ev *event.EventRecord
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		wf := domain.Workflow{}
+		if err := tx.Where(&domain.Workflow{ID: q.WorkflowID}).First(&wf).Error; err != nil {
+			return err
+		}
+		if !s.Perms.HasProjectRole(domain.ProjectRoleManager, wf.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+		if q.ProjectID != 0 && q.ProjectID != wf.ProjectID {
+			return &bizerror.ErrBadParam{Cause: errors.New("workflow is not in project " + q.ProjectID.String())}
+		}
+		origin, err := queryWorkflowDefinition(tx, wf.ID)
+		if err != nil {
+			return err
+		}
+		normalizeDocumentNames(doc, origin)
+		if err := validateWorkflowDefinition(doc.definition()); err != nil {
+			return err
+		}
+		definitionChanges := DiffWorkflowDefinition(origin, doc.definition())
+		result.Changes = append(diffWorkflowBase(&wf, doc.Name, doc.ThemeColor, doc.ThemeIcon), definitionChanges...)
+		if err := checkImportChanges(definitionChanges); err != nil {
+			return err
+		}
+		if q.DryRun {
+			return nil
+		}
+
+		if err := applyImportChanges(tx, &wf, doc, definitionChanges); err != nil {
+			return err
+		}
+		if err := checkStateMachine(tx, wf.ID); err != nil {
+			return err
+		}
+
+		result.Workflow = &domain.WorkflowDetail{}
+		if err := tx.Where(&domain.Workflow{ID: wf.ID}).First(&result.Workflow.Workflow).Error; err != nil {
+			return err
+		}
+		definition, err := queryWorkflowDefinition(tx, wf.ID)
+		if err != nil {
+			return err
+		}
+		result.Workflow.StateMachine = definition.StateMachine
+		result.Workflow.PropertyDefinitions = definition.PropertyDefinitions
+
+		ev, err = createWorkflowChangedEvent(&result.Workflow.Workflow, result.Changes, s, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	invokeEventHandlers(ev)
+	return &result, nil
+}
+
+func importNewWorkflow(q *WorkflowImportQuery, doc *WorkflowDocument, s *session.Session) (*WorkflowImportResult, error) {
+	if q.ProjectID == 0 {
+		return nil, &bizerror.ErrBadParam{Cause: errors.New("projectId or workflowId is required")}
+	}
+	if !s.Perms.HasAnyProjectRole(q.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+	normalizeDocumentNames(doc, &domain.WorkflowDefinition{})
+	if err := validateWorkflowDefinition(doc.definition()); err != nil {
+		return nil, err
+	}
+
+	result := WorkflowImportResult{DryRun: q.DryRun, Changes: DiffWorkflowDefinition(&domain.WorkflowDefinition{}, doc.definition())}
+	if q.DryRun {
+		return &result, nil
+	}
+
+	// orders of states are reassigned in creation, keep the relative orders of document
+	states := append([]state.State{}, doc.States...)
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].Order < states[j].Order
+	})
+	workflow, err := CreateWorkflow(&WorkflowCreation{Name: doc.Name, ProjectID: q.ProjectID, ThemeColor: doc.ThemeColor, ThemeIcon: doc.ThemeIcon,
+		StateMachine:        state.StateMachine{States: states, Transitions: doc.Transitions},
+		PropertyDefinitions: doc.PropertyDefinitions,
+	}, s)
+	if err != nil {
+		return nil, err
+	}
+	result.Workflow = workflow
+	return &result, nil
+}
+
+func diffWorkflowBase(wf *domain.Workflow, name, themeColor, themeIcon string) []DefinitionChange {
+	changes := []DefinitionChange{}
+	if wf.Name != name {
+		changes = append(changes, DefinitionChange{Action: ChangeModified, Kind: ChangeKindWorkflow, Name: "name", Old: wf.Name, New: name})
+	}
+	if wf.ThemeColor != themeColor {
+		changes = append(changes, DefinitionChange{Action: ChangeModified, Kind: ChangeKindWorkflow, Name: "themeColor", Old: wf.ThemeColor, New: themeColor})
+	}
+	if wf.ThemeIcon != themeIcon {
+		changes = append(changes, DefinitionChange{Action: ChangeModified, Kind: ChangeKindWorkflow, Name: "themeIcon", Old: wf.ThemeIcon, New: themeIcon})
+	}
+	return changes
+}
+
+func checkImportChanges(changes []DefinitionChange) error {
+	var unsupported []string
+	for _, c := range changes {
+		switch {
+		case c.Kind == ChangeKindState && c.Action == ChangeRemoved:
+			unsupported = append(unsupported, "state "+c.Name+" is removed")
+		case c.Kind == ChangeKindState && c.Action == ChangeModified && c.Old.(state.State).Category != c.New.(state.State).Category:
+			unsupported = append(unsupported, "category of state "+c.Name+" is changed")
+		case c.Kind == ChangeKindProperty && c.Action == ChangeModified:
+			unsupported = append(unsupported, "property "+c.Name+" is changed")
+		}
+	}
+	if len(unsupported) > 0 {
+		return &bizerror.ErrChangesUnsupported{Changes: unsupported}
+	}
+	return nil
+}
+
+// applyImportChanges applies changes through the same functions which edit workflow piece by piece, but all of them are
+// saved as one version of workflow
+func applyImportChanges(tx *gorm.DB, wf *domain.Workflow, doc *WorkflowDocument, changes []DefinitionChange) error {
+	if wf.Name != doc.Name || wf.ThemeColor != doc.ThemeColor || wf.ThemeIcon != doc.ThemeIcon {
+		if _, err := updateWorkflowBase(tx, wf, doc.Name, doc.ThemeColor, doc.ThemeIcon); err != nil {
+			return err
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if !onlyOrdersChanged(changes) {
+		if err := nextWorkflowVersion(tx, wf.ID); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	for _, c := range changes {
+		var err error
+		switch c.Kind {
+		case ChangeKindState:
+			err = applyStateChange(tx, wf, c, now)
+		case ChangeKindTransition:
+			err = applyTransitionChange(tx, wf.ID, c)
+		case ChangeKindProperty:
+			err = applyPropertyChange(tx, wf.ID, c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyStateChange(tx *gorm.DB, wf *domain.Workflow, c DefinitionChange, now time.Time) error {
+	s := c.New.(state.State)
+	if c.Action == ChangeAdded {
+		_, err := createState(tx, wf.ID, &StateCreating{Name: s.Name, Category: s.Category, Order: s.Order,
+			WipLimit: s.WipLimit, WipSoft: s.WipSoft, Reasons: s.Reasons, Parent: s.Parent}, now)
+		return err
+	}
+	_, err := updateState(tx, wf, WorkflowStateUpdating{OriginName: c.Old.(state.State).Name, Name: s.Name, Order: s.Order,
+		WipLimit: &s.WipLimit, WipSoft: &s.WipSoft, Reasons: &s.Reasons, Parent: s.Parent})
+	return err
+}
+
+func applyTransitionChange(tx *gorm.DB, workflowID types.ID, c DefinitionChange) error {
+	var err error
+	if c.Action == ChangeRemoved {
+		_, err = deleteTransitions(tx, workflowID, []state.Transition{c.Old.(state.Transition)})
+	} else {
+		_, err = saveTransitions(tx, workflowID, []state.Transition{c.New.(state.Transition)})
+	}
+	return err
+}
+
+func applyPropertyChange(tx *gorm.DB, workflowID types.ID, c DefinitionChange) error {
+	if c.Action == ChangeAdded {
+		_, err := createPropertyDefinition(tx, workflowID, c.New.(domain.PropertyDefinition))
+		return err
+	}
+
+	d := WorkflowPropertyDefinition{}
+	if err := tx.Where("workflow_id = ? AND name = ?", workflowID, c.Name).First(&d).Error; err != nil {
+		return err
+	}
+	return deletePropertyDefinition(tx, d)
+}
+
+// normalizeDocumentNames rewrites the names of states referenced in document to the names of states which are matched
+// case-insensitively, the states of origin take precedence, so that the case of names is not reported as a change.
+func normalizeDocumentNames(doc *WorkflowDocument, origin *domain.WorkflowDefinition) {
+	names := map[string]string{}
+	for _, s := range origin.StateMachine.States {
+		names[strings.ToLower(s.Name)] = s.Name
+	}
+	for _, s := range doc.States {
+		if _, found := names[strings.ToLower(s.Name)]; !found {
+			names[strings.ToLower(s.Name)] = s.Name
+		}
+	}
+	normalize := func(name string) string {
+		if n, found := names[strings.ToLower(name)]; found {
+			return n
+		}
+		return name
+	}
+
+	states := make([]state.State, 0, len(doc.States))
+	for _, s := range doc.States {
+		s.Name = normalize(s.Name)
+		if s.Parent != "" {
+			s.Parent = normalize(s.Parent)
+		}
+		states = append(states, s)
+	}
+	transitions := make([]state.Transition, 0, len(doc.Transitions))
+	for _, t := range doc.Transitions {
+		t.From, t.To = normalize(t.From), normalize(t.To)
+		transitions = append(transitions, t)
+	}
+	properties := make([]domain.PropertyDefinition, 0, len(doc.PropertyDefinitions))
+	for _, p := range doc.PropertyDefinitions {
+		if len(p.RequiredStates) > 0 {
+			requiredStates := domain.PropertyStates{}
+			for _, name := range p.RequiredStates {
+				requiredStates = append(requiredStates, normalize(name))
+			}
+			p.RequiredStates = requiredStates
+		}
+		properties = append(properties, p)
+	}
+	doc.States, doc.Transitions, doc.PropertyDefinitions = states, transitions, properties
+}
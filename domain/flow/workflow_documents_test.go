@@ -0,0 +1,129 @@
+package flow_test
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/testinfra"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func genericDocument() *flow.WorkflowDocument {
+	sm := domain.GenericWorkflowTemplate.StateMachine
+	return &flow.WorkflowDocument{Format: flow.WorkflowDocumentFormat, Name: "generic", ThemeColor: "blue", ThemeIcon: "task",
+		States:              append([]state.State{}, sm.States...),
+		Transitions:         append([]state.Transition{}, sm.Transitions...),
+		PropertyDefinitions: []domain.PropertyDefinition{{Name: "resolution", Type: domain.PropTypeText}},
+	}
+}
+
+func TestImportWorkflow(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should report changes of new workflow in dry run mode", func(t *testing.T) {
+		doc := genericDocument()
+		result, err := flow.ImportWorkflow(&flow.WorkflowImportQuery{ProjectID: 1, DryRun: true}, doc, testinfra.BuildSecCtx(100, domain.ProjectRoleCommon+"_1"))
+		Expect(err).To(BeNil())
+		Expect(result.DryRun).To(BeTrue())
+		Expect(result.Workflow).To(BeNil())
+		Expect(len(result.Changes)).To(Equal(3 + 5 + 1))
+		Expect(result.Changes[0]).To(Equal(flow.DefinitionChange{Action: flow.ChangeAdded, Kind: flow.ChangeKindState, Name: "PENDING", New: doc.States[0]}))
+	})
+
+	t.Run("should match names of states case-insensitively", func(t *testing.T) {
+		doc := genericDocument()
+		doc.Transitions[0].From, doc.Transitions[0].To = "pending", "Doing"
+		result, err := flow.ImportWorkflow(&flow.WorkflowImportQuery{ProjectID: 1, DryRun: true}, doc, testinfra.BuildSecCtx(100, domain.ProjectRoleCommon+"_1"))
+		Expect(err).To(BeNil())
+		Expect(result.Changes[3].New).To(Equal(domain.GenericWorkflowTemplate.StateMachine.Transitions[0]))
+	})
+
+	t.Run("should check project, permission and definition", func(t *testing.T) {
+		_, err := flow.ImportWorkflow(&flow.WorkflowImportQuery{}, genericDocument(), testinfra.BuildSecCtx(100, domain.ProjectRoleCommon+"_1"))
+		Expect(err).To(BeAssignableToTypeOf(&bizerror.ErrBadParam{}))
+		Expect(err.Error()).To(Equal("projectId or workflowId is required"))
+
+		_, err = flow.ImportWorkflow(&flow.WorkflowImportQuery{ProjectID: 2}, genericDocument(), testinfra.BuildSecCtx(100, domain.ProjectRoleCommon+"_1"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		doc := genericDocument()
+		doc.States = nil
+		_, err = flow.ImportWorkflow(&flow.WorkflowImportQuery{ProjectID: 1}, doc, testinfra.BuildSecCtx(100, domain.ProjectRoleCommon+"_1"))
+		Expect(err).To(BeAssignableToTypeOf(&state.ErrStateMachineInvalid{}))
+	})
+
+	t.Run("should export and import workflow", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		created, err := flow.ImportWorkflow(&flow.WorkflowImportQuery{ProjectID: 1}, genericDocument(), sec)
+		Expect(err).To(BeNil())
+		Expect(created.Workflow.Version).To(Equal(1))
+		Expect(created.Workflow.PropertyDefinitions).To(Equal(genericDocument().PropertyDefinitions))
+
+		doc, err := flow.ExportWorkflow(created.Workflow.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(doc.Name).To(Equal("generic"))
+		Expect(doc.States).To(Equal(created.Workflow.StateMachine.States))
+		Expect(doc.PropertyDefinitions).To(Equal(genericDocument().PropertyDefinitions))
+		_, err = flow.ExportWorkflow(created.Workflow.ID, testinfra.BuildSecCtx(200, domain.ProjectRoleManager+"_2"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		// unchanged document
+		result, err := flow.ImportWorkflow(&flow.WorkflowImportQuery{WorkflowID: created.Workflow.ID}, doc, sec)
+		Expect(err).To(BeNil())
+		Expect(result.Changes).To(BeEmpty())
+		Expect(result.Workflow.Version).To(Equal(1))
+
+		doc.Name = "generic2"
+		doc.States = append(doc.States, state.State{Name: "REVIEW", Category: state.InProcess, Order: 20000})
+		doc.Transitions = append(doc.Transitions[1:], state.Transition{Name: "review", From: domain.StateDoing.Name, To: "REVIEW"},
+			state.Transition{Name: "pass", From: "REVIEW", To: domain.StateDone.Name})
+		doc.PropertyDefinitions = []domain.PropertyDefinition{{Name: "priority", Type: domain.PropTypeNumber}}
+
+		dryRun, err := flow.ImportWorkflow(&flow.WorkflowImportQuery{WorkflowID: created.Workflow.ID, DryRun: true}, doc, sec)
+		Expect(err).To(BeNil())
+		Expect(len(dryRun.Changes)).To(Equal(1 + 1 + 3 + 2))
+		Expect(dryRun.Workflow).To(BeNil())
+		detail, err := flow.DetailWorkflow(created.Workflow.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(detail.Name).To(Equal("generic"))
+		Expect(detail.Version).To(Equal(1))
+
+		result, err = flow.ImportWorkflow(&flow.WorkflowImportQuery{WorkflowID: created.Workflow.ID}, doc, sec)
+		Expect(err).To(BeNil())
+		Expect(result.Changes).To(Equal(dryRun.Changes))
+		Expect(result.Workflow.Name).To(Equal("generic2"))
+		Expect(result.Workflow.Version).To(Equal(2))
+		Expect(result.Workflow.StateMachine.States).To(HaveLen(4))
+		Expect(result.Workflow.StateMachine.Transitions).To(HaveLen(6))
+		Expect(result.Workflow.PropertyDefinitions).To(Equal(doc.PropertyDefinitions))
+
+		doc.States[3].Name, doc.Transitions[5].From = "review", "Review"
+		result, err = flow.ImportWorkflow(&flow.WorkflowImportQuery{WorkflowID: created.Workflow.ID}, doc, sec)
+		Expect(err).To(BeNil())
+		Expect(result.Changes).To(BeEmpty())
+		Expect(result.Workflow.Version).To(Equal(2))
+
+		doc.States[3].Order = 20002
+		result, err = flow.ImportWorkflow(&flow.WorkflowImportQuery{WorkflowID: created.Workflow.ID}, doc, sec)
+		Expect(err).To(BeNil())
+		Expect(result.Changes).To(HaveLen(1))
+		Expect(result.Workflow.Version).To(Equal(2))
+
+		doc.States[3].Category = state.Rejected
+		_, err = flow.ImportWorkflow(&flow.WorkflowImportQuery{WorkflowID: created.Workflow.ID}, doc, sec)
+		Expect(err).To(Equal(&bizerror.ErrChangesUnsupported{Changes: []string{"category of state REVIEW is changed"}}))
+
+		_, err = flow.ImportWorkflow(&flow.WorkflowImportQuery{WorkflowID: created.Workflow.ID, ProjectID: types.ID(2)}, doc, sec)
+		Expect(err).To(BeAssignableToTypeOf(&bizerror.ErrBadParam{}))
+		_, err = flow.ImportWorkflow(&flow.WorkflowImportQuery{WorkflowID: created.Workflow.ID}, doc, testinfra.BuildSecCtx(100, domain.ProjectRoleCommon+"_1"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+}
@@ -0,0 +1,122 @@
+package flow
+
+import (
+	"encoding/json"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/event"
+	"flywheel/persistence"
+	"flywheel/session"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+const WorkflowEventSourceType = "WORKFLOW"
+
+var (
+	QueryWorkflowEventsFunc = QueryWorkflowEvents
+
+	changeKindRelations = map[string]string{
+		ChangeKindState:      "States",
+		ChangeKindTransition: "Transitions",
+		ChangeKindProperty:   "PropertyDefinitions",
+	}
+	changeKindTargetTypes = map[string]string{
+		ChangeKindState:      "STATE",
+		ChangeKindTransition: "TRANSITION",
+		ChangeKindProperty:   "PROPERTY_DEFINITION",
+	}
+)
+
+// QueryWorkflowEvents returns the change history of workflow, the latest change first
+func QueryWorkflowEvents(id types.ID, s *session.Session) ([]event.EventRecord, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	wf := domain.Workflow{}
+	if err := db.Where(&domain.Workflow{ID: id}).First(&wf).Error; err != nil {
+		return nil, err
+	}
+	if !s.Perms.HasProjectViewPerm(wf.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+
+	records := []event.EventRecord{}
+	if err := db.Where("source_type = ? AND source_id = ?", WorkflowEventSourceType, wf.ID).
+		Order("timestamp DESC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func createWorkflowEvent(wf *domain.Workflow, category event.EventCategory, s *session.Session, tx *gorm.DB) (*event.EventRecord, error) {
+	return event.CreateEvent(WorkflowEventSourceType, wf.ID, wf.Name, category, nil, nil, &s.Identity, types.CurrentTimestamp(), tx)
+}
+
+// createWorkflowChangedEvent records changes of workflow. Changes of workflow kind are reported as updated properties,
+// changes of states, transitions and property definitions are reported as updated relations, the old and new item
+// is described as JSON. No event is created if there is no change.
+func createWorkflowChangedEvent(wf *domain.Workflow, changes []DefinitionChange, s *session.Session, tx *gorm.DB) (*event.EventRecord, error) {
+	var properties []event.UpdatedProperty
+	var relations []event.UpdatedRelation
+	for _, c := range changes {
+		if c.Kind == ChangeKindWorkflow {
+			old, _ := c.Old.(string)
+			new, _ := c.New.(string)
+			properties = append(properties, event.UpdatedProperty{PropertyName: c.Name, PropertyDesc: c.Name,
+				OldValue: old, OldValueDesc: old, NewValue: new, NewValueDesc: new})
+			continue
+		}
+
+		r := event.UpdatedRelation{PropertyName: changeKindRelations[c.Kind], PropertyDesc: changeKindRelations[c.Kind],
+			TargetType: changeKindTargetTypes[c.Kind], TargetTypeDesc: changeKindTargetTypes[c.Kind]}
+		if c.Old != nil {
+			desc, err := json.Marshal(c.Old)
+			if err != nil {
+				return nil, err
+			}
+			r.OldTargetId, r.OldTargetDesc = changedItemName(c.Old, c.Name), string(desc)
+		}
+		if c.New != nil {
+			desc, err := json.Marshal(c.New)
+			if err != nil {
+				return nil, err
+			}
+			r.NewTargetId, r.NewTargetDesc = c.Name, string(desc)
+		}
+		relations = append(relations, r)
+	}
+
+	if len(properties) == 0 && len(relations) == 0 {
+		return nil, nil
+	}
+	category := event.EventCategoryRelationUpdated
+	if len(relations) == 0 {
+		category = event.EventCategoryPropertyUpdated
+	}
+	return event.CreateEvent(WorkflowEventSourceType, wf.ID, wf.Name, category, properties, relations, &s.Identity, types.CurrentTimestamp(), tx)
+}
+
+// changedItemName returns the name of old item, it is different from the name of change when the item is renamed
+func changedItemName(item interface{}, name string) string {
+	switch i := item.(type) {
+	case state.State:
+		return i.Name
+	case state.Transition:
+		return i.Name
+	case domain.PropertyDefinition:
+		return i.Name
+	}
+	return name
+}
+
+func invokeEventHandlers(events ...*event.EventRecord) {
+	if event.InvokeHandlersFunc == nil {
+		return
+	}
+	for _, ev := range events {
+		if ev != nil {
+			event.InvokeHandlersFunc(ev)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package flow_test
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/event"
+	"flywheel/testinfra"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestQueryWorkflowEvents(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should record configuration changes of workflow", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		wf, err := flow.CreateWorkflow(creationDemo, sec)
+		Expect(err).To(BeNil())
+		_, err = flow.UpdateWorkflowBase(wf.ID, &flow.WorkflowBaseUpdation{Name: "new name", ThemeColor: "blue", ThemeIcon: "some-icon"}, sec)
+		Expect(err).To(BeNil())
+		Expect(flow.CreateState(wf.ID, &flow.StateCreating{Name: "REVIEW", Category: state.InProcess, Order: 3,
+			Transitions: []state.Transition{{Name: "review", From: "OPEN", To: "REVIEW"}}}, sec)).To(BeNil())
+		Expect(flow.DeleteWorkflowStateTransitions(wf.ID, []state.Transition{{From: "OPEN", To: "REVIEW"}}, sec)).To(BeNil())
+
+		records, err := flow.QueryWorkflowEvents(wf.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(len(records)).To(Equal(4))
+		for _, r := range records {
+			Expect(r.SourceType).To(Equal(flow.WorkflowEventSourceType))
+			Expect(r.SourceId).To(Equal(wf.ID))
+			Expect(r.CreatorId).To(Equal(sec.Identity.ID))
+		}
+
+		Expect(records[3].EventCategory).To(Equal(event.EventCategoryCreated))
+
+		Expect(records[2].EventCategory).To(Equal(event.EventCategoryPropertyUpdated))
+		Expect(records[2].UpdatedProperties).To(Equal(event.UpdatedProperties{{PropertyName: "name", PropertyDesc: "name",
+			OldValue: "test workflow", OldValueDesc: "test workflow", NewValue: "new name", NewValueDesc: "new name"}}))
+
+		Expect(records[1].EventCategory).To(Equal(event.EventCategoryRelationUpdated))
+		Expect(len(records[1].UpdatedRelations)).To(Equal(2))
+		Expect(records[1].UpdatedRelations[0].PropertyName).To(Equal("States"))
+		Expect(records[1].UpdatedRelations[0].NewTargetId).To(Equal("REVIEW"))
+		Expect(records[1].UpdatedRelations[1].PropertyName).To(Equal("Transitions"))
+		Expect(records[1].UpdatedRelations[1].NewTargetId).To(Equal("review"))
+
+		Expect(records[0].EventCategory).To(Equal(event.EventCategoryRelationUpdated))
+		Expect(records[0].UpdatedRelations).To(Equal(event.UpdatedRelations{{PropertyName: "Transitions", PropertyDesc: "Transitions",
+			TargetType: "TRANSITION", TargetTypeDesc: "TRANSITION", OldTargetId: "review",
+			OldTargetDesc: `{"name":"review","from":"OPEN","to":"REVIEW"}`}}))
+	})
+
+	t.Run("should forbid to query events of other project", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		wf, err := flow.CreateWorkflow(creationDemo, testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1"))
+		Expect(err).To(BeNil())
+
+		records, err := flow.QueryWorkflowEvents(wf.ID, testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_2"))
+		Expect(records).To(BeNil())
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+}
@@ -0,0 +1,234 @@
+package flow
+
+import (
+	"flywheel/account"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/idgen"
+	"flywheel/persistence"
+	"flywheel/session"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+	"github.com/sony/sonyflake"
+)
+
+var (
+	templateIdWorker = sonyflake.NewSonyflake(sonyflake.Settings{})
+
+	// BuiltinWorkflowTemplates are system wide templates which are not stored in database, they can not be changed or deleted
+	BuiltinWorkflowTemplates = []domain.WorkflowTemplateDetail{domain.GenericWorkflowTemplate}
+
+	CreateWorkflowTemplateFunc     = CreateWorkflowTemplate
+	QueryWorkflowTemplatesFunc     = QueryWorkflowTemplates
+	DetailWorkflowTemplateFunc     = DetailWorkflowTemplate
+	UpdateWorkflowTemplateFunc     = UpdateWorkflowTemplate
+	DeleteWorkflowTemplateFunc     = DeleteWorkflowTemplate
+	SaveWorkflowAsTemplateFunc     = SaveWorkflowAsTemplate
+	CreateWorkflowFromTemplateFunc = CreateWorkflowFromTemplate
+)
+
+type WorkflowTemplateRecord struct {
+	domain.WorkflowTemplate
+
+	Definition domain.WorkflowDefinition `sql:"type:MEDIUMTEXT"`
+}
+
+func (r *WorkflowTemplateRecord) TableName() string {
+	return "workflow_templates"
+}
+
+func (r *WorkflowTemplateRecord) detail() *domain.WorkflowTemplateDetail {
+	return &domain.WorkflowTemplateDetail{
+		WorkflowTemplate:    r.WorkflowTemplate,
+		StateMachine:        r.Definition.StateMachine,
+		PropertyDefinitions: r.Definition.PropertyDefinitions,
+	}
+}
+
+func CreateWorkflowTemplate(c *WorkflowTemplateCreation, s *session.Session) (*domain.WorkflowTemplateDetail, error) {
+	if !canManageWorkflowTemplate(c.ProjectID, s) {
+		return nil, bizerror.ErrForbidden
+	}
+	definition := domain.WorkflowDefinition{StateMachine: c.StateMachine, PropertyDefinitions: c.PropertyDefinitions}
+	if err := validateWorkflowDefinition(&definition); err != nil {
+		return nil, err
+	}
+
+	r := WorkflowTemplateRecord{
+		WorkflowTemplate: domain.WorkflowTemplate{ID: idgen.NextID(templateIdWorker), Name: c.Name, ProjectID: c.ProjectID,
+			CreatorID: s.Identity.ID, CreateTime: time.Now().Round(time.Millisecond)},
+		Definition: definition,
+	}
+	if err := persistence.ActiveDataSourceManager.GormDB(s.Context).Create(&r).Error; err != nil {
+		return nil, err
+	}
+	return r.detail(), nil
+}
+
+// QueryWorkflowTemplates returns the built-in templates first, then the stored templates in the order of creation
+func QueryWorkflowTemplates(q *WorkflowTemplateQuery, s *session.Session) ([]domain.WorkflowTemplate, error) {
+	if q.ProjectID != 0 && !s.Perms.HasProjectViewPerm(q.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+
+	templates := []domain.WorkflowTemplate{}
+	for _, t := range BuiltinWorkflowTemplates {
+		templates = append(templates, t.WorkflowTemplate)
+	}
+
+	var records []WorkflowTemplateRecord
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	if err := db.Where("project_id = 0 OR project_id = ?", q.ProjectID).Order("create_time ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		templates = append(templates, r.WorkflowTemplate)
+	}
+	return templates, nil
+}
+
+func DetailWorkflowTemplate(id types.ID, s *session.Session) (*domain.WorkflowTemplateDetail, error) {
+	if t := findBuiltinWorkflowTemplate(id); t != nil {
+		return t, nil
+	}
+	r, err := findWorkflowTemplateRecord(persistence.ActiveDataSourceManager.GormDB(s.Context), id)
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsSystemWide() && !s.Perms.HasProjectViewPerm(r.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+	return r.detail(), nil
+}
+
+func UpdateWorkflowTemplate(id types.ID, c *WorkflowTemplateUpdating, s *session.Session) error {
+	if findBuiltinWorkflowTemplate(id) != nil {
+		return bizerror.ErrForbidden
+	}
+	definition := domain.WorkflowDefinition{StateMachine: c.StateMachine, PropertyDefinitions: c.PropertyDefinitions}
+	if err := validateWorkflowDefinition(&definition); err != nil {
+		return err
+	}
+
+	return persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		r, err := findWorkflowTemplateRecord(tx, id)
+		if err != nil {
+			return err
+		}
+		if !canManageWorkflowTemplate(r.ProjectID, s) {
+			return bizerror.ErrForbidden
+		}
+		return tx.Model(&WorkflowTemplateRecord{}).Where("id = ?", id).
+			Update(map[string]interface{}{"name": c.Name, "definition": definition}).Error
+	})
+}
+
+func DeleteWorkflowTemplate(id types.ID, s *session.Session) error {
+	if findBuiltinWorkflowTemplate(id) != nil {
+		return bizerror.ErrForbidden
+	}
+	return persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		r, err := findWorkflowTemplateRecord(tx, id)
+		if err != nil {
+			return err
+		}
+		if !canManageWorkflowTemplate(r.ProjectID, s) {
+			return bizerror.ErrForbidden
+		}
+		return tx.Where("id = ?", id).Delete(&WorkflowTemplateRecord{}).Error
+	})
+}
+
+// SaveWorkflowAsTemplate saves the current definition of workflow as a template of project c.ProjectID,
+// or as a system wide template if c.ProjectID is 0
+func SaveWorkflowAsTemplate(workflowID types.ID, c *WorkflowTemplateSaving, s *session.Session) (*domain.WorkflowTemplateDetail, error) {
+	if !canManageWorkflowTemplate(c.ProjectID, s) {
+		return nil, bizerror.ErrForbidden
+	}
+
+	r := WorkflowTemplateRecord{
+		WorkflowTemplate: domain.WorkflowTemplate{ID: idgen.NextID(templateIdWorker), Name: c.Name, ProjectID: c.ProjectID,
+			CreatorID: s.Identity.ID, CreateTime: time.Now().Round(time.Millisecond)},
+	}
+	err := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		wf := domain.Workflow{}
+		if err := tx.Where(&domain.Workflow{ID: workflowID}).First(&wf).Error; err != nil {
+			return err
+		}
+		if !s.Perms.HasProjectViewPerm(wf.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+		definition, err := queryWorkflowDefinition(tx, wf.ID)
+		if err != nil {
+			return err
+		}
+		r.Definition = *definition
+		return tx.Create(&r).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.detail(), nil
+}
+
+// CreateWorkflowFromTemplate copies states, transitions and property definitions of template into a new workflow
+func CreateWorkflowFromTemplate(id types.ID, c *WorkflowInstantiation, s *session.Session) (*domain.WorkflowDetail, error) {
+	t, err := DetailWorkflowTemplate(id, s)
+	if err != nil {
+		return nil, err
+	}
+
+	creation := WorkflowCreation{Name: c.Name, ProjectID: c.ProjectID, ThemeColor: c.ThemeColor, ThemeIcon: c.ThemeIcon,
+		StateMachine: state.StateMachine{
+			States:      append([]state.State{}, t.StateMachine.States...),
+			Transitions: append([]state.Transition{}, t.StateMachine.Transitions...),
+		},
+		PropertyDefinitions: append([]domain.PropertyDefinition{}, t.PropertyDefinitions...),
+	}
+	return CreateWorkflow(&creation, s)
+}
+
+func validateWorkflowDefinition(d *domain.WorkflowDefinition) error {
+	if findings := d.StateMachine.Validate(); findings.HasError() {
+		return &state.ErrStateMachineInvalid{Findings: findings.Errors()}
+	}
+	for _, p := range d.PropertyDefinitions {
+		if err := p.ValidateOptions(); err != nil {
+			return err
+		}
+		if err := checkRequiredStates(p, &d.StateMachine); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canManageWorkflowTemplate: system wide templates are managed by system administrators,
+// templates of project are managed by managers of the project
+func canManageWorkflowTemplate(projectID types.ID, s *session.Session) bool {
+	if projectID == 0 {
+		return s.Perms.HasRole(account.SystemAdminPermission.ID)
+	}
+	return s.Perms.HasProjectRole(domain.ProjectRoleManager, projectID)
+}
+
+func findBuiltinWorkflowTemplate(id types.ID) *domain.WorkflowTemplateDetail {
+	for _, t := range BuiltinWorkflowTemplates {
+		if t.ID == id {
+			detail := t
+			return &detail
+		}
+	}
+	return nil
+}
+
+func findWorkflowTemplateRecord(tx *gorm.DB, id types.ID) (*WorkflowTemplateRecord, error) {
+	r := WorkflowTemplateRecord{}
+	if err := tx.Where("id = ?", id).First(&r).Error; err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
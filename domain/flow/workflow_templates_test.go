@@ -0,0 +1,136 @@
+package flow_test
+
+import (
+	"flywheel/account"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/testinfra"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkflowTemplates(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should serve built-in templates without database", func(t *testing.T) {
+		sec := testinfra.BuildSecCtx(100)
+		detail, err := flow.DetailWorkflowTemplate(domain.GenericWorkflowTemplate.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(*detail).To(Equal(domain.GenericWorkflowTemplate))
+
+		err = flow.UpdateWorkflowTemplate(domain.GenericWorkflowTemplate.ID, &flow.WorkflowTemplateUpdating{Name: "test"},
+			testinfra.BuildSecCtx(100, account.SystemAdminPermission.ID))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+		err = flow.DeleteWorkflowTemplate(domain.GenericWorkflowTemplate.ID, testinfra.BuildSecCtx(100, account.SystemAdminPermission.ID))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+
+	t.Run("should check permissions of creation", func(t *testing.T) {
+		creation := &flow.WorkflowTemplateCreation{Name: "test", StateMachine: domain.GenericWorkflowTemplate.StateMachine}
+		_, err := flow.CreateWorkflowTemplate(creation, testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		creation.ProjectID = 1
+		_, err = flow.CreateWorkflowTemplate(creation, testinfra.BuildSecCtx(100, domain.ProjectRoleCommon+"_1"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+
+	t.Run("should reject invalid definition", func(t *testing.T) {
+		creation := &flow.WorkflowTemplateCreation{Name: "test", ProjectID: 1, StateMachine: state.StateMachine{}}
+		_, err := flow.CreateWorkflowTemplate(creation, testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1"))
+		Expect(err).To(Equal(&state.ErrStateMachineInvalid{Findings: state.Findings{
+			{Level: state.FindingLevelError, Code: "state_machine.no_state", Message: "there is no state"}}}))
+	})
+
+	t.Run("should create, query, update and delete templates", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		admin := testinfra.BuildSecCtx(1, account.SystemAdminPermission.ID)
+		manager := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		systemTemplate, err := flow.CreateWorkflowTemplate(&flow.WorkflowTemplateCreation{Name: "system",
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, admin)
+		Expect(err).To(BeNil())
+		projectTemplate, err := flow.CreateWorkflowTemplate(&flow.WorkflowTemplateCreation{Name: "project", ProjectID: 1,
+			StateMachine:        domain.GenericWorkflowTemplate.StateMachine,
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "resolution", Type: domain.PropTypeText}}}, manager)
+		Expect(err).To(BeNil())
+		Expect(projectTemplate.CreatorID).To(Equal(types.ID(100)))
+		_, err = flow.CreateWorkflowTemplate(&flow.WorkflowTemplateCreation{Name: "other", ProjectID: 2,
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, testinfra.BuildSecCtx(200, domain.ProjectRoleManager+"_2"))
+		Expect(err).To(BeNil())
+
+		templates, err := flow.QueryWorkflowTemplates(&flow.WorkflowTemplateQuery{ProjectID: 1}, manager)
+		Expect(err).To(BeNil())
+		Expect(templates).To(Equal([]domain.WorkflowTemplate{domain.GenericWorkflowTemplate.WorkflowTemplate,
+			systemTemplate.WorkflowTemplate, projectTemplate.WorkflowTemplate}))
+		templates, err = flow.QueryWorkflowTemplates(&flow.WorkflowTemplateQuery{}, manager)
+		Expect(err).To(BeNil())
+		Expect(templates).To(Equal([]domain.WorkflowTemplate{domain.GenericWorkflowTemplate.WorkflowTemplate, systemTemplate.WorkflowTemplate}))
+		_, err = flow.QueryWorkflowTemplates(&flow.WorkflowTemplateQuery{ProjectID: 2}, manager)
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		detail, err := flow.DetailWorkflowTemplate(projectTemplate.ID, manager)
+		Expect(err).To(BeNil())
+		Expect(*detail).To(Equal(*projectTemplate))
+		_, err = flow.DetailWorkflowTemplate(projectTemplate.ID, testinfra.BuildSecCtx(200, domain.ProjectRoleManager+"_2"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		Expect(flow.UpdateWorkflowTemplate(systemTemplate.ID, &flow.WorkflowTemplateUpdating{Name: "system2",
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, manager)).To(Equal(bizerror.ErrForbidden))
+		Expect(flow.UpdateWorkflowTemplate(projectTemplate.ID, &flow.WorkflowTemplateUpdating{Name: "project2",
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, manager)).To(BeNil())
+		detail, err = flow.DetailWorkflowTemplate(projectTemplate.ID, manager)
+		Expect(err).To(BeNil())
+		Expect(detail.Name).To(Equal("project2"))
+		Expect(detail.PropertyDefinitions).To(BeEmpty())
+
+		Expect(flow.DeleteWorkflowTemplate(systemTemplate.ID, manager)).To(Equal(bizerror.ErrForbidden))
+		Expect(flow.DeleteWorkflowTemplate(systemTemplate.ID, admin)).To(BeNil())
+		_, err = flow.DetailWorkflowTemplate(systemTemplate.ID, manager)
+		Expect(err).To(Equal(gorm.ErrRecordNotFound))
+	})
+
+	t.Run("should save workflow as template and create workflow from template", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1", domain.ProjectRoleManager+"_2")
+		workflow, err := flow.CreateWorkflowFromTemplate(domain.GenericWorkflowTemplate.ID,
+			&flow.WorkflowInstantiation{Name: "generic", ProjectID: 1, ThemeColor: "blue", ThemeIcon: "foo"}, sec)
+		Expect(err).To(BeNil())
+		Expect(workflow.Version).To(Equal(1))
+		Expect(workflow.StateMachine.Transitions).To(Equal(domain.GenericWorkflowTemplate.StateMachine.Transitions))
+		Expect(domain.GenericWorkflowTemplate.StateMachine.States[0].Order).To(Equal(1))
+
+		_, err = flow.CreatePropertyDefinition(workflow.ID, domain.PropertyDefinition{Name: "resolution", Type: domain.PropTypeText}, sec)
+		Expect(err).To(BeNil())
+
+		template, err := flow.SaveWorkflowAsTemplate(workflow.ID, &flow.WorkflowTemplateSaving{Name: "saved", ProjectID: 2}, sec)
+		Expect(err).To(BeNil())
+		Expect(template.ProjectID).To(Equal(types.ID(2)))
+		Expect(len(template.StateMachine.States)).To(Equal(3))
+		Expect(template.PropertyDefinitions).To(ContainElement(domain.PropertyDefinition{Name: "resolution", Type: domain.PropTypeText}))
+
+		_, err = flow.SaveWorkflowAsTemplate(workflow.ID, &flow.WorkflowTemplateSaving{Name: "saved"}, sec)
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		copied, err := flow.CreateWorkflowFromTemplate(template.ID,
+			&flow.WorkflowInstantiation{Name: "copied", ProjectID: 2, ThemeColor: "blue", ThemeIcon: "foo"}, sec)
+		Expect(err).To(BeNil())
+		detail, err := flow.DetailWorkflowVersion(copied.ID, 1, sec)
+		Expect(err).To(BeNil())
+		Expect(detail.StateMachine.States).To(Equal(template.StateMachine.States))
+		Expect(detail.PropertyDefinitions).To(Equal(template.PropertyDefinitions))
+
+		_, err = flow.CreateWorkflowFromTemplate(template.ID,
+			&flow.WorkflowInstantiation{Name: "copied", ProjectID: 3, ThemeColor: "blue", ThemeIcon: "foo"}, sec)
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+}
@@ -0,0 +1,236 @@
+package flow
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/event"
+	"flywheel/persistence"
+	"flywheel/session"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	DetailWorkflowVersionFunc = DetailWorkflowVersion
+	MigrateWorksFunc          = MigrateWorks
+)
+
+// DetailWorkflowVersion returns the definition of workflow at the specified version
+func DetailWorkflowVersion(id types.ID, version int, s *session.Session) (*domain.WorkflowDetail, error) {
+	workflowDetail := domain.WorkflowDetail{}
+	err := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&domain.Workflow{ID: id}).First(&(workflowDetail.Workflow)).Error; err != nil {
+			return err
+		}
+		if !s.Perms.HasProjectViewPerm(workflowDetail.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+		definition, err := loadWorkflowDefinition(tx, &workflowDetail.Workflow, version)
+		if err != nil {
+			return err
+		}
+		workflowDetail.Version = version
+		workflowDetail.StateMachine = definition.StateMachine
+		workflowDetail.PropertyDefinitions = definition.PropertyDefinitions
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &workflowDetail, nil
+}
+
+func loadWorkflowDefinition(tx *gorm.DB, wf *domain.Workflow, version int) (*domain.WorkflowDefinition, error) {
+	if version == wf.Version {
+		return queryWorkflowDefinition(tx, wf.ID)
+	}
+	snapshot := domain.WorkflowVersion{}
+	if err := tx.Where("workflow_id = ? AND version = ?", wf.ID, version).First(&snapshot).Error; err == gorm.ErrRecordNotFound {
+		return nil, bizerror.ErrWorkflowVersionInvalid
+	} else if err != nil {
+		return nil, err
+	}
+	return &snapshot.Definition, nil
+}
+
+func queryWorkflowDefinition(tx *gorm.DB, workflowID types.ID) (*domain.WorkflowDefinition, error) {
+	stateMachine, err := queryStateMachine(tx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	var propertyDefinitions []WorkflowPropertyDefinition
+	if err := tx.Where("workflow_id = ?", workflowID).Order("name ASC").Find(&propertyDefinitions).Error; err != nil {
+		return nil, err
+	}
+	definition := domain.WorkflowDefinition{StateMachine: *stateMachine, PropertyDefinitions: []domain.PropertyDefinition{}}
+	for _, d := range propertyDefinitions {
+		definition.PropertyDefinitions = append(definition.PropertyDefinitions, d.PropertyDefinition)
+	}
+	return &definition, nil
+}
+
+// onlyOrdersChanged tells whether the changes just rearrange states, the order of states is a matter of presentation,
+// so that such changes do not make a new version of workflow
+func onlyOrdersChanged(changes []DefinitionChange) bool {
+	for _, c := range changes {
+		if c.Action != ChangeModified || c.Kind != ChangeKindState {
+			return false
+		}
+		fields := stateChangedFields(c.Old.(state.State), c.New.(state.State))
+		if len(fields) != 1 || fields[0] != "order" {
+			return false
+		}
+	}
+	return true
+}
+
+// nextWorkflowVersion must be called in the transaction which changes the definition of workflow, before any change is made.
+// It freezes the current definition as a snapshot, the definition changed later in the transaction becomes the next version.
+func nextWorkflowVersion(tx *gorm.DB, workflowID types.ID) error {
+	wf := domain.Workflow{}
+	if err := tx.Where(&domain.Workflow{ID: workflowID}).First(&wf).Error; err != nil {
+		return err
+	}
+	definition, err := queryWorkflowDefinition(tx, wf.ID)
+	if err != nil {
+		return err
+	}
+	snapshot := domain.WorkflowVersion{WorkflowID: wf.ID, Version: wf.Version, Definition: *definition,
+		CreateTime: time.Now().Round(time.Millisecond)}
+	if err := tx.Create(&snapshot).Error; err != nil {
+		return err
+	}
+	return tx.Model(&domain.Workflow{}).Where(&domain.Workflow{ID: wf.ID}).Update("version", wf.Version+1).Error
+}
+
+// MigrateWorks moves works from one version of workflow to a later one. The process step of the state which a work stays in
+// is closed and a new one is opened on the target version if the state is mapped to another one, the history steps are kept untouched.
+func MigrateWorks(id types.ID, m *WorkMigration, s *session.Session) (*WorkMigrationResult, error) {
+	result := WorkMigrationResult{}
+	var events []*event.EventRecord
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		wf := domain.Workflow{}
+		if err := tx.Where(&domain.Workflow{ID: id}).First(&wf).Error; err != nil {
+			return err
+		}
+		if !s.Perms.HasProjectRole(domain.ProjectRoleManager, wf.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+		if m.FromVersion < 0 || m.ToVersion <= m.FromVersion || m.ToVersion > wf.Version {
+			return bizerror.ErrWorkflowVersionInvalid
+		}
+		from, err := loadWorkflowDefinition(tx, &wf, m.FromVersion)
+		if err != nil {
+			return err
+		}
+		to, err := loadWorkflowDefinition(tx, &wf, m.ToVersion)
+		if err != nil {
+			return err
+		}
+		for origin := range m.StateMapping {
+			if _, found := from.StateMachine.FindState(origin); !found {
+				return bizerror.ErrUnknownState
+			}
+		}
+
+		var works []domain.Work
+		if err := tx.Where("flow_id = ? AND flow_version = ?", wf.ID, m.FromVersion).Find(&works).Error; err != nil {
+			return err
+		}
+
+		unmapped := map[string]bool{}
+		for _, w := range works {
+			if target, found := to.StateMachine.FindState(MapState(m.StateMapping, w.StateName)); !found || !to.StateMachine.IsLeaf(target.Name) {
+				unmapped[w.StateName] = true
+			}
+		}
+		if len(unmapped) > 0 {
+			var states []string
+			for name := range unmapped {
+				states = append(states, name)
+			}
+			sort.Strings(states)
+			return &bizerror.ErrStatesUnmapped{States: states}
+		}
+
+		now := types.CurrentTimestamp()
+		for _, w := range works {
+			target, _ := to.StateMachine.FindState(MapState(m.StateMapping, w.StateName))
+			ev, err := moveWork(tx, &wf, &w, target, m.ToVersion, now, s)
+			if err != nil {
+				return err
+			}
+			events = append(events, ev)
+		}
+		result.MigratedWorks = len(works)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if event.InvokeHandlersFunc != nil {
+		for _, ev := range events {
+			event.InvokeHandlersFunc(ev)
+		}
+	}
+	return &result, nil
+}
+
+// moveWork puts work into state target of workflow version toVersion without checking transitions,
+// the open process step is closed and a new one is opened if the state is changed.
+// The state begin time and process times are updated as what a transition into target does.
+func moveWork(tx *gorm.DB, wf *domain.Workflow, w *domain.Work, target state.State, toVersion int, now types.Timestamp, s *session.Session) (*event.EventRecord, error) {
+	updates := map[string]interface{}{"flow_version": toVersion, "state_name": target.Name, "state_category": target.Category}
+	if target.Name != w.StateName {
+		updates["state_begin_time"] = now
+		for column, value := range w.EnterProcessState(target.Category, now) {
+			updates[column] = value
+		}
+	}
+	if err := tx.Model(&domain.Work{}).Where(&domain.Work{ID: w.ID}).Update(updates).Error; err != nil {
+		return nil, err
+	}
+
+	var changes []event.UpdatedProperty
+	if toVersion != w.FlowVersion {
+		changes = append(changes, event.UpdatedProperty{
+			PropertyName: "FlowVersion", PropertyDesc: "FlowVersion",
+			OldValue: strconv.Itoa(w.FlowVersion), OldValueDesc: strconv.Itoa(w.FlowVersion),
+			NewValue: strconv.Itoa(toVersion), NewValueDesc: strconv.Itoa(toVersion),
+		})
+	}
+	if target.Name != w.StateName {
+		if err := tx.Model(&domain.WorkProcessStep{}).
+			Where(&domain.WorkProcessStep{WorkID: w.ID, StateName: w.StateName}).
+			Where("end_time = ?", types.Timestamp{}).
+			Update(&domain.WorkProcessStep{EndTime: now, NextStateName: target.Name, NextStateCategory: target.Category}).Error; err != nil {
+			return nil, err
+		}
+		step := domain.WorkProcessStep{WorkID: w.ID, FlowID: wf.ID, FlowVersion: toVersion, CreatorID: s.Identity.ID, CreatorName: s.Identity.Nickname,
+			StateName: target.Name, StateCategory: target.Category, BeginTime: now}
+		if err := tx.Create(step).Error; err != nil {
+			return nil, err
+		}
+		changes = append(changes, event.UpdatedProperty{
+			PropertyName: "StateName", PropertyDesc: "StateName",
+			OldValue: w.StateName, OldValueDesc: w.StateName,
+			NewValue: target.Name, NewValueDesc: target.Name,
+		})
+	}
+	return event.CreateEvent("WORK", w.ID, w.Identifier, event.EventCategoryExtensionUpdated, changes, nil, &s.Identity, now, tx)
+}
+
+// MapState returns the state which the state is mapped to, the state is mapped to the state of the same name by default
+func MapState(mapping map[string]string, stateName string) string {
+	if target, found := mapping[stateName]; found {
+		return target
+	}
+	return stateName
+}
@@ -0,0 +1,228 @@
+package flow_test
+
+import (
+	"context"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/event"
+	"flywheel/session"
+	"flywheel/testinfra"
+	"testing"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkflowVersions(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should create a new version for each change of definition", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test work", ProjectID: types.ID(1),
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, sec)
+		Expect(err).To(BeNil())
+		Expect(workflow.Version).To(Equal(1))
+
+		Expect(flow.CreateState(workflow.ID, &flow.StateCreating{Name: "REVIEW", Category: state.InProcess, Order: 20001,
+			Transitions: []state.Transition{{Name: "review", From: domain.StateDoing.Name, To: "REVIEW"},
+				{Name: "pass", From: "REVIEW", To: domain.StateDone.Name}}}, sec)).To(BeNil())
+		_, err = flow.CreatePropertyDefinition(workflow.ID, domain.PropertyDefinition{Name: "resolution", Type: domain.PropTypeText}, sec)
+		Expect(err).To(BeNil())
+
+		head, err := flow.DetailWorkflowVersion(workflow.ID, 3, sec)
+		Expect(err).To(BeNil())
+		Expect(head.Version).To(Equal(3))
+		Expect(len(head.StateMachine.States)).To(Equal(4))
+		Expect(head.PropertyDefinitions).To(Equal([]domain.PropertyDefinition{{Name: "resolution", Type: domain.PropTypeText}}))
+
+		v2, err := flow.DetailWorkflowVersion(workflow.ID, 2, sec)
+		Expect(err).To(BeNil())
+		Expect(v2.Version).To(Equal(2))
+		Expect(len(v2.StateMachine.States)).To(Equal(4))
+		Expect(v2.PropertyDefinitions).To(Equal([]domain.PropertyDefinition{}))
+
+		v1, err := flow.DetailWorkflowVersion(workflow.ID, 1, sec)
+		Expect(err).To(BeNil())
+		Expect(v1.StateMachine.States).To(Equal(workflow.StateMachine.States))
+		Expect(v1.StateMachine.Transitions).To(HaveLen(len(workflow.StateMachine.Transitions)))
+
+		_, err = flow.DetailWorkflowVersion(workflow.ID, 4, sec)
+		Expect(err).To(Equal(bizerror.ErrWorkflowVersionInvalid))
+		_, err = flow.DetailWorkflowVersion(workflow.ID, 1, testinfra.BuildSecCtx(200, domain.ProjectRoleManager+"_2"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+
+	t.Run("should not create new version when change is rejected", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test work", ProjectID: types.ID(1),
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, sec)
+		Expect(err).To(BeNil())
+
+		err = flow.CreateWorkflowStateTransitions(workflow.ID, []state.Transition{{Name: "start", From: "NotExist", To: domain.StateDoing.Name}}, sec)
+		Expect(err).To(Equal(bizerror.ErrUnknownState))
+
+		detail, err := flow.DetailWorkflow(workflow.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(detail.Version).To(Equal(1))
+		var versions []domain.WorkflowVersion
+		Expect(testDatabase.DS.GormDB(context.Background()).Find(&versions).Error).To(BeNil())
+		Expect(versions).To(BeEmpty())
+	})
+}
+
+func TestMigrateWorks(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	prepare := func(sec *session.Session) *domain.WorkflowDetail {
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test work", ProjectID: types.ID(1),
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, sec)
+		Expect(err).To(BeNil())
+
+		now := types.CurrentTimestamp()
+		db := testDatabase.DS.GormDB(context.Background())
+		Expect(db.Create(domain.Work{ID: 1, Identifier: "W-1", Name: "w1", ProjectID: 1, CreateTime: now, FlowID: workflow.ID, FlowVersion: 1,
+			StateName: domain.StatePending.Name, StateCategory: domain.StatePending.Category, StateBeginTime: now}).Error).To(BeNil())
+		Expect(db.Create(domain.Work{ID: 2, Identifier: "W-2", Name: "w2", ProjectID: 1, CreateTime: now, FlowID: workflow.ID, FlowVersion: 1,
+			StateName: domain.StateDoing.Name, StateCategory: domain.StateDoing.Category, StateBeginTime: now}).Error).To(BeNil())
+		Expect(db.Create(domain.WorkProcessStep{WorkID: 1, FlowID: workflow.ID, FlowVersion: 1,
+			StateName: domain.StatePending.Name, StateCategory: domain.StatePending.Category, BeginTime: now}).Error).To(BeNil())
+		Expect(db.Create(domain.WorkProcessStep{WorkID: 2, FlowID: workflow.ID, FlowVersion: 1,
+			StateName: domain.StateDoing.Name, StateCategory: domain.StateDoing.Category, BeginTime: now}).Error).To(BeNil())
+
+		// version 2: PENDING is renamed to QUEUED
+		Expect(flow.UpdateWorkflowState(workflow.ID, flow.WorkflowStateUpdating{OriginName: domain.StatePending.Name, Name: "QUEUED", Order: 1}, sec)).To(BeNil())
+		return workflow
+	}
+
+	t.Run("should validate versions and permissions", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow := prepare(sec)
+
+		_, err := flow.MigrateWorks(workflow.ID, &flow.WorkMigration{FromVersion: 1, ToVersion: 2}, testinfra.BuildSecCtx(100, "guest_1"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+		_, err = flow.MigrateWorks(workflow.ID, &flow.WorkMigration{FromVersion: 1, ToVersion: 3}, sec)
+		Expect(err).To(Equal(bizerror.ErrWorkflowVersionInvalid))
+		_, err = flow.MigrateWorks(workflow.ID, &flow.WorkMigration{FromVersion: 2, ToVersion: 1}, sec)
+		Expect(err).To(Equal(bizerror.ErrWorkflowVersionInvalid))
+		_, err = flow.MigrateWorks(workflow.ID, &flow.WorkMigration{FromVersion: 1, ToVersion: 2,
+			StateMapping: map[string]string{"UNKNOWN": "QUEUED"}}, sec)
+		Expect(err).To(Equal(bizerror.ErrUnknownState))
+	})
+
+	t.Run("should reject migration when states are not mapped", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow := prepare(sec)
+
+		_, err := flow.MigrateWorks(workflow.ID, &flow.WorkMigration{FromVersion: 1, ToVersion: 2}, sec)
+		Expect(err).To(Equal(&bizerror.ErrStatesUnmapped{States: []string{domain.StatePending.Name}}))
+	})
+
+	t.Run("should migrate works with state mapping", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		handedEvents := []event.EventRecord{}
+		event.InvokeHandlersFunc = func(record *event.EventRecord) []event.EventHandleResult {
+			handedEvents = append(handedEvents, *record)
+			return nil
+		}
+		event.EventPersistCreateFunc = func(record *event.EventRecord, db *gorm.DB) error {
+			return nil
+		}
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow := prepare(sec)
+		handedEvents = []event.EventRecord{} // events of workflow changes
+
+		result, err := flow.MigrateWorks(workflow.ID, &flow.WorkMigration{FromVersion: 1, ToVersion: 2,
+			StateMapping: map[string]string{domain.StatePending.Name: "QUEUED"}}, sec)
+		Expect(err).To(BeNil())
+		Expect(*result).To(Equal(flow.WorkMigrationResult{MigratedWorks: 2}))
+
+		db := testDatabase.DS.GormDB(context.Background())
+		var works []domain.Work
+		Expect(db.Order("id ASC").Find(&works).Error).To(BeNil())
+		Expect(works[0].FlowVersion).To(Equal(2))
+		Expect(works[0].StateName).To(Equal("QUEUED"))
+		Expect(works[1].FlowVersion).To(Equal(2))
+		Expect(works[1].StateName).To(Equal(domain.StateDoing.Name))
+
+		// the step of renamed state is closed, and the history keeps referring to version 1
+		var steps []domain.WorkProcessStep
+		Expect(db.Where(&domain.WorkProcessStep{WorkID: 1}).Order("flow_version ASC").Find(&steps).Error).To(BeNil())
+		Expect(len(steps)).To(Equal(2))
+		Expect(steps[0].FlowVersion).To(Equal(1))
+		Expect(steps[0].StateName).To(Equal(domain.StatePending.Name))
+		Expect(steps[0].EndTime.IsZero()).To(BeFalse())
+		Expect(steps[0].NextStateName).To(Equal("QUEUED"))
+		Expect(steps[1].FlowVersion).To(Equal(2))
+		Expect(steps[1].StateName).To(Equal("QUEUED"))
+		Expect(steps[1].EndTime.IsZero()).To(BeTrue())
+
+		Expect(db.Where(&domain.WorkProcessStep{WorkID: 2}).Find(&steps).Error).To(BeNil())
+		Expect(len(steps)).To(Equal(1))
+		Expect(steps[0].FlowVersion).To(Equal(1))
+
+		Expect(len(handedEvents)).To(Equal(2))
+		Expect(handedEvents[0].UpdatedProperties).To(Equal(event.UpdatedProperties{
+			{PropertyName: "FlowVersion", PropertyDesc: "FlowVersion", OldValue: "1", OldValueDesc: "1", NewValue: "2", NewValueDesc: "2"},
+			{PropertyName: "StateName", PropertyDesc: "StateName", OldValue: domain.StatePending.Name, OldValueDesc: domain.StatePending.Name,
+				NewValue: "QUEUED", NewValueDesc: "QUEUED"},
+		}))
+		Expect(handedEvents[1].UpdatedProperties).To(Equal(event.UpdatedProperties{
+			{PropertyName: "FlowVersion", PropertyDesc: "FlowVersion", OldValue: "1", OldValueDesc: "1", NewValue: "2", NewValueDesc: "2"},
+		}))
+	})
+
+	t.Run("should update state begin time and process times when state category is changed", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		event.EventPersistCreateFunc = func(record *event.EventRecord, db *gorm.DB) error {
+			return nil
+		}
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow := prepare(sec)
+		db := testDatabase.DS.GormDB(context.Background())
+		stale := types.TimestampOfDate(2020, 1, 2, 3, 4, 5, 0, time.Local)
+		Expect(db.Model(&domain.Work{}).Where("id IN (?)", []types.ID{1, 2}).Update("state_begin_time", stale).Error).To(BeNil())
+		Expect(db.Model(&domain.Work{}).Where("id = ?", 2).
+			Update(map[string]interface{}{"process_begin_time": stale, "process_end_time": stale}).Error).To(BeNil())
+
+		begin := time.Now().Truncate(time.Second)
+		_, err := flow.MigrateWorks(workflow.ID, &flow.WorkMigration{FromVersion: 1, ToVersion: 2,
+			StateMapping: map[string]string{domain.StatePending.Name: domain.StateDone.Name, domain.StateDoing.Name: "QUEUED"}}, sec)
+		Expect(err).To(BeNil())
+
+		var works []domain.Work
+		Expect(db.Order("id ASC").Find(&works).Error).To(BeNil())
+		// backlog work is moved into a done state
+		Expect(works[0].StateName).To(Equal(domain.StateDone.Name))
+		Expect(works[0].StateBeginTime.Time().Before(begin)).To(BeFalse())
+		Expect(works[0].ProcessBeginTime).To(Equal(works[0].StateBeginTime))
+		Expect(works[0].ProcessEndTime).To(Equal(works[0].StateBeginTime))
+		// work in process is moved back to backlog, the process is not ended any more
+		Expect(works[1].StateName).To(Equal("QUEUED"))
+		Expect(works[1].StateBeginTime.Time().Before(begin)).To(BeFalse())
+		Expect(works[1].ProcessBeginTime.Time().Equal(stale.Time())).To(BeTrue())
+		Expect(works[1].ProcessEndTime.IsZero()).To(BeTrue())
+	})
+}
@@ -0,0 +1,81 @@
+package flow
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/persistence"
+	"flywheel/session"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+var QueryStateLoadsFunc = QueryStateLoads
+
+// QueryStateLoads counts the unarchived works in each state of the current definition of workflow
+func QueryStateLoads(workflow *domain.WorkflowDetail, s *session.Session) ([]domain.StateLoad, error) {
+	if !s.Perms.HasProjectViewPerm(workflow.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+	counts, err := countStateWorks(persistence.ActiveDataSourceManager.GormDB(s.Context), workflow.ID)
+	if err != nil {
+		return nil, err
+	}
+	loads := []domain.StateLoad{}
+	for _, st := range workflow.StateMachine.States {
+		works := counts[st.Name]
+		loads = append(loads, domain.StateLoad{State: st.Name, Works: works, WipLimit: st.WipLimit,
+			Exceeded: st.WipLimit > 0 && works > st.WipLimit})
+	}
+	return loads, nil
+}
+
+// CheckWipLimit refuses a new work entering the target state if the state is full, or returns a warning finding
+// if the wip limit of the state is soft. It should be called in the transaction which moves the work, the state
+// is locked before counting so that concurrent transactions can not overfill it.
+func CheckWipLimit(tx *gorm.DB, workflowID types.ID, target state.State) (*state.Finding, error) {
+	if target.WipLimit <= 0 {
+		return nil, nil
+	}
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("workflow_id = ? AND name = ?", workflowID, target.Name).
+		First(&domain.WorkflowState{}).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return CountStateLoad(tx, workflowID, target)
+}
+
+// CountStateLoad is the same as CheckWipLimit but nothing is locked, it is used to predict the outcome of a transition
+// without blocking the transitions which are really performed.
+func CountStateLoad(db *gorm.DB, workflowID types.ID, target state.State) (*state.Finding, error) {
+	if target.WipLimit <= 0 {
+		return nil, nil
+	}
+	works := 0
+	if err := db.Model(&domain.Work{}).Where("flow_id = ? AND state_name = ? AND archive_time = ?", workflowID, target.Name, types.Timestamp{}).
+		Count(&works).Error; err != nil {
+		return nil, err
+	}
+	if works < target.WipLimit {
+		return nil, nil
+	}
+	exceeded := &bizerror.ErrWipLimitExceeded{State: target.Name, WipLimit: target.WipLimit, Works: works}
+	if !target.WipSoft {
+		return nil, exceeded
+	}
+	return &state.Finding{Level: state.FindingLevelWarning, Code: "state.wip_limit_exceeded", Message: exceeded.Error(), State: target.Name}, nil
+}
+
+func countStateWorks(db *gorm.DB, workflowID types.ID) (map[string]int, error) {
+	var records []stateWorkCount
+	if err := db.Model(&domain.Work{}).Select("state_name, COUNT(*) AS count").
+		Where("flow_id = ? AND archive_time = ?", workflowID, types.Timestamp{}).
+		Group("state_name").Scan(&records).Error; err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, r := range records {
+		counts[r.StateName] = r.Count
+	}
+	return counts, nil
+}
@@ -23,12 +23,14 @@ var (
 	QueryWorkflowsFunc     = QueryWorkflows
 	DetailWorkflowFunc     = DetailWorkflow
 	CreateWorkflowFunc     = CreateWorkflow
+	CloneWorkflowFunc      = CloneWorkflow
 	DeleteWorkflowFunc     = DeleteWorkflow
 	UpdateWorkflowBaseFunc = UpdateWorkflowBase
 
 	CreateStateFunc                    = CreateState
 	UpdateWorkflowStateFunc            = UpdateWorkflowState
 	UpdateStateRangeOrdersFunc         = UpdateStateRangeOrders
+	DeleteStateFunc                    = DeleteState
 	CreateWorkflowStateTransitionsFunc = CreateWorkflowStateTransitions
 	DeleteWorkflowStateTransitionsFunc = DeleteWorkflowStateTransitions
 )
@@ -37,6 +39,9 @@ func CreateWorkflow(c *WorkflowCreation, s *session.Session) (*domain.WorkflowDe
 	if !s.Perms.HasAnyProjectRole(c.ProjectID) {
 		return nil, bizerror.ErrForbidden
 	}
+	if err := validateWorkflowDefinition(&domain.WorkflowDefinition{StateMachine: c.StateMachine, PropertyDefinitions: c.PropertyDefinitions}); err != nil {
+		return nil, err
+	}
 
 	workflow := &domain.WorkflowDetail{
 		Workflow: domain.Workflow{
@@ -46,8 +51,10 @@ func CreateWorkflow(c *WorkflowCreation, s *session.Session) (*domain.WorkflowDe
 			ThemeColor: c.ThemeColor,
 			ThemeIcon:  c.ThemeIcon,
 			CreateTime: time.Now().Round(time.Millisecond),
+			Version:    1,
 		},
-		StateMachine: c.StateMachine,
+		StateMachine:        c.StateMachine,
+		PropertyDefinitions: c.PropertyDefinitions,
 	}
 
 	stateNum := len(workflow.StateMachine.States)
@@ -55,6 +62,7 @@ func CreateWorkflow(c *WorkflowCreation, s *session.Session) (*domain.WorkflowDe
 		workflow.StateMachine.States[idx].Order = 10000 + idx + 1
 	}
 
+	var ev *event.EventRecord
 	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
 	err := db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(workflow.Workflow).Error; err != nil {
@@ -63,6 +71,7 @@ func CreateWorkflow(c *WorkflowCreation, s *session.Session) (*domain.WorkflowDe
 		for _, s := range workflow.StateMachine.States {
 			stateEntity := &domain.WorkflowState{
 				WorkflowID: workflow.ID, Order: s.Order, Name: s.Name, Category: s.Category, CreateTime: workflow.CreateTime,
+				WipLimit: s.WipLimit, WipSoft: s.WipSoft, Reasons: s.Reasons, Parent: s.Parent,
 			}
 			if err := tx.Create(stateEntity).Error; err != nil {
 				return err
@@ -71,20 +80,60 @@ func CreateWorkflow(c *WorkflowCreation, s *session.Session) (*domain.WorkflowDe
 		for _, t := range workflow.StateMachine.Transitions {
 			transition := &domain.WorkflowStateTransition{
 				WorkflowID: workflow.ID, Name: t.Name, FromState: t.From, ToState: t.To, CreateTime: workflow.CreateTime,
+				Guards: t.Guards, Hooks: t.Hooks, Triggers: t.Triggers,
 			}
 			if err := tx.Create(transition).Error; err != nil {
 				return err
 			}
 		}
-		return nil
+		for _, p := range workflow.PropertyDefinitions {
+			d := &WorkflowPropertyDefinition{ID: idgen.NextID(propertyDefinitionIdWorker), WorkflowID: workflow.ID, PropertyDefinition: p}
+			if err := tx.Create(d).Error; err != nil {
+				return err
+			}
+		}
+		var err error
+		ev, err = createWorkflowEvent(&workflow.Workflow, event.EventCategoryCreated, s, tx)
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	invokeEventHandlers(ev)
 	return workflow, nil
 }
 
+// CloneWorkflow copies states, transitions and property definitions of workflow into a new workflow,
+// the caller must be manager of both the project of workflow and the target project
+func CloneWorkflow(id types.ID, c *WorkflowCloning, s *session.Session) (*domain.WorkflowDetail, error) {
+	if !s.Perms.HasProjectRole(domain.ProjectRoleManager, c.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+
+	var creation *WorkflowCreation
+	err := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		wf := domain.Workflow{}
+		if err := tx.Where(&domain.Workflow{ID: id}).First(&wf).Error; err != nil {
+			return err
+		}
+		if !s.Perms.HasProjectRole(domain.ProjectRoleManager, wf.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+		definition, err := queryWorkflowDefinition(tx, wf.ID)
+		if err != nil {
+			return err
+		}
+		creation = &WorkflowCreation{Name: c.Name, ProjectID: c.ProjectID, ThemeColor: wf.ThemeColor, ThemeIcon: wf.ThemeIcon,
+			StateMachine: definition.StateMachine, PropertyDefinitions: definition.PropertyDefinitions}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return CreateWorkflow(creation, s)
+}
+
 func DetailWorkflow(id types.ID, s *session.Session) (*domain.WorkflowDetail, error) {
 	workflowDetail := domain.WorkflowDetail{}
 	err := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
@@ -95,28 +144,11 @@ func DetailWorkflow(id types.ID, s *session.Session) (*domain.WorkflowDetail, er
 			return bizerror.ErrForbidden
 		}
 
-		var stateRecords []domain.WorkflowState
-		if err := tx.Where(domain.WorkflowState{WorkflowID: workflowDetail.ID}).Order("`order` ASC").Find(&stateRecords).Error; err != nil {
-			return err
-		}
-		var transitionRecords []domain.WorkflowStateTransition
-		if err := tx.Where(domain.WorkflowStateTransition{WorkflowID: workflowDetail.ID}).Find(&transitionRecords).Error; err != nil {
+		stateMachine, err := loadStateMachine(tx, workflowDetail.ID)
+		if err != nil {
 			return err
 		}
-		stateMachine := state.StateMachine{}
-		for _, record := range stateRecords {
-			stateMachine.States = append(stateMachine.States, state.State{Name: record.Name, Category: record.Category, Order: record.Order})
-		}
-		for _, record := range transitionRecords {
-			from, fromStateFound := stateMachine.FindState(record.FromState)
-			to, toStateFound := stateMachine.FindState(record.ToState)
-			if !fromStateFound || !toStateFound {
-				return bizerror.ErrStateInvalid
-			}
-			stateMachine.Transitions = append(stateMachine.Transitions, state.Transition{Name: record.Name, From: from.Name, To: to.Name})
-		}
-
-		workflowDetail.StateMachine = stateMachine
+		workflowDetail.StateMachine = *stateMachine
 		return nil
 	})
 
@@ -127,8 +159,58 @@ func DetailWorkflow(id types.ID, s *session.Session) (*domain.WorkflowDetail, er
 	return &workflowDetail, nil
 }
 
+func loadStateMachine(tx *gorm.DB, workflowID types.ID) (*state.StateMachine, error) {
+	stateMachine, err := queryStateMachine(tx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range stateMachine.Transitions {
+		_, fromStateFound := stateMachine.FindState(t.From)
+		_, toStateFound := stateMachine.FindState(t.To)
+		if !fromStateFound || !toStateFound {
+			return nil, bizerror.ErrStateInvalid
+		}
+	}
+	return stateMachine, nil
+}
+
+func queryStateMachine(tx *gorm.DB, workflowID types.ID) (*state.StateMachine, error) {
+	var stateRecords []domain.WorkflowState
+	if err := tx.Where(domain.WorkflowState{WorkflowID: workflowID}).Order("`order` ASC").Find(&stateRecords).Error; err != nil {
+		return nil, err
+	}
+	var transitionRecords []domain.WorkflowStateTransition
+	if err := tx.Where(domain.WorkflowStateTransition{WorkflowID: workflowID}).Find(&transitionRecords).Error; err != nil {
+		return nil, err
+	}
+	stateMachine := state.StateMachine{}
+	for _, record := range stateRecords {
+		stateMachine.States = append(stateMachine.States, state.State{Name: record.Name, Category: record.Category, Order: record.Order,
+			WipLimit: record.WipLimit, WipSoft: record.WipSoft, Reasons: record.Reasons, Parent: record.Parent})
+	}
+	for _, record := range transitionRecords {
+		stateMachine.Transitions = append(stateMachine.Transitions, state.Transition{Name: record.Name, From: record.FromState, To: record.ToState,
+			Guards: record.Guards, Hooks: record.Hooks, Triggers: record.Triggers})
+	}
+	return &stateMachine, nil
+}
+
+// checkStateMachine validates the state machine of workflow after it is changed in transaction tx,
+// the changes will be rolled back if there are error findings.
+func checkStateMachine(tx *gorm.DB, workflowID types.ID) error {
+	stateMachine, err := queryStateMachine(tx, workflowID)
+	if err != nil {
+		return err
+	}
+	if findings := stateMachine.Validate(); findings.HasError() {
+		return &state.ErrStateMachineInvalid{Findings: findings.Errors()}
+	}
+	return nil
+}
+
 func DeleteWorkflow(id types.ID, s *session.Session) error {
 	wf := domain.Workflow{}
+	var ev *event.EventRecord
 	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
 	err := db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where(&domain.Workflow{ID: id}).First(&wf).Error; err != nil {
@@ -158,10 +240,20 @@ func DeleteWorkflow(id types.ID, s *session.Session) error {
 			Delete(&WorkflowPropertyDefinition{}).Error; err != nil {
 			return err
 		}
+		if err := tx.Model(&domain.WorkflowVersion{}).Where("workflow_id = ?", wf.ID).
+			Delete(&domain.WorkflowVersion{}).Error; err != nil {
+			return err
+		}
 
-		return nil
+		var err error
+		ev, err = createWorkflowEvent(&wf, event.EventCategoryDeleted, s, tx)
+		return err
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	invokeEventHandlers(ev)
+	return nil
 }
 
 func QueryWorkflows(query *domain.WorkflowQuery, s *session.Session) (*[]domain.Workflow, error) {
@@ -186,6 +278,7 @@ func QueryWorkflows(query *domain.WorkflowQuery, s *session.Session) (*[]domain.
 
 func UpdateWorkflowBase(id types.ID, c *WorkflowBaseUpdation, s *session.Session) (*domain.Workflow, error) {
 	wf := domain.Workflow{}
+	var ev *event.EventRecord
 	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
 	err := db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where(&domain.Workflow{ID: id}).First(&wf).Error; err != nil {
@@ -194,73 +287,114 @@ func UpdateWorkflowBase(id types.ID, c *WorkflowBaseUpdation, s *session.Session
 		if !s.Perms.HasProjectRole(domain.ProjectRoleManager, wf.ProjectID) {
 			return bizerror.ErrForbidden
 		}
-		if err := tx.Model(&domain.Workflow{}).Where(&domain.Workflow{ID: id}).
-			Update(&domain.Workflow{Name: c.Name, ThemeIcon: c.ThemeIcon, ThemeColor: c.ThemeColor}).Error; err != nil {
-			return err
-		}
-		// query again
-		if err := tx.Where(&domain.Workflow{ID: id}).First(&wf).Error; err != nil {
+		changes, err := updateWorkflowBase(tx, &wf, c.Name, c.ThemeColor, c.ThemeIcon)
+		if err != nil {
 			return err
 		}
-		return nil
+		ev, err = createWorkflowChangedEvent(&wf, changes, s, tx)
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
+	invokeEventHandlers(ev)
 	return &wf, nil
 }
 
+// updateWorkflowBase updates name and theme of workflow wf, and reloads it
+func updateWorkflowBase(tx *gorm.DB, wf *domain.Workflow, name, themeColor, themeIcon string) ([]DefinitionChange, error) {
+	changes := diffWorkflowBase(wf, name, themeColor, themeIcon)
+	if err := tx.Model(&domain.Workflow{}).Where(&domain.Workflow{ID: wf.ID}).
+		Update(&domain.Workflow{Name: name, ThemeIcon: themeIcon, ThemeColor: themeColor}).Error; err != nil {
+		return nil, err
+	}
+	// query again
+	if err := tx.Where(&domain.Workflow{ID: wf.ID}).First(wf).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
 func CreateState(workflowID types.ID, creating *StateCreating, s *session.Session) error {
 	now := time.Now()
-	return persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+	var ev *event.EventRecord
+	err := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
 		if err := checkPerms(workflowID, s); err != nil {
 			return err
 		}
-
-		stateEntity := &domain.WorkflowState{
-			WorkflowID: workflowID, Order: creating.Order, Name: creating.Name, Category: creating.Category, CreateTime: now,
-		}
-		if err := tx.Create(stateEntity).Error; err != nil {
+		if err := nextWorkflowVersion(tx, workflowID); err != nil {
 			return err
 		}
 
-		var stateRecords []domain.WorkflowState
-		if err := tx.Where(domain.WorkflowState{WorkflowID: workflowID}).Order("`order` ASC").Find(&stateRecords).Error; err != nil {
+		changes, err := createState(tx, workflowID, creating, now)
+		if err != nil {
 			return err
 		}
-		stateMap := map[string]string{}
-		for _, stateRecord := range stateRecords {
-			stateMap[stateRecord.Name] = stateRecord.Name
+		if err := checkStateMachine(tx, workflowID); err != nil {
+			return err
 		}
 
-		for _, t := range creating.Transitions {
-			if stateMap[t.From] == "" || stateMap[t.To] == "" {
-				return bizerror.ErrUnknownState
-			}
-
-			transition := &domain.WorkflowStateTransition{
-				WorkflowID: workflowID, Name: t.Name, FromState: t.From, ToState: t.To, CreateTime: now,
-			}
-			if err := tx.Create(transition).Error; err != nil {
-				return err
-			}
+		wf := domain.Workflow{}
+		if err := tx.Where(&domain.Workflow{ID: workflowID}).First(&wf).Error; err != nil {
+			return err
 		}
-		return nil
+		ev, err = createWorkflowChangedEvent(&wf, changes, s, tx)
+		return err
 	})
+	if err != nil {
+		return err
+	}
+	invokeEventHandlers(ev)
+	return nil
 }
 
-type workBrief struct {
-	ID         types.ID
-	Identifier string
-	Name       string
+// createState saves the state and the transitions of it
+func createState(tx *gorm.DB, workflowID types.ID, creating *StateCreating, now time.Time) ([]DefinitionChange, error) {
+	stateEntity := &domain.WorkflowState{
+		WorkflowID: workflowID, Order: creating.Order, Name: creating.Name, Category: creating.Category, CreateTime: now,
+		WipLimit: creating.WipLimit, WipSoft: creating.WipSoft, Reasons: creating.Reasons, Parent: creating.Parent,
+	}
+	if err := tx.Create(stateEntity).Error; err != nil {
+		return nil, err
+	}
+	changes := []DefinitionChange{{Action: ChangeAdded, Kind: ChangeKindState, Name: creating.Name,
+		New: state.State{Name: creating.Name, Category: creating.Category, Order: creating.Order,
+			WipLimit: creating.WipLimit, WipSoft: creating.WipSoft, Reasons: creating.Reasons, Parent: creating.Parent}}}
+
+	var stateRecords []domain.WorkflowState
+	if err := tx.Where(domain.WorkflowState{WorkflowID: workflowID}).Order("`order` ASC").Find(&stateRecords).Error; err != nil {
+		return nil, err
+	}
+	stateMap := map[string]string{}
+	for _, stateRecord := range stateRecords {
+		stateMap[stateRecord.Name] = stateRecord.Name
+	}
+
+	for _, t := range creating.Transitions {
+		if stateMap[t.From] == "" || stateMap[t.To] == "" {
+			return nil, bizerror.ErrUnknownState
+		}
+
+		transition := &domain.WorkflowStateTransition{
+			WorkflowID: workflowID, Name: t.Name, FromState: t.From, ToState: t.To, CreateTime: now,
+			Guards: t.Guards, Hooks: t.Hooks, Triggers: t.Triggers,
+		}
+		if err := tx.Create(transition).Error; err != nil {
+			return nil, err
+		}
+		changes = append(changes, DefinitionChange{Action: ChangeAdded, Kind: ChangeKindTransition, Name: t.Name, New: t})
+	}
+	return changes, nil
 }
 
+// UpdateWorkflowState changes the state in the next version of workflow, works running on previous versions are not affected
+// until they are migrated.
 func UpdateWorkflowState(id types.ID, updating WorkflowStateUpdating, s *session.Session) error {
 	workflow := domain.Workflow{}
+	var ev *event.EventRecord
 	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
 
-	events := []event.EventRecord{}
-	err1 := db.Transaction(func(tx *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where(&domain.Workflow{ID: id}).First(&workflow).Error; err != nil {
 			return err
 		}
@@ -268,109 +402,203 @@ func UpdateWorkflowState(id types.ID, updating WorkflowStateUpdating, s *session
 			return bizerror.ErrForbidden
 		}
 
-		// origin state must exist
-		var originState domain.WorkflowState
-		if err := tx.Where(domain.WorkflowState{WorkflowID: workflow.ID, Name: updating.OriginName}).First(&originState).Error; err != nil {
+		if err := nextWorkflowVersion(tx, workflow.ID); err != nil {
+			return err
+		}
+		changes, err := updateState(tx, &workflow, updating)
+		if err != nil {
+			return err
+		}
+		if err := checkStateMachine(tx, workflow.ID); err != nil {
 			return err
 		}
+		ev, err = createWorkflowChangedEvent(&workflow, changes, s, tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	invokeEventHandlers(ev)
+	return nil
+}
 
-		if updating.OriginName != updating.Name {
-			// new state name must not exist
-			var existState []domain.WorkflowState
-			if err := tx.Where(domain.WorkflowState{WorkflowID: workflow.ID, Name: updating.Name}).First(&existState).Error; err != nil {
-				return err
-			}
-			if len(existState) > 0 {
-				return bizerror.ErrStateExisted
-			}
+// updateState replaces the state with the updated one, and renames the references of it when it is renamed
+func updateState(tx *gorm.DB, workflow *domain.Workflow, updating WorkflowStateUpdating) ([]DefinitionChange, error) {
+	// origin state must exist
+	var originState domain.WorkflowState
+	if err := tx.Where(domain.WorkflowState{WorkflowID: workflow.ID, Name: updating.OriginName}).First(&originState).Error; err != nil {
+		return nil, err
+	}
+
+	if updating.OriginName != updating.Name {
+		// new state name must not exist
+		var existState []domain.WorkflowState
+		if err := tx.Where(domain.WorkflowState{WorkflowID: workflow.ID, Name: updating.Name}).First(&existState).Error; err != nil {
+			return nil, err
 		}
+		if len(existState) > 0 {
+			return nil, bizerror.ErrStateExisted
+		}
+	}
 
-		// delete origin state
-		if err := tx.Model(originState).Delete(originState).Error; err != nil {
+	// delete origin state
+	if err := tx.Model(originState).Delete(originState).Error; err != nil {
+		return nil, err
+	}
+	// insert new state
+	stateEntity := &domain.WorkflowState{
+		WorkflowID: workflow.ID, Order: updating.Order, Name: updating.Name, Category: originState.Category, CreateTime: workflow.CreateTime,
+		WipLimit: originState.WipLimit, WipSoft: originState.WipSoft, Reasons: originState.Reasons, Parent: updating.Parent,
+	}
+	if updating.WipLimit != nil {
+		stateEntity.WipLimit = *updating.WipLimit
+	}
+	if updating.WipSoft != nil {
+		stateEntity.WipSoft = *updating.WipSoft
+	}
+	if updating.Reasons != nil {
+		stateEntity.Reasons = *updating.Reasons
+	}
+	if err := tx.Create(stateEntity).Error; err != nil {
+		return nil, err
+	}
+
+	changes := []DefinitionChange{{Action: ChangeModified, Kind: ChangeKindState, Name: updating.Name,
+		Old: state.State{Name: originState.Name, Category: originState.Category, Order: originState.Order,
+			WipLimit: originState.WipLimit, WipSoft: originState.WipSoft, Reasons: originState.Reasons, Parent: originState.Parent},
+		New: state.State{Name: stateEntity.Name, Category: stateEntity.Category, Order: stateEntity.Order,
+			WipLimit: stateEntity.WipLimit, WipSoft: stateEntity.WipSoft, Reasons: stateEntity.Reasons, Parent: stateEntity.Parent},
+	}}
+
+	// update referrers
+	if originState.Name != updating.Name {
+		// workflow_state_transitions:    workflow_id, from_state, to_state
+		if err := tx.Model(&domain.WorkflowStateTransition{}).
+			Where("workflow_id = ?", originState.WorkflowID).
+			Where("from_state LIKE ?", originState.Name).
+			Update(domain.WorkflowStateTransition{FromState: updating.Name}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&domain.WorkflowStateTransition{}).
+			Where("workflow_id = ?", originState.WorkflowID).
+			Where("to_state LIKE ?", originState.Name).
+			Update(domain.WorkflowStateTransition{ToState: updating.Name}).Error; err != nil {
+			return nil, err
+		}
+		// workflow_states:    workflow_id, parent
+		if err := tx.Model(&domain.WorkflowState{}).
+			Where("workflow_id = ?", originState.WorkflowID).
+			Where("parent LIKE ?", originState.Name).
+			Update(domain.WorkflowState{Parent: updating.Name}).Error; err != nil {
+			return nil, err
+		}
+		// workflow_property_definitions:    workflow_id, required_states
+		propertyChanges, err := replaceRequiredState(tx, originState.WorkflowID, originState.Name, updating.Name)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, propertyChanges...)
+	}
+	return changes, nil
+}
+
+// DeleteState removes the state and the transitions from or to it, works in the state are moved to the replacement state
+// and pinned to the new version of workflow. History (closed process steps and archived works) is kept untouched, so the
+// deletion is refused when there is any history in the state unless it is forced.
+func DeleteState(workflowID types.ID, c *StateDeleting, s *session.Session) (*StateDeletionResult, error) {
+	result := StateDeletionResult{}
+	var events []*event.EventRecord
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		wf := domain.Workflow{}
+		if err := tx.Where(&domain.Workflow{ID: workflowID}).First(&wf).Error; err != nil {
 			return err
 		}
-		// insert new state
-		stateEntity := &domain.WorkflowState{
-			WorkflowID: workflow.ID, Order: updating.Order, Name: updating.Name, Category: originState.Category, CreateTime: workflow.CreateTime,
+		if !s.Perms.HasProjectRole(domain.ProjectRoleManager, wf.ProjectID) {
+			return bizerror.ErrForbidden
 		}
-		if err := tx.Create(stateEntity).Error; err != nil {
+
+		stateMachine, err := queryStateMachine(tx, wf.ID)
+		if err != nil {
 			return err
 		}
-
-		// update referrers
-		if originState.Name != updating.Name {
-			// workflow_state_transitions:    workflow_id, from_state, to_state
-			if err := tx.Model(&domain.WorkflowStateTransition{}).
-				Where("workflow_id = ?", originState.WorkflowID).
-				Where("from_state LIKE ?", originState.Name).
-				Update(domain.WorkflowStateTransition{FromState: updating.Name}).Error; err != nil {
-				return err
-			}
-			if err := tx.Model(&domain.WorkflowStateTransition{}).
-				Where("workflow_id = ?", originState.WorkflowID).
-				Where("to_state LIKE ?", originState.Name).
-				Update(domain.WorkflowStateTransition{ToState: updating.Name}).Error; err != nil {
-				return err
-			}
+		deleted, found := stateMachine.FindState(c.Name)
+		if !found {
+			return bizerror.ErrUnknownState
 		}
-		if originState.Name != updating.Name {
-			// work:  flow_id, state_name  state_category
-			worksToUpdate := []workBrief{}
-			if err := tx.Model(&domain.Work{}).
-				Where("flow_id = ?", originState.WorkflowID).
-				Where("state_name LIKE ?", originState.Name).
-				Select("id, identifier, name").
-				Scan(&worksToUpdate).Error; err != nil {
+		replacement, found := stateMachine.FindState(c.Replacement)
+		if !found || replacement.Name == deleted.Name || !stateMachine.IsLeaf(replacement.Name) {
+			return bizerror.ErrUnknownState
+		}
+
+		if !c.Force {
+			steps, archivedWorks := 0, 0
+			if err := tx.Model(&domain.WorkProcessStep{}).
+				Where("flow_id = ? AND state_name = ? AND end_time <> ?", wf.ID, deleted.Name, types.Timestamp{}).Count(&steps).Error; err != nil {
 				return err
 			}
 			if err := tx.Model(&domain.Work{}).
-				Where("flow_id = ?", originState.WorkflowID).
-				Where("state_name LIKE ?", originState.Name).
-				Update(domain.Work{StateName: updating.Name, StateCategory: originState.Category}).Error; err != nil {
+				Where("flow_id = ? AND state_name = ? AND archive_time <> ?", wf.ID, deleted.Name, types.Timestamp{}).Count(&archivedWorks).Error; err != nil {
 				return err
 			}
-			now := types.CurrentTimestamp()
-			for _, w := range worksToUpdate {
-				ev, err := event.CreateEvent("WORK", w.ID, w.Identifier, event.EventCategoryExtensionUpdated,
-					[]event.UpdatedProperty{{
-						PropertyName: "StateName", PropertyDesc: "StateName",
-						OldValue: originState.Name, OldValueDesc: originState.Name,
-						NewValue: updating.Name, NewValueDesc: updating.Name,
-					}}, nil, &s.Identity, now, tx)
-				if err != nil {
-					return err
-				}
-				events = append(events, *ev)
+			if steps > 0 || archivedWorks > 0 {
+				return &bizerror.ErrStateReferenced{State: deleted.Name, ProcessSteps: steps, ArchivedWorks: archivedWorks}
 			}
+		}
 
-			// work_process_steps: flow_id, state_name, state_category, next_state_name, next_state_category
-			if err := tx.Model(&domain.WorkProcessStep{}).
-				Where("flow_id = ?", originState.WorkflowID).
-				Where("state_name LIKE ?", originState.Name).
-				Update(domain.WorkProcessStep{StateName: updating.Name, StateCategory: originState.Category}).Error; err != nil {
-				return err
+		if err := nextWorkflowVersion(tx, wf.ID); err != nil {
+			return err
+		}
+		changes := []DefinitionChange{{Action: ChangeRemoved, Kind: ChangeKindState, Name: deleted.Name, Old: deleted}}
+		for _, t := range stateMachine.Transitions {
+			if t.From == deleted.Name || t.To == deleted.Name {
+				changes = append(changes, DefinitionChange{Action: ChangeRemoved, Kind: ChangeKindTransition, Name: t.Name, Old: t})
 			}
+		}
+		if err := tx.Where("workflow_id = ? AND name = ?", wf.ID, deleted.Name).Delete(&domain.WorkflowState{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workflow_id = ? AND (from_state = ? OR to_state = ?)", wf.ID, deleted.Name, deleted.Name).
+			Delete(&domain.WorkflowStateTransition{}).Error; err != nil {
+			return err
+		}
+		propertyChanges, err := replaceRequiredState(tx, wf.ID, deleted.Name, "")
+		if err != nil {
+			return err
+		}
+		changes = append(changes, propertyChanges...)
 
-			if err := tx.Model(&domain.WorkProcessStep{}).
-				Where("flow_id = ?", originState.WorkflowID).
-				Where("next_state_name LIKE ?", originState.Name).
-				Update(domain.WorkProcessStep{NextStateName: updating.Name, NextStateCategory: originState.Category}).Error; err != nil {
+		var works []domain.Work
+		if err := tx.Where("flow_id = ? AND state_name = ? AND archive_time = ?", wf.ID, deleted.Name, types.Timestamp{}).
+			Find(&works).Error; err != nil {
+			return err
+		}
+		now := types.CurrentTimestamp()
+		for _, w := range works {
+			ev, err := moveWork(tx, &wf, &w, replacement, wf.Version+1, now, s)
+			if err != nil {
 				return err
 			}
+			events = append(events, ev)
+		}
+		result.MovedWorks = len(works)
+
+		if err := checkStateMachine(tx, wf.ID); err != nil {
+			return err
+		}
+		ev, err := createWorkflowChangedEvent(&wf, changes, s, tx)
+		if err != nil {
+			return err
 		}
+		events = append(events, ev)
 		return nil
 	})
-
-	if err1 != nil {
-		return err1
+	if err != nil {
+		return nil, err
 	}
 
-	if event.InvokeHandlersFunc != nil {
-		for _, ev := range events {
-			event.InvokeHandlersFunc(&ev)
-		}
-	}
-	return nil
+	invokeEventHandlers(events...)
+	return &result, nil
 }
 
 func UpdateStateRangeOrders(workflowID types.ID, wantedOrders *[]StateOrderRangeUpdating, s *session.Session) error {
@@ -378,12 +606,25 @@ func UpdateStateRangeOrders(workflowID types.ID, wantedOrders *[]StateOrderRange
 		return nil
 	}
 
-	return persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+	var ev *event.EventRecord
+	err := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
 		if err := checkPerms(workflowID, s); err != nil {
 			return err
 		}
 
+		var changes []DefinitionChange
 		for _, orderUpdating := range *wantedOrders {
+			origin := domain.WorkflowState{}
+			if err := tx.Where(&domain.WorkflowState{WorkflowID: workflowID, Name: orderUpdating.State}).First(&origin).Error; err != nil &&
+				!errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			old := state.State{Name: origin.Name, Category: origin.Category, Order: origin.Order,
+				WipLimit: origin.WipLimit, WipSoft: origin.WipSoft, Reasons: origin.Reasons, Parent: origin.Parent}
+			new := old
+			new.Order = orderUpdating.NewOlder
+			changes = append(changes, DefinitionChange{Action: ChangeModified, Kind: ChangeKindState, Name: orderUpdating.State, Old: old, New: new})
+
 			db := tx.Model(&domain.WorkflowState{}).
 				Where(&domain.WorkflowState{WorkflowID: workflowID, Name: orderUpdating.State}).
 				Update("order", orderUpdating.NewOlder)
@@ -394,8 +635,23 @@ func UpdateStateRangeOrders(workflowID types.ID, wantedOrders *[]StateOrderRange
 				return errors.New("expected affected row is 1, but actual is " + strconv.FormatInt(db.RowsAffected, 10))
 			}
 		}
-		return nil
+		if err := checkStateMachine(tx, workflowID); err != nil {
+			return err
+		}
+
+		wf := domain.Workflow{}
+		if err := tx.Where(&domain.Workflow{ID: workflowID}).First(&wf).Error; err != nil {
+			return err
+		}
+		var err error
+		ev, err = createWorkflowChangedEvent(&wf, changes, s, tx)
+		return err
 	})
+	if err != nil {
+		return err
+	}
+	invokeEventHandlers(ev)
+	return nil
 }
 
 func checkPerms(id types.ID, s *session.Session) error {
@@ -410,10 +666,25 @@ func checkPerms(id types.ID, s *session.Session) error {
 	return nil
 }
 
+// PermitTransitions checks the roles of transitions against the project roles of caller in project projectID
+func PermitTransitions(projectID types.ID, transitions []state.Transition, s *session.Session) []TransitionPermission {
+	hasRole := func(role string) bool {
+		return s != nil && s.Perms.HasProjectRole(role, projectID)
+	}
+	permissions := []TransitionPermission{}
+	for _, t := range transitions {
+		permissions = append(permissions, TransitionPermission{Transition: t, Permitted: t.PermittedTo(hasRole)})
+	}
+	return permissions
+}
+
+// CreateWorkflowStateTransitions saves transitions of workflow, a transition which is already existed will be overwritten,
+// that is the way to edit guards and hooks of it.
 func CreateWorkflowStateTransitions(id types.ID, transitions []state.Transition, s *session.Session) error {
 	workflow := domain.Workflow{}
+	var ev *event.EventRecord
 	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
-	return db.Transaction(func(tx *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where(&domain.Workflow{ID: id}).First(&workflow).Error; err != nil {
 			return err
 		}
@@ -421,55 +692,128 @@ func CreateWorkflowStateTransitions(id types.ID, transitions []state.Transition,
 			return bizerror.ErrForbidden
 		}
 
-		var states []domain.WorkflowState
-		if err := tx.Where(domain.WorkflowState{WorkflowID: workflow.ID}).Find(&states).Error; err != nil {
+		if err := nextWorkflowVersion(tx, workflow.ID); err != nil {
 			return err
 		}
-		stateIndex := map[string]domain.WorkflowState{}
-		for _, t := range states {
-			stateIndex[t.Name] = t
-		}
 
-		for _, t := range transitions {
-			if _, found := stateIndex[t.From]; !found {
-				return bizerror.ErrUnknownState
-			}
-			if _, found := stateIndex[t.To]; !found {
-				return bizerror.ErrUnknownState
-			}
-			transition := &domain.WorkflowStateTransition{
-				WorkflowID: workflow.ID, Name: t.Name, FromState: t.From, ToState: t.To, CreateTime: time.Now(),
-			}
-			if err := tx.Save(transition).Error; err != nil {
-				return err
-			}
+		changes, err := saveTransitions(tx, workflow.ID, transitions)
+		if err != nil {
+			return err
 		}
-		return nil
+		if err := checkStateMachine(tx, workflow.ID); err != nil {
+			return err
+		}
+		ev, err = createWorkflowChangedEvent(&workflow, changes, s, tx)
+		return err
 	})
+	if err != nil {
+		return err
+	}
+	invokeEventHandlers(ev)
+	return nil
+}
+
+// saveTransitions saves transitions of workflow, a transition which is already existed will be overwritten
+func saveTransitions(tx *gorm.DB, workflowID types.ID, transitions []state.Transition) ([]DefinitionChange, error) {
+	var states []domain.WorkflowState
+	if err := tx.Where(domain.WorkflowState{WorkflowID: workflowID}).Find(&states).Error; err != nil {
+		return nil, err
+	}
+	stateIndex := map[string]domain.WorkflowState{}
+	for _, t := range states {
+		stateIndex[t.Name] = t
+	}
+	origin, err := queryStateMachine(tx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []DefinitionChange
+	for _, t := range transitions {
+		if _, found := stateIndex[t.From]; !found {
+			return nil, bizerror.ErrUnknownState
+		}
+		if _, found := stateIndex[t.To]; !found {
+			return nil, bizerror.ErrUnknownState
+		}
+		transition := &domain.WorkflowStateTransition{
+			WorkflowID: workflowID, Name: t.Name, FromState: t.From, ToState: t.To, CreateTime: time.Now(),
+			Guards: t.Guards, Hooks: t.Hooks, Triggers: t.Triggers,
+		}
+		if err := tx.Save(transition).Error; err != nil {
+			return nil, err
+		}
+		if existed, found := findTransition(origin.Transitions, t.From, t.To); !found {
+			changes = append(changes, DefinitionChange{Action: ChangeAdded, Kind: ChangeKindTransition, Name: t.Name, New: t})
+		} else if !isTransitionEqual(existed, t) {
+			changes = append(changes, DefinitionChange{Action: ChangeModified, Kind: ChangeKindTransition, Name: t.Name, Old: existed, New: t})
+		}
+	}
+	return changes, nil
 }
 
 func DeleteWorkflowStateTransitions(id types.ID, transitions []state.Transition, s *session.Session) error {
 	wf := domain.Workflow{}
+	var ev *event.EventRecord
 	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
-	return db.Transaction(func(tx *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where(&domain.Workflow{ID: id}).First(&wf).Error; err != nil {
 			return err
 		}
 		if !s.Perms.HasProjectRole(domain.ProjectRoleManager, wf.ProjectID) {
 			return bizerror.ErrForbidden
 		}
+		if err := nextWorkflowVersion(tx, wf.ID); err != nil {
+			return err
+		}
 
-		for _, t := range transitions {
-			q := tx.Model(&domain.WorkflowStateTransition{}).
-				Where("workflow_id = ?", wf.ID).
-				Where("from_state LIKE ?", t.From).
-				Where("to_state LIKE ?", t.To)
-			if err := q.Delete(&domain.WorkflowStateTransition{}).Error; err != nil {
-				return err
-			}
+		changes, err := deleteTransitions(tx, wf.ID, transitions)
+		if err != nil {
+			return err
 		}
-		return nil
+		if err := checkStateMachine(tx, wf.ID); err != nil {
+			return err
+		}
+		ev, err = createWorkflowChangedEvent(&wf, changes, s, tx)
+		return err
 	})
+	if err != nil {
+		return err
+	}
+	invokeEventHandlers(ev)
+	return nil
+}
+
+// deleteTransitions deletes transitions of workflow which are identified by from state and to state
+func deleteTransitions(tx *gorm.DB, workflowID types.ID, transitions []state.Transition) ([]DefinitionChange, error) {
+	origin, err := queryStateMachine(tx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []DefinitionChange
+	for _, t := range transitions {
+		q := tx.Model(&domain.WorkflowStateTransition{}).
+			Where("workflow_id = ?", workflowID).
+			Where("from_state LIKE ?", t.From).
+			Where("to_state LIKE ?", t.To)
+		if err := q.Delete(&domain.WorkflowStateTransition{}).Error; err != nil {
+			return nil, err
+		}
+		if existed, found := findTransition(origin.Transitions, t.From, t.To); found {
+			changes = append(changes, DefinitionChange{Action: ChangeRemoved, Kind: ChangeKindTransition, Name: existed.Name, Old: existed})
+		}
+	}
+	return changes, nil
+}
+
+func findTransition(transitions []state.Transition, from, to string) (state.Transition, bool) {
+	for _, t := range transitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return state.Transition{}, false
 }
 
 func isWorkflowReferenced(db *gorm.DB, workflowID types.ID) error {
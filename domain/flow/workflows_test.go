@@ -2,12 +2,15 @@ package flow_test
 
 import (
 	"context"
+	"errors"
 	"flywheel/bizerror"
 	"flywheel/domain"
 	"flywheel/domain/flow"
 	"flywheel/domain/state"
 	"flywheel/event"
+	"flywheel/indices/indexlog"
 	"flywheel/persistence"
+	"flywheel/session"
 	"flywheel/testinfra"
 	"testing"
 	"time"
@@ -22,8 +25,9 @@ import (
 func setup(t *testing.T, testDatabase **testinfra.TestDatabase) {
 	db := testinfra.StartMysqlTestDatabase("flywheel")
 	assert.Nil(t, db.DS.GormDB(context.Background()).AutoMigrate(&domain.Work{}, &domain.WorkProcessStep{},
-		&flow.WorkflowPropertyDefinition{},
-		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}).Error)
+		&flow.WorkflowPropertyDefinition{}, &flow.WorkflowTemplateRecord{}, &flow.StateCategoryRecord{},
+		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &domain.WorkflowVersion{},
+		&event.EventRecord{}, &indexlog.IndexLogRecord{}).Error)
 	persistence.ActiveDataSourceManager = db.DS
 	*testDatabase = db
 }
@@ -55,6 +59,17 @@ func TestCreateWorkflow(t *testing.T) {
 		Expect(err).To(Equal(bizerror.ErrForbidden))
 	})
 
+	t.Run("should reject invalid state machine", func(t *testing.T) {
+		creation := &flow.WorkflowCreation{Name: "test workflow", ProjectID: types.ID(1), StateMachine: state.StateMachine{
+			States:      []state.State{{Name: "OPEN", Category: state.InProcess}, {Name: "CLOSED", Category: state.Done}},
+			Transitions: []state.Transition{{Name: "done", From: "OPEN", To: "UNKNOWN"}},
+		}}
+		workflow, err := flow.CreateWorkflow(creation, testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1"))
+		Expect(workflow).To(BeNil())
+		Expect(err).To(Equal(&state.ErrStateMachineInvalid{Findings: state.Findings{{Level: state.FindingLevelError,
+			Code: "transition.unknown_state", Message: "transition done is to unknown state UNKNOWN", Transition: "done", State: "UNKNOWN"}}}))
+	})
+
 	t.Run("should catch database errors", func(t *testing.T) {
 		defer teardown(t, testDatabase)
 		setup(t, &testDatabase)
@@ -125,6 +140,66 @@ func TestCreateWorkflow(t *testing.T) {
 	})
 }
 
+func TestCloneWorkflow(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should clone workflow into other project", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1", domain.ProjectRoleManager+"_2")
+		origin, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test workflow", ProjectID: 1, ThemeColor: "blue", ThemeIcon: "some-icon",
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InProcess, WipLimit: 3}, {Name: "CLOSED", Category: state.Done}},
+				Transitions: []state.Transition{{Name: "done", From: "OPEN", To: "CLOSED", Guards: state.Guards{{Type: state.GuardChecklistDone},
+					{Type: state.GuardProjectRole, Name: domain.ProjectRoleManager}}}},
+			},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "resolution", Type: domain.PropTypeText, RequiredStates: domain.PropertyStates{"CLOSED"}}},
+		}, sec)
+		Expect(err).To(BeNil())
+
+		cloned, err := flow.CloneWorkflow(origin.ID, &flow.WorkflowCloning{Name: "cloned workflow", ProjectID: 2}, sec)
+		Expect(err).To(BeNil())
+		Expect(cloned.ID).ToNot(Equal(origin.ID))
+		Expect(cloned.Name).To(Equal("cloned workflow"))
+		Expect(cloned.ProjectID).To(Equal(types.ID(2)))
+		Expect(cloned.ThemeColor).To(Equal("blue"))
+		Expect(cloned.ThemeIcon).To(Equal("some-icon"))
+		Expect(cloned.Version).To(Equal(1))
+
+		detail, err := flow.DetailWorkflow(cloned.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(detail.StateMachine).To(Equal(origin.StateMachine))
+		definitions, err := flow.QueryPropertyDefinitions(cloned.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(len(definitions)).To(Equal(1))
+		Expect(definitions[0].ID).ToNot(BeZero())
+		Expect(definitions[0].WorkflowID).To(Equal(cloned.ID))
+		Expect(definitions[0].Name).To(Equal("resolution"))
+		Expect(definitions[0].RequiredStates).To(Equal(domain.PropertyStates{"CLOSED"}))
+	})
+
+	t.Run("should require manager of both projects", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		origin, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test workflow", ProjectID: 1, ThemeColor: "blue", ThemeIcon: "some-icon",
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1"))
+		Expect(err).To(BeNil())
+
+		_, err = flow.CloneWorkflow(origin.ID, &flow.WorkflowCloning{Name: "cloned workflow", ProjectID: 2},
+			testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1", domain.ProjectRoleCommon+"_2"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+		_, err = flow.CloneWorkflow(origin.ID, &flow.WorkflowCloning{Name: "cloned workflow", ProjectID: 2},
+			testinfra.BuildSecCtx(100, domain.ProjectRoleCommon+"_1", domain.ProjectRoleManager+"_2"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+		_, err = flow.CloneWorkflow(12345, &flow.WorkflowCloning{Name: "cloned workflow", ProjectID: 2},
+			testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1", domain.ProjectRoleManager+"_2"))
+		Expect(err).To(Equal(gorm.ErrRecordNotFound))
+	})
+}
+
 func TestDetailWorkflow(t *testing.T) {
 	RegisterTestingT(t)
 	var testDatabase *testinfra.TestDatabase
@@ -577,6 +652,25 @@ func TestCreateState(t *testing.T) {
 		Expect(err.Error()).To(Equal(bizerror.ErrUnknownState.Error()))
 	})
 
+	t.Run("should failed when state machine becomes invalid", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test work", ProjectID: types.ID(1),
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, sec)
+		Expect(err).To(BeNil())
+
+		err = flow.CreateState(workflow.ID, &flow.StateCreating{Name: "pending", Category: 1, Order: 101,
+			Transitions: []state.Transition{}}, sec)
+		Expect(err).To(Equal(&state.ErrStateMachineInvalid{Findings: state.Findings{{Level: state.FindingLevelError,
+			Code: "state.duplicated", Message: "state pending is duplicated", State: "pending"}}}))
+
+		var states []domain.WorkflowState
+		Expect(testDatabase.DS.GormDB(context.Background()).Where(domain.WorkflowState{WorkflowID: workflow.ID}).Find(&states).Error).To(BeNil())
+		Expect(len(states)).To(Equal(3))
+	})
+
 	t.Run("should success if everything is ok when creating state", func(t *testing.T) {
 		defer teardown(t, testDatabase)
 		setup(t, &testDatabase)
@@ -774,29 +868,94 @@ func TestUpdateWorkflowState(t *testing.T) {
 		Expect(affectedStateTransitions[2].FromState).To(Equal(domain.StateDoing.Name))
 		Expect(affectedStateTransitions[2].ToState).To(Equal(updating.Name)) // updated
 
+		// works and history are kept on the previous version
 		var work domain.Work
 		Expect(testDatabase.DS.GormDB(context.Background()).Where(domain.Work{ID: 1}).First(&work).Error).To(BeNil())
-		Expect(work.StateName).To(Equal(updating.Name))
-		Expect(work.StateCategory).To(Equal(domain.StatePending.Category))
+		Expect(work.StateName).To(Equal(domain.StatePending.Name))
+		Expect(work.FlowVersion).To(BeZero())
 
 		var workProcessSteps []domain.WorkProcessStep
 		Expect(testDatabase.DS.GormDB(context.Background()).Where(domain.WorkProcessStep{FlowID: workflow.ID}).First(&workProcessSteps).Error).To(BeNil())
 		Expect(len(workProcessSteps)).To(Equal(1))
-		Expect(workProcessSteps[0].StateName).To(Equal(updating.Name))
-		Expect(workProcessSteps[0].StateCategory).To(Equal(domain.StatePending.Category))
-		Expect(workProcessSteps[0].NextStateName).To(Equal(updating.Name))
-		Expect(workProcessSteps[0].NextStateCategory).To(Equal(domain.StatePending.Category))
+		Expect(workProcessSteps[0].StateName).To(Equal(domain.StatePending.Name))
+		Expect(workProcessSteps[0].NextStateName).To(Equal(domain.StatePending.Name))
 
+		// only the change of workflow is recorded, works are untouched
 		Expect(len(handedEvents)).To(Equal(1))
-		Expect((handedEvents)[0].Event).To(Equal(event.Event{SourceId: work.ID, SourceType: "WORK", SourceDesc: work.Identifier,
-			CreatorId: sec.Identity.ID, CreatorName: sec.Identity.Name,
-			UpdatedProperties: []event.UpdatedProperty{{
-				PropertyName: "StateName", PropertyDesc: "StateName",
-				OldValue: updating.OriginName, OldValueDesc: updating.OriginName,
-				NewValue: updating.Name, NewValueDesc: updating.Name,
-			}},
-			EventCategory: event.EventCategoryExtensionUpdated}))
-		Expect(time.Since((handedEvents)[0].Timestamp.Time()) < time.Second).To(BeTrue())
+		Expect(handedEvents[0].SourceType).To(Equal(flow.WorkflowEventSourceType))
+		Expect(handedEvents[0].UpdatedRelations[0].OldTargetId).To(Equal(domain.StatePending.Name))
+		Expect(handedEvents[0].UpdatedRelations[0].NewTargetId).To(Equal("QUEUED"))
+		Expect(handedEvents).To(Equal(persistedEvents))
+
+		var versions []domain.WorkflowVersion
+		Expect(testDatabase.DS.GormDB(context.Background()).Where("workflow_id = ?", workflow.ID).Find(&versions).Error).To(BeNil())
+		Expect(len(versions)).To(Equal(1))
+		Expect(versions[0].Version).To(Equal(1))
+		Expect(versions[0].Definition.StateMachine.States[0].Name).To(Equal(domain.StatePending.Name))
+		detail, err := flow.DetailWorkflow(workflow.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(detail.Version).To(Equal(2))
+	})
+
+	t.Run("should rename required states of property definitions", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		_, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test work", ProjectID: types.ID(1), StateMachine: domain.GenericWorkflowTemplate.StateMachine,
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "estimation", Type: domain.PropTypeNumber, RequiredStates: domain.PropertyStates{"UNKNOWN"}}}}, sec)
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("required state UNKNOWN of property estimation is unknown")}))
+
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test work", ProjectID: types.ID(1), StateMachine: domain.GenericWorkflowTemplate.StateMachine,
+			PropertyDefinitions: []domain.PropertyDefinition{
+				{Name: "estimation", Type: domain.PropTypeNumber, RequiredStates: domain.PropertyStates{domain.StateDoing.Name, domain.StateDone.Name}},
+				{Name: "description", Type: domain.PropTypeTextArea},
+			}}, sec)
+		Expect(err).To(BeNil())
+
+		Expect(flow.UpdateWorkflowState(workflow.ID, flow.WorkflowStateUpdating{OriginName: domain.StateDoing.Name, Name: "WORKING", Order: 2}, sec)).To(BeNil())
+		detail, err := flow.DetailWorkflow(workflow.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(detail.PropertyDefinitions[0].RequiredStates).To(Equal(domain.PropertyStates{"WORKING", domain.StateDone.Name}))
+		Expect(detail.PropertyDefinitions[1].RequiredStates).To(BeNil())
+	})
+
+	t.Run("should keep wip limit and reasons of state when they are omitted", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		doing, rejected := domain.StateDoing, state.State{Name: "REJECTED", Category: state.Rejected, Order: 4, Reasons: state.Reasons{"duplicated"}}
+		doing.WipLimit, doing.WipSoft = 3, true
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test work", ProjectID: types.ID(1), StateMachine: state.StateMachine{
+			States: []state.State{domain.StatePending, doing, domain.StateDone, rejected},
+			Transitions: []state.Transition{{Name: "begin", From: domain.StatePending.Name, To: doing.Name},
+				{Name: "done", From: doing.Name, To: domain.StateDone.Name}, {Name: "reject", From: doing.Name, To: rejected.Name}},
+		}}, sec)
+		Expect(err).To(BeNil())
+
+		Expect(flow.UpdateWorkflowState(workflow.ID, flow.WorkflowStateUpdating{OriginName: doing.Name, Name: "WORKING", Order: 2}, sec)).To(BeNil())
+		Expect(flow.UpdateWorkflowState(workflow.ID, flow.WorkflowStateUpdating{OriginName: rejected.Name, Name: rejected.Name, Order: 4}, sec)).To(BeNil())
+		detail, err := flow.DetailWorkflow(workflow.ID, sec)
+		Expect(err).To(BeNil())
+		working, _ := detail.StateMachine.FindState("WORKING")
+		Expect(working.WipLimit).To(Equal(3))
+		Expect(working.WipSoft).To(BeTrue())
+		r, _ := detail.StateMachine.FindState(rejected.Name)
+		Expect(r.Reasons).To(Equal(state.Reasons{"duplicated"}))
+
+		wipLimit, wipSoft, reasons := 0, false, state.Reasons{"obsolete"}
+		Expect(flow.UpdateWorkflowState(workflow.ID, flow.WorkflowStateUpdating{OriginName: "WORKING", Name: "WORKING", Order: 2,
+			WipLimit: &wipLimit, WipSoft: &wipSoft}, sec)).To(BeNil())
+		Expect(flow.UpdateWorkflowState(workflow.ID, flow.WorkflowStateUpdating{OriginName: rejected.Name, Name: rejected.Name, Order: 4,
+			Reasons: &reasons}, sec)).To(BeNil())
+		detail, err = flow.DetailWorkflow(workflow.ID, sec)
+		Expect(err).To(BeNil())
+		working, _ = detail.StateMachine.FindState("WORKING")
+		Expect(working.WipLimit).To(BeZero())
+		Expect(working.WipSoft).To(BeFalse())
+		r, _ = detail.StateMachine.FindState(rejected.Name)
+		Expect(r.Reasons).To(Equal(state.Reasons{"obsolete"}))
 	})
 
 	t.Run("should be able to catch database error", func(t *testing.T) {
@@ -811,13 +970,9 @@ func TestUpdateWorkflowState(t *testing.T) {
 
 		updating := flow.WorkflowStateUpdating{OriginName: domain.StatePending.Name, Name: "QUEUED", Order: 2000}
 
-		testDatabase.DS.GormDB(context.Background()).DropTable(&domain.WorkProcessStep{})
+		testDatabase.DS.GormDB(context.Background()).DropTable(&domain.WorkflowVersion{})
 		Expect(flow.UpdateWorkflowState(workflow.ID, updating, sec).Error()).
-			To(Equal("Error 1146: Table '" + testDatabase.TestDatabaseName + ".work_process_steps' doesn't exist"))
-
-		testDatabase.DS.GormDB(context.Background()).DropTable(&domain.Work{})
-		Expect(flow.UpdateWorkflowState(workflow.ID, updating, sec).Error()).
-			To(Equal("Error 1146: Table '" + testDatabase.TestDatabaseName + ".works' doesn't exist"))
+			To(Equal("Error 1146: Table '" + testDatabase.TestDatabaseName + ".workflow_versions' doesn't exist"))
 
 		testDatabase.DS.GormDB(context.Background()).DropTable(&domain.WorkflowStateTransition{})
 		Expect(flow.UpdateWorkflowState(workflow.ID, updating, sec).Error()).
@@ -833,6 +988,174 @@ func TestUpdateWorkflowState(t *testing.T) {
 	})
 }
 
+func TestDeleteState(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	prepare := func(sec *session.Session) *domain.WorkflowDetail {
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test work", ProjectID: types.ID(1),
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, sec)
+		Expect(err).To(BeNil())
+		Expect(flow.CreateState(workflow.ID, &flow.StateCreating{Name: "REVIEW", Category: state.InProcess, Order: 20001,
+			Transitions: []state.Transition{{Name: "review", From: domain.StateDoing.Name, To: "REVIEW"},
+				{Name: "pass", From: "REVIEW", To: domain.StateDone.Name}}}, sec)).To(BeNil())
+
+		now := types.CurrentTimestamp()
+		db := testDatabase.DS.GormDB(context.Background())
+		Expect(db.Create(domain.Work{ID: 1, Identifier: "W-1", Name: "w1", ProjectID: 1, CreateTime: now, FlowID: workflow.ID, FlowVersion: 2,
+			StateName: "REVIEW", StateCategory: state.InProcess, StateBeginTime: now}).Error).To(BeNil())
+		Expect(db.Create(domain.WorkProcessStep{WorkID: 1, FlowID: workflow.ID, FlowVersion: 2,
+			StateName: "REVIEW", StateCategory: state.InProcess, BeginTime: now}).Error).To(BeNil())
+		return workflow
+	}
+
+	t.Run("should validate states and permissions", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow := prepare(sec)
+
+		_, err := flow.DeleteState(workflow.ID, &flow.StateDeleting{Name: "REVIEW", Replacement: domain.StateDoing.Name},
+			testinfra.BuildSecCtx(100, domain.ProjectRoleCommon+"_1"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+		_, err = flow.DeleteState(workflow.ID, &flow.StateDeleting{Name: "UNKNOWN", Replacement: domain.StateDoing.Name}, sec)
+		Expect(err).To(Equal(bizerror.ErrUnknownState))
+		_, err = flow.DeleteState(workflow.ID, &flow.StateDeleting{Name: "REVIEW", Replacement: "REVIEW"}, sec)
+		Expect(err).To(Equal(bizerror.ErrUnknownState))
+	})
+
+	t.Run("should move works to replacement state", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		handedEvents := []event.EventRecord{}
+		event.InvokeHandlersFunc = func(record *event.EventRecord) []event.EventHandleResult {
+			handedEvents = append(handedEvents, *record)
+			return nil
+		}
+		event.EventPersistCreateFunc = func(record *event.EventRecord, db *gorm.DB) error {
+			return nil
+		}
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow := prepare(sec)
+		handedEvents = []event.EventRecord{} // events of workflow changes
+
+		result, err := flow.DeleteState(workflow.ID, &flow.StateDeleting{Name: "REVIEW", Replacement: domain.StateDoing.Name}, sec)
+		Expect(err).To(BeNil())
+		Expect(*result).To(Equal(flow.StateDeletionResult{MovedWorks: 1}))
+
+		detail, err := flow.DetailWorkflow(workflow.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(detail.Version).To(Equal(3))
+		Expect(len(detail.StateMachine.States)).To(Equal(3))
+		Expect(len(detail.StateMachine.Transitions)).To(Equal(5))
+
+		db := testDatabase.DS.GormDB(context.Background())
+		w := domain.Work{}
+		Expect(db.Where("id = ?", 1).First(&w).Error).To(BeNil())
+		Expect(w.StateName).To(Equal(domain.StateDoing.Name))
+		Expect(w.FlowVersion).To(Equal(3))
+		var steps []domain.WorkProcessStep
+		Expect(db.Where(&domain.WorkProcessStep{WorkID: 1}).Order("flow_version ASC").Find(&steps).Error).To(BeNil())
+		Expect(len(steps)).To(Equal(2))
+		Expect(steps[0].NextStateName).To(Equal(domain.StateDoing.Name))
+		Expect(steps[1].StateName).To(Equal(domain.StateDoing.Name))
+		Expect(steps[1].FlowVersion).To(Equal(3))
+
+		Expect(len(handedEvents)).To(Equal(2))
+		Expect(handedEvents[0].UpdatedProperties).To(Equal(event.UpdatedProperties{
+			{PropertyName: "FlowVersion", PropertyDesc: "FlowVersion", OldValue: "2", OldValueDesc: "2", NewValue: "3", NewValueDesc: "3"},
+			{PropertyName: "StateName", PropertyDesc: "StateName", OldValue: "REVIEW", OldValueDesc: "REVIEW",
+				NewValue: domain.StateDoing.Name, NewValueDesc: domain.StateDoing.Name},
+		}))
+		Expect(handedEvents[1].SourceType).To(Equal(flow.WorkflowEventSourceType))
+		Expect(handedEvents[1].EventCategory).To(Equal(event.EventCategoryRelationUpdated))
+		Expect(len(handedEvents[1].UpdatedRelations)).To(Equal(3))
+		Expect(handedEvents[1].UpdatedRelations[0].OldTargetId).To(Equal("REVIEW"))
+	})
+
+	t.Run("should refuse deletion of state referenced by history unless forced", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		event.EventPersistCreateFunc = func(record *event.EventRecord, db *gorm.DB) error {
+			return nil
+		}
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow := prepare(sec)
+		db := testDatabase.DS.GormDB(context.Background())
+		Expect(db.Create(domain.WorkProcessStep{WorkID: 2, FlowID: workflow.ID, FlowVersion: 2, StateName: "REVIEW", StateCategory: state.InProcess,
+			BeginTime: types.CurrentTimestamp(), EndTime: types.CurrentTimestamp()}).Error).To(BeNil())
+
+		_, err := flow.DeleteState(workflow.ID, &flow.StateDeleting{Name: "REVIEW", Replacement: domain.StateDoing.Name}, sec)
+		Expect(err).To(Equal(&bizerror.ErrStateReferenced{State: "REVIEW", ProcessSteps: 1}))
+
+		result, err := flow.DeleteState(workflow.ID, &flow.StateDeleting{Name: "REVIEW", Replacement: domain.StateDoing.Name, Force: true}, sec)
+		Expect(err).To(BeNil())
+		Expect(result.MovedWorks).To(Equal(1))
+	})
+
+	t.Run("should remove deleted state from required states of property definitions", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow := prepare(sec)
+		_, err := flow.CreatePropertyDefinition(workflow.ID, domain.PropertyDefinition{Name: "reviewer", Type: domain.PropTypeText,
+			RequiredStates: domain.PropertyStates{"REVIEW", domain.StateDone.Name}}, sec)
+		Expect(err).To(BeNil())
+
+		_, err = flow.DeleteState(workflow.ID, &flow.StateDeleting{Name: "REVIEW", Replacement: domain.StateDoing.Name}, sec)
+		Expect(err).To(BeNil())
+		properties, err := flow.QueryPropertyDefinitions(workflow.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(len(properties)).To(Equal(1))
+		Expect(properties[0].RequiredStates).To(Equal(domain.PropertyStates{domain.StateDone.Name}))
+	})
+
+	t.Run("should update state begin time and process times of moved works", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		event.EventPersistCreateFunc = func(record *event.EventRecord, db *gorm.DB) error {
+			return nil
+		}
+		sec := testinfra.BuildSecCtx(100, domain.ProjectRoleManager+"_1")
+		workflow := prepare(sec)
+		db := testDatabase.DS.GormDB(context.Background())
+		stale := types.TimestampOfDate(2020, 1, 2, 3, 4, 5, 0, time.Local)
+		Expect(db.Model(&domain.Work{}).Where("id = ?", 1).Update("state_begin_time", stale).Error).To(BeNil())
+
+		begin := time.Now().Truncate(time.Second)
+		_, err := flow.DeleteState(workflow.ID, &flow.StateDeleting{Name: "REVIEW", Replacement: domain.StateDone.Name, Force: true}, sec)
+		Expect(err).To(BeNil())
+
+		w := domain.Work{}
+		Expect(db.Where("id = ?", 1).First(&w).Error).To(BeNil())
+		Expect(w.StateName).To(Equal(domain.StateDone.Name))
+		Expect(w.StateBeginTime.Time().Before(begin)).To(BeFalse())
+		Expect(w.ProcessBeginTime).To(Equal(w.StateBeginTime))
+		Expect(w.ProcessEndTime).To(Equal(w.StateBeginTime))
+
+		Expect(flow.CreateState(workflow.ID, &flow.StateCreating{Name: "REOPENED", Category: state.InProcess, Order: 20002,
+			Transitions: []state.Transition{{Name: "reopen", From: domain.StateDone.Name, To: "REOPENED"}}}, sec)).To(BeNil())
+		Expect(flow.CreateState(workflow.ID, &flow.StateCreating{Name: "OBSOLETE", Category: state.Done, Order: 20003,
+			Transitions: []state.Transition{{Name: "obsolete", From: domain.StateDoing.Name, To: "OBSOLETE"}}}, sec)).To(BeNil())
+		Expect(db.Model(&domain.Work{}).Where("id = ?", 1).Update(map[string]interface{}{"state_name": "OBSOLETE",
+			"flow_version": 5}).Error).To(BeNil())
+		_, err = flow.DeleteState(workflow.ID, &flow.StateDeleting{Name: "OBSOLETE", Replacement: "REOPENED", Force: true}, sec)
+		Expect(err).To(BeNil())
+		moved := domain.Work{}
+		Expect(db.Where("id = ?", 1).First(&moved).Error).To(BeNil())
+		Expect(moved.StateName).To(Equal("REOPENED"))
+		Expect(moved.ProcessBeginTime).To(Equal(w.ProcessBeginTime))
+		Expect(moved.ProcessEndTime.IsZero()).To(BeTrue())
+		Expect(moved.StateBeginTime.Time().Before(w.StateBeginTime.Time())).To(BeFalse())
+	})
+}
+
 func TestUpdateStateRangeOrders(t *testing.T) {
 	RegisterTestingT(t)
 	var testDatabase *testinfra.TestDatabase
@@ -931,6 +1254,10 @@ func TestUpdateStateRangeOrders(t *testing.T) {
 		Expect(affectedStates[2].Name).To(Equal("DONE"))
 		Expect(affectedStates[2].Category).To(Equal(state.Done))
 		Expect(affectedStates[2].Order).To(Equal(10003))
+
+		reordered := domain.Workflow{}
+		Expect(testDatabase.DS.GormDB(context.Background()).Where(&domain.Workflow{ID: workflow.ID}).First(&reordered).Error).To(BeNil())
+		Expect(reordered.Version).To(Equal(workflow.Version))
 	})
 
 	t.Run("should be able to catch database error", func(t *testing.T) {
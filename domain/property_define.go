@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"flywheel/bizerror"
+	"flywheel/domain/state"
 	"fmt"
 	"strconv"
 	"strings"
@@ -23,15 +24,22 @@ const (
 )
 
 type PropertyDefinition struct {
-	Name string `json:"name" binding:"required" gorm:"unique_index:uni_workflow_prop"`
-	Type string `json:"type" binding:"required,oneof=text textarea number time select"`
+	Name string `json:"name" yaml:"name" binding:"required" gorm:"unique_index:uni_workflow_prop"`
+	Type string `json:"type" yaml:"type" binding:"required,oneof=text textarea number time select"`
 
-	Title   string          `json:"title"`
-	Options PropertyOptions `json:"options" sql:"type:VARCHAR(1024)"`
+	Title   string          `json:"title"   yaml:"title,omitempty"`
+	Options PropertyOptions `json:"options" yaml:"options,omitempty" sql:"type:VARCHAR(1024)"`
+
+	// RequiredStates are the states which a work can enter only when the property of it has a value
+	RequiredStates PropertyStates `json:"requiredStates,omitempty" yaml:"requiredStates,omitempty" sql:"type:VARCHAR(1024)"`
+	// DefaultValue is assigned to the property of work when the work is created
+	DefaultValue string `json:"defaultValue,omitempty" yaml:"defaultValue,omitempty"`
 }
 
 type PropertyOptions map[string]interface{}
+type PropertyStates []string
 
+// ValidateOptions checks the options and the default value of property definition
 func (t PropertyDefinition) ValidateOptions() error {
 	if t.Type == PropTypeSelect {
 		_, err := t.ValidateSelectOptions()
@@ -39,9 +47,33 @@ func (t PropertyDefinition) ValidateOptions() error {
 			return err
 		}
 	}
+	if t.DefaultValue != "" {
+		if _, err := t.ValidateValue(t.DefaultValue); err != nil {
+			return bizerror.ErrPropertyDefinitionInvalid
+		}
+	}
 	return nil
 }
 
+// IsRequiredBy reports whether the property must have a value before a work enters state stateName, the requirement
+// on a parent state applies to all states under it. Only state stateName itself is matched when sm is nil.
+func (t PropertyDefinition) IsRequiredBy(stateName string, sm *state.StateMachine) bool {
+	names := []string{stateName}
+	if sm != nil {
+		for _, ancestor := range sm.Ancestors(stateName) {
+			names = append(names, ancestor.Name)
+		}
+	}
+	for _, s := range t.RequiredStates {
+		for _, name := range names {
+			if s == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (t PropertyDefinition) ValidateSelectOptions() ([]string, error) {
 	val := t.Options[OptionKeySelectEnum]
 	enums, ok := val.([]string)
@@ -100,6 +132,32 @@ func (c *PropertyOptions) Scan(v interface{}) error {
 	return json.Unmarshal([]byte(jsonString), c)
 }
 
+func (t PropertyStates) Value() (driver.Value, error) {
+	jsonBytes, err := json.Marshal(&t)
+	if err != nil {
+		return nil, err
+	}
+	return string(jsonBytes), nil
+}
+
+func (c *PropertyStates) Scan(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	jsonString, ok := v.(string)
+	if !ok {
+		jsonByte, ok := v.([]byte)
+		if !ok {
+			return fmt.Errorf("type is neither string nor []byte: %T %v", v, v)
+		}
+		jsonString = string(jsonByte)
+	}
+	if jsonString == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(jsonString), c)
+}
+
 var ErrUnsupportedPropertyType = errors.New("unsupported property type")
 
 func (d PropertyDefinition) ValidateValue(raw string) (interface{}, error) {
@@ -3,6 +3,7 @@ package domain
 import (
 	"errors"
 	"flywheel/bizerror"
+	"flywheel/domain/state"
 	"strconv"
 	"testing"
 	"time"
@@ -120,6 +121,49 @@ func TestPropertyDefinition_ValidateOptions(t *testing.T) {
 			Options: map[string]interface{}{OptionKeySelectEnum: []interface{}{100, 200}}}.ValidateOptions()).
 			To(Equal(bizerror.ErrPropertyDefinitionInvalid))
 	})
+
+	t.Run("be able to validate default value", func(t *testing.T) {
+		Expect(PropertyDefinition{Type: PropTypeNumber, DefaultValue: "3"}.ValidateOptions()).To(BeNil())
+		Expect(PropertyDefinition{Type: PropTypeSelect, DefaultValue: "cat",
+			Options: map[string]interface{}{OptionKeySelectEnum: []string{"Cat", "Dog"}}}.ValidateOptions()).To(BeNil())
+
+		Expect(PropertyDefinition{Type: PropTypeNumber, DefaultValue: "three"}.ValidateOptions()).
+			To(Equal(bizerror.ErrPropertyDefinitionInvalid))
+		Expect(PropertyDefinition{Type: PropTypeSelect, DefaultValue: "Bird",
+			Options: map[string]interface{}{OptionKeySelectEnum: []string{"Cat", "Dog"}}}.ValidateOptions()).
+			To(Equal(bizerror.ErrPropertyDefinitionInvalid))
+	})
+}
+
+func TestPropertyDefinition_IsRequiredBy(t *testing.T) {
+	RegisterTestingT(t)
+
+	d := PropertyDefinition{Name: "resolution", Type: PropTypeText, RequiredStates: PropertyStates{"DONE", "REJECTED"}}
+	Expect(d.IsRequiredBy("DONE", nil)).To(BeTrue())
+	Expect(d.IsRequiredBy("DOING", nil)).To(BeFalse())
+	Expect(PropertyDefinition{Name: "resolution", Type: PropTypeText}.IsRequiredBy("DONE", nil)).To(BeFalse())
+
+	sm := state.NewStateMachine([]state.State{
+		{Name: "DOING", Category: state.InProcess},
+		{Name: "DONE", Category: state.Done},
+		{Name: "RELEASED", Category: state.Done, Parent: "DONE"},
+		{Name: "ARCHIVED", Category: state.Done, Parent: "RELEASED"},
+	}, nil)
+	Expect(d.IsRequiredBy("RELEASED", sm)).To(BeTrue())
+	Expect(d.IsRequiredBy("ARCHIVED", sm)).To(BeTrue())
+	Expect(d.IsRequiredBy("ARCHIVED", nil)).To(BeFalse())
+	Expect(d.IsRequiredBy("DOING", sm)).To(BeFalse())
+}
+
+func TestPropertyStates_Scan(t *testing.T) {
+	RegisterTestingT(t)
+
+	var states PropertyStates
+	Expect(states.Scan(nil)).To(BeNil())
+	Expect(states).To(BeNil())
+	Expect(states.Scan([]byte(`["DONE"]`))).To(BeNil())
+	Expect(states).To(Equal(PropertyStates{"DONE"}))
+	Expect(PropertyStates{"DONE", "REJECTED"}.Value()).To(Equal(`["DONE","REJECTED"]`))
 }
 
 func TestPropertyOptions_Value(t *testing.T) {
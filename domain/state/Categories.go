@@ -0,0 +1,75 @@
+package state
+
+import (
+	"sort"
+	"sync"
+)
+
+// CustomCategoryMin is the smallest value of custom categories, the values below it are reserved for base categories
+const CustomCategoryMin Category = 100
+
+// CategoryDefinition is a custom category of states, it behaves as its Base category when the process of works is
+// measured, so works in "Blocked" (base InProcess) are still in process while they can be shown apart on boards.
+type CategoryDefinition struct {
+	Category   Category `json:"category"   yaml:"category"`
+	Name       string   `json:"name"       yaml:"name"`
+	ThemeColor string   `json:"themeColor" yaml:"themeColor"`
+	Base       Category `json:"base"       yaml:"base"`
+}
+
+var (
+	customCategoriesLock sync.RWMutex
+	customCategories     = map[Category]CategoryDefinition{}
+)
+
+// SetCustomCategories replaces all custom categories known by the state machines
+func SetCustomCategories(definitions []CategoryDefinition) {
+	categories := map[Category]CategoryDefinition{}
+	for _, d := range definitions {
+		categories[d.Category] = d
+	}
+	customCategoriesLock.Lock()
+	defer customCategoriesLock.Unlock()
+	customCategories = categories
+}
+
+// CustomCategories returns the custom categories in the order of category value
+func CustomCategories() []CategoryDefinition {
+	customCategoriesLock.RLock()
+	defer customCategoriesLock.RUnlock()
+	definitions := []CategoryDefinition{}
+	for _, d := range customCategories {
+		definitions = append(definitions, d)
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Category < definitions[j].Category })
+	return definitions
+}
+
+func findCustomCategory(c Category) (CategoryDefinition, bool) {
+	customCategoriesLock.RLock()
+	defer customCategoriesLock.RUnlock()
+	d, found := customCategories[c]
+	return d, found
+}
+
+// IsBaseCategory reports whether c is one of InBacklog, InProcess, Done and Rejected
+func IsBaseCategory(c Category) bool {
+	return c == InBacklog || c == InProcess || c == Done || c == Rejected
+}
+
+// BaseCategory returns the base category which c behaves as, a base category is returned as is
+func BaseCategory(c Category) Category {
+	if d, found := findCustomCategory(c); found {
+		return d.Base
+	}
+	return c
+}
+
+// BaseCategories returns the definitions of base categories, a base category is the base of itself
+func BaseCategories() []CategoryDefinition {
+	var definitions []CategoryDefinition
+	for _, c := range []Category{InBacklog, InProcess, Done, Rejected} {
+		definitions = append(definitions, CategoryDefinition{Category: c, Name: categoryNames[c], ThemeColor: categoryColors[c], Base: c})
+	}
+	return definitions
+}
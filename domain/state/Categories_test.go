@@ -0,0 +1,53 @@
+package state_test
+
+import (
+	"flywheel/domain/state"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Categories", func() {
+	blocked := state.CategoryDefinition{Category: 100, Name: "Waiting for customer", ThemeColor: "#FFF59D", Base: state.InProcess}
+	BeforeEach(func() {
+		state.SetCustomCategories([]state.CategoryDefinition{blocked})
+	})
+	AfterEach(func() {
+		state.SetCustomCategories(nil)
+	})
+
+	It("should map custom categories to their base categories", func() {
+		Expect(state.CustomCategories()).To(Equal([]state.CategoryDefinition{blocked}))
+		Expect(state.IsCategoryValid(blocked.Category)).To(BeTrue())
+		Expect(state.IsCategoryValid(101)).To(BeFalse())
+		Expect(state.IsBaseCategory(blocked.Category)).To(BeFalse())
+		Expect(state.BaseCategory(blocked.Category)).To(Equal(state.InProcess))
+		Expect(state.BaseCategory(state.Done)).To(Equal(state.Done))
+		Expect(state.CategoryName(blocked.Category)).To(Equal("Waiting for customer"))
+		Expect(state.BaseCategories()).To(Equal([]state.CategoryDefinition{
+			{Category: state.InBacklog, Name: "InBacklog", ThemeColor: "#B0BEC5", Base: state.InBacklog},
+			{Category: state.InProcess, Name: "InProcess", ThemeColor: "#90CAF9", Base: state.InProcess},
+			{Category: state.Done, Name: "Done", ThemeColor: "#A5D6A7", Base: state.Done},
+			{Category: state.Rejected, Name: "Rejected", ThemeColor: "#EF9A9A", Base: state.Rejected},
+		}))
+	})
+
+	It("should validate state machine by base categories", func() {
+		sm := state.NewStateMachine(
+			[]state.State{{Name: "OPEN", Category: state.InBacklog}, {Name: "DOING", Category: state.InProcess},
+				{Name: "WAITING", Category: blocked.Category, Parent: "DOING"}, {Name: "CLOSED", Category: state.Done}},
+			[]state.Transition{{Name: "wait", From: "OPEN", To: "WAITING"}})
+		Expect(sm.Validate()).To(Equal(state.Findings{
+			{Level: state.FindingLevelWarning, Code: "state.dead_end", Message: "there is no transition out of state WAITING", State: "WAITING"},
+			{Level: state.FindingLevelWarning, Code: "state.unreachable", Message: "state CLOSED can not be reached", State: "CLOSED"},
+		}))
+	})
+
+	It("should render custom categories in diagrams", func() {
+		sm := state.NewStateMachine([]state.State{{Name: "WAITING", Category: blocked.Category}}, nil)
+		diagram, err := sm.Render(state.DiagramMermaid, nil)
+		Expect(err).To(BeNil())
+		Expect(strings.Contains(diagram, "classDef Waitingforcustomer fill:#FFF59D")).To(BeTrue())
+	})
+})
@@ -0,0 +1,156 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	DiagramPlantUML = "plantuml"
+	DiagramDot      = "dot"
+	DiagramMermaid  = "mermaid"
+)
+
+var (
+	categoryNames  = map[Category]string{InBacklog: "InBacklog", InProcess: "InProcess", Done: "Done", Rejected: "Rejected"}
+	categoryColors = map[Category]string{InBacklog: "#B0BEC5", InProcess: "#90CAF9", Done: "#A5D6A7", Rejected: "#EF9A9A"}
+
+	// the names written into diagrams can neither close the quotes around them nor break the line
+	plantUMLEscaper = strings.NewReplacer(`"`, `'`, "\r", "", "\n", `\n`)
+	dotEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", `\n`)
+	mermaidEscaper  = strings.NewReplacer(`"`, "#quot;", ":", "#58;", ";", "#59;", "\r", "", "\n", "<br>")
+)
+
+func CategoryName(c Category) string {
+	if name, found := categoryNames[c]; found {
+		return name
+	}
+	if d, found := findCustomCategory(c); found {
+		return d.Name
+	}
+	return "Category" + strconv.Itoa(int(c))
+}
+
+func categoryColor(c Category) string {
+	if color, found := categoryColors[c]; found {
+		return color
+	}
+	if d, found := findCustomCategory(c); found && d.ThemeColor != "" {
+		return d.ThemeColor
+	}
+	return "#E0E0E0"
+}
+
+// categoryClass is the name of category which can be used as an identifier in diagrams
+func categoryClass(c Category) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, CategoryName(c))
+}
+
+type diagramNode struct {
+	id    string
+	label string
+	state State
+}
+
+// Render draws the state machine in format plantuml, dot or mermaid. States are grouped and coloured by category,
+// the number of works is appended to the label of each state when counts is not nil.
+func (sm *StateMachine) Render(format string, counts map[string]int) (string, error) {
+	nodes := map[string]diagramNode{}
+	var categories []Category
+	groups := map[Category][]diagramNode{}
+
+	states := append([]State{}, sm.States...)
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].Order < states[j].Order
+	})
+	for idx, s := range states {
+		label := s.Name
+		if counts != nil {
+			label = label + " (" + strconv.Itoa(counts[s.Name]) + ")"
+		}
+		n := diagramNode{id: "s" + strconv.Itoa(idx), label: label, state: s}
+		nodes[s.Name] = n
+		if _, found := groups[s.Category]; !found {
+			categories = append(categories, s.Category)
+		}
+		groups[s.Category] = append(groups[s.Category], n)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i] < categories[j]
+	})
+
+	var b strings.Builder
+	switch format {
+	case DiagramPlantUML:
+		b.WriteString("@startuml\nhide empty description\n")
+		for _, c := range categories {
+			fmt.Fprintf(&b, "state \"%s\" as c%d {\n", plantUMLEscaper.Replace(CategoryName(c)), c)
+			for _, n := range groups[c] {
+				fmt.Fprintf(&b, "  state \"%s\" as %s %s\n", plantUMLEscaper.Replace(n.label), n.id, categoryColor(c))
+			}
+			b.WriteString("}\n")
+		}
+		for _, t := range sm.Transitions {
+			from, to, ok := transitionNodes(nodes, t)
+			if ok {
+				fmt.Fprintf(&b, "%s --> %s : %s\n", from.id, to.id, plantUMLEscaper.Replace(t.Name))
+			}
+		}
+		b.WriteString("@enduml\n")
+	case DiagramDot:
+		b.WriteString("digraph workflow {\n  rankdir=LR;\n  node [shape=box, style=\"rounded,filled\"];\n")
+		for _, c := range categories {
+			fmt.Fprintf(&b, "  subgraph cluster_%d {\n    label=\"%s\";\n", c, dotEscaper.Replace(CategoryName(c)))
+			for _, n := range groups[c] {
+				fmt.Fprintf(&b, "    %s [label=\"%s\", fillcolor=\"%s\"];\n", n.id, dotEscaper.Replace(n.label), categoryColor(c))
+			}
+			b.WriteString("  }\n")
+		}
+		for _, t := range sm.Transitions {
+			from, to, ok := transitionNodes(nodes, t)
+			if ok {
+				fmt.Fprintf(&b, "  %s -> %s [label=\"%s\"];\n", from.id, to.id, dotEscaper.Replace(t.Name))
+			}
+		}
+		b.WriteString("}\n")
+	case DiagramMermaid:
+		b.WriteString("stateDiagram-v2\n")
+		for _, c := range categories {
+			for _, n := range groups[c] {
+				fmt.Fprintf(&b, "  state \"%s\" as %s\n", mermaidEscaper.Replace(n.label), n.id)
+			}
+		}
+		for _, t := range sm.Transitions {
+			from, to, ok := transitionNodes(nodes, t)
+			if ok {
+				fmt.Fprintf(&b, "  %s --> %s : %s\n", from.id, to.id, mermaidEscaper.Replace(t.Name))
+			}
+		}
+		for _, c := range categories {
+			fmt.Fprintf(&b, "  classDef %s fill:%s\n", categoryClass(c), categoryColor(c))
+			var ids []string
+			for _, n := range groups[c] {
+				ids = append(ids, n.id)
+			}
+			fmt.Fprintf(&b, "  class %s %s\n", strings.Join(ids, ","), categoryClass(c))
+		}
+	default:
+		return "", errors.New("unsupported diagram format " + format)
+	}
+	return b.String(), nil
+}
+
+func transitionNodes(nodes map[string]diagramNode, t Transition) (diagramNode, diagramNode, bool) {
+	from, fromFound := nodes[t.From]
+	to, toFound := nodes[t.To]
+	return from, to, fromFound && toFound
+}
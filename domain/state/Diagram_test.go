@@ -0,0 +1,99 @@
+package state_test
+
+import (
+	"flywheel/domain/state"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diagram", func() {
+	sm := state.NewStateMachine(
+		[]state.State{{Name: "DONE", Category: state.Done, Order: 3}, {Name: "PENDING", Category: state.InBacklog, Order: 1},
+			{Name: `"DOING"`, Category: state.InProcess, Order: 2}},
+		[]state.Transition{{Name: "begin", From: "PENDING", To: `"DOING"`}, {Name: "finish", From: `"DOING"`, To: "DONE"}})
+
+	It("should render plantuml", func() {
+		diagram, err := sm.Render(state.DiagramPlantUML, nil)
+		Expect(err).To(BeNil())
+		Expect(diagram).To(Equal(`@startuml
+hide empty description
+state "InBacklog" as c1 {
+  state "PENDING" as s0 #B0BEC5
+}
+state "InProcess" as c2 {
+  state "'DOING'" as s1 #90CAF9
+}
+state "Done" as c3 {
+  state "DONE" as s2 #A5D6A7
+}
+s0 --> s1 : begin
+s1 --> s2 : finish
+@enduml
+`))
+	})
+
+	It("should render dot with counts", func() {
+		diagram, err := sm.Render(state.DiagramDot, map[string]int{"PENDING": 3})
+		Expect(err).To(BeNil())
+		Expect(diagram).To(Equal(`digraph workflow {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled"];
+  subgraph cluster_1 {
+    label="InBacklog";
+    s0 [label="PENDING (3)", fillcolor="#B0BEC5"];
+  }
+  subgraph cluster_2 {
+    label="InProcess";
+    s1 [label="\"DOING\" (0)", fillcolor="#90CAF9"];
+  }
+  subgraph cluster_3 {
+    label="Done";
+    s2 [label="DONE (0)", fillcolor="#A5D6A7"];
+  }
+  s0 -> s1 [label="begin"];
+  s1 -> s2 [label="finish"];
+}
+`))
+	})
+
+	It("should render mermaid", func() {
+		diagram, err := sm.Render(state.DiagramMermaid, nil)
+		Expect(err).To(BeNil())
+		Expect(diagram).To(Equal(`stateDiagram-v2
+  state "PENDING" as s0
+  state "#quot;DOING#quot;" as s1
+  state "DONE" as s2
+  s0 --> s1 : begin
+  s1 --> s2 : finish
+  classDef InBacklog fill:#B0BEC5
+  class s0 InBacklog
+  classDef InProcess fill:#90CAF9
+  class s1 InProcess
+  classDef Done fill:#A5D6A7
+  class s2 Done
+`))
+	})
+
+	It("should escape names of transitions", func() {
+		escaped := state.NewStateMachine([]state.State{{Name: "PENDING", Category: state.InBacklog, Order: 1}, {Name: "DONE", Category: state.Done, Order: 2}},
+			[]state.Transition{{Name: "close: \"now\"\nor later", From: "PENDING", To: "DONE"}})
+
+		diagram, err := escaped.Render(state.DiagramPlantUML, nil)
+		Expect(err).To(BeNil())
+		Expect(diagram).To(ContainSubstring("s0 --> s1 : close: 'now'\\nor later\n"))
+
+		diagram, err = escaped.Render(state.DiagramDot, nil)
+		Expect(err).To(BeNil())
+		Expect(diagram).To(ContainSubstring(`  s0 -> s1 [label="close: \"now\"\nor later"];`))
+
+		diagram, err = escaped.Render(state.DiagramMermaid, nil)
+		Expect(err).To(BeNil())
+		Expect(diagram).To(ContainSubstring("  s0 --> s1 : close#58; #quot;now#quot;<br>or later\n"))
+	})
+
+	It("should reject unknown format", func() {
+		_, err := sm.Render("svg", nil)
+		Expect(err).To(MatchError("unsupported diagram format svg"))
+	})
+})
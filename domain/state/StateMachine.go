@@ -24,15 +24,56 @@ const (
 )
 
 type State struct {
-	Name     string   `json:"name"     validate:"required"`
-	Category Category `json:"category" validate:"required"`
-	Order    int      `json:"order"`
+	Name     string   `json:"name"     yaml:"name"     validate:"required"`
+	Category Category `json:"category" yaml:"category" validate:"required"`
+	Order    int      `json:"order"    yaml:"order"`
+
+	// WipLimit is the max number of unarchived works in the state, 0 means unlimited.
+	// A soft limit does not reject works entering the state, it is only reported as exceeded.
+	WipLimit int  `json:"wipLimit,omitempty" yaml:"wipLimit,omitempty"`
+	WipSoft  bool `json:"wipSoft,omitempty"  yaml:"wipSoft,omitempty"`
+
+	// Reasons are chosen from when a work enters the state, only states of category Rejected have reasons
+	Reasons Reasons `json:"reasons,omitempty" yaml:"reasons,omitempty"`
+
+	// Parent is the name of the state which contains this one, a work is only in the states without children (leaf states).
+	// A child state has the same base category as its parent, and inherits the transitions from its parent.
+	Parent string `json:"parent,omitempty" yaml:"parent,omitempty"`
+}
+
+// Reasons are the reasons of rejection defined by a state of category Rejected
+type Reasons []string
+
+// Contains reports whether reason is one of the reasons
+func (r Reasons) Contains(reason string) bool {
+	for _, item := range r {
+		if item == reason {
+			return true
+		}
+	}
+	return false
 }
 
 type Transition struct {
-	Name string `json:"name" validate:"required" binding:"required"`
-	From string `json:"from" validate:"required" binding:"required"`
-	To   string `json:"to"   validate:"required" binding:"required"`
+	Name string `json:"name" yaml:"name" validate:"required" binding:"required"`
+	From string `json:"from" yaml:"from" validate:"required" binding:"required"`
+	To   string `json:"to"   yaml:"to"   validate:"required" binding:"required"`
+
+	Guards Guards `json:"guards,omitempty" yaml:"guards,omitempty" validate:"dive" binding:"dive"`
+	Hooks  Hooks  `json:"hooks,omitempty"  yaml:"hooks,omitempty"  validate:"dive" binding:"dive"`
+	// Triggers perform the transition automatically, see work.EvaluateTriggers
+	Triggers Triggers `json:"triggers,omitempty" yaml:"triggers,omitempty" validate:"dive" binding:"dive"`
+}
+
+// PermittedTo reports whether the caller who has the project roles checked by hasRole satisfies the projectRole guards
+// of the transition, any project member is permitted if there is no projectRole guard
+func (t Transition) PermittedTo(hasRole func(role string) bool) bool {
+	for _, g := range t.Guards {
+		if g.Type == GuardProjectRole && !hasRole(g.Name) {
+			return false
+		}
+	}
+	return true
 }
 
 func NewStateMachine(states []State, transitions []Transition) *StateMachine {
@@ -48,19 +89,93 @@ func (sm *StateMachine) FindState(stateName string) (State, bool) {
 	return State{}, false
 }
 
+// AvailableTransitions returns the transitions from fromState to toState, an empty state name matches any state.
+// Transitions declared on the ancestors of fromState are inherited unless fromState or a nearer ancestor declares
+// a transition to the same state, the inherited transitions are returned as if they were declared on fromState.
 func (sm *StateMachine) AvailableTransitions(fromState string, toState string) []Transition {
-	var r []Transition
-	for _, transition := range sm.Transitions {
-		if (fromState == "" || fromState == transition.From) && (toState == "" || toState == transition.To) {
-			r = append(r, transition)
+	r := []Transition{}
+	if fromState == "" {
+		for _, transition := range sm.Transitions {
+			if toState == "" || toState == transition.To {
+				r = append(r, transition)
+			}
 		}
+		return r
 	}
-	if r == nil {
-		r = []Transition{}
+
+	declaringStates := []string{fromState}
+	for _, ancestor := range sm.Ancestors(fromState) {
+		declaringStates = append(declaringStates, ancestor.Name)
+	}
+	declaredTargets := map[string]bool{}
+	for _, declaringState := range declaringStates {
+		var targets []string
+		for _, transition := range sm.Transitions {
+			if transition.From != declaringState || (toState != "" && toState != transition.To) || declaredTargets[transition.To] {
+				continue
+			}
+			if declaringState != fromState {
+				// an inherited transition to the state itself is meaningless
+				if transition.To == fromState {
+					continue
+				}
+				transition.From = fromState
+			}
+			targets = append(targets, transition.To)
+			r = append(r, transition)
+		}
+		for _, target := range targets {
+			declaredTargets[target] = true
+		}
 	}
 	return r
 }
 
+// Ancestors returns the ancestors of state, from its parent to the top level one
+func (sm *StateMachine) Ancestors(stateName string) []State {
+	var ancestors []State
+	visited := map[string]bool{stateName: true}
+	current, found := sm.FindState(stateName)
+	for found && current.Parent != "" && !visited[current.Parent] {
+		visited[current.Parent] = true
+		if current, found = sm.FindState(current.Parent); found {
+			ancestors = append(ancestors, current)
+		}
+	}
+	return ancestors
+}
+
+// IsLeaf reports whether the state has no children, works are only in leaf states
+func (sm *StateMachine) IsLeaf(stateName string) bool {
+	for _, s := range sm.States {
+		if s.Parent == stateName {
+			return false
+		}
+	}
+	return true
+}
+
+// LeafStates returns the names of leaf states which are the state itself or its descendants
+func (sm *StateMachine) LeafStates(stateName string) []string {
+	var leaves []string
+	for _, s := range sm.States {
+		if !sm.IsLeaf(s.Name) {
+			continue
+		}
+		if s.Name == stateName {
+			leaves = append(leaves, s.Name)
+			continue
+		}
+		for _, ancestor := range sm.Ancestors(s.Name) {
+			if ancestor.Name == stateName {
+				leaves = append(leaves, s.Name)
+				break
+			}
+		}
+	}
+	return leaves
+}
+
 type transitionList []Transition
 
 func (I transitionList) Len() int {
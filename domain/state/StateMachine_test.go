@@ -85,5 +85,58 @@ var _ = Describe("StateMachine", func() {
 
 			})
 		})
+
+		Context("With nested states", func() {
+			nested := state.NewStateMachine(
+				[]state.State{{Name: "PENDING", Category: state.InBacklog}, {Name: "IN_PROGRESS", Category: state.InProcess},
+					{Name: "CODING", Category: state.InProcess, Parent: "IN_PROGRESS"}, {Name: "REVIEW", Category: state.InProcess, Parent: "IN_PROGRESS"},
+					{Name: "DONE", Category: state.Done}},
+				[]state.Transition{
+					{Name: "begin", From: "PENDING", To: "CODING"},
+					{Name: "finish", From: "IN_PROGRESS", To: "DONE"},
+					{Name: "back to coding", From: "IN_PROGRESS", To: "CODING"},
+					{Name: "review", From: "CODING", To: "REVIEW"},
+					{Name: "approve", From: "REVIEW", To: "DONE", Guards: state.Guards{{Type: state.GuardProjectRole, Name: "manager"}}},
+				})
+
+			It("should inherit transitions from parent states", func() {
+				Ω(nested.AvailableTransitions("CODING", "")).Should(Equal([]state.Transition{
+					{Name: "review", From: "CODING", To: "REVIEW"},
+					{Name: "finish", From: "CODING", To: "DONE"},
+				}))
+				Ω(nested.AvailableTransitions("REVIEW", "")).Should(Equal([]state.Transition{
+					{Name: "approve", From: "REVIEW", To: "DONE", Guards: state.Guards{{Type: state.GuardProjectRole, Name: "manager"}}},
+					{Name: "back to coding", From: "REVIEW", To: "CODING"},
+				}))
+				Ω(nested.AvailableTransitions("REVIEW", "DONE")).Should(Equal([]state.Transition{
+					{Name: "approve", From: "REVIEW", To: "DONE", Guards: state.Guards{{Type: state.GuardProjectRole, Name: "manager"}}},
+				}))
+				Ω(nested.AvailableTransitions("", "DONE")).Should(Equal([]state.Transition{
+					{Name: "finish", From: "IN_PROGRESS", To: "DONE"},
+					{Name: "approve", From: "REVIEW", To: "DONE", Guards: state.Guards{{Type: state.GuardProjectRole, Name: "manager"}}},
+				}))
+			})
+
+			It("should resolve ancestors and leaf states", func() {
+				Ω(nested.Ancestors("REVIEW")).Should(Equal([]state.State{{Name: "IN_PROGRESS", Category: state.InProcess}}))
+				Ω(nested.Ancestors("PENDING")).Should(BeEmpty())
+				Ω(nested.IsLeaf("IN_PROGRESS")).Should(BeFalse())
+				Ω(nested.IsLeaf("CODING")).Should(BeTrue())
+				Ω(nested.LeafStates("IN_PROGRESS")).Should(Equal([]string{"CODING", "REVIEW"}))
+				Ω(nested.LeafStates("DONE")).Should(Equal([]string{"DONE"}))
+				Ω(nested.Validate()).Should(Equal(state.Findings{}))
+			})
+		})
+	})
+
+	Describe("PermittedTo", func() {
+		It("should permit transition to callers with roles of projectRole guards", func() {
+			hasRole := func(role string) bool { return role == "common" }
+			Ω(state.Transition{Name: "begin"}.PermittedTo(hasRole)).Should(BeTrue())
+			Ω(state.Transition{Name: "begin", Guards: state.Guards{{Type: state.GuardChecklistDone},
+				{Type: state.GuardProjectRole, Name: "common"}}}.PermittedTo(hasRole)).Should(BeTrue())
+			Ω(state.Transition{Name: "close", Guards: state.Guards{{Type: state.GuardProjectRole, Name: "common"},
+				{Type: state.GuardProjectRole, Name: "manager"}}}.PermittedTo(hasRole)).Should(BeFalse())
+		})
 	})
 })
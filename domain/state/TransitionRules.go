@@ -0,0 +1,116 @@
+package state
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// guards are evaluated before the state changed, a transition is refused when any guard is not satisfied
+const (
+	GuardChecklistDone = "checklistDone" // all check items of the work are done
+	GuardPropertySet   = "propertySet"   // property Name of the work has a non-empty value
+	GuardProjectRole   = "projectRole"   // caller has role Name in the project of the work
+)
+
+// hooks are performed after the state changed, in the same transaction of the transition
+const (
+	HookSetProperty = "setProperty" // assign Value to property Name of the work
+	HookAddLabel    = "addLabel"    // attach label Name of the project to the work
+	HookEmitEvent   = "emitEvent"   // record an event named Name for the work
+)
+
+// triggers perform the transition automatically, the transition is performed when any trigger is fired
+const (
+	TriggerStateTimeout  = "stateTimeout"  // the work has been in the from state for Hours hours
+	TriggerChecklistDone = "checklistDone" // the work has check items and all of them are done
+	TriggerPropertyValue = "propertyValue" // property Name of the work has value Value
+)
+
+type Guard struct {
+	Type string `json:"type" yaml:"type" validate:"required,oneof=checklistDone propertySet projectRole" binding:"required,oneof=checklistDone propertySet projectRole"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty" validate:"required_unless=Type checklistDone" binding:"required_unless=Type checklistDone"`
+}
+
+type Hook struct {
+	Type  string `json:"type" yaml:"type" validate:"required,oneof=setProperty addLabel emitEvent" binding:"required,oneof=setProperty addLabel emitEvent"`
+	Name  string `json:"name" yaml:"name" validate:"required" binding:"required"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+type Trigger struct {
+	Type  string `json:"type" yaml:"type" validate:"required,oneof=stateTimeout checklistDone propertyValue" binding:"required,oneof=stateTimeout checklistDone propertyValue"`
+	Hours int    `json:"hours,omitempty" yaml:"hours,omitempty" validate:"required_if=Type stateTimeout,min=0" binding:"required_if=Type stateTimeout,min=0"`
+	Name  string `json:"name,omitempty" yaml:"name,omitempty" validate:"required_if=Type propertyValue" binding:"required_if=Type propertyValue"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+type Guards []Guard
+type Hooks []Hook
+type Triggers []Trigger
+
+func (t Guards) Value() (driver.Value, error) {
+	jsonBytes, err := json.Marshal(&t)
+	if err != nil {
+		return nil, err
+	}
+	return string(jsonBytes), nil
+}
+
+func (c *Guards) Scan(v interface{}) error {
+	return scanJson(v, c)
+}
+
+func (t Hooks) Value() (driver.Value, error) {
+	jsonBytes, err := json.Marshal(&t)
+	if err != nil {
+		return nil, err
+	}
+	return string(jsonBytes), nil
+}
+
+func (c *Hooks) Scan(v interface{}) error {
+	return scanJson(v, c)
+}
+
+func (t Triggers) Value() (driver.Value, error) {
+	jsonBytes, err := json.Marshal(&t)
+	if err != nil {
+		return nil, err
+	}
+	return string(jsonBytes), nil
+}
+
+func (c *Triggers) Scan(v interface{}) error {
+	return scanJson(v, c)
+}
+
+func (t Reasons) Value() (driver.Value, error) {
+	jsonBytes, err := json.Marshal(&t)
+	if err != nil {
+		return nil, err
+	}
+	return string(jsonBytes), nil
+}
+
+func (c *Reasons) Scan(v interface{}) error {
+	return scanJson(v, c)
+}
+
+func scanJson(v interface{}, target interface{}) error {
+	if v == nil {
+		return nil
+	}
+	jsonString, ok := v.(string)
+	if !ok {
+		jsonByte, ok := v.([]byte)
+		if !ok {
+			return fmt.Errorf("type is neither string nor []byte: %T %v", v, v)
+		}
+		jsonString = string(jsonByte)
+	}
+	if jsonString == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(jsonString), target)
+}
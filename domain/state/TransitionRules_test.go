@@ -0,0 +1,86 @@
+package state_test
+
+import (
+	"flywheel/domain/state"
+
+	"github.com/go-playground/validator/v10"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TransitionRules", func() {
+	Describe("Guards", func() {
+		It("should be able to convert to database value and scan back", func() {
+			guards := state.Guards{{Type: state.GuardChecklistDone}, {Type: state.GuardPropertySet, Name: "resolution"}}
+			v, err := guards.Value()
+			Expect(err).To(BeNil())
+			Expect(v).To(Equal(`[{"type":"checklistDone"},{"type":"propertySet","name":"resolution"}]`))
+
+			scanned := state.Guards{}
+			Expect(scanned.Scan(v)).To(BeNil())
+			Expect(scanned).To(Equal(guards))
+
+			scanned = state.Guards{}
+			Expect(scanned.Scan([]byte(v.(string)))).To(BeNil())
+			Expect(scanned).To(Equal(guards))
+		})
+
+		It("should scan null and empty value as empty guards", func() {
+			var scanned state.Guards
+			Expect(scanned.Scan(nil)).To(BeNil())
+			Expect(scanned).To(BeNil())
+			Expect(scanned.Scan("")).To(BeNil())
+			Expect(scanned).To(BeNil())
+			Expect(scanned.Scan("null")).To(BeNil())
+			Expect(scanned).To(BeNil())
+		})
+
+		It("should failed to scan unexpected type", func() {
+			var scanned state.Guards
+			err := scanned.Scan(100)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("type is neither string nor []byte: int 100"))
+		})
+	})
+
+	Describe("Hooks", func() {
+		It("should be able to convert to database value and scan back", func() {
+			hooks := state.Hooks{{Type: state.HookSetProperty, Name: "resolution", Value: "fixed"}, {Type: state.HookEmitEvent, Name: "released"}}
+			v, err := hooks.Value()
+			Expect(err).To(BeNil())
+			Expect(v).To(Equal(`[{"type":"setProperty","name":"resolution","value":"fixed"},{"type":"emitEvent","name":"released"}]`))
+
+			scanned := state.Hooks{}
+			Expect(scanned.Scan(v)).To(BeNil())
+			Expect(scanned).To(Equal(hooks))
+		})
+	})
+
+	Describe("Transition validation", func() {
+		v := validator.New()
+
+		It("should accept valid guards and hooks", func() {
+			t := state.Transition{Name: "finish", From: "DOING", To: "DONE",
+				Guards: state.Guards{{Type: state.GuardChecklistDone}, {Type: state.GuardProjectRole, Name: "manager"}},
+				Hooks:  state.Hooks{{Type: state.HookAddLabel, Name: "finished"}}}
+			Expect(v.Struct(t)).To(BeNil())
+		})
+
+		It("should reject unknown guard type and guard without name", func() {
+			t := state.Transition{Name: "finish", From: "DOING", To: "DONE",
+				Guards: state.Guards{{Type: "unknown", Name: "x"}, {Type: state.GuardPropertySet}}}
+			err := v.Struct(t)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("Key: 'Transition.Guards[0].Type' Error:Field validation for 'Type' failed on the 'oneof' tag\n" +
+				"Key: 'Transition.Guards[1].Name' Error:Field validation for 'Name' failed on the 'required_unless' tag"))
+		})
+
+		It("should reject hook without type or name", func() {
+			t := state.Transition{Name: "finish", From: "DOING", To: "DONE", Hooks: state.Hooks{{}}}
+			err := v.Struct(t)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("Key: 'Transition.Hooks[0].Type' Error:Field validation for 'Type' failed on the 'required' tag\n" +
+				"Key: 'Transition.Hooks[0].Name' Error:Field validation for 'Name' failed on the 'required' tag"))
+		})
+	})
+})
@@ -0,0 +1,237 @@
+package state
+
+import (
+	"flywheel/bizerror"
+	"net/http"
+	"strings"
+)
+
+const (
+	FindingLevelError   = "error"
+	FindingLevelWarning = "warning"
+)
+
+// Finding is a problem of state machine definition, a state machine with error findings must not be saved
+type Finding struct {
+	Level   string `json:"level"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	State      string `json:"state,omitempty"`
+	Transition string `json:"transition,omitempty"`
+}
+
+type Findings []Finding
+
+func (f Findings) HasError() bool {
+	for _, finding := range f {
+		if finding.Level == FindingLevelError {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Findings) Errors() Findings {
+	errs := Findings{}
+	for _, finding := range f {
+		if finding.Level == FindingLevelError {
+			errs = append(errs, finding)
+		}
+	}
+	return errs
+}
+
+// ErrStateMachineInvalid is returned when a state machine with error findings is going to be saved
+type ErrStateMachineInvalid struct {
+	Findings Findings
+}
+
+func (e *ErrStateMachineInvalid) Error() string {
+	var messages []string
+	for _, f := range e.Findings {
+		messages = append(messages, f.Message)
+	}
+	return "state machine is invalid: " + strings.Join(messages, "; ")
+}
+func (e *ErrStateMachineInvalid) Respond() *bizerror.BizErrorDetail {
+	return &bizerror.BizErrorDetail{Status: http.StatusBadRequest, Code: "workflow.state_machine_invalid", Message: e.Error(), Data: e.Findings}
+}
+
+// IsCategoryValid reports whether c is a base category or a known custom category
+func IsCategoryValid(c Category) bool {
+	if IsBaseCategory(c) {
+		return true
+	}
+	_, found := findCustomCategory(c)
+	return found
+}
+
+// Validate lint the state machine, findings of level error are returned before findings of level warning
+func (sm *StateMachine) Validate() Findings {
+	errs := Findings{}
+	warnings := Findings{}
+
+	if len(sm.States) == 0 {
+		errs = append(errs, Finding{Level: FindingLevelError, Code: "state_machine.no_state", Message: "there is no state"})
+	}
+
+	stateIndex := map[string]State{}
+	hasTerminal := false
+	for _, s := range sm.States {
+		if strings.TrimSpace(s.Name) == "" {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "state.name_empty", Message: "state name is empty", State: s.Name})
+			continue
+		}
+		key := strings.ToLower(s.Name)
+		if _, found := stateIndex[key]; found {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "state.duplicated", Message: "state " + s.Name + " is duplicated", State: s.Name})
+			continue
+		}
+		stateIndex[key] = s
+		if !IsCategoryValid(s.Category) {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "state.category_invalid", Message: "category of state " + s.Name + " is invalid", State: s.Name})
+		}
+		if s.WipLimit < 0 {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "state.wip_limit_invalid", Message: "wip limit of state " + s.Name + " is negative", State: s.Name})
+		}
+		if len(s.Reasons) > 0 && BaseCategory(s.Category) != Rejected {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "state.reasons_not_allowed", Message: "state " + s.Name + " has reasons but it is not of category Rejected", State: s.Name})
+		}
+		for _, reason := range s.Reasons {
+			if strings.TrimSpace(reason) == "" {
+				errs = append(errs, Finding{Level: FindingLevelError, Code: "state.reason_empty", Message: "reason of state " + s.Name + " is empty", State: s.Name})
+				break
+			}
+		}
+		if base := BaseCategory(s.Category); base == Done || base == Rejected {
+			hasTerminal = true
+		}
+	}
+
+	for _, s := range sm.States {
+		if s.Parent == "" || s.Name == "" {
+			continue
+		}
+		parent, found := stateIndex[strings.ToLower(s.Parent)]
+		if !found {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "state.unknown_parent", Message: "parent " + s.Parent + " of state " + s.Name + " is unknown", State: s.Name})
+			continue
+		}
+		if BaseCategory(parent.Category) != BaseCategory(s.Category) {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "state.category_mismatch", Message: "category of state " + s.Name + " is different from its parent " + parent.Name, State: s.Name})
+		}
+		cyclic := s.Parent == s.Name
+		for _, ancestor := range sm.Ancestors(s.Name) {
+			cyclic = cyclic || ancestor.Parent == s.Name
+		}
+		if cyclic {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "state.parent_cycle", Message: "state " + s.Name + " is an ancestor of itself", State: s.Name})
+		}
+	}
+
+	transitionIndex := map[string]Transition{}
+	namesOfFromState := map[string]bool{}
+	outgoing := map[string][]string{}
+	for _, t := range sm.Transitions {
+		if strings.TrimSpace(t.Name) == "" {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "transition.name_empty", Message: "name of transition from " + t.From + " to " + t.To + " is empty", Transition: t.Name})
+		}
+		from, fromFound := stateIndex[strings.ToLower(t.From)]
+		if !fromFound {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "transition.unknown_state", Message: "transition " + t.Name + " is from unknown state " + t.From, Transition: t.Name, State: t.From})
+		}
+		to, toFound := stateIndex[strings.ToLower(t.To)]
+		if !toFound {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "transition.unknown_state", Message: "transition " + t.Name + " is to unknown state " + t.To, Transition: t.Name, State: t.To})
+		}
+		if !fromFound || !toFound {
+			continue
+		}
+		if !sm.IsLeaf(to.Name) {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "transition.to_parent_state", Message: "transition " + t.Name + " is to state " + t.To + " which has children", Transition: t.Name, State: t.To})
+		}
+
+		key := strings.ToLower(t.From) + "\n" + strings.ToLower(t.To)
+		if _, found := transitionIndex[key]; found {
+			errs = append(errs, Finding{Level: FindingLevelError, Code: "transition.duplicated", Message: "transition from " + t.From + " to " + t.To + " is duplicated", Transition: t.Name})
+			continue
+		}
+		transitionIndex[key] = t
+
+		nameKey := strings.ToLower(t.From) + "\n" + strings.ToLower(t.Name)
+		if namesOfFromState[nameKey] {
+			warnings = append(warnings, Finding{Level: FindingLevelWarning, Code: "transition.name_duplicated", Message: "there are more than one transition named " + t.Name + " from state " + t.From, Transition: t.Name, State: t.From})
+		}
+		namesOfFromState[nameKey] = true
+
+		outgoing[from.Name] = append(outgoing[from.Name], to.Name)
+	}
+
+	if len(sm.States) > 0 && !hasTerminal {
+		warnings = append(warnings, Finding{Level: FindingLevelWarning, Code: "state_machine.no_terminal_state", Message: "there is no state of category Done or Rejected"})
+	}
+
+	// leaf states inherit the transitions from their ancestors, and a parent state is reached when any of its children is reached
+	for _, s := range sm.States {
+		if !sm.IsLeaf(s.Name) {
+			continue
+		}
+		for _, ancestor := range sm.Ancestors(s.Name) {
+			outgoing[s.Name] = append(outgoing[s.Name], outgoing[ancestor.Name]...)
+		}
+	}
+	reachable := sm.reachableStates(outgoing)
+	for _, s := range sm.States {
+		if reachable[s.Name] && sm.IsLeaf(s.Name) {
+			for _, ancestor := range sm.Ancestors(s.Name) {
+				reachable[ancestor.Name] = true
+			}
+		}
+	}
+	for _, s := range sm.States {
+		if _, found := stateIndex[strings.ToLower(s.Name)]; !found || s.Name == "" {
+			continue
+		}
+		if !reachable[s.Name] {
+			warnings = append(warnings, Finding{Level: FindingLevelWarning, Code: "state.unreachable", Message: "state " + s.Name + " can not be reached", State: s.Name})
+		}
+		if BaseCategory(s.Category) == InProcess && sm.IsLeaf(s.Name) && len(outgoing[s.Name]) == 0 {
+			warnings = append(warnings, Finding{Level: FindingLevelWarning, Code: "state.dead_end", Message: "there is no transition out of state " + s.Name, State: s.Name})
+		}
+	}
+
+	return append(errs, warnings...)
+}
+
+// reachableStates walk through transitions from the initial states: the leaf states of category InBacklog,
+// or the first state if there is no leaf state of category InBacklog
+func (sm *StateMachine) reachableStates(outgoing map[string][]string) map[string]bool {
+	var queue []string
+	for _, s := range sm.States {
+		if BaseCategory(s.Category) == InBacklog && sm.IsLeaf(s.Name) {
+			queue = append(queue, s.Name)
+		}
+	}
+	if len(queue) == 0 && len(sm.States) > 0 {
+		first := sm.States[0]
+		for _, s := range sm.States {
+			if s.Order < first.Order {
+				first = s
+			}
+		}
+		queue = append(queue, first.Name)
+	}
+
+	reachable := map[string]bool{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if reachable[current] {
+			continue
+		}
+		reachable[current] = true
+		queue = append(queue, outgoing[current]...)
+	}
+	return reachable
+}
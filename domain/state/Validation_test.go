@@ -0,0 +1,121 @@
+package state_test
+
+import (
+	"flywheel/domain/state"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validation", func() {
+	Describe("Validate", func() {
+		It("should return no finding for valid state machine", func() {
+			sm := state.NewStateMachine(
+				[]state.State{{Name: "PENDING", Category: state.InBacklog, Order: 1}, {Name: "DOING", Category: state.InProcess, Order: 2},
+					{Name: "DONE", Category: state.Done, Order: 3}},
+				[]state.Transition{{Name: "begin", From: "PENDING", To: "DOING"}, {Name: "done", From: "DOING", To: "DONE"}})
+			Expect(sm.Validate()).To(Equal(state.Findings{}))
+		})
+
+		It("should report error when there is no state", func() {
+			findings := state.NewStateMachine(nil, nil).Validate()
+			Expect(findings).To(Equal(state.Findings{
+				{Level: state.FindingLevelError, Code: "state_machine.no_state", Message: "there is no state"},
+			}))
+			Expect(findings.HasError()).To(BeTrue())
+		})
+
+		It("should report errors of states", func() {
+			sm := state.NewStateMachine(
+				[]state.State{{Name: "", Category: state.InBacklog}, {Name: "OPEN", Category: state.InBacklog},
+					{Name: "open", Category: state.InBacklog}, {Name: "CLOSED", Category: 100, WipLimit: -1}},
+				[]state.Transition{{Name: "close", From: "OPEN", To: "CLOSED"}})
+			Expect(sm.Validate()).To(Equal(state.Findings{
+				{Level: state.FindingLevelError, Code: "state.name_empty", Message: "state name is empty"},
+				{Level: state.FindingLevelError, Code: "state.duplicated", Message: "state open is duplicated", State: "open"},
+				{Level: state.FindingLevelError, Code: "state.category_invalid", Message: "category of state CLOSED is invalid", State: "CLOSED"},
+				{Level: state.FindingLevelError, Code: "state.wip_limit_invalid", Message: "wip limit of state CLOSED is negative", State: "CLOSED"},
+				{Level: state.FindingLevelWarning, Code: "state_machine.no_terminal_state", Message: "there is no state of category Done or Rejected"},
+			}))
+		})
+
+		It("should report errors of reasons", func() {
+			sm := state.NewStateMachine(
+				[]state.State{{Name: "OPEN", Category: state.InBacklog, Reasons: state.Reasons{"invalid"}},
+					{Name: "REJECTED", Category: state.Rejected, Reasons: state.Reasons{"duplicated", " "}}},
+				[]state.Transition{{Name: "reject", From: "OPEN", To: "REJECTED"}})
+			Expect(sm.Validate()).To(Equal(state.Findings{
+				{Level: state.FindingLevelError, Code: "state.reasons_not_allowed", Message: "state OPEN has reasons but it is not of category Rejected", State: "OPEN"},
+				{Level: state.FindingLevelError, Code: "state.reason_empty", Message: "reason of state REJECTED is empty", State: "REJECTED"},
+			}))
+		})
+
+		It("should report errors of nested states", func() {
+			sm := state.NewStateMachine(
+				[]state.State{{Name: "OPEN", Category: state.InBacklog}, {Name: "WORKING", Category: state.InProcess},
+					{Name: "CODING", Category: state.InProcess, Parent: "WORKING"}, {Name: "TESTING", Category: state.Done, Parent: "WORKING"},
+					{Name: "A", Category: state.InProcess, Parent: "B"}, {Name: "B", Category: state.InProcess, Parent: "A"},
+					{Name: "LOST", Category: state.Done, Parent: "UNKNOWN"}},
+				[]state.Transition{{Name: "begin", From: "OPEN", To: "WORKING"}, {Name: "test", From: "CODING", To: "TESTING"}})
+			Expect(sm.Validate().Errors()).To(Equal(state.Findings{
+				{Level: state.FindingLevelError, Code: "state.category_mismatch", Message: "category of state TESTING is different from its parent WORKING", State: "TESTING"},
+				{Level: state.FindingLevelError, Code: "state.parent_cycle", Message: "state A is an ancestor of itself", State: "A"},
+				{Level: state.FindingLevelError, Code: "state.parent_cycle", Message: "state B is an ancestor of itself", State: "B"},
+				{Level: state.FindingLevelError, Code: "state.unknown_parent", Message: "parent UNKNOWN of state LOST is unknown", State: "LOST"},
+				{Level: state.FindingLevelError, Code: "transition.to_parent_state", Message: "transition begin is to state WORKING which has children", Transition: "begin", State: "WORKING"},
+			}))
+		})
+
+		It("should report errors of transitions", func() {
+			sm := state.NewStateMachine(
+				[]state.State{{Name: "OPEN", Category: state.InBacklog}, {Name: "CLOSED", Category: state.Done}},
+				[]state.Transition{{Name: "", From: "OPEN", To: "CLOSED"}, {Name: "close", From: "OPEN", To: "CLOSED"},
+					{Name: "reopen", From: "CLOSED", To: "UNKNOWN"}})
+			Expect(sm.Validate()).To(Equal(state.Findings{
+				{Level: state.FindingLevelError, Code: "transition.name_empty", Message: "name of transition from OPEN to CLOSED is empty"},
+				{Level: state.FindingLevelError, Code: "transition.duplicated", Message: "transition from OPEN to CLOSED is duplicated", Transition: "close"},
+				{Level: state.FindingLevelError, Code: "transition.unknown_state", Message: "transition reopen is to unknown state UNKNOWN",
+					Transition: "reopen", State: "UNKNOWN"},
+			}))
+		})
+
+		It("should report warnings of unreachable, dead end states and duplicated transition names", func() {
+			sm := state.NewStateMachine(
+				[]state.State{{Name: "PENDING", Category: state.InBacklog, Order: 1}, {Name: "DOING", Category: state.InProcess, Order: 2},
+					{Name: "DONE", Category: state.Done, Order: 3}, {Name: "REVIEW", Category: state.InProcess, Order: 4}},
+				[]state.Transition{{Name: "go", From: "PENDING", To: "DOING"}, {Name: "go", From: "PENDING", To: "DONE"},
+					{Name: "review", From: "REVIEW", To: "DONE"}})
+			findings := sm.Validate()
+			Expect(findings).To(Equal(state.Findings{
+				{Level: state.FindingLevelWarning, Code: "transition.name_duplicated", Message: "there are more than one transition named go from state PENDING",
+					Transition: "go", State: "PENDING"},
+				{Level: state.FindingLevelWarning, Code: "state.dead_end", Message: "there is no transition out of state DOING", State: "DOING"},
+				{Level: state.FindingLevelWarning, Code: "state.unreachable", Message: "state REVIEW can not be reached", State: "REVIEW"},
+			}))
+			Expect(findings.HasError()).To(BeFalse())
+			Expect(findings.Errors()).To(Equal(state.Findings{}))
+		})
+
+		It("should walk from the first state when there is no backlog state", func() {
+			sm := state.NewStateMachine(
+				[]state.State{{Name: "CLOSED", Category: state.Done, Order: 2}, {Name: "OPEN", Category: state.InProcess, Order: 1}},
+				[]state.Transition{{Name: "close", From: "OPEN", To: "CLOSED"}})
+			Expect(sm.Validate()).To(Equal(state.Findings{}))
+		})
+	})
+
+	Describe("ErrStateMachineInvalid", func() {
+		It("should respond with findings", func() {
+			err := &state.ErrStateMachineInvalid{Findings: state.Findings{
+				{Level: state.FindingLevelError, Code: "state.name_empty", Message: "state name is empty"},
+				{Level: state.FindingLevelError, Code: "state.duplicated", Message: "state a is duplicated", State: "a"},
+			}}
+			Expect(err.Error()).To(Equal("state machine is invalid: state name is empty; state a is duplicated"))
+			detail := err.Respond()
+			Expect(detail.Status).To(Equal(http.StatusBadRequest))
+			Expect(detail.Code).To(Equal("workflow.state_machine_invalid"))
+			Expect(detail.Data).To(Equal(err.Findings))
+		})
+	})
+})
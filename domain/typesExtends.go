@@ -13,12 +13,19 @@ type WorkCreation struct {
 
 	InitialStateName string `json:"initialStateName" binding:"required"`
 	PriorityLevel    int    `json:"priorityLevel"`
+
+	ParentID types.ID `json:"parentId"`
 }
 
 type WorkUpdating struct {
 	Name string `json:"name"`
 }
 
+// WorkParentUpdating moves a work under work ParentID of the same project, the work becomes top level if ParentID is zero
+type WorkParentUpdating struct {
+	ParentID types.ID `json:"parentId"`
+}
+
 type WorkOrderRangeUpdating struct {
 	ID       types.ID `json:"id" binding:"required"`
 	NewOlder int64    `json:"newOrder"`
@@ -35,6 +42,11 @@ type WorkQuery struct {
 	Name            string           `json:"name" form:"name"`
 	ProjectID       types.ID         `json:"projectId" form:"projectId"`
 	StateCategories []state.Category `json:"stateCategories" form:"stateCategory"`
+	ParentID        types.ID         `json:"parentId" form:"parentId"`
+	// AssignedToMe selects the works which the caller is assigned to
+	AssignedToMe bool `json:"assignedToMe" form:"assignedToMe"`
+	// Keyword matches the name and the comments of works
+	Keyword string `json:"keyword" form:"keyword"`
 
 	ArchiveState string `json:"archiveState" form:"archiveState" binding:"omitempty,oneof=ON OFF ALL"`
 }
@@ -55,3 +67,10 @@ type ProjectRole struct {
 	ProjectName       string `json:"projectName"`
 	ProjectIdentifier string `json:"projectIdentifier"`
 }
+
+// WorkflowChanging moves a work to workflow FlowID of the same project, the work in state X is moved to
+// state StateMapping[X], or to state X of the new workflow if X is not in StateMapping
+type WorkflowChanging struct {
+	FlowID       types.ID          `json:"flowId"       binding:"required"`
+	StateMapping map[string]string `json:"stateMapping"`
+}
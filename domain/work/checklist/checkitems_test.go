@@ -29,7 +29,8 @@ func checkitemsTestSetup(t *testing.T, testDatabase **testinfra.TestDatabase) (*
 	*testDatabase = db
 	// migration
 	Expect(db.DS.GormDB(context.Background()).AutoMigrate(&checklist.CheckItem{}, &domain.Project{}, &domain.ProjectMember{}, &domain.Work{}, &domain.WorkProcessStep{},
-		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}).Error).To(BeNil())
+		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &domain.WorkflowVersion{}, &flow.WorkflowPropertyDefinition{},
+		&event.EventRecord{}).Error).To(BeNil())
 
 	persistence.ActiveDataSourceManager = db.DS
 
@@ -200,7 +201,7 @@ func TestListWorkCheckitems(t *testing.T) {
 
 		// be able to list checkitems with system permissions
 		cs2, err = checklist.ListWorkCheckItems(w2.ID, &session.Session{
-			Identity: session.Identity{ID: 10, Name: "index-robot"},
+			Identity: session.IndexRobot,
 			Perms:    authority.Permissions{account.SystemViewPermission.ID}})
 		Expect(err).To(BeNil())
 		Expect(len(cs2)).To(Equal(1))
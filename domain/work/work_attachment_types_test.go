@@ -0,0 +1,41 @@
+package work
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDetectAttachmentMimeType(t *testing.T) {
+	RegisterTestingT(t)
+
+	// the header of compound file, which is the container of .doc and .xls
+	doc := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3E, 0x00, 0x03, 0x00, 0xFE, 0xFF, 0x09, 0x00}, make([]byte, 480)...)
+	// the local file header of zip, which is the container of .docx
+	docx := append([]byte("PK\x03\x04\x14\x00\x06\x00\x08\x00\x00\x00\x21\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"+
+		"\x13\x00\x00\x00[Content_Types].xml"), make([]byte, 64)...)
+
+	t.Run("should detect office documents by content and extension", func(t *testing.T) {
+		Expect(detectAttachmentMimeType("report.doc", doc)).To(Equal("application/msword"))
+		Expect(detectAttachmentMimeType("report.xls", doc)).To(Equal("application/vnd.ms-excel"))
+		Expect(detectAttachmentMimeType("report.docx", docx)).
+			To(Equal("application/vnd.openxmlformats-officedocument.wordprocessingml.document"))
+		Expect(detectAttachmentMimeType("REPORT.DOCX", docx)).
+			To(Equal("application/vnd.openxmlformats-officedocument.wordprocessingml.document"))
+		Expect(detectAttachmentMimeType("report.zip", docx)).To(Equal("application/zip"))
+		Expect(detectAttachmentMimeType("data.json", []byte(`{"a": 1}`))).To(Equal("application/json"))
+	})
+
+	t.Run("should not trust extension which does not match content", func(t *testing.T) {
+		Expect(detectAttachmentMimeType("report.docx", []byte("hello"))).To(Equal("text/plain"))
+		Expect(detectAttachmentMimeType("report.doc", docx)).To(Equal("application/zip"))
+
+		_, err := detectAttachmentMimeType("report.doc", []byte{0x7f, 'E', 'L', 'F', 0x02})
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("type application/octet-stream of attachment is not supported")}))
+		_, err = detectAttachmentMimeType("report", doc)
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("type application/octet-stream of attachment is not supported")}))
+	})
+}
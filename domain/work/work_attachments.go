@@ -0,0 +1,267 @@
+package work
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/client/s3"
+	"flywheel/domain"
+	"flywheel/event"
+	"flywheel/idgen"
+	"flywheel/persistence"
+	"flywheel/session"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/sony/sonyflake"
+)
+
+var (
+	workAttachmentIdWorker = sonyflake.NewSonyflake(sonyflake.Settings{})
+
+	CreateWorkAttachmentFunc   = CreateWorkAttachment
+	DeleteWorkAttachmentFunc   = DeleteWorkAttachment
+	QueryWorkAttachmentsFunc   = QueryWorkAttachments
+	DownloadWorkAttachmentFunc = DownloadWorkAttachment
+	ClearWorkAttachmentsFunc   = clearWorkAttachments
+
+	// AttachmentMaxSize is the max size in bytes of an attachment
+	AttachmentMaxSize int64 = 20 << 20
+	// AttachmentMimeTypes are the accepted MIME types of attachments, the ones end with '/' or '.' are prefixes
+	AttachmentMimeTypes = []string{"image/", "text/", "application/pdf", "application/zip", "application/x-gzip",
+		"application/json", "application/msword", "application/vnd.ms-excel", "application/vnd.openxmlformats-officedocument."}
+
+	// oleSignature is the header of compound files, e.g. .doc and .xls
+	oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+)
+
+func init() {
+	// the builtin table of package mime lacks these types, and the tables of system are not always present
+	for ext, mimeType := range map[string]string{".json": "application/json", ".doc": "application/msword",
+		".xls": "application/vnd.ms-excel", ".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation"} {
+		if mime.TypeByExtension(ext) == "" {
+			_ = mime.AddExtensionType(ext, mimeType)
+		}
+	}
+}
+
+// WorkAttachment is the metadata of a file attached to a work, the content is saved in the object store with key ObjectKey
+type WorkAttachment struct {
+	ID        types.ID `json:"id" gorm:"primary_key"`
+	WorkID    types.ID `json:"workId" gorm:"index"`
+	Name      string   `json:"name" sql:"type:VARCHAR(255) NOT NULL"`
+	Size      int64    `json:"size"`
+	MimeType  string   `json:"mimeType" sql:"type:VARCHAR(128) NOT NULL"`
+	Checksum  string   `json:"checksum" sql:"type:CHAR(64) NOT NULL"`
+	ObjectKey string   `json:"-" sql:"type:VARCHAR(255) NOT NULL"`
+
+	UploaderID   types.ID        `json:"uploaderId"`
+	UploaderName string          `json:"uploaderName"`
+	CreateTime   types.Timestamp `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
+}
+
+// CreateWorkAttachment saves the content read from r in the object store and records the metadata of it,
+// the MIME type is detected from the content rather than trusting the client, see detectAttachmentMimeType
+func CreateWorkAttachment(workID types.ID, name string, r io.Reader, s *session.Session) (*WorkAttachment, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &bizerror.ErrBadParam{Cause: errors.New("name of attachment is empty")}
+	}
+
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	w, err := findWorkAndCheckPerms(db, workID, s)
+	if err != nil {
+		return nil, err
+	}
+	if !w.ArchiveTime.IsZero() {
+		return nil, bizerror.ErrArchiveStatusInvalid
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(r, AttachmentMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > AttachmentMaxSize {
+		return nil, &bizerror.ErrBadParam{Cause: fmt.Errorf("size of attachment exceeds the limit of %d bytes", AttachmentMaxSize)}
+	}
+	mimeType, err := detectAttachmentMimeType(name, content)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(content)
+	id := idgen.NextID(workAttachmentIdWorker)
+	attachment := &WorkAttachment{ID: id, WorkID: w.ID, Name: name, Size: int64(len(content)), MimeType: mimeType,
+		Checksum: hex.EncodeToString(checksum[:]), ObjectKey: "attachments/" + w.ID.String() + "/" + id.String(),
+		UploaderID: s.Identity.ID, UploaderName: s.Identity.Name, CreateTime: types.CurrentTimestamp()}
+	if err := s3.PutObjectFunc(attachment.ObjectKey, bytes.NewReader(content), s); err != nil {
+		return nil, err
+	}
+
+	var ev *event.EventRecord
+	err1 := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(attachment).Error; err != nil {
+			return err
+		}
+		var err error
+		ev, err = event.CreateEvent("WORK", w.ID, w.Identifier, event.EventCategoryExtensionUpdated,
+			[]event.UpdatedProperty{{
+				PropertyName: "Attachment", PropertyDesc: "Attachment",
+				NewValue: attachment.Name, NewValueDesc: attachment.Name,
+			}}, nil, &s.Identity, attachment.CreateTime, tx)
+		return err
+	})
+	if err1 != nil {
+		removeAttachmentObjects([]string{attachment.ObjectKey}, s)
+		return nil, err1
+	}
+
+	if event.InvokeHandlersFunc != nil {
+		event.InvokeHandlersFunc(ev)
+	}
+	return attachment, nil
+}
+
+// DeleteWorkAttachment removes the attachment, the caller must be the uploader or a manager of the project
+func DeleteWorkAttachment(id types.ID, s *session.Session) error {
+	var attachment WorkAttachment
+	var ev *event.EventRecord
+	err1 := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&attachment).Error; err != nil {
+			return err
+		}
+		w, err := findWorkAndCheckPerms(tx, attachment.WorkID, s)
+		if err != nil {
+			return err
+		}
+		if attachment.UploaderID != s.Identity.ID && !s.Perms.HasProjectRole(domain.ProjectRoleManager, w.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+
+		if err := tx.Delete(&WorkAttachment{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		ev, err = event.CreateEvent("WORK", w.ID, w.Identifier, event.EventCategoryExtensionUpdated,
+			[]event.UpdatedProperty{{
+				PropertyName: "Attachment", PropertyDesc: "Attachment",
+				OldValue: attachment.Name, OldValueDesc: attachment.Name,
+			}}, nil, &s.Identity, types.CurrentTimestamp(), tx)
+		return err
+	})
+	if err1 != nil {
+		return err1
+	}
+
+	removeAttachmentObjects([]string{attachment.ObjectKey}, s)
+	if event.InvokeHandlersFunc != nil {
+		event.InvokeHandlersFunc(ev)
+	}
+	return nil
+}
+
+// QueryWorkAttachments returns the attachments of work in the order of being uploaded
+func QueryWorkAttachments(workID types.ID, s *session.Session) ([]WorkAttachment, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	if err := checkWorkViewPerm(db, workID, s); err != nil {
+		return nil, err
+	}
+	attachments := []WorkAttachment{}
+	if err := db.Where("work_id = ?", workID).Order("create_time ASC").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// DownloadWorkAttachment opens the content of attachment, the caller must be able to view the project of the work
+func DownloadWorkAttachment(id types.ID, s *session.Session) (*WorkAttachment, io.ReadCloser, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	attachment := WorkAttachment{}
+	if err := db.Where("id = ?", id).First(&attachment).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := checkWorkViewPerm(db, attachment.WorkID, s); err != nil {
+		return nil, nil, err
+	}
+
+	r, err := s3.GetObjectFunc(attachment.ObjectKey, s)
+	if err != nil {
+		if err == s3.ErrObjectNotFound {
+			return nil, nil, bizerror.ErrNotFound
+		}
+		return nil, nil, err
+	}
+	return &attachment, r, nil
+}
+
+// detectAttachmentMimeType sniffs the type of content, the type by extension of name is taken only if it refines
+// the sniffed one, as the sniffing can not tell office documents and json from their containers
+func detectAttachmentMimeType(name string, content []byte) (string, error) {
+	mimeType, _, err := mime.ParseMediaType(http.DetectContentType(content))
+	if err != nil {
+		return "", err
+	}
+	if declared, _, err := mime.ParseMediaType(mime.TypeByExtension(path.Ext(name))); err == nil &&
+		refinesSniffedType(declared, mimeType, content) {
+		mimeType = declared
+	}
+	for _, accepted := range AttachmentMimeTypes {
+		if mimeType == accepted || (strings.HasSuffix(accepted, "/") || strings.HasSuffix(accepted, ".")) &&
+			strings.HasPrefix(mimeType, accepted) {
+			return mimeType, nil
+		}
+	}
+	return "", &bizerror.ErrBadParam{Cause: errors.New("type " + mimeType + " of attachment is not supported")}
+}
+
+func refinesSniffedType(declared, sniffed string, content []byte) bool {
+	switch {
+	case strings.HasPrefix(declared, "application/vnd.openxmlformats-officedocument."):
+		return sniffed == "application/zip"
+	case declared == "application/msword" || declared == "application/vnd.ms-excel":
+		return bytes.HasPrefix(content, oleSignature)
+	case declared == "application/json":
+		return sniffed == "text/plain"
+	}
+	return false
+}
+
+// clearWorkAttachments deletes the attachment records of work and returns the keys of their objects,
+// the objects should be removed after the transaction is committed
+func clearWorkAttachments(workID types.ID, tx *gorm.DB) ([]string, error) {
+	if workID == types.ID(0) {
+		return nil, nil
+	}
+	var attachments []WorkAttachment
+	if err := tx.Where("work_id = ?", workID).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Delete(&WorkAttachment{}, "work_id = ?", workID).Error; err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(attachments))
+	for _, a := range attachments {
+		keys = append(keys, a.ObjectKey)
+	}
+	return keys, nil
+}
+
+// removeAttachmentObjects removes objects in best effort, an object which fails to be removed is only orphaned
+func removeAttachmentObjects(keys []string, s *session.Session) {
+	for _, key := range keys {
+		if err := s3.DeleteObjectFunc(key, s); err != nil {
+			logrus.Warnf("remove attachment object %s: %v", key, err)
+		}
+	}
+}
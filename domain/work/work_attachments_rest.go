@@ -0,0 +1,99 @@
+package work
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/misc"
+	"flywheel/session"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+var (
+	PathWorkAttachments = "/v1/work-attachments"
+)
+
+type workAttachmentQuery struct {
+	WorkID types.ID `form:"workId" binding:"required"`
+}
+
+func RegisterWorkAttachmentsRestAPI(r *gin.Engine, middleWares ...gin.HandlerFunc) {
+	g := r.Group(PathWorkAttachments, middleWares...)
+	g.GET("", handleQueryWorkAttachments)
+	g.POST("", handleCreateWorkAttachment)
+	g.GET(":id/content", handleDownloadWorkAttachment)
+	g.DELETE(":id", handleDeleteWorkAttachment)
+}
+
+func handleQueryWorkAttachments(c *gin.Context) {
+	query := workAttachmentQuery{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	attachments, err := QueryWorkAttachmentsFunc(query.WorkID, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusOK, &misc.PagedBody{List: attachments, Total: uint64(len(attachments))})
+}
+
+// handleCreateWorkAttachment accepts a multipart form with field 'workId' and file 'file'
+func handleCreateWorkAttachment(c *gin.Context) {
+	query := workAttachmentQuery{}
+	if err := c.ShouldBindWith(&query, binding.FormMultipart); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	if file.Size > AttachmentMaxSize {
+		panic(&bizerror.ErrBadParam{Cause: fmt.Errorf("size of attachment exceeds the limit of %d bytes", AttachmentMaxSize)})
+	}
+	src, err := file.Open()
+	if err != nil {
+		panic(err)
+	}
+	defer src.Close()
+
+	attachment, err := CreateWorkAttachmentFunc(query.WorkID, file.Filename, src, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func handleDownloadWorkAttachment(c *gin.Context) {
+	attachment, r, err := DownloadWorkAttachmentFunc(parseAttachmentID(c), session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	defer r.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.MimeType, r, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}),
+		"ETag":                strconv.Quote(attachment.Checksum),
+	})
+}
+
+func handleDeleteWorkAttachment(c *gin.Context) {
+	err := DeleteWorkAttachmentFunc(parseAttachmentID(c), session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseAttachmentID(c *gin.Context) types.ID {
+	parsedId, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+	}
+	return parsedId
+}
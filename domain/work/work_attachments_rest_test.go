@@ -0,0 +1,135 @@
+package work_test
+
+import (
+	"bytes"
+	"flywheel/bizerror"
+	"flywheel/domain/work"
+	"flywheel/session"
+	"flywheel/testinfra"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkAttachmentsAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	work.RegisterWorkAttachmentsRestAPI(router)
+
+	buildUploadRequest := func(workId string, fileName string, content []byte) *http.Request {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		if workId != "" {
+			Expect(writer.WriteField("workId", workId)).To(BeNil())
+		}
+		if fileName != "" {
+			part, err := writer.CreateFormFile("file", fileName)
+			Expect(err).To(BeNil())
+			_, err = part.Write(content)
+			Expect(err).To(BeNil())
+		}
+		Expect(writer.Close()).To(BeNil())
+		req := httptest.NewRequest(http.MethodPost, work.PathWorkAttachments, body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	t.Run("should be able to validate parameters", func(t *testing.T) {
+		status, _, _ := testinfra.ExecuteRequest(buildUploadRequest("", "a.txt", []byte("hello")), router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+
+		status, _, _ = testinfra.ExecuteRequest(buildUploadRequest("10", "", nil), router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+
+		maxSize := work.AttachmentMaxSize
+		defer func() { work.AttachmentMaxSize = maxSize }()
+		work.AttachmentMaxSize = 4
+		status, body, _ := testinfra.ExecuteRequest(buildUploadRequest("10", "a.txt", []byte("hello")), router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"size of attachment exceeds the limit of 4 bytes","data":null}`))
+
+		req := httptest.NewRequest(http.MethodGet, work.PathWorkAttachments, nil)
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+
+		req = httptest.NewRequest(http.MethodDelete, work.PathWorkAttachments+"/abc", nil)
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+	})
+
+	t.Run("should be able to upload attachment", func(t *testing.T) {
+		var workId types.ID
+		var name, content string
+		work.CreateWorkAttachmentFunc = func(id types.ID, n string, r io.Reader, s *session.Session) (*work.WorkAttachment, error) {
+			b, err := ioutil.ReadAll(r)
+			Expect(err).To(BeNil())
+			workId, name, content = id, n, string(b)
+			return &work.WorkAttachment{ID: 1, WorkID: id, Name: n, Size: int64(len(b)), MimeType: "text/plain",
+				Checksum: "abc", ObjectKey: "attachments/10/1", UploaderID: 100, UploaderName: "bob"}, nil
+		}
+		status, body, _ := testinfra.ExecuteRequest(buildUploadRequest("10", "a.txt", []byte("hello")), router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(workId).To(Equal(types.ID(10)))
+		Expect(name).To(Equal("a.txt"))
+		Expect(content).To(Equal("hello"))
+		Expect(body).To(MatchJSON(`{"id": "1", "workId": "10", "name": "a.txt", "size": 5, "mimeType": "text/plain",
+			"checksum": "abc", "uploaderId": "100", "uploaderName": "bob", "createTime": null}`))
+	})
+
+	t.Run("should be able to query attachments of work", func(t *testing.T) {
+		var workId types.ID
+		work.QueryWorkAttachmentsFunc = func(id types.ID, s *session.Session) ([]work.WorkAttachment, error) {
+			workId = id
+			return []work.WorkAttachment{{ID: 1, WorkID: id, Name: "a.txt", Size: 5, MimeType: "text/plain", Checksum: "abc"}}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, work.PathWorkAttachments+"?workId=10", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(workId).To(Equal(types.ID(10)))
+		Expect(body).To(MatchJSON(`{"total": 1, "data": [{"id": "1", "workId": "10", "name": "a.txt", "size": 5, "mimeType": "text/plain",
+			"checksum": "abc", "uploaderId": "0", "uploaderName": "", "createTime": null}]}`))
+	})
+
+	t.Run("should be able to download attachment", func(t *testing.T) {
+		work.DownloadWorkAttachmentFunc = func(id types.ID, s *session.Session) (*work.WorkAttachment, io.ReadCloser, error) {
+			return &work.WorkAttachment{ID: id, WorkID: 10, Name: "a b.txt", Size: 5, MimeType: "text/plain", Checksum: "abc"},
+				ioutil.NopCloser(strings.NewReader("hello")), nil
+		}
+		req := httptest.NewRequest(http.MethodGet, work.PathWorkAttachments+"/1/content", nil)
+		status, body, resp := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("hello"))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/plain"))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(`attachment; filename="a b.txt"`))
+		Expect(resp.Header.Get("ETag")).To(Equal(`"abc"`))
+
+		work.DownloadWorkAttachmentFunc = func(id types.ID, s *session.Session) (*work.WorkAttachment, io.ReadCloser, error) {
+			return nil, nil, bizerror.ErrForbidden
+		}
+		status, _, _ = testinfra.ExecuteRequest(httptest.NewRequest(http.MethodGet, work.PathWorkAttachments+"/1/content", nil), router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+
+	t.Run("should be able to delete attachment", func(t *testing.T) {
+		var attachmentId types.ID
+		work.DeleteWorkAttachmentFunc = func(id types.ID, s *session.Session) error {
+			attachmentId = id
+			return nil
+		}
+		req := httptest.NewRequest(http.MethodDelete, work.PathWorkAttachments+"/1", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusNoContent))
+		Expect(attachmentId).To(Equal(types.ID(1)))
+	})
+}
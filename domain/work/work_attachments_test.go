@@ -0,0 +1,86 @@
+package work_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/client/s3"
+	"flywheel/domain"
+	"flywheel/domain/work"
+	"flywheel/event"
+	"flywheel/testinfra"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkAttachments(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should upload, download and delete attachments", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, persistedEvents, _ := workProgressTestSetup(t, &testDatabase)
+
+		root, err := ioutil.TempDir("", "work_attachments_test")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(root)
+		s3.ActiveStore = &s3.LocalStore{Root: root}
+		s3.GetObjectFunc, s3.PutObjectFunc, s3.DeleteObjectFunc = s3.GetObject, s3.PutObject, s3.DeleteObject
+
+		uploader := testinfra.BuildSecCtx(types.ID(200), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		other := testinfra.BuildSecCtx(types.ID(201), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		outsider := testinfra.BuildSecCtx(types.ID(202), domain.ProjectRoleCommon+"_2000")
+		w := buildWork("work", workflow.ID, project1.ID, uploader)
+
+		_, err = work.CreateWorkAttachment(w.ID, "a.txt", bytes.NewReader([]byte("hello")), outsider)
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+		_, err = work.CreateWorkAttachment(w.ID, "a.bin", bytes.NewReader([]byte{0x7f, 'E', 'L', 'F', 0x02}), uploader)
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("type application/octet-stream of attachment is not supported")}))
+
+		attachment, err := work.CreateWorkAttachment(w.ID, "a.txt", bytes.NewReader([]byte("hello")), uploader)
+		Expect(err).To(BeNil())
+		Expect(attachment.Size).To(Equal(int64(5)))
+		Expect(attachment.MimeType).To(Equal("text/plain"))
+		Expect(attachment.Checksum).To(Equal("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+		Expect(attachment.UploaderID).To(Equal(types.ID(200)))
+		last := (*persistedEvents)[len(*persistedEvents)-1]
+		Expect(last.EventCategory).To(Equal(event.EventCategoryExtensionUpdated))
+		Expect(last.UpdatedProperties).To(Equal(event.UpdatedProperties{{PropertyName: "Attachment", PropertyDesc: "Attachment",
+			NewValue: "a.txt", NewValueDesc: "a.txt"}}))
+
+		attachments, err := work.QueryWorkAttachments(w.ID, other)
+		Expect(err).To(BeNil())
+		Expect(len(attachments)).To(Equal(1))
+		Expect(attachments[0].ObjectKey).To(Equal(attachment.ObjectKey))
+
+		_, _, err = work.DownloadWorkAttachment(attachment.ID, outsider)
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+		detail, r, err := work.DownloadWorkAttachment(attachment.ID, other)
+		Expect(err).To(BeNil())
+		content, err := ioutil.ReadAll(r)
+		Expect(r.Close()).To(BeNil())
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal("hello"))
+		Expect(detail.Name).To(Equal("a.txt"))
+
+		Expect(work.DeleteWorkAttachment(attachment.ID, other)).To(Equal(bizerror.ErrForbidden))
+		Expect(work.DeleteWorkAttachment(attachment.ID, uploader)).To(BeNil())
+		_, err = s3.ActiveStore.GetObject(attachment.ObjectKey)
+		Expect(err).To(Equal(s3.ErrObjectNotFound))
+
+		attachment, err = work.CreateWorkAttachment(w.ID, "b.txt", bytes.NewReader([]byte("world")), uploader)
+		Expect(err).To(BeNil())
+		Expect(work.DeleteWork(w.ID, uploader)).To(BeNil())
+		_, err = s3.ActiveStore.GetObject(attachment.ObjectKey)
+		Expect(err).To(Equal(s3.ErrObjectNotFound))
+		count := 0
+		Expect(testDatabase.DS.GormDB(context.Background()).Model(&work.WorkAttachment{}).
+			Where("work_id = ?", w.ID).Count(&count).Error).To(BeNil())
+		Expect(count).To(BeZero())
+	})
+}
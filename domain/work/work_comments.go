@@ -0,0 +1,356 @@
+package work
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"flywheel/account"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/event"
+	"flywheel/idgen"
+	"flywheel/persistence"
+	"flywheel/session"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+	"github.com/sony/sonyflake"
+)
+
+var (
+	workCommentIdWorker         = sonyflake.NewSonyflake(sonyflake.Settings{})
+	workCommentRevisionIdWorker = sonyflake.NewSonyflake(sonyflake.Settings{})
+
+	CreateWorkCommentFunc         = CreateWorkComment
+	UpdateWorkCommentFunc         = UpdateWorkComment
+	DeleteWorkCommentFunc         = DeleteWorkComment
+	QueryWorkCommentsFunc         = QueryWorkComments
+	QueryWorkCommentRevisionsFunc = QueryWorkCommentRevisions
+	QueryCommentTextsOfWorksFunc  = QueryCommentTextsOfWorks
+	ClearWorkCommentsFunc         = clearWorkComments
+
+	// CommentSummaryMaxLength is the max number of characters of comment content recorded in events
+	CommentSummaryMaxLength = 200
+
+	// mentionPattern matches '@name' which is at the beginning or preceded by a non-word character, e.g. not in 'a@b.com'
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.\-]+)`)
+)
+
+// WorkComment is a markdown comment on a work, Mentions are the users mentioned in the content by '@name'
+type WorkComment struct {
+	ID       types.ID        `json:"id" gorm:"primary_key"`
+	WorkID   types.ID        `json:"workId" gorm:"index"`
+	Content  string          `json:"content" sql:"type:TEXT NOT NULL"`
+	Mentions CommentMentions `json:"mentions" sql:"type:TEXT"`
+
+	CreatorID   types.ID        `json:"creatorId"`
+	CreatorName string          `json:"creatorName"`
+	CreateTime  types.Timestamp `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
+	UpdateTime  types.Timestamp `json:"updateTime" sql:"type:DATETIME(6)"`
+}
+
+// WorkCommentRevision keeps the content of a comment before it was edited
+type WorkCommentRevision struct {
+	ID        types.ID `json:"id" gorm:"primary_key"`
+	CommentID types.ID `json:"commentId" gorm:"index"`
+	Content   string   `json:"content" sql:"type:TEXT NOT NULL"`
+
+	EditorID   types.ID        `json:"editorId"`
+	EditorName string          `json:"editorName"`
+	CreateTime types.Timestamp `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
+}
+
+type CommentMentions []WorkParticipant
+
+func (m CommentMentions) Value() (driver.Value, error) {
+	jsonBytes, err := json.Marshal(&m)
+	if err != nil {
+		return nil, err
+	}
+	return string(jsonBytes), nil
+}
+
+func (m *CommentMentions) Scan(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	jsonString, ok := v.(string)
+	if !ok {
+		jsonByte, ok := v.([]byte)
+		if !ok {
+			return fmt.Errorf("type is neither string nor []byte: %T %v", v, v)
+		}
+		jsonString = string(jsonByte)
+	}
+	if jsonString == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(jsonString), m)
+}
+
+type WorkCommentCreation struct {
+	WorkID  types.ID `json:"workId" binding:"required"`
+	Content string   `json:"content" binding:"required,max=10000"`
+}
+
+type WorkCommentUpdating struct {
+	Content string `json:"content" binding:"required,max=10000"`
+}
+
+func CreateWorkComment(c *WorkCommentCreation, s *session.Session) (*WorkComment, error) {
+	var comment *WorkComment
+	var ev *event.EventRecord
+	err1 := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		w, err := findWorkAndCheckPerms(tx, c.WorkID, s)
+		if err != nil {
+			return err
+		}
+		if !w.ArchiveTime.IsZero() {
+			return bizerror.ErrArchiveStatusInvalid
+		}
+		mentions, err := parseMentions(tx, c.Content)
+		if err != nil {
+			return err
+		}
+
+		now := types.CurrentTimestamp()
+		comment = &WorkComment{ID: idgen.NextID(workCommentIdWorker), WorkID: w.ID, Content: c.Content, Mentions: mentions,
+			CreatorID: s.Identity.ID, CreatorName: s.Identity.Name, CreateTime: now, UpdateTime: now}
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+
+		ev, err = event.CreateEvent("WORK", w.ID, w.Identifier, event.EventCategoryExtensionUpdated,
+			[]event.UpdatedProperty{{
+				PropertyName: "Comment", PropertyDesc: "Comment",
+				NewValue: commentSummary(comment.Content), NewValueDesc: commentSummary(comment.Content),
+			}}, nil, &s.Identity, now, tx)
+		return err
+	})
+	if err1 != nil {
+		return nil, err1
+	}
+
+	if event.InvokeHandlersFunc != nil {
+		event.InvokeHandlersFunc(ev)
+	}
+	return comment, nil
+}
+
+// UpdateWorkComment edits the content of comment, only the author can edit it and the old content is kept as a revision
+func UpdateWorkComment(id types.ID, u *WorkCommentUpdating, s *session.Session) (*WorkComment, error) {
+	var comment WorkComment
+	var ev *event.EventRecord
+	err1 := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&comment).Error; err != nil {
+			return err
+		}
+		w, err := findWorkAndCheckPerms(tx, comment.WorkID, s)
+		if err != nil {
+			return err
+		}
+		if comment.CreatorID != s.Identity.ID {
+			return bizerror.ErrForbidden
+		}
+		if !w.ArchiveTime.IsZero() {
+			return bizerror.ErrArchiveStatusInvalid
+		}
+		if comment.Content == u.Content {
+			return nil
+		}
+		mentions, err := parseMentions(tx, u.Content)
+		if err != nil {
+			return err
+		}
+
+		now := types.CurrentTimestamp()
+		revision := WorkCommentRevision{ID: idgen.NextID(workCommentRevisionIdWorker), CommentID: comment.ID, Content: comment.Content,
+			EditorID: s.Identity.ID, EditorName: s.Identity.Name, CreateTime: now}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&WorkComment{}).Where("id = ?", comment.ID).
+			Updates(map[string]interface{}{"content": u.Content, "mentions": mentions, "update_time": now}).Error; err != nil {
+			return err
+		}
+
+		ev, err = event.CreateEvent("WORK", w.ID, w.Identifier, event.EventCategoryExtensionUpdated,
+			[]event.UpdatedProperty{{
+				PropertyName: "Comment", PropertyDesc: "Comment",
+				OldValue: commentSummary(comment.Content), OldValueDesc: commentSummary(comment.Content),
+				NewValue: commentSummary(u.Content), NewValueDesc: commentSummary(u.Content),
+			}}, nil, &s.Identity, now, tx)
+		comment.Content, comment.Mentions, comment.UpdateTime = u.Content, mentions, now
+		return err
+	})
+	if err1 != nil {
+		return nil, err1
+	}
+
+	if ev != nil && event.InvokeHandlersFunc != nil {
+		event.InvokeHandlersFunc(ev)
+	}
+	return &comment, nil
+}
+
+// DeleteWorkComment removes the comment and its revisions, the caller must be the author or a manager of the project
+func DeleteWorkComment(id types.ID, s *session.Session) error {
+	var ev *event.EventRecord
+	err1 := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		comment := WorkComment{}
+		if err := tx.Where("id = ?", id).First(&comment).Error; err != nil {
+			return err
+		}
+		w, err := findWorkAndCheckPerms(tx, comment.WorkID, s)
+		if err != nil {
+			return err
+		}
+		if comment.CreatorID != s.Identity.ID && !s.Perms.HasProjectRole(domain.ProjectRoleManager, w.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+		if !w.ArchiveTime.IsZero() {
+			return bizerror.ErrArchiveStatusInvalid
+		}
+
+		if err := tx.Delete(&WorkComment{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&WorkCommentRevision{}, "comment_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		ev, err = event.CreateEvent("WORK", w.ID, w.Identifier, event.EventCategoryExtensionUpdated,
+			[]event.UpdatedProperty{{
+				PropertyName: "Comment", PropertyDesc: "Comment",
+				OldValue: commentSummary(comment.Content), OldValueDesc: commentSummary(comment.Content),
+			}}, nil, &s.Identity, types.CurrentTimestamp(), tx)
+		return err
+	})
+	if err1 != nil {
+		return err1
+	}
+
+	if event.InvokeHandlersFunc != nil {
+		event.InvokeHandlersFunc(ev)
+	}
+	return nil
+}
+
+// QueryWorkComments returns the comments of work in the order of being created
+func QueryWorkComments(workID types.ID, s *session.Session) ([]WorkComment, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	if err := checkWorkViewPerm(db, workID, s); err != nil {
+		return nil, err
+	}
+	comments := []WorkComment{}
+	if err := db.Where("work_id = ?", workID).Order("create_time ASC").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// QueryWorkCommentRevisions returns the edit history of comment, the latest revision is the first
+func QueryWorkCommentRevisions(commentID types.ID, s *session.Session) ([]WorkCommentRevision, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	comment := WorkComment{}
+	if err := db.Where("id = ?", commentID).First(&comment).Error; err != nil {
+		return nil, err
+	}
+	if err := checkWorkViewPerm(db, comment.WorkID, s); err != nil {
+		return nil, err
+	}
+	revisions := []WorkCommentRevision{}
+	if err := db.Where("comment_id = ?", commentID).Order("create_time DESC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// QueryCommentTextsOfWorks returns the content of comments grouped by work, it is used to build the indexed documents of works
+func QueryCommentTextsOfWorks(workIds []types.ID, s *session.Session) (map[types.ID][]string, error) {
+	texts := map[types.ID][]string{}
+	if len(workIds) == 0 {
+		return texts, nil
+	}
+	var comments []WorkComment
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	if err := db.Select("work_id, content").Where("work_id IN (?)", workIds).Order("create_time ASC").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		texts[c.WorkID] = append(texts[c.WorkID], c.Content)
+	}
+	return texts, nil
+}
+
+func checkWorkViewPerm(db *gorm.DB, workID types.ID, s *session.Session) error {
+	w := domain.Work{}
+	if err := db.Where("id = ?", workID).First(&w).Error; err != nil {
+		return err
+	}
+	if !s.Perms.HasProjectViewPerm(w.ProjectID) {
+		return bizerror.ErrForbidden
+	}
+	return nil
+}
+
+// parseMentions resolves '@name' in content to users, the names which match no user are ignored
+func parseMentions(tx *gorm.DB, content string) (CommentMentions, error) {
+	mentions := CommentMentions{}
+	names := mentionedNames(content)
+	if len(names) == 0 {
+		return mentions, nil
+	}
+
+	var users []account.User
+	if err := tx.Where("name IN (?)", names).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	userMap := map[string]account.User{}
+	for _, u := range users {
+		userMap[u.Name] = u
+	}
+	for _, name := range names {
+		if u, found := userMap[name]; found {
+			mentions = append(mentions, WorkParticipant{ID: u.ID, Name: u.Name, Nickname: u.Nickname})
+		}
+	}
+	return mentions, nil
+}
+
+// commentSummary is the leading CommentSummaryMaxLength characters of content, which is recorded in events
+func commentSummary(content string) string {
+	runes := []rune(content)
+	if len(runes) <= CommentSummaryMaxLength {
+		return content
+	}
+	return string(runes[:CommentSummaryMaxLength]) + "..."
+}
+
+// mentionedNames returns the distinct names mentioned in content, the trailing '.' and '-' are the punctuation
+// of sentence rather than a part of name, e.g. 'thanks @alice.'
+func mentionedNames(content string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimRight(m[1], ".-")
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func clearWorkComments(workID types.ID, tx *gorm.DB) error {
+	if workID == types.ID(0) {
+		return nil
+	}
+	if err := tx.Where("comment_id IN (?)", tx.Model(&WorkComment{}).Select("id").Where("work_id = ?", workID).SubQuery()).
+		Delete(&WorkCommentRevision{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&WorkComment{}, "work_id = ?", workID).Error
+}
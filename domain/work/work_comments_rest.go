@@ -0,0 +1,93 @@
+package work
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/misc"
+	"flywheel/session"
+	"net/http"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+var (
+	PathWorkComments = "/v1/work-comments"
+)
+
+type workCommentQuery struct {
+	WorkID types.ID `form:"workId" binding:"required"`
+}
+
+func RegisterWorkCommentsRestAPI(r *gin.Engine, middleWares ...gin.HandlerFunc) {
+	g := r.Group(PathWorkComments, middleWares...)
+	g.GET("", handleQueryWorkComments)
+	g.POST("", handleCreateWorkComment)
+	g.PUT(":id", handleUpdateWorkComment)
+	g.DELETE(":id", handleDeleteWorkComment)
+	g.GET(":id/revisions", handleQueryWorkCommentRevisions)
+}
+
+func handleQueryWorkComments(c *gin.Context) {
+	query := workCommentQuery{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	comments, err := QueryWorkCommentsFunc(query.WorkID, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusOK, &misc.PagedBody{List: comments, Total: uint64(len(comments))})
+}
+
+func handleCreateWorkComment(c *gin.Context) {
+	req := WorkCommentCreation{}
+	err := c.ShouldBindBodyWith(&req, binding.JSON)
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	comment, err := CreateWorkCommentFunc(&req, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusCreated, comment)
+}
+
+func handleUpdateWorkComment(c *gin.Context) {
+	parsedId := parseCommentID(c)
+	req := WorkCommentUpdating{}
+	err := c.ShouldBindBodyWith(&req, binding.JSON)
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	comment, err := UpdateWorkCommentFunc(parsedId, &req, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusOK, comment)
+}
+
+func handleDeleteWorkComment(c *gin.Context) {
+	err := DeleteWorkCommentFunc(parseCommentID(c), session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func handleQueryWorkCommentRevisions(c *gin.Context) {
+	revisions, err := QueryWorkCommentRevisionsFunc(parseCommentID(c), session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusOK, &misc.PagedBody{List: revisions, Total: uint64(len(revisions))})
+}
+
+func parseCommentID(c *gin.Context) types.ID {
+	parsedId, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+	}
+	return parsedId
+}
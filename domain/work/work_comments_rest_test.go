@@ -0,0 +1,133 @@
+package work_test
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain/work"
+	"flywheel/session"
+	"flywheel/testinfra"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkCommentsAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	work.RegisterWorkCommentsRestAPI(router)
+
+	t.Run("should be able to validate parameters", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, work.PathWorkComments, strings.NewReader(`{"workId": "10"}`))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message": "Key: 'WorkCommentCreation.Content' Error:Field validation for 'Content' failed on the 'required' tag", "data":null}`))
+
+		req = httptest.NewRequest(http.MethodGet, work.PathWorkComments, nil)
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+
+		req = httptest.NewRequest(http.MethodPut, work.PathWorkComments+"/abc", strings.NewReader(`{"content": "updated"}`))
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+
+		req = httptest.NewRequest(http.MethodPut, work.PathWorkComments+"/1", strings.NewReader(`{}`))
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+
+		tooLong := strings.Repeat("a", 10001)
+		req = httptest.NewRequest(http.MethodPost, work.PathWorkComments, strings.NewReader(`{"workId": "10", "content": "`+tooLong+`"}`))
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message": "Key: 'WorkCommentCreation.Content' Error:Field validation for 'Content' failed on the 'max' tag", "data":null}`))
+
+		req = httptest.NewRequest(http.MethodPut, work.PathWorkComments+"/1", strings.NewReader(`{"content": "`+tooLong+`"}`))
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+
+	t.Run("should be able to create comment", func(t *testing.T) {
+		var creation *work.WorkCommentCreation
+		work.CreateWorkCommentFunc = func(c *work.WorkCommentCreation, s *session.Session) (*work.WorkComment, error) {
+			creation = c
+			return &work.WorkComment{ID: 1, WorkID: c.WorkID, Content: c.Content,
+				Mentions: work.CommentMentions{{ID: 200, Name: "alice"}}, CreatorID: 100, CreatorName: "bob"}, nil
+		}
+		req := httptest.NewRequest(http.MethodPost, work.PathWorkComments, strings.NewReader(`{"workId": "10", "content": "hi @alice"}`))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(*creation).To(Equal(work.WorkCommentCreation{WorkID: 10, Content: "hi @alice"}))
+		Expect(body).To(MatchJSON(`{"id": "1", "workId": "10", "content": "hi @alice",
+			"mentions": [{"id": "200", "name": "alice", "nickname": ""}],
+			"creatorId": "100", "creatorName": "bob", "createTime": null, "updateTime": null}`))
+	})
+
+	t.Run("should be able to update comment", func(t *testing.T) {
+		var commentId types.ID
+		var updating *work.WorkCommentUpdating
+		work.UpdateWorkCommentFunc = func(id types.ID, u *work.WorkCommentUpdating, s *session.Session) (*work.WorkComment, error) {
+			commentId, updating = id, u
+			return &work.WorkComment{ID: id, WorkID: 10, Content: u.Content, Mentions: work.CommentMentions{}}, nil
+		}
+		req := httptest.NewRequest(http.MethodPut, work.PathWorkComments+"/1", strings.NewReader(`{"content": "updated"}`))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(commentId).To(Equal(types.ID(1)))
+		Expect(*updating).To(Equal(work.WorkCommentUpdating{Content: "updated"}))
+		Expect(body).To(MatchJSON(`{"id": "1", "workId": "10", "content": "updated", "mentions": [],
+			"creatorId": "0", "creatorName": "", "createTime": null, "updateTime": null}`))
+
+		work.UpdateWorkCommentFunc = func(id types.ID, u *work.WorkCommentUpdating, s *session.Session) (*work.WorkComment, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req = httptest.NewRequest(http.MethodPut, work.PathWorkComments+"/1", strings.NewReader(`{"content": "updated"}`))
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+
+	t.Run("should be able to query comments and revisions", func(t *testing.T) {
+		var workId types.ID
+		work.QueryWorkCommentsFunc = func(id types.ID, s *session.Session) ([]work.WorkComment, error) {
+			workId = id
+			return []work.WorkComment{{ID: 1, WorkID: id, Content: "hi", Mentions: work.CommentMentions{}}}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, work.PathWorkComments+"?workId=10", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(workId).To(Equal(types.ID(10)))
+		Expect(body).To(MatchJSON(`{"total": 1, "data": [{"id": "1", "workId": "10", "content": "hi", "mentions": [],
+			"creatorId": "0", "creatorName": "", "createTime": null, "updateTime": null}]}`))
+
+		var commentId types.ID
+		work.QueryWorkCommentRevisionsFunc = func(id types.ID, s *session.Session) ([]work.WorkCommentRevision, error) {
+			commentId = id
+			return []work.WorkCommentRevision{{ID: 2, CommentID: id, Content: "hello", EditorID: 100, EditorName: "bob"}}, nil
+		}
+		req = httptest.NewRequest(http.MethodGet, work.PathWorkComments+"/1/revisions", nil)
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(commentId).To(Equal(types.ID(1)))
+		Expect(body).To(MatchJSON(`{"total": 1, "data": [{"id": "2", "commentId": "1", "content": "hello",
+			"editorId": "100", "editorName": "bob", "createTime": null}]}`))
+	})
+
+	t.Run("should be able to delete comment", func(t *testing.T) {
+		var commentId types.ID
+		work.DeleteWorkCommentFunc = func(id types.ID, s *session.Session) error {
+			commentId = id
+			return nil
+		}
+		req := httptest.NewRequest(http.MethodDelete, work.PathWorkComments+"/1", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusNoContent))
+		Expect(commentId).To(Equal(types.ID(1)))
+	})
+}
@@ -0,0 +1,143 @@
+package work_test
+
+import (
+	"context"
+	"flywheel/account"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/work"
+	"flywheel/event"
+	"flywheel/testinfra"
+	"strings"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkComments(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should create, edit, query and delete comments", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, persistedEvents, _ := workProgressTestSetup(t, &testDatabase)
+
+		db := testDatabase.DS.GormDB(context.Background())
+		Expect(db.Save(&account.User{ID: 200, Name: "alice"}).Error).To(BeNil())
+		Expect(db.Save(&account.User{ID: 201, Name: "bob", Nickname: "Bob"}).Error).To(BeNil())
+
+		author := testinfra.BuildSecCtx(types.ID(200), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		other := testinfra.BuildSecCtx(types.ID(201), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		manager := testinfra.BuildSecCtx(types.ID(202), domain.ProjectRoleManager+"_"+project1.ID.String())
+		w := buildWork("work", workflow.ID, project1.ID, author)
+
+		_, err := work.CreateWorkComment(&work.WorkCommentCreation{WorkID: w.ID, Content: "hi"},
+			testinfra.BuildSecCtx(types.ID(203), domain.ProjectRoleCommon+"_2000"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		comment, err := work.CreateWorkComment(&work.WorkCommentCreation{WorkID: w.ID,
+			Content: "@bob please review, cc @nobody and mail@alice.com, thanks @bob"}, author)
+		Expect(err).To(BeNil())
+		Expect(comment.Mentions).To(Equal(work.CommentMentions{{ID: 201, Name: "bob", Nickname: "Bob"}}))
+		last := (*persistedEvents)[len(*persistedEvents)-1]
+		Expect(last.EventCategory).To(Equal(event.EventCategoryExtensionUpdated))
+		Expect(last.UpdatedProperties).To(Equal(event.UpdatedProperties{{PropertyName: "Comment", PropertyDesc: "Comment",
+			NewValue: comment.Content, NewValueDesc: comment.Content}}))
+
+		_, err = work.UpdateWorkComment(comment.ID, &work.WorkCommentUpdating{Content: "changed"}, other)
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+		updated, err := work.UpdateWorkComment(comment.ID, &work.WorkCommentUpdating{Content: "@alice done"}, author)
+		Expect(err).To(BeNil())
+		Expect(updated.Content).To(Equal("@alice done"))
+		Expect(updated.Mentions).To(Equal(work.CommentMentions{{ID: 200, Name: "alice"}}))
+
+		comments, err := work.QueryWorkComments(w.ID, other)
+		Expect(err).To(BeNil())
+		Expect(len(comments)).To(Equal(1))
+		Expect(comments[0].Content).To(Equal("@alice done"))
+		Expect(comments[0].Mentions).To(Equal(work.CommentMentions{{ID: 200, Name: "alice"}}))
+
+		revisions, err := work.QueryWorkCommentRevisions(comment.ID, other)
+		Expect(err).To(BeNil())
+		Expect(len(revisions)).To(Equal(1))
+		Expect(revisions[0].Content).To(Equal("@bob please review, cc @nobody and mail@alice.com, thanks @bob"))
+		Expect(revisions[0].EditorID).To(Equal(types.ID(200)))
+
+		texts, err := work.QueryCommentTextsOfWorks([]types.ID{w.ID}, author)
+		Expect(err).To(BeNil())
+		Expect(texts).To(Equal(map[types.ID][]string{w.ID: {"@alice done"}}))
+
+		Expect(work.DeleteWorkComment(comment.ID, other)).To(Equal(bizerror.ErrForbidden))
+		Expect(work.DeleteWorkComment(comment.ID, manager)).To(BeNil())
+		comments, err = work.QueryWorkComments(w.ID, other)
+		Expect(err).To(BeNil())
+		Expect(comments).To(BeEmpty())
+
+		_, err = work.CreateWorkComment(&work.WorkCommentCreation{WorkID: w.ID, Content: "hi"}, author)
+		Expect(err).To(BeNil())
+		Expect(work.DeleteWork(w.ID, author)).To(BeNil())
+		texts, err = work.QueryCommentTextsOfWorks([]types.ID{w.ID}, author)
+		Expect(err).To(BeNil())
+		Expect(texts).To(BeEmpty())
+	})
+
+	t.Run("should not edit or delete comments of archived work", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		author := testinfra.BuildSecCtx(types.ID(200), domain.ProjectRoleManager+"_"+project1.ID.String())
+		w := buildWork("work", workflow.ID, project1.ID, author)
+		comment, err := work.CreateWorkComment(&work.WorkCommentCreation{WorkID: w.ID, Content: "hi"}, author)
+		Expect(err).To(BeNil())
+
+		db := testDatabase.DS.GormDB(context.Background())
+		Expect(db.Model(&domain.Work{}).Where("id = ?", w.ID).Update("archive_time", types.CurrentTimestamp()).Error).To(BeNil())
+
+		_, err = work.UpdateWorkComment(comment.ID, &work.WorkCommentUpdating{Content: "changed"}, author)
+		Expect(err).To(Equal(bizerror.ErrArchiveStatusInvalid))
+		Expect(work.DeleteWorkComment(comment.ID, author)).To(Equal(bizerror.ErrArchiveStatusInvalid))
+
+		comments, err := work.QueryWorkComments(w.ID, author)
+		Expect(err).To(BeNil())
+		Expect(len(comments)).To(Equal(1))
+		Expect(comments[0].Content).To(Equal("hi"))
+	})
+
+	t.Run("should not take trailing punctuation as part of mentioned name", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		db := testDatabase.DS.GormDB(context.Background())
+		Expect(db.Save(&account.User{ID: 200, Name: "alice"}).Error).To(BeNil())
+		Expect(db.Save(&account.User{ID: 201, Name: "bob.li", Nickname: "Bob"}).Error).To(BeNil())
+
+		author := testinfra.BuildSecCtx(types.ID(200), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		w := buildWork("work", workflow.ID, project1.ID, author)
+
+		comment, err := work.CreateWorkComment(&work.WorkCommentCreation{WorkID: w.ID, Content: "thanks @alice."}, author)
+		Expect(err).To(BeNil())
+		Expect(comment.Mentions).To(Equal(work.CommentMentions{{ID: 200, Name: "alice"}}))
+
+		comment, err = work.CreateWorkComment(&work.WorkCommentCreation{WorkID: w.ID, Content: "@bob.li -- @alice--"}, author)
+		Expect(err).To(BeNil())
+		Expect(comment.Mentions).To(Equal(work.CommentMentions{{ID: 201, Name: "bob.li", Nickname: "Bob"}, {ID: 200, Name: "alice"}}))
+	})
+
+	t.Run("should record summary of long comment in events", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, persistedEvents, _ := workProgressTestSetup(t, &testDatabase)
+
+		author := testinfra.BuildSecCtx(types.ID(200), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		w := buildWork("work", workflow.ID, project1.ID, author)
+
+		content := strings.Repeat("中", work.CommentSummaryMaxLength+1)
+		comment, err := work.CreateWorkComment(&work.WorkCommentCreation{WorkID: w.ID, Content: content}, author)
+		Expect(err).To(BeNil())
+		Expect(comment.Content).To(Equal(content))
+		summary := strings.Repeat("中", work.CommentSummaryMaxLength) + "..."
+		last := (*persistedEvents)[len(*persistedEvents)-1]
+		Expect(last.UpdatedProperties).To(Equal(event.UpdatedProperties{{PropertyName: "Comment", PropertyDesc: "Comment",
+			NewValue: summary, NewValueDesc: summary}}))
+	})
+}
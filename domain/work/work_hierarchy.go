@@ -0,0 +1,257 @@
+package work
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/domain/work/checklist"
+	"flywheel/event"
+	"flywheel/persistence"
+	"flywheel/session"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	UpdateWorkParentFunc   = UpdateWorkParent
+	QueryWorkChildrenFunc  = QueryWorkChildren
+	QueryWorkAncestorsFunc = QueryWorkAncestors
+
+	InnerAppendRollupsFunc = InnerAppendRollups
+)
+
+// WorkRollup summarizes the progress of the children of a work
+type WorkRollup struct {
+	Children        int                    `json:"children"`
+	StateCategories map[state.Category]int `json:"stateCategories"`
+
+	CheckItems     int `json:"checkItems"`
+	DoneCheckItems int `json:"doneCheckItems"`
+}
+
+// UpdateWorkParent moves the work under another work of the same project, or to the top level if u.ParentID is zero
+func UpdateWorkParent(id types.ID, u *domain.WorkParentUpdating, s *session.Session) error {
+	var ev *event.EventRecord
+	err1 := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		w, err := findWorkAndCheckPerms(tx, id, s)
+		if err != nil {
+			return err
+		}
+		if !w.ArchiveTime.IsZero() {
+			return bizerror.ErrArchiveStatusInvalid
+		}
+		if w.ParentID == u.ParentID {
+			return nil
+		}
+
+		newParent, err := checkWorkParent(tx, w, u.ParentID)
+		if err != nil {
+			return err
+		}
+		var oldParent *domain.Work
+		if w.ParentID != 0 {
+			oldParent = &domain.Work{}
+			if err := tx.Where("id = ?", w.ParentID).First(oldParent).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&domain.Work{}).Where("id = ?", id).Update("parent_id", u.ParentID).Error; err != nil {
+			return err
+		}
+
+		ev, err = CreateWorkRelationUpdatedEvent(w, []event.UpdatedRelation{parentUpdatedRelation(oldParent, newParent)},
+			&s.Identity, types.CurrentTimestamp(), tx)
+		return err
+	})
+	if err1 != nil {
+		return err1
+	}
+
+	if event.InvokeHandlersFunc != nil {
+		event.InvokeHandlersFunc(ev)
+	}
+	return nil
+}
+
+// checkWorkParent loads the work parentID which w is going to be moved under,
+// the parent must be in the same project of w and must not be w itself or any descendant of w
+func checkWorkParent(tx *gorm.DB, w *domain.Work, parentID types.ID) (*domain.Work, error) {
+	if parentID == 0 {
+		return nil, nil
+	}
+	if parentID == w.ID {
+		return nil, &bizerror.ErrWorkParentInvalid{Reason: "work can not be the parent of itself"}
+	}
+
+	parent := domain.Work{}
+	if err := tx.Where("id = ?", parentID).First(&parent).Error; err == gorm.ErrRecordNotFound {
+		return nil, &bizerror.ErrWorkParentInvalid{Reason: "parent work is not found"}
+	} else if err != nil {
+		return nil, err
+	}
+	if parent.ProjectID != w.ProjectID {
+		return nil, &bizerror.ErrWorkParentInvalid{Reason: "parent work is in another project"}
+	}
+
+	visited := map[types.ID]bool{parent.ID: true}
+	for ancestorID := parent.ParentID; ancestorID != 0; {
+		if ancestorID == w.ID {
+			return nil, &bizerror.ErrWorkParentInvalid{Reason: "work is an ancestor of its parent"}
+		}
+		if visited[ancestorID] {
+			break
+		}
+		visited[ancestorID] = true
+
+		ancestor := domain.Work{}
+		if err := tx.Select("id, parent_id").Where("id = ?", ancestorID).First(&ancestor).Error; err == gorm.ErrRecordNotFound {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		ancestorID = ancestor.ParentID
+	}
+	return &parent, nil
+}
+
+func parentUpdatedRelation(oldParent, newParent *domain.Work) event.UpdatedRelation {
+	r := event.UpdatedRelation{PropertyName: "Parent", PropertyDesc: "Parent", TargetType: "WORK", TargetTypeDesc: "Work"}
+	if oldParent != nil {
+		r.OldTargetId, r.OldTargetDesc = oldParent.ID.String(), oldParent.Identifier
+	}
+	if newParent != nil {
+		r.NewTargetId, r.NewTargetDesc = newParent.ID.String(), newParent.Identifier
+	}
+	return r
+}
+
+// detachWorkChildren moves the children of the deleted work to the top level
+func detachWorkChildren(tx *gorm.DB, w *domain.Work, s *session.Session) ([]*event.EventRecord, error) {
+	var children []domain.Work
+	if err := tx.Where("parent_id = ?", w.ID).Find(&children).Error; err != nil {
+		return nil, err
+	}
+	if len(children) == 0 {
+		return nil, nil
+	}
+	if err := tx.Model(&domain.Work{}).Where("parent_id = ?", w.ID).Update("parent_id", 0).Error; err != nil {
+		return nil, err
+	}
+
+	var events []*event.EventRecord
+	now := types.CurrentTimestamp()
+	for i := range children {
+		ev, err := CreateWorkRelationUpdatedEvent(&children[i], []event.UpdatedRelation{parentUpdatedRelation(w, nil)}, &s.Identity, now, tx)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// QueryWorkChildren returns the children of work in the order of creation
+func QueryWorkChildren(id types.ID, s *session.Session) ([]WorkDetail, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	w := domain.Work{}
+	if err := db.Where("id = ?", id).First(&w).Error; err != nil {
+		return nil, err
+	}
+	if !s.Perms.HasProjectViewPerm(w.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+
+	var children []domain.Work
+	if err := db.Where("parent_id = ?", id).Order("create_time ASC").Find(&children).Error; err != nil {
+		return nil, err
+	}
+	details := make([]WorkDetail, 0, len(children))
+	for _, c := range children {
+		details = append(details, WorkDetail{Work: c})
+	}
+	if len(details) == 0 {
+		return details, nil
+	}
+	return ExtendWorksFunc(details, s)
+}
+
+// QueryWorkAncestors returns the ancestors of work, from its parent to the top level one
+func QueryWorkAncestors(id types.ID, s *session.Session) ([]domain.Work, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	w := domain.Work{}
+	if err := db.Where("id = ?", id).First(&w).Error; err != nil {
+		return nil, err
+	}
+	if !s.Perms.HasProjectViewPerm(w.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+
+	ancestors := []domain.Work{}
+	visited := map[types.ID]bool{w.ID: true}
+	for parentID := w.ParentID; parentID != 0 && !visited[parentID]; {
+		visited[parentID] = true
+		parent := domain.Work{}
+		if err := db.Where("id = ?", parentID).First(&parent).Error; err == gorm.ErrRecordNotFound {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, parent)
+		parentID = parent.ParentID
+	}
+	return ancestors, nil
+}
+
+// InnerAppendRollups appends the summary of children to the works which have children
+func InnerAppendRollups(works []WorkDetail, s *session.Session) error {
+	ids := []types.ID{}
+	for _, w := range works {
+		ids = append(ids, w.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	var children []domain.Work
+	if err := db.Select("id, parent_id, state_category").Where("parent_id IN (?)", ids).Find(&children).Error; err != nil {
+		return err
+	}
+	if len(children) == 0 {
+		return nil
+	}
+
+	rollups := map[types.ID]*WorkRollup{}
+	parents := map[types.ID]types.ID{}
+	var childIds []types.ID
+	for _, c := range children {
+		r := rollups[c.ParentID]
+		if r == nil {
+			r = &WorkRollup{StateCategories: map[state.Category]int{}}
+			rollups[c.ParentID] = r
+		}
+		r.Children++
+		r.StateCategories[c.StateCategory]++
+		parents[c.ID] = c.ParentID
+		childIds = append(childIds, c.ID)
+	}
+
+	items, err := checklist.InnerListWorksCheckItemsFunc(childIds, db)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		r := rollups[parents[item.WorkId]]
+		r.CheckItems++
+		if item.Done {
+			r.DoneCheckItems++
+		}
+	}
+
+	for i := range works {
+		works[i].Rollup = rollups[works[i].ID]
+	}
+	return nil
+}
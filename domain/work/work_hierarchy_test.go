@@ -0,0 +1,128 @@
+package work_test
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/domain/work"
+	"flywheel/domain/work/checklist"
+	"flywheel/event"
+	"flywheel/testinfra"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkHierarchy(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should create works under parent and refuse invalid parents", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, project2, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String(),
+			domain.ProjectRoleManager+"_"+project2.ID.String())
+		epic := buildWork("epic", workflow.ID, project1.ID, sec)
+
+		story, err := work.CreateWork(&domain.WorkCreation{Name: "story", ProjectID: project1.ID, FlowID: workflow.ID,
+			InitialStateName: domain.StatePending.Name, ParentID: epic.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(story.ParentID).To(Equal(epic.ID))
+
+		_, err = work.CreateWork(&domain.WorkCreation{Name: "task", ProjectID: project1.ID, FlowID: workflow.ID,
+			InitialStateName: domain.StatePending.Name, ParentID: 404}, sec)
+		Expect(err).To(Equal(&bizerror.ErrWorkParentInvalid{Reason: "parent work is not found"}))
+
+		Expect(work.UpdateWorkParent(epic.ID, &domain.WorkParentUpdating{ParentID: epic.ID}, sec)).To(
+			Equal(&bizerror.ErrWorkParentInvalid{Reason: "work can not be the parent of itself"}))
+		Expect(work.UpdateWorkParent(epic.ID, &domain.WorkParentUpdating{ParentID: story.ID}, sec)).To(
+			Equal(&bizerror.ErrWorkParentInvalid{Reason: "work is an ancestor of its parent"}))
+
+		otherWorkflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "other workflow", ProjectID: project2.ID,
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, sec)
+		Expect(err).To(BeNil())
+		other := buildWork("other", otherWorkflow.ID, project2.ID, sec)
+		Expect(work.UpdateWorkParent(other.ID, &domain.WorkParentUpdating{ParentID: epic.ID}, sec)).To(
+			Equal(&bizerror.ErrWorkParentInvalid{Reason: "parent work is in another project"}))
+
+		Expect(work.UpdateWorkParent(story.ID, &domain.WorkParentUpdating{ParentID: epic.ID},
+			testinfra.BuildSecCtx(types.ID(124), domain.ProjectRoleManager+"_"+project2.ID.String()))).To(Equal(bizerror.ErrForbidden))
+	})
+
+	t.Run("should move works and query children and ancestors", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, persistedEvents, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		epic := buildWork("epic", workflow.ID, project1.ID, sec)
+		story := buildWork("story", workflow.ID, project1.ID, sec)
+		task := buildWork("task", workflow.ID, project1.ID, sec)
+
+		Expect(work.UpdateWorkParent(story.ID, &domain.WorkParentUpdating{ParentID: epic.ID}, sec)).To(BeNil())
+		Expect(work.UpdateWorkParent(task.ID, &domain.WorkParentUpdating{ParentID: story.ID}, sec)).To(BeNil())
+		last := (*persistedEvents)[len(*persistedEvents)-1]
+		Expect(last.EventCategory).To(Equal(event.EventCategoryRelationUpdated))
+		Expect(last.UpdatedRelations).To(Equal(event.UpdatedRelations{{PropertyName: "Parent", PropertyDesc: "Parent",
+			TargetType: "WORK", TargetTypeDesc: "Work", NewTargetId: story.ID.String(), NewTargetDesc: story.Identifier}}))
+
+		ancestors, err := work.QueryWorkAncestors(task.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(len(ancestors)).To(Equal(2))
+		Expect(ancestors[0].ID).To(Equal(story.ID))
+		Expect(ancestors[1].ID).To(Equal(epic.ID))
+
+		children, err := work.QueryWorkChildren(epic.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(len(children)).To(Equal(1))
+		Expect(children[0].ID).To(Equal(story.ID))
+		Expect(children[0].State.Name).To(Equal(domain.StatePending.Name))
+
+		Expect(work.UpdateWorkParent(task.ID, &domain.WorkParentUpdating{ParentID: 0}, sec)).To(BeNil())
+		last = (*persistedEvents)[len(*persistedEvents)-1]
+		Expect(last.UpdatedRelations).To(Equal(event.UpdatedRelations{{PropertyName: "Parent", PropertyDesc: "Parent",
+			TargetType: "WORK", TargetTypeDesc: "Work", OldTargetId: story.ID.String(), OldTargetDesc: story.Identifier}}))
+		ancestors, err = work.QueryWorkAncestors(task.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(ancestors).To(BeEmpty())
+
+		_, err = work.QueryWorkChildren(epic.ID, testinfra.BuildSecCtx(types.ID(124), domain.ProjectRoleManager+"_2000"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+
+	t.Run("should roll up children onto parent and detach children of deleted parent", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		epic := buildWork("epic", workflow.ID, project1.ID, sec)
+		story1 := buildWork("story1", workflow.ID, project1.ID, sec)
+		story2 := buildWork("story2", workflow.ID, project1.ID, sec)
+		Expect(work.UpdateWorkParent(story1.ID, &domain.WorkParentUpdating{ParentID: epic.ID}, sec)).To(BeNil())
+		Expect(work.UpdateWorkParent(story2.ID, &domain.WorkParentUpdating{ParentID: epic.ID}, sec)).To(BeNil())
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: story2.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDoing.Name}, sec)).To(BeNil())
+		item, err := checklist.CreateCheckItem(checklist.CheckItemCreation{Name: "item1", WorkId: story1.ID}, sec)
+		Expect(err).To(BeNil())
+		done := true
+		Expect(checklist.UpdateCheckItem(item.ID, checklist.CheckItemUpdate{Done: &done}, sec)).To(BeNil())
+		_, err = checklist.CreateCheckItem(checklist.CheckItemCreation{Name: "item2", WorkId: story2.ID}, sec)
+		Expect(err).To(BeNil())
+
+		detail, err := work.DetailWork(epic.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(*detail.Rollup).To(Equal(work.WorkRollup{Children: 2,
+			StateCategories: map[state.Category]int{state.InBacklog: 1, state.InProcess: 1}, CheckItems: 2, DoneCheckItems: 1}))
+
+		detail, err = work.DetailWork(story1.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.Rollup).To(BeNil())
+
+		Expect(work.DeleteWork(epic.ID, sec)).To(BeNil())
+		detail, err = work.DetailWork(story1.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.ParentID).To(BeZero())
+	})
+}
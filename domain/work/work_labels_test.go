@@ -28,8 +28,9 @@ func workLabelsTestSetup(t *testing.T, testDatabase **testinfra.TestDatabase) (*
 	db := testinfra.StartMysqlTestDatabase("flywheel")
 	*testDatabase = db
 	// migration
-	Expect(db.DS.GormDB(context.Background()).AutoMigrate(&WorkLabelRelation{}, &label.Label{}, &domain.Project{}, &domain.ProjectMember{}, &domain.Work{}, &domain.WorkProcessStep{},
-		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &checklist.CheckItem{}).Error).To(BeNil())
+	Expect(db.DS.GormDB(context.Background()).AutoMigrate(&WorkLabelRelation{}, &WorkLink{}, &WorkParticipantRecord{}, &WorkComment{}, &WorkCommentRevision{}, &WorkAttachment{}, &account.User{}, &label.Label{}, &domain.Project{}, &domain.ProjectMember{}, &domain.Work{}, &domain.WorkProcessStep{},
+		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &domain.WorkflowVersion{}, &checklist.CheckItem{}, &flow.WorkflowPropertyDefinition{},
+		&event.EventRecord{}).Error).To(BeNil())
 
 	persistence.ActiveDataSourceManager = db.DS
 
@@ -0,0 +1,332 @@
+package work
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/event"
+	"flywheel/idgen"
+	"flywheel/persistence"
+	"flywheel/session"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+	"github.com/sony/sonyflake"
+)
+
+// LinkType is the type of link from the perspective of one work, e.g. W-1 blocks W-2 while W-2 is blocked-by W-1
+type LinkType string
+
+const (
+	LinkBlocks       LinkType = "blocks"
+	LinkBlockedBy    LinkType = "blocked-by"
+	LinkRelatesTo    LinkType = "relates-to"
+	LinkDuplicates   LinkType = "duplicates"
+	LinkDuplicatedBy LinkType = "duplicated-by"
+	LinkClones       LinkType = "clones"
+	LinkClonedBy     LinkType = "cloned-by"
+)
+
+var (
+	workLinkIdWorker = sonyflake.NewSonyflake(sonyflake.Settings{})
+
+	CreateWorkLinkFunc = CreateWorkLink
+	DeleteWorkLinkFunc = DeleteWorkLink
+	QueryWorkLinksFunc = QueryWorkLinks
+
+	// linkInverses maps each link type to the type seen from the other side, only the forward types are stored
+	linkInverses = map[LinkType]LinkType{
+		LinkBlocks:       LinkBlockedBy,
+		LinkBlockedBy:    LinkBlocks,
+		LinkRelatesTo:    LinkRelatesTo,
+		LinkDuplicates:   LinkDuplicatedBy,
+		LinkDuplicatedBy: LinkDuplicates,
+		LinkClones:       LinkClonedBy,
+		LinkClonedBy:     LinkClones,
+	}
+	forwardLinkTypes = map[LinkType]bool{LinkBlocks: true, LinkRelatesTo: true, LinkDuplicates: true, LinkClones: true}
+)
+
+// WorkLink is a directional link from work SourceID to work TargetID, Type is always a forward type
+type WorkLink struct {
+	ID       types.ID `json:"id" gorm:"primary_key"`
+	Type     LinkType `json:"type"`
+	SourceID types.ID `json:"sourceId" gorm:"index"`
+	TargetID types.ID `json:"targetId" gorm:"index"`
+
+	CreateTime types.Timestamp `json:"createTime" sql:"type:DATETIME(6) NOT NULL"`
+	CreatorID  types.ID        `json:"creatorId"`
+}
+
+// WorkLinkCreation links work WorkID to work TargetID, e.g. {workId: W-1, type: blocked-by, targetId: W-2}
+type WorkLinkCreation struct {
+	WorkID   types.ID `json:"workId" binding:"required"`
+	Type     LinkType `json:"type" binding:"required"`
+	TargetID types.ID `json:"targetId" binding:"required"`
+}
+
+// WorkLinkBrief is a link seen from one work, WorkID is the work at the other side
+type WorkLinkBrief struct {
+	ID   types.ID `json:"id"`
+	Type LinkType `json:"type"`
+
+	WorkID        types.ID       `json:"workId"`
+	Identifier    string         `json:"identifier"`
+	Name          string         `json:"name"`
+	StateCategory state.Category `json:"stateCategory"`
+}
+
+func CreateWorkLink(c *WorkLinkCreation, s *session.Session) (*WorkLink, error) {
+	if _, found := linkInverses[c.Type]; !found {
+		return nil, &bizerror.ErrBadParam{Cause: errors.New("unknown link type " + string(c.Type))}
+	}
+	if c.WorkID == c.TargetID {
+		return nil, &bizerror.ErrBadParam{Cause: errors.New("work can not be linked to itself")}
+	}
+
+	var link *WorkLink
+	var events []*event.EventRecord
+	err1 := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		w, err := findWorkAndCheckPerms(tx, c.WorkID, s)
+		if err != nil {
+			return err
+		}
+		target := domain.Work{}
+		if err := tx.Where("id = ?", c.TargetID).First(&target).Error; err != nil {
+			return err
+		}
+		if !s.Perms.HasProjectViewPerm(target.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+
+		link = &WorkLink{ID: idgen.NextID(workLinkIdWorker), Type: c.Type, SourceID: w.ID, TargetID: target.ID,
+			CreateTime: types.CurrentTimestamp(), CreatorID: s.Identity.ID}
+		source := w
+		if !forwardLinkTypes[c.Type] {
+			link.Type, link.SourceID, link.TargetID = linkInverses[c.Type], target.ID, w.ID
+			declaredTarget := target
+			source, target = &declaredTarget, *w
+		}
+
+		existed := 0
+		q := tx.Model(&WorkLink{}).Where("type = ? AND source_id = ? AND target_id = ?", link.Type, link.SourceID, link.TargetID)
+		if link.Type == LinkRelatesTo {
+			q = tx.Model(&WorkLink{}).Where("type = ? AND ((source_id = ? AND target_id = ?) OR (source_id = ? AND target_id = ?))",
+				link.Type, link.SourceID, link.TargetID, link.TargetID, link.SourceID)
+		}
+		if err := q.Count(&existed).Error; err != nil {
+			return err
+		}
+		if existed > 0 {
+			return &bizerror.ErrBadParam{Cause: errors.New("link " + string(link.Type) + " from " + source.Identifier +
+				" to " + target.Identifier + " exists")}
+		}
+
+		if err := tx.Create(link).Error; err != nil {
+			return err
+		}
+		events, err = createWorkLinkEvents(link, source, &target, false, link.CreateTime, tx, s)
+		return err
+	})
+	if err1 != nil {
+		return nil, err1
+	}
+
+	if event.InvokeHandlersFunc != nil {
+		for _, ev := range events {
+			event.InvokeHandlersFunc(ev)
+		}
+	}
+	return link, nil
+}
+
+// DeleteWorkLink removes the link, the caller must be a member of the project of either side
+func DeleteWorkLink(id types.ID, s *session.Session) error {
+	var events []*event.EventRecord
+	err1 := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		link := WorkLink{}
+		if err := tx.Where("id = ?", id).First(&link).Error; err != nil {
+			return err
+		}
+		source, target := domain.Work{}, domain.Work{}
+		if err := tx.Where("id = ?", link.SourceID).First(&source).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", link.TargetID).First(&target).Error; err != nil {
+			return err
+		}
+		if !s.Perms.HasAnyProjectRole(source.ProjectID) && !s.Perms.HasAnyProjectRole(target.ProjectID) {
+			return bizerror.ErrForbidden
+		}
+
+		if err := tx.Delete(&WorkLink{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var err error
+		events, err = createWorkLinkEvents(&link, &source, &target, true, types.CurrentTimestamp(), tx, s)
+		return err
+	})
+	if err1 != nil {
+		return err1
+	}
+
+	if event.InvokeHandlersFunc != nil {
+		for _, ev := range events {
+			event.InvokeHandlersFunc(ev)
+		}
+	}
+	return nil
+}
+
+// QueryWorkLinks returns the links of work from its perspective
+func QueryWorkLinks(workID types.ID, s *session.Session) ([]WorkLinkBrief, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	w := domain.Work{}
+	if err := db.Where("id = ?", workID).First(&w).Error; err != nil {
+		return nil, err
+	}
+	if !s.Perms.HasProjectViewPerm(w.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+	return queryWorkLinks(db, workID)
+}
+
+func queryWorkLinks(db *gorm.DB, workID types.ID) ([]WorkLinkBrief, error) {
+	var links []WorkLink
+	if err := db.Where("source_id = ? OR target_id = ?", workID, workID).Order("create_time ASC").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	briefs := []WorkLinkBrief{}
+	if len(links) == 0 {
+		return briefs, nil
+	}
+
+	var otherIds []types.ID
+	for _, l := range links {
+		if l.SourceID == workID {
+			otherIds = append(otherIds, l.TargetID)
+		} else {
+			otherIds = append(otherIds, l.SourceID)
+		}
+	}
+	var others []domain.Work
+	if err := db.Where("id IN (?)", otherIds).Find(&others).Error; err != nil {
+		return nil, err
+	}
+	otherMap := map[types.ID]domain.Work{}
+	for _, o := range others {
+		otherMap[o.ID] = o
+	}
+
+	for i, l := range links {
+		other, found := otherMap[otherIds[i]]
+		if !found {
+			continue
+		}
+		linkType := l.Type
+		if l.SourceID != workID {
+			linkType = linkInverses[l.Type]
+		}
+		briefs = append(briefs, WorkLinkBrief{ID: l.ID, Type: linkType,
+			WorkID: other.ID, Identifier: other.Identifier, Name: other.Name, StateCategory: other.StateCategory})
+	}
+	return briefs, nil
+}
+
+// createWorkLinkEvents creates a relation updated event for each side of the link
+func createWorkLinkEvents(link *WorkLink, source, target *domain.Work, removed bool,
+	now types.Timestamp, tx *gorm.DB, s *session.Session) ([]*event.EventRecord, error) {
+
+	sides := []struct {
+		work     *domain.Work
+		other    *domain.Work
+		linkType LinkType
+	}{{source, target, link.Type}, {target, source, linkInverses[link.Type]}}
+
+	var events []*event.EventRecord
+	for _, side := range sides {
+		r := event.UpdatedRelation{PropertyName: string(side.linkType), PropertyDesc: string(side.linkType),
+			TargetType: "WORK", TargetTypeDesc: "Work"}
+		if removed {
+			r.OldTargetId, r.OldTargetDesc = side.other.ID.String(), side.other.Identifier
+		} else {
+			r.NewTargetId, r.NewTargetDesc = side.other.ID.String(), side.other.Identifier
+		}
+		ev, err := CreateWorkRelationUpdatedEvent(side.work, []event.UpdatedRelation{r}, &s.Identity, now, tx)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// clearWorkLinks removes the links of the deleted work, the works at the other side are notified
+func clearWorkLinks(tx *gorm.DB, w *domain.Work, s *session.Session) ([]*event.EventRecord, error) {
+	var links []WorkLink
+	if err := tx.Where("source_id = ? OR target_id = ?", w.ID, w.ID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, nil
+	}
+	if err := tx.Delete(&WorkLink{}, "source_id = ? OR target_id = ?", w.ID, w.ID).Error; err != nil {
+		return nil, err
+	}
+
+	var events []*event.EventRecord
+	now := types.CurrentTimestamp()
+	for _, l := range links {
+		otherID, linkType := l.SourceID, l.Type
+		if l.SourceID == w.ID {
+			otherID, linkType = l.TargetID, linkInverses[l.Type]
+		}
+		other := domain.Work{}
+		if err := tx.Where("id = ?", otherID).First(&other).Error; err == gorm.ErrRecordNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ev, err := CreateWorkRelationUpdatedEvent(&other, []event.UpdatedRelation{{
+			PropertyName: string(linkType), PropertyDesc: string(linkType), TargetType: "WORK", TargetTypeDesc: "Work",
+			OldTargetId: w.ID.String(), OldTargetDesc: w.Identifier,
+		}}, &s.Identity, now, tx)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// checkWorkNotBlocked returns the reasons when a work which is blocked by unfinished works is going to enter toState,
+// only entering a state of category InProcess from other categories is refused
+func checkWorkNotBlocked(tx *gorm.DB, w *domain.Work, toState state.State) ([]string, error) {
+	if state.BaseCategory(toState.Category) != state.InProcess || state.BaseCategory(w.StateCategory) == state.InProcess {
+		return nil, nil
+	}
+	var links []WorkLink
+	if err := tx.Where("type = ? AND target_id = ?", LinkBlocks, w.ID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, nil
+	}
+	var blockerIds []types.ID
+	for _, l := range links {
+		blockerIds = append(blockerIds, l.SourceID)
+	}
+	var blockers []domain.Work
+	if err := tx.Where("id IN (?)", blockerIds).Order("create_time ASC").Find(&blockers).Error; err != nil {
+		return nil, err
+	}
+
+	var reasons []string
+	for _, b := range blockers {
+		if base := state.BaseCategory(b.StateCategory); base != state.Done && base != state.Rejected {
+			reasons = append(reasons, "blocked by "+b.Identifier)
+		}
+	}
+	return reasons, nil
+}
@@ -0,0 +1,65 @@
+package work
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/misc"
+	"flywheel/session"
+	"net/http"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+var (
+	PathWorkLinks = "/v1/work-links"
+)
+
+type workLinkQuery struct {
+	WorkID types.ID `form:"workId" binding:"required"`
+}
+
+func RegisterWorkLinksRestAPI(r *gin.Engine, middleWares ...gin.HandlerFunc) {
+	g := r.Group(PathWorkLinks, middleWares...)
+	g.GET("", handleQueryWorkLinks)
+	g.POST("", handleCreateWorkLink)
+	g.DELETE(":id", handleDeleteWorkLink)
+}
+
+func handleQueryWorkLinks(c *gin.Context) {
+	query := workLinkQuery{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	links, err := QueryWorkLinksFunc(query.WorkID, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusOK, &misc.PagedBody{List: links, Total: uint64(len(links))})
+}
+
+func handleCreateWorkLink(c *gin.Context) {
+	req := WorkLinkCreation{}
+	err := c.ShouldBindBodyWith(&req, binding.JSON)
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	link, err := CreateWorkLinkFunc(&req, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+func handleDeleteWorkLink(c *gin.Context) {
+	parsedId, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+	}
+	err = DeleteWorkLinkFunc(parsedId, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,90 @@
+package work_test
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain/state"
+	"flywheel/domain/work"
+	"flywheel/session"
+	"flywheel/testinfra"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkLinksAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	work.RegisterWorkLinksRestAPI(router)
+
+	t.Run("should be able to validate parameters", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, work.PathWorkLinks, strings.NewReader(`{"workId": "10", "targetId": "20"}`))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message": "Key: 'WorkLinkCreation.Type' Error:Field validation for 'Type' failed on the 'required' tag", "data":null}`))
+
+		req = httptest.NewRequest(http.MethodGet, work.PathWorkLinks, nil)
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+
+		req = httptest.NewRequest(http.MethodDelete, work.PathWorkLinks+"/abc", nil)
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+	})
+
+	t.Run("should be able to create link", func(t *testing.T) {
+		var creation *work.WorkLinkCreation
+		work.CreateWorkLinkFunc = func(c *work.WorkLinkCreation, s *session.Session) (*work.WorkLink, error) {
+			creation = c
+			return &work.WorkLink{ID: 1, Type: work.LinkBlocks, SourceID: c.TargetID, TargetID: c.WorkID}, nil
+		}
+		req := httptest.NewRequest(http.MethodPost, work.PathWorkLinks,
+			strings.NewReader(`{"workId": "10", "type": "blocked-by", "targetId": "20"}`))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(*creation).To(Equal(work.WorkLinkCreation{WorkID: 10, Type: work.LinkBlockedBy, TargetID: 20}))
+		Expect(body).To(MatchJSON(`{"id": "1", "type": "blocks", "sourceId": "20", "targetId": "10", "createTime": null, "creatorId": "0"}`))
+	})
+
+	t.Run("should be able to query links of work", func(t *testing.T) {
+		var workId types.ID
+		work.QueryWorkLinksFunc = func(id types.ID, s *session.Session) ([]work.WorkLinkBrief, error) {
+			workId = id
+			return []work.WorkLinkBrief{{ID: 1, Type: work.LinkBlockedBy, WorkID: 20, Identifier: "W-20", Name: "blocker",
+				StateCategory: state.InProcess}}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, work.PathWorkLinks+"?workId=10", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(workId).To(Equal(types.ID(10)))
+		Expect(body).To(MatchJSON(`{"total": 1, "data": [{"id": "1", "type": "blocked-by", "workId": "20", "identifier": "W-20",
+			"name": "blocker", "stateCategory": 2}]}`))
+	})
+
+	t.Run("should be able to delete link", func(t *testing.T) {
+		var linkId types.ID
+		work.DeleteWorkLinkFunc = func(id types.ID, s *session.Session) error {
+			linkId = id
+			return nil
+		}
+		req := httptest.NewRequest(http.MethodDelete, work.PathWorkLinks+"/1", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusNoContent))
+		Expect(linkId).To(Equal(types.ID(1)))
+
+		work.DeleteWorkLinkFunc = func(id types.ID, s *session.Session) error {
+			return bizerror.ErrForbidden
+		}
+		req = httptest.NewRequest(http.MethodDelete, work.PathWorkLinks+"/1", nil)
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+}
@@ -0,0 +1,110 @@
+package work_test
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/work"
+	"flywheel/event"
+	"flywheel/testinfra"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkLinks(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should create, query and delete links", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, persistedEvents, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		w1 := buildWork("work1", workflow.ID, project1.ID, sec)
+		w2 := buildWork("work2", workflow.ID, project1.ID, sec)
+
+		_, err := work.CreateWorkLink(&work.WorkLinkCreation{WorkID: w1.ID, Type: "unknown", TargetID: w2.ID}, sec)
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("unknown link type unknown")}))
+		_, err = work.CreateWorkLink(&work.WorkLinkCreation{WorkID: w1.ID, Type: work.LinkRelatesTo, TargetID: w1.ID}, sec)
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("work can not be linked to itself")}))
+		_, err = work.CreateWorkLink(&work.WorkLinkCreation{WorkID: w1.ID, Type: work.LinkBlocks, TargetID: w2.ID},
+			testinfra.BuildSecCtx(types.ID(124), domain.ProjectRoleManager+"_2000"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		// work1 is blocked by work2, the link is stored as work2 blocks work1
+		link, err := work.CreateWorkLink(&work.WorkLinkCreation{WorkID: w1.ID, Type: work.LinkBlockedBy, TargetID: w2.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(link.Type).To(Equal(work.LinkBlocks))
+		Expect(link.SourceID).To(Equal(w2.ID))
+		Expect(link.TargetID).To(Equal(w1.ID))
+		events := *persistedEvents
+		Expect(events[len(events)-2].SourceId).To(Equal(w2.ID))
+		Expect(events[len(events)-2].EventCategory).To(Equal(event.EventCategoryRelationUpdated))
+		Expect(events[len(events)-2].UpdatedRelations).To(Equal(event.UpdatedRelations{{PropertyName: "blocks", PropertyDesc: "blocks",
+			TargetType: "WORK", TargetTypeDesc: "Work", NewTargetId: w1.ID.String(), NewTargetDesc: w1.Identifier}}))
+		Expect(events[len(events)-1].SourceId).To(Equal(w1.ID))
+		Expect(events[len(events)-1].UpdatedRelations).To(Equal(event.UpdatedRelations{{PropertyName: "blocked-by", PropertyDesc: "blocked-by",
+			TargetType: "WORK", TargetTypeDesc: "Work", NewTargetId: w2.ID.String(), NewTargetDesc: w2.Identifier}}))
+
+		_, err = work.CreateWorkLink(&work.WorkLinkCreation{WorkID: w2.ID, Type: work.LinkBlocks, TargetID: w1.ID}, sec)
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("link blocks from " + w2.Identifier + " to " + w1.Identifier + " exists")}))
+		_, err = work.CreateWorkLink(&work.WorkLinkCreation{WorkID: w1.ID, Type: work.LinkRelatesTo, TargetID: w2.ID}, sec)
+		Expect(err).To(BeNil())
+		_, err = work.CreateWorkLink(&work.WorkLinkCreation{WorkID: w2.ID, Type: work.LinkRelatesTo, TargetID: w1.ID}, sec)
+		Expect(err).ToNot(BeNil())
+
+		links, err := work.QueryWorkLinks(w1.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(len(links)).To(Equal(2))
+		Expect(links[0]).To(Equal(work.WorkLinkBrief{ID: link.ID, Type: work.LinkBlockedBy,
+			WorkID: w2.ID, Identifier: w2.Identifier, Name: w2.Name, StateCategory: w2.StateCategory}))
+		Expect(links[1].Type).To(Equal(work.LinkRelatesTo))
+
+		detail, err := work.DetailWork(w2.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(len(detail.Links)).To(Equal(2))
+		Expect(detail.Links[0].Type).To(Equal(work.LinkBlocks))
+		Expect(detail.Links[0].WorkID).To(Equal(w1.ID))
+
+		Expect(work.DeleteWorkLink(link.ID, testinfra.BuildSecCtx(types.ID(124), domain.ProjectRoleManager+"_2000"))).To(Equal(bizerror.ErrForbidden))
+		Expect(work.DeleteWorkLink(link.ID, sec)).To(BeNil())
+		events = *persistedEvents
+		Expect(events[len(events)-1].UpdatedRelations).To(Equal(event.UpdatedRelations{{PropertyName: "blocked-by", PropertyDesc: "blocked-by",
+			TargetType: "WORK", TargetTypeDesc: "Work", OldTargetId: w2.ID.String(), OldTargetDesc: w2.Identifier}}))
+		links, err = work.QueryWorkLinks(w1.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(len(links)).To(Equal(1))
+
+		Expect(work.DeleteWork(w2.ID, sec)).To(BeNil())
+		links, err = work.QueryWorkLinks(w1.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(links).To(BeEmpty())
+		events = *persistedEvents
+		Expect(events[len(events)-1].SourceId).To(Equal(w1.ID))
+		Expect(events[len(events)-1].UpdatedRelations).To(Equal(event.UpdatedRelations{{PropertyName: "relates-to", PropertyDesc: "relates-to",
+			TargetType: "WORK", TargetTypeDesc: "Work", OldTargetId: w2.ID.String(), OldTargetDesc: w2.Identifier}}))
+	})
+
+	t.Run("should refuse to start blocked work", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		blocked := buildWork("blocked", workflow.ID, project1.ID, sec)
+		blocker := buildWork("blocker", workflow.ID, project1.ID, sec)
+		_, err := work.CreateWorkLink(&work.WorkLinkCreation{WorkID: blocker.ID, Type: work.LinkBlocks, TargetID: blocked.ID}, sec)
+		Expect(err).To(BeNil())
+
+		start := &domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: blocked.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDoing.Name}
+		_, err = work.CreateWorkStateTransition(start, sec)
+		Expect(err).To(Equal(&bizerror.ErrTransitionRefused{
+			Transition: "begin", Reasons: []string{"blocked by " + blocker.Identifier}}))
+
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: blocker.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDone.Name}, sec)).To(BeNil())
+		Expect(work.CreateWorkStateTransition(start, sec)).To(BeNil())
+	})
+}
@@ -0,0 +1,190 @@
+package work
+
+import (
+	"errors"
+	"flywheel/account"
+	"flywheel/bizerror"
+	"flywheel/event"
+	"flywheel/persistence"
+	"flywheel/session"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	ParticipantAssignee = "assignee"
+	ParticipantWatcher  = "watcher"
+)
+
+var (
+	UpdateWorkParticipantsFunc    = UpdateWorkParticipants
+	QueryParticipantsOfWorksFunc  = QueryParticipantsOfWorks
+	ClearWorkParticipantsFunc     = clearWorkParticipants
+	participantRelationProperties = map[string]string{ParticipantAssignee: "Assignee", ParticipantWatcher: "Watcher"}
+)
+
+// WorkParticipantRecord binds a user to a work as an assignee or a watcher
+type WorkParticipantRecord struct {
+	WorkID types.ID `gorm:"primary_key" sql:"type:BIGINT UNSIGNED NOT NULL"`
+	UserID types.ID `gorm:"primary_key" sql:"type:BIGINT UNSIGNED NOT NULL"`
+	Role   string   `gorm:"primary_key" sql:"type:VARCHAR(16) NOT NULL"`
+
+	CreateTime types.Timestamp `sql:"type:DATETIME(6)"`
+	CreatorID  types.ID
+}
+
+func (r *WorkParticipantRecord) TableName() string {
+	return "work_participants"
+}
+
+type WorkParticipant struct {
+	ID       types.ID `json:"id"`
+	Name     string   `json:"name"`
+	Nickname string   `json:"nickname"`
+}
+
+type WorkParticipantBrief struct {
+	WorkID types.ID
+	Role   string
+	WorkParticipant
+}
+
+// WorkParticipantsUpdating replaces all assignees or watchers of a work with users UserIDs
+type WorkParticipantsUpdating struct {
+	UserIDs []types.ID `json:"userIds"`
+}
+
+// UpdateWorkParticipants replaces the participants of work in role, the participants must be members of the project of work
+func UpdateWorkParticipants(id types.ID, role string, u *WorkParticipantsUpdating, s *session.Session) error {
+	property, found := participantRelationProperties[role]
+	if !found {
+		return &bizerror.ErrBadParam{Cause: errors.New("unknown participant role " + role)}
+	}
+
+	var ev *event.EventRecord
+	err1 := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		w, err := findWorkAndCheckPerms(tx, id, s)
+		if err != nil {
+			return err
+		}
+		if !w.ArchiveTime.IsZero() {
+			return bizerror.ErrArchiveStatusInvalid
+		}
+
+		var existed []WorkParticipantRecord
+		if err := tx.Where("work_id = ? AND role = ?", id, role).Find(&existed).Error; err != nil {
+			return err
+		}
+		wanted := map[types.ID]bool{}
+		var added []types.ID
+		for _, uid := range u.UserIDs {
+			if !wanted[uid] {
+				wanted[uid] = true
+				added = append(added, uid)
+			}
+		}
+		var removed []types.ID
+		for _, r := range existed {
+			if wanted[r.UserID] {
+				added = removeID(added, r.UserID)
+			} else {
+				removed = append(removed, r.UserID)
+			}
+		}
+		if len(added) == 0 && len(removed) == 0 {
+			return nil
+		}
+
+		users, err := loadParticipantUsers(tx, append(append([]types.ID{}, added...), removed...))
+		if err != nil {
+			return err
+		}
+		for _, uid := range added {
+			if _, found := users[uid]; !found {
+				return &bizerror.ErrBadParam{Cause: errors.New("user " + uid.String() + " is not found")}
+			}
+			if _, projectRoles := account.LoadPermFunc(uid); !projectRoles.HasProject(w.ProjectID) {
+				return &bizerror.ErrBadParam{Cause: errors.New("user " + users[uid].Name + " is not a member of the project")}
+			}
+		}
+
+		now := types.CurrentTimestamp()
+		var updates []event.UpdatedRelation
+		if len(removed) > 0 {
+			if err := tx.Where("work_id = ? AND role = ? AND user_id IN (?)", id, role, removed).Delete(&WorkParticipantRecord{}).Error; err != nil {
+				return err
+			}
+			for _, uid := range removed {
+				updates = append(updates, event.UpdatedRelation{PropertyName: property, PropertyDesc: property, TargetType: "USER", TargetTypeDesc: "User",
+					OldTargetId: uid.String(), OldTargetDesc: users[uid].DisplayName()})
+			}
+		}
+		for _, uid := range added {
+			r := WorkParticipantRecord{WorkID: id, UserID: uid, Role: role, CreateTime: now, CreatorID: s.Identity.ID}
+			if err := tx.Create(&r).Error; err != nil {
+				return err
+			}
+			updates = append(updates, event.UpdatedRelation{PropertyName: property, PropertyDesc: property, TargetType: "USER", TargetTypeDesc: "User",
+				NewTargetId: uid.String(), NewTargetDesc: users[uid].DisplayName()})
+		}
+
+		ev, err = CreateWorkRelationUpdatedEvent(w, updates, &s.Identity, now, tx)
+		return err
+	})
+	if err1 != nil {
+		return err1
+	}
+
+	if ev != nil && event.InvokeHandlersFunc != nil {
+		event.InvokeHandlersFunc(ev)
+	}
+	return nil
+}
+
+func loadParticipantUsers(tx *gorm.DB, ids []types.ID) (map[types.ID]account.User, error) {
+	var users []account.User
+	if err := tx.Where("id IN (?)", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	m := map[types.ID]account.User{}
+	for _, u := range users {
+		m[u.ID] = u
+	}
+	return m, nil
+}
+
+func removeID(ids []types.ID, id types.ID) []types.ID {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+// QueryParticipantsOfWorks returns the assignees and watchers of works in the order of being added
+func QueryParticipantsOfWorks(workIds []types.ID, s *session.Session) ([]WorkParticipantBrief, error) {
+	var briefs []WorkParticipantBrief
+	if len(workIds) == 0 {
+		return briefs, nil
+	}
+
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	if err := db.Model(&WorkParticipantRecord{}).
+		Select("work_participants.work_id, work_participants.role, users.id, users.name, users.nickname").
+		Where("work_participants.work_id IN (?)", workIds).
+		Joins("INNER JOIN users ON users.id = work_participants.user_id").
+		Order("work_participants.create_time ASC").
+		Scan(&briefs).Error; err != nil {
+		return nil, err
+	}
+	return briefs, nil
+}
+
+func clearWorkParticipants(workID types.ID, tx *gorm.DB) error {
+	if workID == types.ID(0) {
+		return nil
+	}
+	return tx.Delete(&WorkParticipantRecord{}, "work_id = ?", workID).Error
+}
@@ -0,0 +1,78 @@
+package work_test
+
+import (
+	"context"
+	"errors"
+	"flywheel/account"
+	"flywheel/authority"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/work"
+	"flywheel/event"
+	"flywheel/testinfra"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestUpdateWorkParticipants(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should update assignees and watchers of work", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		defer account.LoadPermFuncReset()
+		workflow, project1, _, persistedEvents, _ := workProgressTestSetup(t, &testDatabase)
+		work.QueryParticipantsOfWorksFunc = work.QueryParticipantsOfWorks
+
+		db := testDatabase.DS.GormDB(context.Background())
+		Expect(db.Save(&account.User{ID: 200, Name: "alice"}).Error).To(BeNil())
+		Expect(db.Save(&account.User{ID: 201, Name: "bob", Nickname: "Bob"}).Error).To(BeNil())
+		Expect(db.Save(&account.User{ID: 202, Name: "eve"}).Error).To(BeNil())
+		account.LoadPermFunc = func(uid types.ID) (authority.Permissions, authority.ProjectRoles) {
+			if uid == 202 {
+				return authority.Permissions{}, authority.ProjectRoles{}
+			}
+			return authority.Permissions{domain.ProjectRoleCommon + "_" + project1.ID.String()},
+				authority.ProjectRoles{{ProjectID: project1.ID, Role: domain.ProjectRoleCommon}}
+		}
+
+		sec := testinfra.BuildSecCtx(types.ID(200), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		w := buildWork("work", workflow.ID, project1.ID, sec)
+
+		Expect(work.UpdateWorkParticipants(w.ID, "owner", &work.WorkParticipantsUpdating{UserIDs: []types.ID{200}}, sec)).To(
+			Equal(&bizerror.ErrBadParam{Cause: errors.New("unknown participant role owner")}))
+		Expect(work.UpdateWorkParticipants(w.ID, work.ParticipantAssignee, &work.WorkParticipantsUpdating{UserIDs: []types.ID{404}}, sec)).To(
+			Equal(&bizerror.ErrBadParam{Cause: errors.New("user 404 is not found")}))
+		Expect(work.UpdateWorkParticipants(w.ID, work.ParticipantAssignee, &work.WorkParticipantsUpdating{UserIDs: []types.ID{202}}, sec)).To(
+			Equal(&bizerror.ErrBadParam{Cause: errors.New("user eve is not a member of the project")}))
+		Expect(work.UpdateWorkParticipants(w.ID, work.ParticipantAssignee, &work.WorkParticipantsUpdating{UserIDs: []types.ID{200}},
+			testinfra.BuildSecCtx(types.ID(203), domain.ProjectRoleCommon+"_2000"))).To(Equal(bizerror.ErrForbidden))
+
+		Expect(work.UpdateWorkParticipants(w.ID, work.ParticipantAssignee, &work.WorkParticipantsUpdating{UserIDs: []types.ID{200, 201, 200}}, sec)).To(BeNil())
+		Expect(work.UpdateWorkParticipants(w.ID, work.ParticipantWatcher, &work.WorkParticipantsUpdating{UserIDs: []types.ID{201}}, sec)).To(BeNil())
+		detail, err := work.DetailWork(w.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.Assignees).To(Equal([]work.WorkParticipant{{ID: 200, Name: "alice"}, {ID: 201, Name: "bob", Nickname: "Bob"}}))
+		Expect(detail.Watchers).To(Equal([]work.WorkParticipant{{ID: 201, Name: "bob", Nickname: "Bob"}}))
+
+		count := len(*persistedEvents)
+		Expect(work.UpdateWorkParticipants(w.ID, work.ParticipantAssignee, &work.WorkParticipantsUpdating{UserIDs: []types.ID{201, 200}}, sec)).To(BeNil())
+		Expect(len(*persistedEvents)).To(Equal(count))
+
+		Expect(work.UpdateWorkParticipants(w.ID, work.ParticipantAssignee, &work.WorkParticipantsUpdating{UserIDs: []types.ID{201}}, sec)).To(BeNil())
+		last := (*persistedEvents)[len(*persistedEvents)-1]
+		Expect(last.EventCategory).To(Equal(event.EventCategoryRelationUpdated))
+		Expect(last.UpdatedRelations).To(Equal(event.UpdatedRelations{{PropertyName: "Assignee", PropertyDesc: "Assignee",
+			TargetType: "USER", TargetTypeDesc: "User", OldTargetId: "200", OldTargetDesc: "alice"}}))
+		detail, err = work.DetailWork(w.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.Assignees).To(Equal([]work.WorkParticipant{{ID: 201, Name: "bob", Nickname: "Bob"}}))
+
+		Expect(work.DeleteWork(w.ID, sec)).To(BeNil())
+		participants, err := work.QueryParticipantsOfWorks([]types.ID{w.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(participants).To(BeEmpty())
+	})
+}
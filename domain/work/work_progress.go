@@ -41,29 +41,45 @@ func QueryProcessSteps(query *domain.WorkProcessStepQuery, s *session.Session) (
 	return &processSteps, nil
 }
 
-func CreateWorkStateTransition(c *domain.WorkProcessStepCreation, s *session.Session) error {
+// CreateWorkStateTransition moves the work to another state, the returned warnings tell the problems which do not
+// refuse the transition, e.g. the soft wip limit of target state is reached
+func CreateWorkStateTransition(c *domain.WorkProcessStepCreation, s *session.Session) (state.Findings, error) {
 	workflow, err := flow.DetailWorkflowFunc(c.FlowID, s)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	// the transition is checked against the version of workflow which the work is running on
+	pinned := domain.Work{}
+	if err := db.Where(&domain.Work{ID: c.WorkID}).Select("flow_version").First(&pinned).Error; err == nil && pinned.FlowVersion != workflow.Version {
+		if workflow, err = flow.DetailWorkflowVersionFunc(c.FlowID, pinned.FlowVersion, s); err != nil {
+			return nil, err
+		}
 	}
 	// check whether the transition is acceptable
 	availableTransitions := workflow.StateMachine.AvailableTransitions(c.FromState, c.ToState)
 	if len(availableTransitions) != 1 {
-		return errors.New("transition from " + c.FromState + " to " + c.ToState + " is not invalid")
+		return nil, errors.New("transition from " + c.FromState + " to " + c.ToState + " is not invalid")
 	}
 
 	now := types.CurrentTimestamp()
 	fromState, found := workflow.FindState(c.FromState)
 	if !found {
-		return errors.New("invalid state " + fromState.Name)
+		return nil, errors.New("invalid state " + fromState.Name)
 	}
 	toState, found := workflow.FindState(c.ToState)
 	if !found {
-		return errors.New("invalid state " + toState.Name)
+		return nil, errors.New("invalid state " + toState.Name)
+	}
+
+	transition := availableTransitions[0]
+	if err := checkTransitionReason(toState, c.Reason); err != nil {
+		return nil, err
 	}
 
-	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
 	var ev *event.EventRecord
+	var hookEvents []*event.EventRecord
+	var warnings state.Findings
 	err = db.Transaction(func(tx *gorm.DB) error {
 		// check perms
 		work := domain.Work{ID: c.WorkID}
@@ -76,6 +92,26 @@ func CreateWorkStateTransition(c *domain.WorkProcessStepCreation, s *session.Ses
 		if !work.ArchiveTime.IsZero() {
 			return bizerror.ErrArchiveStatusInvalid
 		}
+		if work.FlowVersion != workflow.Version {
+			return bizerror.ErrWorkflowVersionInvalid
+		}
+		// the roles required by transition are checked as projectRole guards
+		if err := checkTransitionGuards(transition, &work, tx, s); err != nil {
+			return err
+		}
+		if reasons, err := checkWorkNotBlocked(tx, &work, toState); err != nil {
+			return err
+		} else if len(reasons) > 0 {
+			return &bizerror.ErrTransitionRefused{Transition: transition.Name, Reasons: reasons}
+		}
+		if err := checkRequiredProperties(workflow, &work, toState, tx); err != nil {
+			return err
+		}
+		if wipWarning, err := flow.CheckWipLimit(tx, workflow.ID, toState); err != nil {
+			return err
+		} else if wipWarning != nil {
+			warnings = append(warnings, *wipWarning)
+		}
 
 		query := tx.Model(&domain.Work{}).Where(&domain.Work{ID: c.WorkID, StateName: c.FromState}).
 			Update(&domain.Work{StateName: c.ToState, StateCategory: toState.Category, StateBeginTime: now})
@@ -87,17 +123,8 @@ func CreateWorkStateTransition(c *domain.WorkProcessStepCreation, s *session.Ses
 		}
 
 		// update work: beginProcessTime and endProcessTime
-		if work.ProcessBeginTime.IsZero() && toState.Category != state.InBacklog {
-			if err := tx.Model(&domain.Work{}).Where(&domain.Work{ID: c.WorkID}).Update("process_begin_time", &now).Error; err != nil {
-				return err
-			}
-		}
-		if work.ProcessEndTime.IsZero() && toState.Category == state.Done {
-			if err := tx.Model(&domain.Work{}).Where(&domain.Work{ID: c.WorkID}).Update("process_end_time", &now).Error; err != nil {
-				return err
-			}
-		} else if !work.ProcessEndTime.IsZero() && toState.Category != state.Done {
-			if err := tx.Model(&domain.Work{}).Where(&domain.Work{ID: c.WorkID}).Update("process_end_time", nil).Error; err != nil {
+		if updates := work.EnterProcessState(toState.Category, now); len(updates) > 0 {
+			if err := tx.Model(&domain.Work{}).Where(&domain.Work{ID: c.WorkID}).Update(updates).Error; err != nil {
 				return err
 			}
 		}
@@ -113,17 +140,28 @@ func CreateWorkStateTransition(c *domain.WorkProcessStepCreation, s *session.Ses
 		if ret.RowsAffected != 1 {
 			return bizerror.ErrWorkProcessStepStateInvalid
 		}
-		nextProcessStep := domain.WorkProcessStep{WorkID: work.ID, FlowID: work.FlowID, CreatorID: s.Identity.ID, CreatorName: s.Identity.Nickname,
-			StateName: toState.Name, StateCategory: toState.Category, BeginTime: now}
+		nextProcessStep := domain.WorkProcessStep{WorkID: work.ID, FlowID: work.FlowID, FlowVersion: work.FlowVersion,
+			CreatorID: s.Identity.ID, CreatorName: s.Identity.Nickname,
+			StateName: toState.Name, StateCategory: toState.Category, BeginTime: now, Comment: c.Comment, Reason: c.Reason}
 		if err := tx.Create(nextProcessStep).Error; err != nil {
 			return err
 		}
 
-		ev, err = CreateWorkPropertyUpdatedEvent(&work,
-			[]event.UpdatedProperty{{
-				PropertyName: "StateName", PropertyDesc: "StateName", OldValue: work.StateName, OldValueDesc: work.StateName, NewValue: c.ToState, NewValueDesc: c.ToState,
-			}},
-			&s.Identity, now, tx)
+		updates := []event.UpdatedProperty{{
+			PropertyName: "StateName", PropertyDesc: "StateName", OldValue: work.StateName, OldValueDesc: work.StateName, NewValue: c.ToState, NewValueDesc: c.ToState,
+		}}
+		if c.Comment != "" {
+			updates = append(updates, event.UpdatedProperty{PropertyName: "Comment", PropertyDesc: "Comment", NewValue: c.Comment, NewValueDesc: c.Comment})
+		}
+		if c.Reason != "" {
+			updates = append(updates, event.UpdatedProperty{PropertyName: "Reason", PropertyDesc: "Reason", NewValue: c.Reason, NewValueDesc: c.Reason})
+		}
+		ev, err = CreateWorkPropertyUpdatedEvent(&work, updates, &s.Identity, now, tx)
+		if err != nil {
+			return err
+		}
+
+		hookEvents, err = performTransitionHooks(transition, &work, now, tx, s)
 		if err != nil {
 			return err
 		}
@@ -131,11 +169,29 @@ func CreateWorkStateTransition(c *domain.WorkProcessStepCreation, s *session.Ses
 		return nil
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if event.InvokeHandlersFunc != nil {
 		event.InvokeHandlersFunc(ev)
+		for _, hookEvent := range hookEvents {
+			event.InvokeHandlersFunc(hookEvent)
+		}
 	}
 
+	return warnings, nil
+}
+
+// checkTransitionReason checks that reason is empty or one of the reasons of the state to be entered,
+// a reason is only accepted by states of category Rejected
+func checkTransitionReason(toState state.State, reason string) error {
+	if reason == "" {
+		return nil
+	}
+	if state.BaseCategory(toState.Category) != state.Rejected {
+		return &bizerror.ErrBadParam{Cause: errors.New("reason is not accepted by state " + toState.Name)}
+	}
+	if !toState.Reasons.Contains(reason) {
+		return &bizerror.ErrBadParam{Cause: errors.New("reason " + reason + " is not defined by state " + toState.Name)}
+	}
 	return nil
 }
@@ -2,6 +2,7 @@ package work_test
 
 import (
 	"context"
+	"errors"
 	"flywheel/account"
 	"flywheel/bizerror"
 	"flywheel/domain"
@@ -32,8 +33,9 @@ func workProgressTestSetup(t *testing.T, testDatabase **testinfra.TestDatabase)
 	*testDatabase = db
 	// migration
 	Expect(db.DS.GormDB(context.Background()).AutoMigrate(&domain.Project{}, &domain.ProjectMember{}, &domain.Work{}, &domain.WorkProcessStep{},
-		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{},
-		&work.WorkLabelRelation{}, &label.Label{}, &checklist.CheckItem{}).Error).To(BeNil())
+		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &domain.WorkflowVersion{},
+		&work.WorkLabelRelation{}, &work.WorkLink{}, &work.WorkParticipantRecord{}, &work.WorkComment{}, &work.WorkCommentRevision{}, &work.WorkAttachment{}, &account.User{}, &label.Label{}, &checklist.CheckItem{},
+		&flow.WorkflowPropertyDefinition{}, &work.WorkPropertyValueRecord{}, &event.EventRecord{}).Error).To(BeNil())
 
 	persistence.ActiveDataSourceManager = db.DS
 	var err error
@@ -63,6 +65,7 @@ func workProgressTestSetup(t *testing.T, testDatabase **testinfra.TestDatabase)
 		return nil
 	}
 	flow.DetailWorkflowFunc = flow.DetailWorkflow
+	flow.DetailWorkflowVersionFunc = flow.DetailWorkflowVersion
 
 	return workflowDetail, project1, project2, &persistedEvents, &handedEvents
 }
@@ -148,7 +151,7 @@ func TestQueryProcessSteps(t *testing.T) {
 		Expect(err).To(BeZero())
 
 		// do transition
-		err = work.CreateWorkStateTransition(
+		_, err = work.CreateWorkStateTransition(
 			&domain.WorkProcessStepCreation{FlowID: workflowDetail.ID, WorkID: work1.ID, FromState: work1.StateName, ToState: domain.StateDoing.Name}, secCtx)
 		Expect(err).To(BeNil())
 
@@ -184,7 +187,7 @@ func TestQueryProcessSteps(t *testing.T) {
 		Expect(step2.CreatorID).To(Equal(secCtx.Identity.ID))
 		Expect(step2.CreatorName).To(Equal(secCtx.Identity.Nickname))
 
-		err = work.CreateWorkStateTransition(
+		_, err = work.CreateWorkStateTransition(
 			&domain.WorkProcessStepCreation{FlowID: workflowDetail.ID, WorkID: work1.ID, FromState: domain.StateDoing.Name, ToState: domain.StateDone.Name}, secCtx)
 		Expect(err).To(BeNil())
 		results, err = work.QueryProcessSteps(&domain.WorkProcessStepQuery{WorkID: work1.ID}, secCtx)
@@ -225,7 +228,7 @@ func TestCreateWorkStateTransition(t *testing.T) {
 		defer workProgressTestTeardown(t, testDatabase)
 		_, _, _, persistedEvents, handedEvents := workProgressTestSetup(t, &testDatabase)
 
-		err := work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: 2}, testinfra.BuildSecCtx(123))
+		_, err := work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: 2}, testinfra.BuildSecCtx(123))
 		Expect(err).ToNot(BeNil())
 		Expect(err.Error()).To(Equal("record not found"))
 		Expect(len(*persistedEvents)).To(BeZero())
@@ -241,7 +244,7 @@ func TestCreateWorkStateTransition(t *testing.T) {
 		workflow, err := flow.CreateWorkflow(workflowCreation, sec)
 		Expect(err).To(BeNil())
 
-		err = work.CreateWorkStateTransition(
+		_, err = work.CreateWorkStateTransition(
 			&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: 1, FromState: "DONE", ToState: "DOING"}, sec)
 		Expect(err).ToNot(BeNil())
 		Expect(err.Error()).To(Equal("transition from DONE to DOING is not invalid"))
@@ -256,7 +259,7 @@ func TestCreateWorkStateTransition(t *testing.T) {
 		err := testDatabase.DS.GormDB(context.Background()).DropTable(&domain.Work{}).Error
 		Expect(err).To(BeNil())
 
-		err = work.CreateWorkStateTransition(
+		_, err = work.CreateWorkStateTransition(
 			&domain.WorkProcessStepCreation{FlowID: 1, WorkID: 1, FromState: "PENDING", ToState: "DOING"},
 			testinfra.BuildSecCtx(123))
 		Expect(err).ToNot(BeNil())
@@ -273,7 +276,7 @@ func TestCreateWorkStateTransition(t *testing.T) {
 		workflow, err := flow.CreateWorkflow(workflowCreation, sec)
 		Expect(err).To(BeNil())
 
-		err = work.CreateWorkStateTransition(
+		_, err = work.CreateWorkStateTransition(
 			&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: workflow.ID, FromState: "PENDING", ToState: "DOING"}, sec)
 		Expect(err).ToNot(BeNil())
 		Expect(err.Error()).To(Equal("record not found"))
@@ -298,7 +301,7 @@ func TestCreateWorkStateTransition(t *testing.T) {
 
 		*persistedEvents = []event.EventRecord{}
 		*handedEvents = []event.EventRecord{}
-		err = work.CreateWorkStateTransition(
+		_, err = work.CreateWorkStateTransition(
 			&domain.WorkProcessStepCreation{FlowID: detail.FlowID, WorkID: detail.ID, FromState: "PENDING", ToState: "DOING"},
 			testinfra.BuildSecCtx(types.ID(1), domain.ProjectRoleManager+"_100", domain.ProjectRoleManager+"_"+project2.ID.String()))
 		Expect(err).ToNot(BeNil())
@@ -319,7 +322,7 @@ func TestCreateWorkStateTransition(t *testing.T) {
 
 		*persistedEvents = []event.EventRecord{}
 		*handedEvents = []event.EventRecord{}
-		err = work.CreateWorkStateTransition(
+		_, err = work.CreateWorkStateTransition(
 			&domain.WorkProcessStepCreation{FlowID: detail.FlowID, WorkID: detail.ID, FromState: "DOING", ToState: "DONE"}, sec)
 		Expect(err).ToNot(BeNil())
 		Expect(err.Error()).To(Equal("expected affected row is 1, but actual is 0"))
@@ -342,7 +345,7 @@ func TestCreateWorkStateTransition(t *testing.T) {
 		*persistedEvents = []event.EventRecord{}
 		*handedEvents = []event.EventRecord{}
 		transition := domain.WorkProcessStepCreation{FlowID: detail.FlowID, WorkID: detail.ID, FromState: "PENDING", ToState: "DOING"}
-		err = work.CreateWorkStateTransition(&transition,
+		_, err = work.CreateWorkStateTransition(&transition,
 			testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_333"))
 		Expect(err).ToNot(BeZero())
 		Expect(len(*persistedEvents)).To(BeZero())
@@ -360,14 +363,14 @@ func TestCreateWorkStateTransition(t *testing.T) {
 		detail := buildWork("test work", workflow.ID, project1.ID, sec)
 
 		transition := domain.WorkProcessStepCreation{FlowID: detail.FlowID, WorkID: detail.ID, FromState: "PENDING", ToState: "DONE"}
-		err = work.CreateWorkStateTransition(&transition, sec)
+		_, err = work.CreateWorkStateTransition(&transition, sec)
 		Expect(err).To(BeZero())
 		Expect(work.ArchiveWorks([]types.ID{detail.ID}, sec)).To(BeNil())
 
 		*persistedEvents = []event.EventRecord{}
 		*handedEvents = []event.EventRecord{}
 		transition = domain.WorkProcessStepCreation{FlowID: detail.FlowID, WorkID: detail.ID, FromState: "DONE", ToState: "PENDING"}
-		err = work.CreateWorkStateTransition(&transition, sec)
+		_, err = work.CreateWorkStateTransition(&transition, sec)
 		Expect(err).To(Equal(bizerror.ErrArchiveStatusInvalid))
 		Expect(len(*persistedEvents)).To(BeZero())
 		Expect(*handedEvents).To(Equal(*persistedEvents))
@@ -393,12 +396,12 @@ func TestCreateWorkStateTransition(t *testing.T) {
 		Expect(testDatabase.DS.GormDB(context.Background()).Model(&domain.WorkProcessStep{}).Scan(&processSteps).Error).To(BeNil())
 		Expect(processSteps).ToNot(BeNil())
 		Expect(len(processSteps)).To(Equal(1))
-		Expect(processSteps[0]).To(Equal(domain.WorkProcessStep{WorkID: detail.ID, FlowID: detail.FlowID,
+		Expect(processSteps[0]).To(Equal(domain.WorkProcessStep{WorkID: detail.ID, FlowID: detail.FlowID, FlowVersion: 1,
 			CreatorID: sec.Identity.ID, CreatorName: sec.Identity.Nickname,
 			StateName: creation.FromState, StateCategory: 1, BeginTime: detail.CreateTime, EndTime: types.Timestamp{}}))
 
 		// do: create a new process step
-		err = work.CreateWorkStateTransition(&creation, sec)
+		_, err = work.CreateWorkStateTransition(&creation, sec)
 		Expect(err).To(BeNil())
 
 		// assert: event
@@ -428,17 +431,17 @@ func TestCreateWorkStateTransition(t *testing.T) {
 		Expect(testDatabase.DS.GormDB(context.Background()).Model(&domain.WorkProcessStep{}).Scan(&processSteps).Error).To(BeNil())
 		Expect(processSteps).ToNot(BeNil())
 		Expect(len(processSteps)).To(Equal(2))
-		Expect(processSteps[0]).To(Equal(domain.WorkProcessStep{WorkID: detail.ID, FlowID: detail.FlowID,
+		Expect(processSteps[0]).To(Equal(domain.WorkProcessStep{WorkID: detail.ID, FlowID: detail.FlowID, FlowVersion: 1,
 			StateName: creation.FromState, StateCategory: 1, BeginTime: detail.CreateTime, EndTime: handleTimestamp,
 			CreatorID: sec.Identity.ID, CreatorName: sec.Identity.Nickname,
 			NextStateName: creation.ToState, NextStateCategory: state.InProcess}))
-		Expect(processSteps[1]).To(Equal(domain.WorkProcessStep{WorkID: detail.ID, FlowID: detail.FlowID,
+		Expect(processSteps[1]).To(Equal(domain.WorkProcessStep{WorkID: detail.ID, FlowID: detail.FlowID, FlowVersion: 1,
 			CreatorID: sec.Identity.ID, CreatorName: sec.Identity.Nickname,
 			StateName: creation.ToState, StateCategory: 2, BeginTime: handleTimestamp, EndTime: types.Timestamp{}}))
 
 		// do: transit to done state
 		creation = domain.WorkProcessStepCreation{FlowID: detail.FlowID, WorkID: detail.ID, FromState: "DOING", ToState: "DONE"}
-		err = work.CreateWorkStateTransition(&creation, sec)
+		_, err = work.CreateWorkStateTransition(&creation, sec)
 		Expect(err).To(BeNil())
 		// assert: processEndTime should be set
 		detail, err = work.DetailWork(detail.Identifier, sec)
@@ -449,7 +452,7 @@ func TestCreateWorkStateTransition(t *testing.T) {
 
 		// do: transit back to process state
 		creation = domain.WorkProcessStepCreation{FlowID: detail.FlowID, WorkID: detail.ID, FromState: "DONE", ToState: "PENDING"}
-		err = work.CreateWorkStateTransition(&creation, sec)
+		_, err = work.CreateWorkStateTransition(&creation, sec)
 		Expect(err).To(BeNil())
 		// assert: processEndTime should be reset to nil
 		detail, err = work.DetailWork(detail.Identifier, sec)
@@ -459,3 +462,363 @@ func TestCreateWorkStateTransition(t *testing.T) {
 		Expect(detail.ProcessEndTime.IsZero()).To(BeTrue())
 	})
 }
+
+func TestCreateWorkStateTransitionOnPinnedVersion(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should transit work by the version of workflow which it is running on", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		workflowCreation := &flow.WorkflowCreation{Name: "test workflow", ProjectID: project1.ID, StateMachine: domain.GenericWorkflowTemplate.StateMachine}
+		workflow, err := flow.CreateWorkflow(workflowCreation, sec)
+		Expect(err).To(BeNil())
+		detail := buildWork("test work", workflow.ID, project1.ID, sec)
+		Expect(detail.FlowVersion).To(Equal(1))
+
+		// version 2: PENDING is renamed to QUEUED, the work keeps running on version 1
+		Expect(flow.UpdateWorkflowState(workflow.ID, flow.WorkflowStateUpdating{OriginName: "PENDING", Name: "QUEUED", Order: 1}, sec)).To(BeNil())
+		detail, err = work.DetailWork(detail.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.State.Name).To(Equal("PENDING"))
+		Expect(detail.Type.Version).To(Equal(2))
+
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID, FromState: "PENDING", ToState: "DOING"}, sec)
+		Expect(err).To(BeNil())
+
+		var processSteps []domain.WorkProcessStep
+		Expect(testDatabase.DS.GormDB(context.Background()).Where(&domain.WorkProcessStep{WorkID: detail.ID}).
+			Order("begin_time ASC").Find(&processSteps).Error).To(BeNil())
+		Expect(len(processSteps)).To(Equal(2))
+		Expect(processSteps[1].FlowVersion).To(Equal(1))
+		Expect(processSteps[1].StateName).To(Equal("DOING"))
+
+		// after migrated, the work runs on version 2
+		_, err = flow.MigrateWorks(workflow.ID, &flow.WorkMigration{FromVersion: 1, ToVersion: 2}, sec)
+		Expect(err).To(BeNil())
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID, FromState: "DOING", ToState: "QUEUED"}, sec)
+		Expect(err).To(BeNil())
+		detail, err = work.DetailWork(detail.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.FlowVersion).To(Equal(2))
+		Expect(detail.StateName).To(Equal("QUEUED"))
+	})
+}
+
+func TestCreateWorkStateTransitionWithRoles(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should only permit callers with roles of transition", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		managerSec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		commonSec := testinfra.BuildSecCtx(types.ID(124), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test workflow", ProjectID: project1.ID, StateMachine: state.StateMachine{
+			States: []state.State{domain.StatePending, domain.StateDoing, domain.StateDone},
+			Transitions: []state.Transition{
+				{Name: "begin", From: domain.StatePending.Name, To: domain.StateDoing.Name},
+				{Name: "finish", From: domain.StateDoing.Name, To: domain.StateDone.Name, Guards: state.Guards{{Type: state.GuardProjectRole, Name: domain.ProjectRoleManager}}},
+			},
+		}}, managerSec)
+		Expect(err).To(BeNil())
+		detail := buildWork("test work", workflow.ID, project1.ID, commonSec)
+
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDoing.Name}, commonSec)).To(BeNil())
+		detail, err = work.DetailWork(detail.ID.String(), commonSec)
+		Expect(err).To(BeNil())
+		Expect(detail.Transitions).To(Equal([]flow.TransitionPermission{{Transition: workflow.StateMachine.Transitions[1], Permitted: false}}))
+
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StateDoing.Name, ToState: domain.StateDone.Name}, commonSec)
+		Expect(err).To(Equal(&bizerror.ErrTransitionRefused{Transition: "finish", Reasons: []string{"role manager is required"}}))
+
+		detail, err = work.DetailWork(detail.ID.String(), managerSec)
+		Expect(err).To(BeNil())
+		Expect(detail.Transitions).To(Equal([]flow.TransitionPermission{{Transition: workflow.StateMachine.Transitions[1], Permitted: true}}))
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StateDoing.Name, ToState: domain.StateDone.Name}, managerSec)).To(BeNil())
+	})
+}
+
+func TestCreateWorkStateTransitionWithRequiredProperties(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should refuse to enter state until required properties are filled", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test workflow", ProjectID: project1.ID,
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine,
+			PropertyDefinitions: []domain.PropertyDefinition{
+				{Name: "estimate", Type: domain.PropTypeNumber, RequiredStates: domain.PropertyStates{domain.StateDoing.Name}, DefaultValue: "1"},
+				{Name: "resolution", Type: domain.PropTypeText, RequiredStates: domain.PropertyStates{domain.StateDone.Name}},
+			},
+		}, sec)
+		Expect(err).To(BeNil())
+		detail := buildWork("test work", workflow.ID, project1.ID, sec)
+
+		values, err := work.QueryWorkPropertyValues([]types.ID{detail.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(values[0].PropertyValues[0].Name).To(Equal("estimate"))
+		Expect(values[0].PropertyValues[0].Value).To(Equal("1"))
+
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDoing.Name}, sec)).To(BeNil())
+
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StateDoing.Name, ToState: domain.StateDone.Name}, sec)
+		Expect(err).To(Equal(&bizerror.ErrPropertiesRequired{State: domain.StateDone.Name, Properties: []string{"resolution"}}))
+
+		_, err = work.AssignWorkPropertyValue(work.WorkPropertyAssign{WorkId: detail.ID, Name: "resolution", Value: "fixed"}, sec)
+		Expect(err).To(BeNil())
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StateDoing.Name, ToState: domain.StateDone.Name}, sec)).To(BeNil())
+	})
+}
+
+func TestWipLimits(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should refuse works entering full state unless the limit is soft", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		pending, doing := domain.StatePending, domain.StateDoing
+		pending.WipLimit, pending.WipSoft = 1, true
+		doing.WipLimit = 1
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "kanban", ProjectID: project1.ID, StateMachine: state.StateMachine{
+			States:      []state.State{pending, doing, domain.StateDone},
+			Transitions: []state.Transition{{Name: "begin", From: pending.Name, To: doing.Name}, {Name: "finish", From: doing.Name, To: domain.StateDone.Name}},
+		}}, sec)
+		Expect(err).To(BeNil())
+		work1 := buildWork("work 1", workflow.ID, project1.ID, sec)
+		work2 := buildWork("work 2", workflow.ID, project1.ID, sec)
+
+		loads, err := flow.QueryStateLoads(workflow, sec)
+		Expect(err).To(BeNil())
+		Expect(loads).To(Equal([]domain.StateLoad{{State: pending.Name, Works: 2, WipLimit: 1, Exceeded: true},
+			{State: doing.Name, Works: 0, WipLimit: 1}, {State: domain.StateDone.Name}}))
+
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: work1.ID,
+			FromState: pending.Name, ToState: doing.Name}, sec)).To(BeNil())
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: work2.ID,
+			FromState: pending.Name, ToState: doing.Name}, sec)
+		Expect(err).To(Equal(&bizerror.ErrWipLimitExceeded{State: doing.Name, WipLimit: 1, Works: 1}))
+
+		_, err = work.CreateWork(&domain.WorkCreation{Name: "work 3", ProjectID: project1.ID, FlowID: workflow.ID, InitialStateName: doing.Name}, sec)
+		Expect(err).To(Equal(&bizerror.ErrWipLimitExceeded{State: doing.Name, WipLimit: 1, Works: 1}))
+
+		detail, err := work.CreateWork(&domain.WorkCreation{Name: "work 4", ProjectID: project1.ID, FlowID: workflow.ID, InitialStateName: pending.Name}, sec)
+		Expect(err).To(BeNil())
+		Expect(detail.Warnings).To(Equal(state.Findings{{Level: state.FindingLevelWarning, Code: "state.wip_limit_exceeded",
+			Message: "wip limit of state PENDING is exceeded: 1 works, limit 1", State: pending.Name}}))
+
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: work1.ID,
+			FromState: doing.Name, ToState: domain.StateDone.Name}, sec)).To(BeNil())
+		warnings, err := work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: work2.ID,
+			FromState: pending.Name, ToState: doing.Name}, sec)
+		Expect(err).To(BeNil())
+		Expect(warnings).To(BeNil())
+	})
+}
+
+func TestCreateWorkStateTransitionWithCommentAndReason(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should record comment and reason of transition", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, persistedEvents, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		rejected := state.State{Name: "REJECTED", Category: state.Rejected, Reasons: state.Reasons{"duplicated", "won't fix"}}
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test workflow", ProjectID: project1.ID, StateMachine: state.StateMachine{
+			States: []state.State{domain.StatePending, domain.StateDoing, rejected},
+			Transitions: []state.Transition{{Name: "begin", From: domain.StatePending.Name, To: domain.StateDoing.Name},
+				{Name: "reject", From: domain.StateDoing.Name, To: rejected.Name}},
+		}}, sec)
+		Expect(err).To(BeNil())
+		detail := buildWork("test work", workflow.ID, project1.ID, sec)
+
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDoing.Name, Reason: "duplicated"}, sec)
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("reason is not accepted by state DOING")}))
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDoing.Name, Comment: "start now"}, sec)).To(BeNil())
+
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StateDoing.Name, ToState: rejected.Name, Reason: "invalid"}, sec)
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("reason invalid is not defined by state REJECTED")}))
+		*persistedEvents = []event.EventRecord{}
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StateDoing.Name, ToState: rejected.Name, Comment: "see #12", Reason: "duplicated"}, sec)).To(BeNil())
+
+		Expect(len(*persistedEvents)).To(Equal(1))
+		Expect((*persistedEvents)[0].UpdatedProperties).To(Equal(event.UpdatedProperties{
+			{PropertyName: "StateName", PropertyDesc: "StateName", OldValue: "DOING", OldValueDesc: "DOING", NewValue: "REJECTED", NewValueDesc: "REJECTED"},
+			{PropertyName: "Comment", PropertyDesc: "Comment", NewValue: "see #12", NewValueDesc: "see #12"},
+			{PropertyName: "Reason", PropertyDesc: "Reason", NewValue: "duplicated", NewValueDesc: "duplicated"},
+		}))
+
+		steps, err := work.QueryProcessSteps(&domain.WorkProcessStepQuery{WorkID: detail.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(len(*steps)).To(Equal(3))
+		Expect((*steps)[0].Comment).To(BeEmpty())
+		Expect((*steps)[1].Comment).To(Equal("start now"))
+		Expect((*steps)[2].Comment).To(Equal("see #12"))
+		Expect((*steps)[2].Reason).To(Equal("duplicated"))
+	})
+}
+
+func TestCreateWorkStateTransitionWithGuardsAndHooks(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	buildGuardedWorkflow := func(projectID types.ID, s *session.Session) *domain.WorkflowDetail {
+		workflowCreation := &flow.WorkflowCreation{Name: "guarded workflow", ProjectID: projectID, StateMachine: state.StateMachine{
+			States: []state.State{domain.StatePending, domain.StateDone},
+			Transitions: []state.Transition{{Name: "close", From: domain.StatePending.Name, To: domain.StateDone.Name,
+				Guards: state.Guards{
+					{Type: state.GuardChecklistDone},
+					{Type: state.GuardPropertySet, Name: "resolution"},
+					{Type: state.GuardProjectRole, Name: domain.ProjectRoleManager},
+				},
+				Hooks: state.Hooks{
+					{Type: state.HookSetProperty, Name: "closeNote", Value: "closed by hook"},
+					{Type: state.HookAddLabel, Name: "closed"},
+					{Type: state.HookEmitEvent, Name: "work-closed"},
+				},
+			}},
+		}}
+		workflow, err := flow.CreateWorkflow(workflowCreation, s)
+		Expect(err).To(BeNil())
+		_, err = flow.CreatePropertyDefinition(workflow.ID, domain.PropertyDefinition{Name: "resolution", Type: domain.PropTypeText}, s)
+		Expect(err).To(BeNil())
+		_, err = flow.CreatePropertyDefinition(workflow.ID, domain.PropertyDefinition{Name: "closeNote", Type: domain.PropTypeText}, s)
+		Expect(err).To(BeNil())
+		return workflow
+	}
+
+	t.Run("should refuse transition with all reasons when guards are not satisfied", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, persistedEvents, handedEvents := workProgressTestSetup(t, &testDatabase)
+
+		managerSec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		commonSec := testinfra.BuildSecCtx(types.ID(124), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		workflow := buildGuardedWorkflow(project1.ID, managerSec)
+		detail := buildWork("test work", workflow.ID, project1.ID, commonSec)
+		_, err := checklist.CreateCheckItem(checklist.CheckItemCreation{Name: "item", WorkId: detail.ID}, commonSec)
+		Expect(err).To(BeNil())
+
+		*persistedEvents = []event.EventRecord{}
+		*handedEvents = []event.EventRecord{}
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDone.Name}, commonSec)
+		Expect(err).To(Equal(&bizerror.ErrTransitionRefused{Transition: "close",
+			Reasons: []string{"1 check items are not done", "property resolution is not set", "role manager is required"}}))
+		Expect(len(*persistedEvents)).To(BeZero())
+		Expect(*handedEvents).To(Equal(*persistedEvents))
+
+		detail, err = work.DetailWork(detail.ID.String(), commonSec)
+		Expect(err).To(BeNil())
+		Expect(detail.StateName).To(Equal(domain.StatePending.Name))
+	})
+
+	t.Run("should perform hooks in transition when guards are satisfied", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, persistedEvents, handedEvents := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		workflow := buildGuardedWorkflow(project1.ID, sec)
+		detail := buildWork("test work", workflow.ID, project1.ID, sec)
+		l, err := label.CreateLabel(label.LabelCreation{Name: "closed", ThemeColor: "red", ProjectID: project1.ID}, sec)
+		Expect(err).To(BeNil())
+		_, err = work.AssignWorkPropertyValue(work.WorkPropertyAssign{WorkId: detail.ID, Name: "resolution", Value: "fixed"}, sec)
+		Expect(err).To(BeNil())
+
+		*persistedEvents = []event.EventRecord{}
+		*handedEvents = []event.EventRecord{}
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDone.Name}, sec)
+		Expect(err).To(BeNil())
+
+		Expect(len(*persistedEvents)).To(Equal(4))
+		Expect((*persistedEvents)[1].Event).To(Equal(event.Event{SourceId: detail.ID, SourceType: "WORK", SourceDesc: detail.Identifier,
+			CreatorId: sec.Identity.ID, CreatorName: sec.Identity.Name, EventCategory: event.EventCategoryPropertyUpdated,
+			UpdatedProperties: []event.UpdatedProperty{{PropertyName: "closeNote", PropertyDesc: "closeNote",
+				NewValue: "closed by hook", NewValueDesc: "closed by hook"}}}))
+		Expect((*persistedEvents)[2].Event).To(Equal(event.Event{SourceId: detail.ID, SourceType: "WORK", SourceDesc: detail.Identifier,
+			CreatorId: sec.Identity.ID, CreatorName: sec.Identity.Name, EventCategory: event.EventCategoryRelationUpdated,
+			UpdatedRelations: []event.UpdatedRelation{{PropertyName: "Label", PropertyDesc: "Label", TargetType: "LABEL", TargetTypeDesc: "Label",
+				NewTargetId: l.ID.String(), NewTargetDesc: "closed"}}}))
+		Expect((*persistedEvents)[3].Event).To(Equal(event.Event{SourceId: detail.ID, SourceType: "WORK", SourceDesc: detail.Identifier,
+			CreatorId: sec.Identity.ID, CreatorName: sec.Identity.Name, EventCategory: event.EventCategoryExtensionUpdated,
+			UpdatedProperties: []event.UpdatedProperty{{PropertyName: "TransitionHook", PropertyDesc: "TransitionHook", NewValue: "work-closed"}}}))
+		Expect(*handedEvents).To(Equal(*persistedEvents))
+
+		values, err := work.QueryWorkPropertyValues([]types.ID{detail.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(len(values)).To(Equal(1))
+		for _, v := range values[0].PropertyValues {
+			if v.Name == "closeNote" {
+				Expect(v.Value).To(Equal("closed by hook"))
+			}
+		}
+
+		detail, err = work.DetailWork(detail.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.StateName).To(Equal(domain.StateDone.Name))
+		Expect(detail.Labels).To(Equal([]label.LabelBrief{{ID: l.ID, Name: "closed", ThemeColor: "red"}}))
+	})
+
+	t.Run("should rollback transition when hook failed", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, persistedEvents, handedEvents := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		workflow := buildGuardedWorkflow(project1.ID, sec)
+		detail := buildWork("test work", workflow.ID, project1.ID, sec)
+		_, err := work.AssignWorkPropertyValue(work.WorkPropertyAssign{WorkId: detail.ID, Name: "resolution", Value: "fixed"}, sec)
+		Expect(err).To(BeNil())
+
+		// label 'closed' is not exist
+		*persistedEvents = []event.EventRecord{}
+		*handedEvents = []event.EventRecord{}
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDone.Name}, sec)
+		Expect(err).To(Equal(bizerror.ErrLabelNotFound))
+		Expect(len(*handedEvents)).To(BeZero())
+
+		detail, err = work.DetailWork(detail.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.StateName).To(Equal(domain.StatePending.Name))
+	})
+	t.Run("should refuse transition with unsupported hook", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, handedEvents := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "hooked workflow", ProjectID: project1.ID, StateMachine: state.StateMachine{
+			States: []state.State{domain.StatePending, domain.StateDone},
+			Transitions: []state.Transition{{Name: "close", From: domain.StatePending.Name, To: domain.StateDone.Name,
+				Hooks: state.Hooks{{Type: "sendMail", Name: "owner"}}}},
+		}}, sec)
+		Expect(err).To(BeNil())
+		detail := buildWork("test work", workflow.ID, project1.ID, sec)
+
+		*handedEvents = []event.EventRecord{}
+		_, err = work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDone.Name}, sec)
+		Expect(err).To(Equal(&bizerror.ErrHookUnsupported{Transition: "close", Hook: "sendMail"}))
+		Expect(len(*handedEvents)).To(BeZero())
+	})
+}
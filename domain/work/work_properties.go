@@ -101,6 +101,24 @@ func AssignWorkPropertyValue(req WorkPropertyAssign, c *session.Session) (*WorkP
 	return r, nil
 }
 
+// assignDefaultPropertyValues assigns the default values of property definitions to the new created work
+func assignDefaultPropertyValues(tx *gorm.DB, w *domain.Work) error {
+	var definitions []flow.WorkflowPropertyDefinition
+	if err := tx.Where("workflow_id = ?", w.FlowID).Find(&definitions).Error; err != nil {
+		return err
+	}
+	for _, d := range definitions {
+		if d.DefaultValue == "" {
+			continue
+		}
+		r := &WorkPropertyValueRecord{WorkId: w.ID, Name: d.Name, Value: d.DefaultValue, PropertyDefinitionId: d.ID, Type: d.Type}
+		if err := tx.Create(r).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func IsPropertyDefinitionReferencedByWork(propDefinitionId types.ID, tx *gorm.DB) error {
 	r := WorkPropertyValueRecord{}
 	if err := tx.Model(&r).Where("property_definition_id = ?", propDefinitionId).First(&r).Error; err == gorm.ErrRecordNotFound {
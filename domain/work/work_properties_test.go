@@ -33,7 +33,8 @@ func workPropertiesTestSetup(t *testing.T, testDatabase **testinfra.TestDatabase
 	Expect(db.DS.GormDB(context.Background()).AutoMigrate(&work.WorkLabelRelation{}, &label.Label{}, &domain.Project{},
 		&domain.ProjectMember{}, &domain.Work{}, &domain.WorkProcessStep{},
 		&flow.WorkflowPropertyDefinition{}, &work.WorkPropertyValueRecord{},
-		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &checklist.CheckItem{}).Error).To(BeNil())
+		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &domain.WorkflowVersion{}, &checklist.CheckItem{},
+		&event.EventRecord{}).Error).To(BeNil())
 
 	persistence.ActiveDataSourceManager = db.DS
 
@@ -0,0 +1,186 @@
+package work
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/domain/work/checklist"
+	"flywheel/persistence"
+	"flywheel/session"
+	"strings"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+var SimulateTransitionsFunc = SimulateTransitions
+
+// TransitionSimulation describes the work to be simulated, which is either an existing work WorkID or a hypothetical one
+// in state StateName. PropertyValues and UndoneCheckItems override the ones of the work, and StateMachine replaces the
+// definition of workflow to see what happens if the workflow is changed.
+type TransitionSimulation struct {
+	WorkID           types.ID            `json:"workId"`
+	StateName        string              `json:"stateName"`
+	PropertyValues   map[string]string   `json:"propertyValues"`
+	UndoneCheckItems *int                `json:"undoneCheckItems"`
+	StateMachine     *state.StateMachine `json:"stateMachine"`
+}
+
+// TransitionOutcome is what happens when the transition is performed, the transition is refused if Permitted is false
+// or there are any Refusals
+type TransitionOutcome struct {
+	state.Transition
+	Permitted bool     `json:"permitted"`
+	Refusals  []string `json:"refusals"`
+
+	StateCategory    state.Category  `json:"stateCategory"`
+	ProcessBeginTime types.Timestamp `json:"processBeginTime"`
+	ProcessEndTime   types.Timestamp `json:"processEndTime"`
+}
+
+type simulatedWork struct {
+	domain.Work
+	values      map[string]string // keyed by lower case name
+	undoneItems int
+}
+
+// SimulateTransitions evaluates all transitions from the state of the simulated work, nothing is written
+func SimulateTransitions(flowID types.ID, sim *TransitionSimulation, s *session.Session) ([]TransitionOutcome, error) {
+	workflow, err := flow.DetailWorkflowFunc(flowID, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var outcomes []TransitionOutcome
+	err = persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		w, err := loadSimulatedWork(tx, workflow, sim, s)
+		if err != nil {
+			return err
+		}
+		if w.FlowVersion != workflow.Version && sim.StateMachine == nil {
+			if workflow, err = flow.DetailWorkflowVersionFunc(flowID, w.FlowVersion, s); err != nil {
+				return err
+			}
+		}
+		stateMachine := &workflow.StateMachine
+		if sim.StateMachine != nil {
+			if findings := sim.StateMachine.Validate(); findings.HasError() {
+				return &state.ErrStateMachineInvalid{Findings: findings.Errors()}
+			}
+			stateMachine = sim.StateMachine
+		}
+		current, found := stateMachine.FindState(w.StateName)
+		if !found {
+			return bizerror.ErrUnknownState
+		}
+		w.StateCategory = current.Category
+		definitions, err := loadPropertyDefinitions(workflow, tx)
+		if err != nil {
+			return err
+		}
+
+		now := types.CurrentTimestamp()
+		outcomes = []TransitionOutcome{}
+		for _, t := range stateMachine.AvailableTransitions(w.StateName, "") {
+			target, _ := stateMachine.FindState(t.To)
+			outcome := TransitionOutcome{Transition: t, Refusals: []string{}, StateCategory: target.Category,
+				Permitted:        t.PermittedTo(func(role string) bool { return s.Perms.HasProjectRole(role, w.ProjectID) }),
+				ProcessBeginTime: w.ProcessBeginTime, ProcessEndTime: w.ProcessEndTime}
+
+			reasons, err := guardRefusals(t, w, s)
+			if err != nil {
+				return err
+			}
+			outcome.Refusals = append(outcome.Refusals, reasons...)
+			blockers, err := checkWorkNotBlocked(tx, &w.Work, target)
+			if err != nil {
+				return err
+			}
+			outcome.Refusals = append(outcome.Refusals, blockers...)
+			if missing := missingRequiredProperties(definitions, stateMachine, target, w.values); len(missing) > 0 {
+				outcome.Refusals = append(outcome.Refusals, (&bizerror.ErrPropertiesRequired{State: target.Name, Properties: missing}).Error())
+			}
+			if _, err := flow.CountStateLoad(tx, workflow.ID, target); err != nil {
+				var wipErr *bizerror.ErrWipLimitExceeded
+				if !errors.As(err, &wipErr) {
+					return err
+				}
+				outcome.Refusals = append(outcome.Refusals, wipErr.Error())
+			}
+
+			// the same as the process timestamps are maintained in CreateWorkStateTransition
+			moved := w.Work
+			moved.EnterProcessState(target.Category, now)
+			outcome.ProcessBeginTime, outcome.ProcessEndTime = moved.ProcessBeginTime, moved.ProcessEndTime
+			outcomes = append(outcomes, outcome)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+func loadSimulatedWork(tx *gorm.DB, workflow *domain.WorkflowDetail, sim *TransitionSimulation, s *session.Session) (*simulatedWork, error) {
+	w := &simulatedWork{values: map[string]string{}}
+	if sim.WorkID == 0 {
+		if sim.StateName == "" {
+			return nil, &bizerror.ErrBadParam{Cause: errors.New("workId or stateName is required")}
+		}
+		w.Work = domain.Work{FlowID: workflow.ID, FlowVersion: workflow.Version, ProjectID: workflow.ProjectID, StateName: sim.StateName}
+	} else {
+		if err := tx.Where("id = ?", sim.WorkID).First(&w.Work).Error; err != nil {
+			return nil, err
+		}
+		if !s.Perms.HasProjectViewPerm(w.ProjectID) {
+			return nil, bizerror.ErrForbidden
+		}
+		if w.FlowID != workflow.ID {
+			return nil, &bizerror.ErrBadParam{Cause: errors.New("work " + w.ID.String() + " is not running on workflow " + workflow.ID.String())}
+		}
+		if sim.StateName != "" {
+			w.StateName = sim.StateName
+		}
+
+		var values []WorkPropertyValueRecord
+		if err := tx.Where("work_id = ?", w.ID).Find(&values).Error; err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			w.values[strings.ToLower(v.Name)] = v.Value
+		}
+		items, err := checklist.InnerListWorksCheckItemsFunc([]types.ID{w.ID}, tx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if !item.Done {
+				w.undoneItems++
+			}
+		}
+	}
+
+	for name, value := range sim.PropertyValues {
+		w.values[strings.ToLower(name)] = value
+	}
+	if sim.UndoneCheckItems != nil {
+		w.undoneItems = *sim.UndoneCheckItems
+	}
+	return w, nil
+}
+
+// GuardedWork makes simulatedWork a GuardSubject, so that the guards are checked against the overridden values
+func (w *simulatedWork) GuardedWork() *domain.Work {
+	return &w.Work
+}
+
+func (w *simulatedWork) UndoneCheckItems() (int, error) {
+	return w.undoneItems, nil
+}
+
+func (w *simulatedWork) PropertyValue(name string) (string, error) {
+	return w.values[strings.ToLower(name)], nil
+}
@@ -0,0 +1,131 @@
+package work_test
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/domain/work"
+	"flywheel/domain/work/checklist"
+	"flywheel/testinfra"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestSimulateTransitions(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should simulate transitions without writing anything", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "simulated workflow", ProjectID: project1.ID,
+			StateMachine: state.StateMachine{
+				States: []state.State{domain.StatePending, domain.StateDoing, domain.StateDone},
+				Transitions: []state.Transition{
+					{Name: "begin", From: domain.StatePending.Name, To: domain.StateDoing.Name},
+					{Name: "finish", From: domain.StateDoing.Name, To: domain.StateDone.Name, Guards: state.Guards{{Type: state.GuardChecklistDone}}},
+					{Name: "close", From: domain.StatePending.Name, To: domain.StateDone.Name,
+						Guards: state.Guards{{Type: state.GuardProjectRole, Name: domain.ProjectRoleManager}}},
+				},
+			},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "resolution", Type: domain.PropTypeText, RequiredStates: domain.PropertyStates{domain.StateDone.Name}}},
+		}, testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String()))
+		Expect(err).To(BeNil())
+
+		outcomes, err := work.SimulateTransitions(workflow.ID, &work.TransitionSimulation{StateName: domain.StatePending.Name}, sec)
+		Expect(err).To(BeNil())
+		Expect(len(outcomes)).To(Equal(2))
+		Expect(outcomes[0].Name).To(Equal("begin"))
+		Expect(outcomes[0].Permitted).To(BeTrue())
+		Expect(outcomes[0].Refusals).To(BeEmpty())
+		Expect(outcomes[0].StateCategory).To(Equal(state.InProcess))
+		Expect(outcomes[0].ProcessBeginTime.IsZero()).To(BeFalse())
+		Expect(outcomes[0].ProcessEndTime.IsZero()).To(BeTrue())
+		Expect(outcomes[1].Name).To(Equal("close"))
+		Expect(outcomes[1].Permitted).To(BeFalse())
+		Expect(outcomes[1].Refusals).To(Equal([]string{"role manager is required", "properties are required by state DONE: resolution"}))
+		Expect(outcomes[1].StateCategory).To(Equal(state.Done))
+		Expect(outcomes[1].ProcessEndTime.IsZero()).To(BeFalse())
+
+		detail := buildWork("test work", workflow.ID, project1.ID, sec)
+		Expect(work.CreateWorkStateTransition(&domain.WorkProcessStepCreation{FlowID: workflow.ID, WorkID: detail.ID,
+			FromState: domain.StatePending.Name, ToState: domain.StateDoing.Name}, sec)).To(BeNil())
+		_, err = checklist.CreateCheckItem(checklist.CheckItemCreation{Name: "item", WorkId: detail.ID}, sec)
+		Expect(err).To(BeNil())
+		doing, err := work.DetailWork(detail.ID.String(), sec)
+		Expect(err).To(BeNil())
+
+		outcomes, err = work.SimulateTransitions(workflow.ID, &work.TransitionSimulation{WorkID: detail.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(len(outcomes)).To(Equal(1))
+		Expect(outcomes[0].Name).To(Equal("finish"))
+		Expect(outcomes[0].Permitted).To(BeTrue())
+		Expect(outcomes[0].Refusals).To(Equal([]string{"1 check items are not done", "properties are required by state DONE: resolution"}))
+		Expect(outcomes[0].ProcessBeginTime).To(Equal(doing.ProcessBeginTime))
+
+		undone := 0
+		outcomes, err = work.SimulateTransitions(workflow.ID, &work.TransitionSimulation{WorkID: detail.ID,
+			PropertyValues: map[string]string{"Resolution": "fixed"}, UndoneCheckItems: &undone}, sec)
+		Expect(err).To(BeNil())
+		Expect(outcomes[0].Refusals).To(BeEmpty())
+
+		// nothing is written
+		after, err := work.DetailWork(detail.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(after.StateName).To(Equal(domain.StateDoing.Name))
+		Expect(after.ProcessEndTime).To(Equal(doing.ProcessEndTime))
+	})
+
+	t.Run("should refuse to start blocked work", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		workflow, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		blocked := buildWork("blocked", workflow.ID, project1.ID, sec)
+		blocker := buildWork("blocker", workflow.ID, project1.ID, sec)
+		_, err := work.CreateWorkLink(&work.WorkLinkCreation{WorkID: blocker.ID, Type: work.LinkBlocks, TargetID: blocked.ID}, sec)
+		Expect(err).To(BeNil())
+
+		outcomes, err := work.SimulateTransitions(workflow.ID, &work.TransitionSimulation{WorkID: blocked.ID}, sec)
+		Expect(err).To(BeNil())
+		for _, outcome := range outcomes {
+			if outcome.To == domain.StateDoing.Name {
+				Expect(outcome.Refusals).To(Equal([]string{"blocked by " + blocker.Identifier}))
+			} else {
+				Expect(outcome.Refusals).To(BeEmpty())
+			}
+		}
+
+		outcomes, err = work.SimulateTransitions(workflow.ID, &work.TransitionSimulation{WorkID: blocked.ID, StateName: domain.StateDoing.Name}, sec)
+		Expect(err).To(BeNil())
+		for _, outcome := range outcomes {
+			Expect(outcome.Refusals).To(BeEmpty())
+		}
+	})
+
+	t.Run("should check simulation", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "simulated workflow", ProjectID: project1.ID,
+			StateMachine: domain.GenericWorkflowTemplate.StateMachine}, sec)
+		Expect(err).To(BeNil())
+
+		_, err = work.SimulateTransitions(workflow.ID, &work.TransitionSimulation{}, sec)
+		Expect(err).To(BeAssignableToTypeOf(&bizerror.ErrBadParam{}))
+		_, err = work.SimulateTransitions(workflow.ID, &work.TransitionSimulation{StateName: "UNKNOWN"}, sec)
+		Expect(err).To(Equal(bizerror.ErrUnknownState))
+		_, err = work.SimulateTransitions(workflow.ID, &work.TransitionSimulation{StateName: domain.StatePending.Name,
+			StateMachine: &state.StateMachine{}}, sec)
+		Expect(err).To(BeAssignableToTypeOf(&state.ErrStateMachineInvalid{}))
+		_, err = work.SimulateTransitions(workflow.ID, &work.TransitionSimulation{StateName: domain.StatePending.Name},
+			testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_2"))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+}
@@ -0,0 +1,42 @@
+package work
+
+import (
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/session"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestGuardRefusals(t *testing.T) {
+	RegisterTestingT(t)
+
+	transition := state.Transition{Name: "done", Guards: []state.Guard{
+		{Type: state.GuardChecklistDone}, {Type: state.GuardPropertySet, Name: "Owner"}, {Type: state.GuardProjectRole, Name: "manager"}}}
+
+	t.Run("should check simulated values through guard checkers", func(t *testing.T) {
+		w := &simulatedWork{Work: domain.Work{ProjectID: 100}, values: map[string]string{}, undoneItems: 2}
+		reasons, err := guardRefusals(transition, w, nil)
+		Expect(err).To(BeNil())
+		Expect(reasons).To(Equal([]string{"2 check items are not done", "property Owner is not set", "role manager is required"}))
+
+		w = &simulatedWork{Work: domain.Work{ProjectID: 100}, values: map[string]string{"owner": "bob"}}
+		reasons, err = guardRefusals(transition, w, &session.Session{Perms: []string{"manager_100"}})
+		Expect(err).To(BeNil())
+		Expect(reasons).To(BeNil())
+	})
+
+	t.Run("should use checkers registered for simulation", func(t *testing.T) {
+		GuardCheckers["custom"] = func(g state.Guard, subject GuardSubject, s *session.Session) (string, error) {
+			value, err := subject.PropertyValue(g.Name)
+			return "custom " + g.Name + "=" + value, err
+		}
+		defer delete(GuardCheckers, "custom")
+
+		w := &simulatedWork{values: map[string]string{"priority": "high"}}
+		reasons, err := guardRefusals(state.Transition{Guards: []state.Guard{{Type: "custom", Name: "Priority"}, {Type: "unknown"}}}, w, nil)
+		Expect(err).To(BeNil())
+		Expect(reasons).To(Equal([]string{"custom Priority=high", "unsupported guard unknown"}))
+	})
+}
@@ -0,0 +1,261 @@
+package work
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/label"
+	"flywheel/domain/state"
+	"flywheel/domain/work/checklist"
+	"flywheel/event"
+	"flywheel/session"
+	"strconv"
+	"strings"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+// GuardSubject provides the work and the values which guards are checked against, they are the stored ones when
+// a transition is performed, and the overridden ones when transitions are simulated
+type GuardSubject interface {
+	GuardedWork() *domain.Work
+	UndoneCheckItems() (int, error)
+	PropertyValue(name string) (string, error)
+}
+
+// GuardChecker returns a non-empty reason when the guard is not satisfied
+type GuardChecker func(g state.Guard, subject GuardSubject, s *session.Session) (string, error)
+
+// HookPerformer may return an event which will be handled after the transaction committed
+type HookPerformer func(h state.Hook, w *domain.Work, now types.Timestamp, tx *gorm.DB, s *session.Session) (*event.EventRecord, error)
+
+var (
+	GuardCheckers = map[string]GuardChecker{
+		state.GuardChecklistDone: checkChecklistDone,
+		state.GuardPropertySet:   checkPropertySet,
+		state.GuardProjectRole:   checkProjectRole,
+	}
+	HookPerformers = map[string]HookPerformer{
+		state.HookSetProperty: performSetProperty,
+		state.HookAddLabel:    performAddLabel,
+		state.HookEmitEvent:   performEmitEvent,
+	}
+)
+
+func checkTransitionGuards(t state.Transition, w *domain.Work, tx *gorm.DB, s *session.Session) error {
+	reasons, err := guardRefusals(t, &storedWork{work: w, tx: tx}, s)
+	if err != nil {
+		return err
+	}
+	if len(reasons) > 0 {
+		return &bizerror.ErrTransitionRefused{Transition: t.Name, Reasons: reasons}
+	}
+	return nil
+}
+
+// guardRefusals returns the reasons of the guards of transition which are not satisfied by subject
+func guardRefusals(t state.Transition, subject GuardSubject, s *session.Session) ([]string, error) {
+	var reasons []string
+	for _, g := range t.Guards {
+		checker, found := GuardCheckers[g.Type]
+		if !found {
+			reasons = append(reasons, "unsupported guard "+g.Type)
+			continue
+		}
+		reason, err := checker(g, subject, s)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons, nil
+}
+
+// storedWork is the GuardSubject backed by the values of work stored in database
+type storedWork struct {
+	work *domain.Work
+	tx   *gorm.DB
+}
+
+func (w *storedWork) GuardedWork() *domain.Work {
+	return w.work
+}
+
+func (w *storedWork) UndoneCheckItems() (int, error) {
+	items, err := checklist.InnerListWorksCheckItemsFunc([]types.ID{w.work.ID}, w.tx)
+	if err != nil {
+		return 0, err
+	}
+	undone := 0
+	for _, item := range items {
+		if !item.Done {
+			undone++
+		}
+	}
+	return undone, nil
+}
+
+func (w *storedWork) PropertyValue(name string) (string, error) {
+	r := WorkPropertyValueRecord{}
+	err := w.tx.Model(&r).Where("work_id = ? AND name LIKE ?", w.work.ID, name).First(&r).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return "", err
+	}
+	return r.Value, nil
+}
+
+// checkRequiredProperties refuses to enter the target state when some properties required by it have no value
+func checkRequiredProperties(workflow *domain.WorkflowDetail, w *domain.Work, target state.State, tx *gorm.DB) error {
+	definitions, err := loadPropertyDefinitions(workflow, tx)
+	if err != nil {
+		return err
+	}
+	var values []WorkPropertyValueRecord
+	if err := tx.Where("work_id = ?", w.ID).Find(&values).Error; err != nil {
+		return err
+	}
+	filled := map[string]string{}
+	for _, v := range values {
+		filled[strings.ToLower(v.Name)] = v.Value
+	}
+	if missing := missingRequiredProperties(definitions, &workflow.StateMachine, target, filled); len(missing) > 0 {
+		return &bizerror.ErrPropertiesRequired{State: target.Name, Properties: missing}
+	}
+	return nil
+}
+
+// loadPropertyDefinitions: the detail of a frozen version carries its property definitions,
+// the property definitions of current version are loaded from database
+func loadPropertyDefinitions(workflow *domain.WorkflowDetail, tx *gorm.DB) ([]domain.PropertyDefinition, error) {
+	if workflow.PropertyDefinitions != nil {
+		return workflow.PropertyDefinitions, nil
+	}
+	var records []flow.WorkflowPropertyDefinition
+	if err := tx.Where("workflow_id = ?", workflow.ID).Order("name ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	definitions := []domain.PropertyDefinition{}
+	for _, r := range records {
+		definitions = append(definitions, r.PropertyDefinition)
+	}
+	return definitions, nil
+}
+
+// missingRequiredProperties returns the properties required by target state or its ancestors which have no value,
+// values are keyed by lower case name
+func missingRequiredProperties(definitions []domain.PropertyDefinition, sm *state.StateMachine, target state.State, values map[string]string) []string {
+	var missing []string
+	for _, d := range definitions {
+		if d.IsRequiredBy(target.Name, sm) && values[strings.ToLower(d.Name)] == "" {
+			missing = append(missing, d.Name)
+		}
+	}
+	return missing
+}
+
+func performTransitionHooks(t state.Transition, w *domain.Work, now types.Timestamp, tx *gorm.DB, s *session.Session) ([]*event.EventRecord, error) {
+	var events []*event.EventRecord
+	for _, h := range t.Hooks {
+		performer, found := HookPerformers[h.Type]
+		if !found {
+			return nil, &bizerror.ErrHookUnsupported{Transition: t.Name, Hook: h.Type}
+		}
+		ev, err := performer(h, w, now, tx, s)
+		if err != nil {
+			return nil, err
+		}
+		if ev != nil {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+func checkChecklistDone(g state.Guard, subject GuardSubject, s *session.Session) (string, error) {
+	undone, err := subject.UndoneCheckItems()
+	if err != nil {
+		return "", err
+	}
+	if undone > 0 {
+		return strconv.Itoa(undone) + " check items are not done", nil
+	}
+	return "", nil
+}
+
+func checkPropertySet(g state.Guard, subject GuardSubject, s *session.Session) (string, error) {
+	value, err := subject.PropertyValue(g.Name)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "property " + g.Name + " is not set", nil
+	}
+	return "", nil
+}
+
+func checkProjectRole(g state.Guard, subject GuardSubject, s *session.Session) (string, error) {
+	if s == nil || !s.Perms.HasProjectRole(g.Name, subject.GuardedWork().ProjectID) {
+		return "role " + g.Name + " is required", nil
+	}
+	return "", nil
+}
+
+func performSetProperty(h state.Hook, w *domain.Work, now types.Timestamp, tx *gorm.DB, s *session.Session) (*event.EventRecord, error) {
+	d := flow.WorkflowPropertyDefinition{}
+	if err := tx.Model(&d).Where("workflow_id = ? AND name LIKE ?", w.FlowID, h.Name).First(&d).Error; err == gorm.ErrRecordNotFound {
+		return nil, bizerror.ErrPropertyDefinitionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err := d.ValidateValue(h.Value); err != nil {
+		return nil, err
+	}
+
+	origin := WorkPropertyValueRecord{}
+	if err := tx.Where("work_id = ? AND name LIKE ?", w.ID, d.Name).First(&origin).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	r := &WorkPropertyValueRecord{WorkId: w.ID, Name: d.Name, Value: h.Value, PropertyDefinitionId: d.ID, Type: d.Type}
+	if err := tx.Save(r).Error; err != nil {
+		return nil, err
+	}
+	if h.Value == "" {
+		if err := tx.Model(r).Update("value", "").Error; err != nil {
+			return nil, err
+		}
+	}
+	return CreateWorkPropertyUpdatedEvent(w, []event.UpdatedProperty{{
+		PropertyName: d.Name, PropertyDesc: d.Name,
+		OldValue: origin.Value, OldValueDesc: origin.Value, NewValue: h.Value, NewValueDesc: h.Value,
+	}}, &s.Identity, now, tx)
+}
+
+func performAddLabel(h state.Hook, w *domain.Work, now types.Timestamp, tx *gorm.DB, s *session.Session) (*event.EventRecord, error) {
+	var l label.Label
+	if err := tx.Where(&label.Label{Name: h.Name, ProjectID: w.ProjectID}).First(&l).Error; err == gorm.ErrRecordNotFound {
+		return nil, bizerror.ErrLabelNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	r := &WorkLabelRelation{WorkId: w.ID, LabelId: l.ID, CreateTime: now, CreatorId: s.Identity.ID}
+	if err := tx.Save(r).Error; err != nil {
+		return nil, err
+	}
+	return CreateWorkRelationUpdatedEvent(w, []event.UpdatedRelation{{
+		PropertyName: "Label", PropertyDesc: "Label", TargetType: "LABEL", TargetTypeDesc: "Label",
+		NewTargetId: l.ID.String(), NewTargetDesc: l.Name,
+	}}, &s.Identity, now, tx)
+}
+
+func performEmitEvent(h state.Hook, w *domain.Work, now types.Timestamp, tx *gorm.DB, s *session.Session) (*event.EventRecord, error) {
+	return event.CreateEvent("WORK", w.ID, w.Identifier, event.EventCategoryExtensionUpdated,
+		[]event.UpdatedProperty{{
+			PropertyName: "TransitionHook", PropertyDesc: "TransitionHook",
+			NewValue: h.Name, NewValueDesc: h.Value,
+		}}, nil, &s.Identity, now, tx)
+}
@@ -0,0 +1,101 @@
+package work
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/persistence"
+	"flywheel/session"
+
+	"github.com/fundwit/go-commons/types"
+)
+
+var (
+	QueryWorkTransitionsFunc  = QueryWorkTransitions
+	PerformWorkTransitionFunc = PerformWorkTransition
+)
+
+// QueryWorkTransitions returns the outcomes of the transitions out of the current state of work which the caller is
+// permitted to perform, they are simulated by SimulateTransitions and a transition with any refusal can not be performed now
+func QueryWorkTransitions(workID types.ID, s *session.Session) ([]TransitionOutcome, error) {
+	w := domain.Work{}
+	if err := persistence.ActiveDataSourceManager.GormDB(s.Context).Where(&domain.Work{ID: workID}).First(&w).Error; err != nil {
+		return nil, err
+	}
+	if !s.Perms.HasProjectViewPerm(w.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+
+	permitted := []TransitionOutcome{}
+	if !w.ArchiveTime.IsZero() || !s.Perms.HasAnyProjectRole(w.ProjectID) {
+		return permitted, nil
+	}
+	outcomes, err := SimulateTransitionsFunc(w.FlowID, &TransitionSimulation{WorkID: w.ID}, s)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range outcomes {
+		if o.Permitted {
+			permitted = append(permitted, o)
+		}
+	}
+	return permitted, nil
+}
+
+// TransitionPerforming is the optional comment and reason of a transition performed by name
+type TransitionPerforming struct {
+	Comment string `json:"comment"`
+	Reason  string `json:"reason"`
+}
+
+// PerformWorkTransition performs the transition named name out of the current state of work, and returns the warnings of it
+func PerformWorkTransition(workID types.ID, name string, p *TransitionPerforming, s *session.Session) (state.Findings, error) {
+	w, transitions, err := loadWorkTransitions(workID, s)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Perms.HasAnyProjectRole(w.ProjectID) {
+		return nil, bizerror.ErrForbidden
+	}
+
+	var matched []state.Transition
+	for _, t := range transitions {
+		if t.Name == name {
+			matched = append(matched, t)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, &bizerror.ErrBadParam{Cause: errors.New("no transition named " + name + " from state " + w.StateName)}
+	}
+	if len(matched) > 1 {
+		return nil, &bizerror.ErrBadParam{Cause: errors.New("more than one transition named " + name + " from state " + w.StateName)}
+	}
+
+	return CreateWorkStateTransitionFunc(&domain.WorkProcessStepCreation{FlowID: w.FlowID, WorkID: w.ID,
+		FromState: w.StateName, ToState: matched[0].To, Comment: p.Comment, Reason: p.Reason}, s)
+}
+
+// loadWorkTransitions loads the work and the transitions out of its current state in the version of workflow it is running on
+func loadWorkTransitions(workID types.ID, s *session.Session) (*domain.Work, []state.Transition, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(s.Context)
+	w := domain.Work{}
+	if err := db.Where(&domain.Work{ID: workID}).First(&w).Error; err != nil {
+		return nil, nil, err
+	}
+	if !s.Perms.HasProjectViewPerm(w.ProjectID) {
+		return nil, nil, bizerror.ErrForbidden
+	}
+
+	workflow, err := flow.DetailWorkflowFunc(w.FlowID, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if workflow.Version != w.FlowVersion {
+		if workflow, err = flow.DetailWorkflowVersionFunc(w.FlowID, w.FlowVersion, s); err != nil {
+			return nil, nil, err
+		}
+	}
+	return &w, workflow.StateMachine.AvailableTransitions(w.StateName, ""), nil
+}
@@ -0,0 +1,124 @@
+package work_test
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/domain/work"
+	"flywheel/testinfra"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkTransitionsByName(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should list and perform transitions of work by name", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		managerSec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		commonSec := testinfra.BuildSecCtx(types.ID(124), domain.ProjectRoleCommon+"_"+project1.ID.String())
+		otherSec := testinfra.BuildSecCtx(types.ID(125), domain.ProjectRoleManager+"_2000")
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test workflow", ProjectID: project1.ID, StateMachine: state.StateMachine{
+			States: []state.State{domain.StatePending, domain.StateDoing, domain.StateDone},
+			Transitions: []state.Transition{
+				{Name: "begin", From: domain.StatePending.Name, To: domain.StateDoing.Name},
+				{Name: "close", From: domain.StatePending.Name, To: domain.StateDone.Name,
+					Guards: state.Guards{{Type: state.GuardProjectRole, Name: domain.ProjectRoleManager}, {Type: state.GuardPropertySet, Name: "resolution"}}},
+				{Name: "finish", From: domain.StateDoing.Name, To: domain.StateDone.Name},
+			},
+		}}, managerSec)
+		Expect(err).To(BeNil())
+		detail := buildWork("test work", workflow.ID, project1.ID, commonSec)
+
+		transitions, err := work.QueryWorkTransitions(detail.ID, commonSec)
+		Expect(err).To(BeNil())
+		Expect(len(transitions)).To(Equal(1))
+		Expect(transitions[0].Transition).To(Equal(workflow.StateMachine.Transitions[0]))
+		Expect(transitions[0].Refusals).To(BeEmpty())
+		transitions, err = work.QueryWorkTransitions(detail.ID, managerSec)
+		Expect(err).To(BeNil())
+		Expect(len(transitions)).To(Equal(2))
+		Expect(transitions[0].Transition).To(Equal(workflow.StateMachine.Transitions[0]))
+		Expect(transitions[1].Transition).To(Equal(workflow.StateMachine.Transitions[1]))
+		Expect(transitions[1].Refusals).To(Equal([]string{"property resolution is not set"}))
+		_, err = work.QueryWorkTransitions(detail.ID, otherSec)
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		_, err = work.PerformWorkTransition(detail.ID, "close", &work.TransitionPerforming{}, commonSec)
+		Expect(err).To(Equal(&bizerror.ErrTransitionRefused{Transition: "close",
+			Reasons: []string{"role manager is required", "property resolution is not set"}}))
+		_, err = work.PerformWorkTransition(detail.ID, "finish", &work.TransitionPerforming{}, commonSec)
+		Expect(err).To(Equal(&bizerror.ErrBadParam{Cause: errors.New("no transition named finish from state PENDING")}))
+		_, err = work.PerformWorkTransition(detail.ID, "begin", &work.TransitionPerforming{}, otherSec)
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+
+		Expect(work.PerformWorkTransition(detail.ID, "begin", &work.TransitionPerforming{}, commonSec)).To(BeNil())
+		detail, err = work.DetailWork(detail.ID.String(), commonSec)
+		Expect(err).To(BeNil())
+		Expect(detail.StateName).To(Equal(domain.StateDoing.Name))
+
+		transitions, err = work.QueryWorkTransitions(detail.ID, commonSec)
+		Expect(err).To(BeNil())
+		Expect(len(transitions)).To(Equal(1))
+		Expect(transitions[0].Transition).To(Equal(workflow.StateMachine.Transitions[2]))
+		Expect(work.PerformWorkTransition(detail.ID, "finish", &work.TransitionPerforming{}, commonSec)).To(BeNil())
+		detail, err = work.DetailWork(detail.ID.String(), commonSec)
+		Expect(err).To(BeNil())
+		Expect(detail.StateName).To(Equal(domain.StateDone.Name))
+	})
+}
+
+func TestWorkTransitionsOfNestedStates(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should inherit transitions from parent states", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		inProgress := state.State{Name: "IN_PROGRESS", Category: state.InProcess}
+		coding := state.State{Name: "CODING", Category: state.InProcess, Parent: inProgress.Name}
+		review := state.State{Name: "REVIEW", Category: state.InProcess, Parent: inProgress.Name}
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "test workflow", ProjectID: project1.ID, StateMachine: state.StateMachine{
+			States: []state.State{domain.StatePending, inProgress, coding, review, domain.StateDone},
+			Transitions: []state.Transition{
+				{Name: "begin", From: domain.StatePending.Name, To: coding.Name},
+				{Name: "review", From: coding.Name, To: review.Name},
+				{Name: "finish", From: inProgress.Name, To: domain.StateDone.Name},
+			},
+		}}, sec)
+		Expect(err).To(BeNil())
+		Expect(workflow.StateMachine.States[2].Parent).To(Equal(inProgress.Name))
+
+		_, err = work.CreateWork(&domain.WorkCreation{Name: "test work", ProjectID: project1.ID, FlowID: workflow.ID,
+			InitialStateName: inProgress.Name}, sec)
+		Expect(err).To(Equal(bizerror.ErrStateInvalid))
+
+		detail := buildWork("test work", workflow.ID, project1.ID, sec)
+		Expect(work.PerformWorkTransition(detail.ID, "begin", &work.TransitionPerforming{}, sec)).To(BeNil())
+		Expect(work.PerformWorkTransition(detail.ID, "review", &work.TransitionPerforming{}, sec)).To(BeNil())
+
+		transitions, err := work.QueryWorkTransitions(detail.ID, sec)
+		Expect(err).To(BeNil())
+		Expect(len(transitions)).To(Equal(1))
+		Expect(transitions[0].Transition).To(Equal(state.Transition{Name: "finish", From: review.Name, To: domain.StateDone.Name}))
+		detail, err = work.DetailWork(detail.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.StateName).To(Equal(review.Name))
+		Expect(detail.ParentStates).To(Equal([]state.State{workflow.StateMachine.States[1]}))
+
+		Expect(work.PerformWorkTransition(detail.ID, "finish", &work.TransitionPerforming{}, sec)).To(BeNil())
+		detail, err = work.DetailWork(detail.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(detail.StateName).To(Equal(domain.StateDone.Name))
+		Expect(detail.ParentStates).To(BeNil())
+	})
+}
@@ -0,0 +1,165 @@
+package work
+
+import (
+	"context"
+	"flywheel/authority"
+	"flywheel/domain"
+	"flywheel/domain/state"
+	"flywheel/domain/work/checklist"
+	"flywheel/persistence"
+	"flywheel/session"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// TriggerEvaluator reports whether the trigger is fired for the work
+type TriggerEvaluator func(t state.Trigger, w *domain.Work, now types.Timestamp, db *gorm.DB) (bool, error)
+
+var (
+	TriggerEvaluators = map[string]TriggerEvaluator{
+		state.TriggerStateTimeout:  evaluateStateTimeout,
+		state.TriggerChecklistDone: evaluateChecklistDone,
+		state.TriggerPropertyValue: evaluatePropertyValue,
+	}
+
+	EvaluateTriggersFunc      = EvaluateTriggers
+	TriggerEvaluationInterval = time.Minute
+)
+
+// StartTriggerEvaluation evaluates triggers periodically until the returned stop function is called
+func StartTriggerEvaluation() (stop func()) {
+	ticker := time.NewTicker(TriggerEvaluationInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if performed, err := EvaluateTriggersFunc(types.CurrentTimestamp()); err != nil {
+					logrus.Warnf("trigger evaluation: %v", err)
+				} else if performed > 0 {
+					logrus.Infof("trigger evaluation: %d transitions are performed", performed)
+				}
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// EvaluateTriggers performs the transitions of which any trigger is fired, and returns the number of performed transitions.
+// Only works running on the current version of workflow are evaluated. The transition is performed as a normal one,
+// it is refused by guards and it fails when the state of work has been changed by others, so several instances can
+// evaluate triggers at the same time: the work is transited by only one of them.
+func EvaluateTriggers(now types.Timestamp) (int, error) {
+	db := persistence.ActiveDataSourceManager.GormDB(context.Background())
+
+	var records []domain.WorkflowStateTransition
+	if err := db.Where("triggers IS NOT NULL AND triggers NOT IN (?)", []string{"", "null", "[]"}).Find(&records).Error; err != nil {
+		return 0, err
+	}
+
+	performed := 0
+	for _, r := range records {
+		workflow := domain.Workflow{}
+		if err := db.Where(&domain.Workflow{ID: r.WorkflowID}).First(&workflow).Error; err != nil {
+			logrus.Warnf("trigger evaluation: failed to load workflow %s: %v", r.WorkflowID, err)
+			continue
+		}
+
+		// a transition declared on a parent state is inherited by the works in its leaf states
+		var stateRecords []domain.WorkflowState
+		if err := db.Where(&domain.WorkflowState{WorkflowID: workflow.ID}).Find(&stateRecords).Error; err != nil {
+			logrus.Warnf("trigger evaluation: failed to load states of workflow %s: %v", workflow.ID, err)
+			continue
+		}
+		stateMachine := state.StateMachine{}
+		for _, record := range stateRecords {
+			stateMachine.States = append(stateMachine.States, state.State{Name: record.Name, Parent: record.Parent})
+		}
+
+		var works []domain.Work
+		if err := db.Where("flow_id = ? AND flow_version = ? AND state_name IN (?) AND archive_time = ?",
+			workflow.ID, workflow.Version, stateMachine.LeafStates(r.FromState), types.Timestamp{}).Find(&works).Error; err != nil {
+			logrus.Warnf("trigger evaluation: failed to load works of transition %s: %v", r.Name, err)
+			continue
+		}
+		for i := range works {
+			w := &works[i]
+			fired, err := isAnyTriggerFired(r.Triggers, w, now, db)
+			if err != nil {
+				logrus.Warnf("trigger evaluation: failed to evaluate triggers of transition %s for work %s: %v", r.Name, w.Identifier, err)
+				continue
+			}
+			if !fired {
+				continue
+			}
+			_, err = CreateWorkStateTransitionFunc(&domain.WorkProcessStepCreation{FlowID: w.FlowID, WorkID: w.ID,
+				FromState: w.StateName, ToState: r.ToState}, triggerSession(w.ProjectID))
+			if err != nil {
+				logrus.Infof("trigger evaluation: transition %s of work %s is not performed: %v", r.Name, w.Identifier, err)
+				continue
+			}
+			performed++
+		}
+	}
+	return performed, nil
+}
+
+// triggerSession acts as a manager of the project which the work belongs to
+func triggerSession(projectID types.ID) *session.Session {
+	return &session.Session{Context: context.Background(), Identity: session.TriggerRobot,
+		Perms: authority.Permissions{domain.ProjectRoleManager + "_" + projectID.String()}}
+}
+
+func isAnyTriggerFired(triggers state.Triggers, w *domain.Work, now types.Timestamp, db *gorm.DB) (bool, error) {
+	for _, t := range triggers {
+		evaluator, found := TriggerEvaluators[t.Type]
+		if !found {
+			continue
+		}
+		fired, err := evaluator(t, w, now, db)
+		if err != nil {
+			return false, err
+		}
+		if fired {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func evaluateStateTimeout(t state.Trigger, w *domain.Work, now types.Timestamp, db *gorm.DB) (bool, error) {
+	deadline := w.StateBeginTime.Time().Add(time.Duration(t.Hours) * time.Hour)
+	return !now.Time().Before(deadline), nil
+}
+
+func evaluateChecklistDone(t state.Trigger, w *domain.Work, now types.Timestamp, db *gorm.DB) (bool, error) {
+	items, err := checklist.InnerListWorksCheckItemsFunc([]types.ID{w.ID}, db)
+	if err != nil {
+		return false, err
+	}
+	for _, item := range items {
+		if !item.Done {
+			return false, nil
+		}
+	}
+	return len(items) > 0, nil
+}
+
+func evaluatePropertyValue(t state.Trigger, w *domain.Work, now types.Timestamp, db *gorm.DB) (bool, error) {
+	r := WorkPropertyValueRecord{}
+	err := db.Model(&r).Where("work_id = ? AND name LIKE ?", w.ID, t.Name).First(&r).Error
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return r.Value == t.Value, nil
+}
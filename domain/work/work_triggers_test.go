@@ -0,0 +1,115 @@
+package work_test
+
+import (
+	"context"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/domain/work"
+	"flywheel/domain/work/checklist"
+	"flywheel/session"
+	"flywheel/testinfra"
+	"testing"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestEvaluateTriggers(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should perform transitions when triggers are fired", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		workflow, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "triggered workflow", ProjectID: project1.ID,
+			StateMachine: state.StateMachine{
+				States: []state.State{domain.StatePending, domain.StateDoing, domain.StateDone},
+				Transitions: []state.Transition{
+					{Name: "begin", From: domain.StatePending.Name, To: domain.StateDoing.Name,
+						Triggers: state.Triggers{{Type: state.TriggerPropertyValue, Name: "status", Value: "go"}}},
+					{Name: "finish", From: domain.StateDoing.Name, To: domain.StateDone.Name,
+						Guards:   state.Guards{{Type: state.GuardProjectRole, Name: domain.ProjectRoleManager}},
+						Triggers: state.Triggers{{Type: state.TriggerChecklistDone}}},
+					{Name: "reopen", From: domain.StateDone.Name, To: domain.StatePending.Name,
+						Triggers: state.Triggers{{Type: state.TriggerStateTimeout, Hours: 1}}},
+				},
+			},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "status", Type: domain.PropTypeText}},
+		}, sec)
+		Expect(err).To(BeNil())
+		detail := buildWork("test work", workflow.ID, project1.ID, sec)
+
+		currentState := func() string {
+			d, err := work.DetailWork(detail.ID.String(), sec)
+			Expect(err).To(BeNil())
+			return d.StateName
+		}
+
+		performed, err := work.EvaluateTriggers(types.CurrentTimestamp())
+		Expect(err).To(BeNil())
+		Expect(performed).To(BeZero())
+
+		_, err = work.AssignWorkPropertyValue(work.WorkPropertyAssign{WorkId: detail.ID, Name: "status", Value: "go"}, sec)
+		Expect(err).To(BeNil())
+		performed, err = work.EvaluateTriggers(types.CurrentTimestamp())
+		Expect(err).To(BeNil())
+		Expect(performed).To(Equal(1))
+		Expect(currentState()).To(Equal(domain.StateDoing.Name))
+
+		item, err := checklist.CreateCheckItem(checklist.CheckItemCreation{Name: "item", WorkId: detail.ID}, sec)
+		Expect(err).To(BeNil())
+		performed, err = work.EvaluateTriggers(types.CurrentTimestamp())
+		Expect(err).To(BeNil())
+		Expect(performed).To(BeZero())
+		done := true
+		Expect(checklist.UpdateCheckItem(item.ID, checklist.CheckItemUpdate{Done: &done}, sec)).To(BeNil())
+		performed, err = work.EvaluateTriggers(types.CurrentTimestamp())
+		Expect(err).To(BeNil())
+		Expect(performed).To(Equal(1))
+		Expect(currentState()).To(Equal(domain.StateDone.Name))
+
+		_, err = work.AssignWorkPropertyValue(work.WorkPropertyAssign{WorkId: detail.ID, Name: "status", Value: "stop"}, sec)
+		Expect(err).To(BeNil())
+		performed, err = work.EvaluateTriggers(types.CurrentTimestamp())
+		Expect(err).To(BeNil())
+		Expect(performed).To(BeZero())
+		performed, err = work.EvaluateTriggers(types.Timestamp(time.Now().Add(2 * time.Hour)))
+		Expect(err).To(BeNil())
+		Expect(performed).To(Equal(1))
+		Expect(currentState()).To(Equal(domain.StatePending.Name))
+
+		steps, err := work.QueryProcessSteps(&domain.WorkProcessStepQuery{WorkID: detail.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(*steps).To(HaveLen(4))
+		Expect((*steps)[3].CreatorID).To(Equal(session.TriggerRobot.ID))
+		Expect((*steps)[3].CreatorName).To(Equal("Trigger Robot"))
+	})
+
+	t.Run("should go on evaluating other transitions when works of one transition can not be loaded", func(t *testing.T) {
+		defer workProgressTestTeardown(t, testDatabase)
+		_, project1, _, _, _ := workProgressTestSetup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(types.ID(123), domain.ProjectRoleManager+"_"+project1.ID.String())
+		_, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "triggered workflow", ProjectID: project1.ID,
+			StateMachine: state.StateMachine{
+				States: []state.State{domain.StatePending, domain.StateDone},
+				Transitions: []state.Transition{
+					{Name: "close", From: domain.StatePending.Name, To: domain.StateDone.Name,
+						Triggers: state.Triggers{{Type: state.TriggerChecklistDone}}},
+					{Name: "reopen", From: domain.StateDone.Name, To: domain.StatePending.Name,
+						Triggers: state.Triggers{{Type: state.TriggerStateTimeout, Hours: 1}}},
+				},
+			},
+		}, sec)
+		Expect(err).To(BeNil())
+
+		testDatabase.DS.GormDB(context.Background()).DropTable(&domain.Work{})
+		performed, err := work.EvaluateTriggers(types.CurrentTimestamp())
+		Expect(err).To(BeNil())
+		Expect(performed).To(BeZero())
+	})
+}
@@ -0,0 +1,160 @@
+package work
+
+import (
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/event"
+	"flywheel/persistence"
+	"flywheel/session"
+	"sort"
+	"strconv"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/jinzhu/gorm"
+)
+
+var ChangeWorkflowFunc = ChangeWorkflow
+
+// WorkflowChangeResult reports which property values are carried over to the new workflow,
+// and which are dropped because the new workflow has no property definition of the same name and type.
+type WorkflowChangeResult struct {
+	Work              domain.Work `json:"work"`
+	CarriedProperties []string    `json:"carriedProperties"`
+	DroppedProperties []string    `json:"droppedProperties"`
+	// Warnings tell the problems which do not stop the change, e.g. the soft wip limit of target state is reached
+	Warnings state.Findings `json:"warnings,omitempty"`
+}
+
+func ChangeWorkflow(id types.ID, c *domain.WorkflowChanging, s *session.Session) (*WorkflowChangeResult, error) {
+	workflow, err := flow.DetailWorkflowFunc(c.FlowID, s)
+	if err != nil {
+		return nil, err
+	}
+
+	result := WorkflowChangeResult{CarriedProperties: []string{}, DroppedProperties: []string{}}
+	var ev *event.EventRecord
+	err = persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
+		w, err := findWorkAndCheckPerms(tx, id, s)
+		if err != nil {
+			return err
+		}
+		if !w.ArchiveTime.IsZero() {
+			return bizerror.ErrArchiveStatusInvalid
+		}
+		if w.ProjectID != workflow.ProjectID {
+			return &bizerror.ErrBadParam{Cause: errors.New("workflow " + workflow.ID.String() + " is not in the project of work")}
+		}
+		if w.FlowID == workflow.ID {
+			return &bizerror.ErrBadParam{Cause: errors.New("work is already running on workflow " + workflow.ID.String())}
+		}
+		target, found := workflow.FindState(flow.MapState(c.StateMapping, w.StateName))
+		if !found || !workflow.StateMachine.IsLeaf(target.Name) {
+			return &bizerror.ErrStatesUnmapped{States: []string{w.StateName}}
+		}
+
+		// the same as a transition, the wip limit of target state is checked before the work enters it
+		if wipWarning, err := flow.CheckWipLimit(tx, workflow.ID, target); err != nil {
+			return err
+		} else if wipWarning != nil {
+			result.Warnings = state.Findings{*wipWarning}
+		}
+
+		now := types.CurrentTimestamp()
+		updates := map[string]interface{}{"flow_id": workflow.ID, "flow_version": workflow.Version,
+			"state_name": target.Name, "state_category": target.Category, "state_begin_time": now}
+		for column, value := range w.EnterProcessState(target.Category, now) {
+			updates[column] = value
+		}
+		if err := tx.Model(&domain.Work{}).Where(&domain.Work{ID: w.ID}).Update(updates).Error; err != nil {
+			return err
+		}
+
+		// the step on the old workflow is closed, a new step is started on the new workflow
+		if err := tx.Model(&domain.WorkProcessStep{}).
+			Where(&domain.WorkProcessStep{WorkID: w.ID, FlowID: w.FlowID, StateName: w.StateName}).
+			Where("end_time = ?", types.Timestamp{}).
+			Update(&domain.WorkProcessStep{EndTime: now, NextStateName: target.Name, NextStateCategory: target.Category}).Error; err != nil {
+			return err
+		}
+		step := domain.WorkProcessStep{WorkID: w.ID, FlowID: workflow.ID, FlowVersion: workflow.Version,
+			CreatorID: s.Identity.ID, CreatorName: s.Identity.Nickname,
+			StateName: target.Name, StateCategory: target.Category, BeginTime: now}
+		if err := tx.Create(step).Error; err != nil {
+			return err
+		}
+
+		if err := carryOverPropertyValues(tx, w.ID, workflow.ID, &result); err != nil {
+			return err
+		}
+		// only the carried values are left, they must fill the properties required by target state
+		if err := checkRequiredProperties(workflow, w, target, tx); err != nil {
+			return err
+		}
+
+		ev, err = CreateWorkPropertyUpdatedEvent(w, []event.UpdatedProperty{
+			{PropertyName: "FlowID", PropertyDesc: "FlowID",
+				OldValue: w.FlowID.String(), OldValueDesc: w.FlowID.String(), NewValue: workflow.ID.String(), NewValueDesc: workflow.Name},
+			{PropertyName: "FlowVersion", PropertyDesc: "FlowVersion",
+				OldValue: strconv.Itoa(w.FlowVersion), OldValueDesc: strconv.Itoa(w.FlowVersion),
+				NewValue: strconv.Itoa(workflow.Version), NewValueDesc: strconv.Itoa(workflow.Version)},
+			{PropertyName: "StateName", PropertyDesc: "StateName",
+				OldValue: w.StateName, OldValueDesc: w.StateName, NewValue: target.Name, NewValueDesc: target.Name},
+		}, &s.Identity, now, tx)
+		if err != nil {
+			return err
+		}
+
+		return tx.Where(&domain.Work{ID: w.ID}).First(&result.Work).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if event.InvokeHandlersFunc != nil {
+		event.InvokeHandlersFunc(ev)
+	}
+
+	return &result, nil
+}
+
+// carryOverPropertyValues rebinds the property values of work to the definitions of workflow with the same name and type,
+// the other values are deleted.
+func carryOverPropertyValues(tx *gorm.DB, workID, workflowID types.ID, result *WorkflowChangeResult) error {
+	var values []WorkPropertyValueRecord
+	if err := tx.Where("work_id = ?", workID).Find(&values).Error; err != nil {
+		return err
+	}
+	var definitions []flow.WorkflowPropertyDefinition
+	if err := tx.Where("workflow_id = ?", workflowID).Find(&definitions).Error; err != nil {
+		return err
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+
+	for _, v := range values {
+		var matched *flow.WorkflowPropertyDefinition
+		for i := range definitions {
+			if definitions[i].Name == v.Name && definitions[i].Type == v.Type {
+				matched = &definitions[i]
+				break
+			}
+		}
+		q := tx.Model(&WorkPropertyValueRecord{}).Where("work_id = ? AND name = ?", v.WorkId, v.Name)
+		if matched != nil {
+			if err := q.Update("property_definition_id", matched.ID).Error; err != nil {
+				return err
+			}
+			result.CarriedProperties = append(result.CarriedProperties, v.Name)
+		} else {
+			if err := q.Delete(&WorkPropertyValueRecord{}).Error; err != nil {
+				return err
+			}
+			result.DroppedProperties = append(result.DroppedProperties, v.Name)
+		}
+	}
+	return nil
+}
@@ -26,6 +26,14 @@ func RegisterWorksRestAPI(r *gin.Engine, middleWares ...gin.HandlerFunc) {
 	g.GET(":id", handleDetail)
 	g.PUT(":id", handleUpdate)
 	g.DELETE(":id", handleDelete)
+	g.PUT(":id/workflow", handleChangeWorkflow)
+	g.GET(":id/transitions", handleQueryTransitions)
+	g.POST(":id/transitions/:name", handlePerformTransition)
+	g.PUT(":id/parent", handleUpdateParent)
+	g.GET(":id/children", handleQueryChildren)
+	g.GET(":id/ancestors", handleQueryAncestors)
+	g.PUT(":id/assignees", handleUpdateParticipants(work.ParticipantAssignee))
+	g.PUT(":id/watchers", handleUpdateParticipants(work.ParticipantWatcher))
 
 	o := r.Group("/v1/work-orders", middleWares...)
 	o.PUT("", handleUpdateOrders)
@@ -91,6 +99,129 @@ func handleUpdate(c *gin.Context) {
 	c.JSON(http.StatusOK, updatedWork)
 }
 
+func handleChangeWorkflow(c *gin.Context) {
+	parsedId, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+	}
+
+	changing := domain.WorkflowChanging{}
+	err = c.ShouldBindBodyWith(&changing, binding.JSON)
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	result, err := work.ChangeWorkflowFunc(parsedId, &changing, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func handleQueryTransitions(c *gin.Context) {
+	parsedId, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+	}
+
+	transitions, err := work.QueryWorkTransitionsFunc(parsedId, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusOK, transitions)
+}
+
+func handlePerformTransition(c *gin.Context) {
+	parsedId, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+	}
+
+	// the body is optional
+	performing := work.TransitionPerforming{}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindBodyWith(&performing, binding.JSON); err != nil {
+			panic(&bizerror.ErrBadParam{Cause: err})
+		}
+	}
+
+	warnings, err := work.PerformWorkTransitionFunc(parsedId, c.Param("name"), &performing, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	if len(warnings) > 0 {
+		c.JSON(http.StatusCreated, gin.H{"warnings": warnings})
+		return
+	}
+	c.Status(http.StatusCreated)
+}
+
+func handleUpdateParent(c *gin.Context) {
+	parsedId, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+	}
+
+	updating := domain.WorkParentUpdating{}
+	err = c.ShouldBindBodyWith(&updating, binding.JSON)
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	err = work.UpdateWorkParentFunc(parsedId, &updating, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.AbortWithStatus(http.StatusOK)
+}
+
+func handleQueryChildren(c *gin.Context) {
+	parsedId, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+	}
+
+	children, err := work.QueryWorkChildrenFunc(parsedId, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusOK, &misc.PagedBody{List: children, Total: uint64(len(children))})
+}
+
+func handleQueryAncestors(c *gin.Context) {
+	parsedId, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+	}
+
+	ancestors, err := work.QueryWorkAncestorsFunc(parsedId, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		panic(err)
+	}
+	c.JSON(http.StatusOK, ancestors)
+}
+
+func handleUpdateParticipants(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parsedId, err := types.ParseID(c.Param("id"))
+		if err != nil {
+			panic(&bizerror.ErrBadParam{Cause: errors.New("invalid id '" + c.Param("id") + "'")})
+		}
+
+		updating := work.WorkParticipantsUpdating{}
+		err = c.ShouldBindBodyWith(&updating, binding.JSON)
+		if err != nil {
+			panic(&bizerror.ErrBadParam{Cause: err})
+		}
+
+		err = work.UpdateWorkParticipantsFunc(parsedId, role, &updating, session.ExtractSessionFromGinContext(c))
+		if err != nil {
+			panic(err)
+		}
+		c.AbortWithStatus(http.StatusOK)
+	}
+}
+
 func handleUpdateOrders(c *gin.Context) {
 	var updating []domain.WorkOrderRangeUpdating
 	err := c.ShouldBindBodyWith(&updating, binding.JSON)
@@ -49,7 +49,7 @@ func beforeEach() {
 		StateMachine:        domain.GenericWorkflowTemplate.StateMachine,
 	}
 	demoWorkflowJson = `{"id": "` + demoWorkflow.ID.String() + `", "name": "` + demoWorkflow.Name +
-		`", "themeColor": "orange", "themeIcon": "el-icon-star-on", "projectId": "` +
+		`", "themeColor": "orange", "themeIcon": "el-icon-star-on", "version": 0, "projectId": "` +
 		demoWorkflow.ProjectID.String() + `", "createTime": "` + timeString + `"}`
 }
 
@@ -85,7 +85,7 @@ func TestCreateWorkAPI(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/v1/works", bytes.NewReader(reqBody))
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusCreated))
-		Expect(body).To(MatchJSON(`{"id":"123","name":"test work", "identifier":"TEST-1","projectId":"333","flowId":"` + demoWorkflow.ID.String() + `", "orderInState": ` +
+		Expect(body).To(MatchJSON(`{"id":"123","name":"test work", "identifier":"TEST-1","projectId":"333","parentId":"0","flowId":"` + demoWorkflow.ID.String() + `", "flowVersion": 0, "orderInState": ` +
 			strconv.FormatInt(demoTime.Time().UnixNano()/1e6, 10) + `, "createTime":"` + timeString + `",
 			"labels": [{"id":"100", "name":"label100", "themeColor":"red"}], "checklist":null,
 			"stateName":"PENDING", "stateCategory": 1, "type": ` + demoWorkflowJson + `,"state":{"name": "PENDING", "category": 1, "order": 1},
@@ -156,11 +156,11 @@ func TestQueryWorkAPI(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/v1/works?name=aaa", nil)
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusOK))
-		Expect(body).To(MatchJSON(`{"data":[{"id":"1","name":"work1","identifier":"W-1","projectId":"333","flowId":"1",
+		Expect(body).To(MatchJSON(`{"data":[{"id":"1","name":"work1","identifier":"W-1","projectId":"333","parentId":"0","flowId":"1","flowVersion":0,
 			"createTime":"` + timeString + `","orderInState": ` + strconv.FormatInt(demoTime.Time().UnixNano()/1e6, 10) + ` ,
 			"stateName":"PENDING", "stateCategory": 1, "state":{"name":"PENDING", "category":1, "order": 1},"checklist":null,
 			"stateBeginTime": null, "processBeginTime": null, "processEndTime": null, "archivedTime": null, "type":null, "labels":null }, 
-			{"id":"2","name":"work2","identifier":"W-2","projectId":"333","flowId":"1", "orderInState": ` + strconv.FormatInt(demoTime.Time().UnixNano()/1e6, 10) + `,
+			{"id":"2","name":"work2","identifier":"W-2","projectId":"333","parentId":"0","flowId":"1","flowVersion":0, "orderInState": ` + strconv.FormatInt(demoTime.Time().UnixNano()/1e6, 10) + `,
 			"createTime":"` + timeString + `","stateName":"DONE", "stateCategory": 3, "state":{"name":"DONE", "category":3, "order": 3},
 			"stateBeginTime": null, "processBeginTime": null, "processEndTime": null, "archivedTime": null,
 			"type":null, "labels":null,"checklist":null
@@ -231,7 +231,7 @@ func TestDetailWorkAPI(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/v1/works/123", nil)
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusOK))
-		Expect(body).To(MatchJSON(`{"id":"123","name":"test work","identifier":"W-1", "projectId":"100","flowId":"` + demoWorkflow.ID.String() + `",
+		Expect(body).To(MatchJSON(`{"id":"123","name":"test work","identifier":"W-1", "projectId":"100","parentId":"0","flowId":"` + demoWorkflow.ID.String() + `", "flowVersion": 0,
 			"createTime":"` + timeString + `","orderInState": 999,
 			"labels": [{"id":"100", "name":"label100", "themeColor":"red"}],
 			"stateName":"DOING", "stateCategory": 2, "state":{"name":"DOING", "category":2, "order": 2},
@@ -288,7 +288,7 @@ func TestUpdateWorkAPI(t *testing.T) {
 		Expect(status).To(Equal(http.StatusOK))
 		Expect(body).To(MatchJSON(`{"id":"100","name":"new-name","identifier":"W-1","stateName":"PENDING", "stateCategory": 1,
 			"stateBeginTime": null, "processBeginTime": null, "processEndTime": null, "archivedTime": null,
-			"projectId":"333","flowId":"1","createTime":"` +
+			"projectId":"333","parentId":"0","flowId":"1","flowVersion":0,"createTime":"` +
 			timeString + `", "orderInState": ` + strconv.FormatInt(demoTime.Time().UnixNano()/1e6, 10) + `}`))
 	})
 }
@@ -330,6 +330,269 @@ func TestDeleteWorkAPI(t *testing.T) {
 	})
 }
 
+func TestChangeWorkflowAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should failed when body bind failed", func(t *testing.T) {
+		beforeEach()
+
+		req := httptest.NewRequest(http.MethodPut, "/v1/works/100/workflow", bytes.NewReader([]byte(`{}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message":"Key: 'WorkflowChanging.FlowID' Error:Field validation for 'FlowID' failed on the 'required' tag","data":null}`))
+	})
+
+	t.Run("should failed when service failed", func(t *testing.T) {
+		beforeEach()
+
+		work.ChangeWorkflowFunc = func(id types.ID, c *domain.WorkflowChanging, s *session.Session) (*work.WorkflowChangeResult, error) {
+			return nil, &bizerror.ErrStatesUnmapped{States: []string{"PENDING"}}
+		}
+		req := httptest.NewRequest(http.MethodPut, "/v1/works/100/workflow", bytes.NewReader([]byte(`{"flowId": "200"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"workflow.states_unmapped","message":"states are not mapped: PENDING","data":["PENDING"]}`))
+	})
+
+	t.Run("should be able to change workflow of work", func(t *testing.T) {
+		beforeEach()
+
+		var workId types.ID
+		var changing *domain.WorkflowChanging
+		work.ChangeWorkflowFunc = func(id types.ID, c *domain.WorkflowChanging, s *session.Session) (*work.WorkflowChangeResult, error) {
+			workId, changing = id, c
+			return &work.WorkflowChangeResult{
+				Work: domain.Work{ID: 100, Name: "w1", Identifier: "W-1", ProjectID: 333, CreateTime: demoTime,
+					FlowID: 200, FlowVersion: 2, StateName: "OPEN", StateCategory: state.InBacklog},
+				CarriedProperties: []string{"priority"}, DroppedProperties: []string{}}, nil
+		}
+		req := httptest.NewRequest(http.MethodPut, "/v1/works/100/workflow", bytes.NewReader([]byte(
+			`{"flowId": "200", "stateMapping": {"PENDING": "OPEN"}}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(workId).To(Equal(types.ID(100)))
+		Expect(*changing).To(Equal(domain.WorkflowChanging{FlowID: 200, StateMapping: map[string]string{"PENDING": "OPEN"}}))
+		Expect(body).To(MatchJSON(`{"work": {"id":"100","name":"w1","identifier":"W-1","stateName":"OPEN", "stateCategory": 1,
+			"stateBeginTime": null, "processBeginTime": null, "processEndTime": null, "archivedTime": null,
+			"projectId":"333","parentId":"0","flowId":"200","flowVersion":2,"createTime":"` + timeString + `", "orderInState": 0},
+			"carriedProperties": ["priority"], "droppedProperties": []}`))
+	})
+}
+
+func TestQueryWorkTransitionsAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should failed when id is invalid", func(t *testing.T) {
+		beforeEach()
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/works/abc/transitions", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+	})
+
+	t.Run("should failed when service failed", func(t *testing.T) {
+		beforeEach()
+
+		work.QueryWorkTransitionsFunc = func(id types.ID, s *session.Session) ([]work.TransitionOutcome, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/works/100/transitions", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+
+	t.Run("should be able to query transitions of work", func(t *testing.T) {
+		beforeEach()
+
+		var workId types.ID
+		work.QueryWorkTransitionsFunc = func(id types.ID, s *session.Session) ([]work.TransitionOutcome, error) {
+			workId = id
+			return []work.TransitionOutcome{
+				{Transition: state.Transition{Name: "begin", From: "PENDING", To: "DOING"}, Permitted: true, Refusals: []string{},
+					StateCategory: state.InProcess},
+				{Transition: state.Transition{Name: "close", From: "PENDING", To: "DONE"}, Permitted: true,
+					Refusals: []string{"1 check items are not done"}, StateCategory: state.Done}}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/works/100/transitions", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(workId).To(Equal(types.ID(100)))
+		Expect(body).To(MatchJSON(`[{"name": "begin", "from": "PENDING", "to": "DOING", "permitted": true, "refusals": [],
+				"stateCategory": 2, "processBeginTime": null, "processEndTime": null},
+			{"name": "close", "from": "PENDING", "to": "DONE", "permitted": true, "refusals": ["1 check items are not done"],
+				"stateCategory": 3, "processBeginTime": null, "processEndTime": null}]`))
+	})
+}
+
+func TestPerformWorkTransitionAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should failed when id is invalid", func(t *testing.T) {
+		beforeEach()
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/works/abc/transitions/begin", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+	})
+
+	t.Run("should failed when service failed", func(t *testing.T) {
+		beforeEach()
+
+		work.PerformWorkTransitionFunc = func(id types.ID, name string, p *work.TransitionPerforming, s *session.Session) (state.Findings, error) {
+			return nil, &bizerror.ErrTransitionRefused{Transition: name, Reasons: []string{"1 check items are not done"}}
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/works/100/transitions/begin", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"workflow.transition_refused",
+			"message":"transition begin is refused: 1 check items are not done","data":["1 check items are not done"]}`))
+	})
+
+	t.Run("should be able to perform transition of work", func(t *testing.T) {
+		beforeEach()
+
+		var workId types.ID
+		var transitionName string
+		var performing *work.TransitionPerforming
+		work.PerformWorkTransitionFunc = func(id types.ID, name string, p *work.TransitionPerforming, s *session.Session) (state.Findings, error) {
+			workId, transitionName, performing = id, name, p
+			if name == "overload" {
+				return state.Findings{{Level: state.FindingLevelWarning, Code: "state.wip_limit_exceeded",
+					Message: "wip limit of state DOING is exceeded: 3 works, limit 3", State: "DOING"}}, nil
+			}
+			return nil, nil
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/works/100/transitions/begin", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(workId).To(Equal(types.ID(100)))
+		Expect(transitionName).To(Equal("begin"))
+		Expect(*performing).To(Equal(work.TransitionPerforming{}))
+
+		req = httptest.NewRequest(http.MethodPost, "/v1/works/100/transitions/reject",
+			bytes.NewReader([]byte(`{"comment": "see #12", "reason": "duplicated"}`)))
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(transitionName).To(Equal("reject"))
+		Expect(*performing).To(Equal(work.TransitionPerforming{Comment: "see #12", Reason: "duplicated"}))
+
+		req = httptest.NewRequest(http.MethodPost, "/v1/works/100/transitions/overload", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(body).To(MatchJSON(`{"warnings": [{"level": "warning", "code": "state.wip_limit_exceeded",
+			"message": "wip limit of state DOING is exceeded: 3 works, limit 3", "state": "DOING"}]}`))
+	})
+}
+
+func TestUpdateWorkParentAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should failed when id is invalid", func(t *testing.T) {
+		beforeEach()
+
+		req := httptest.NewRequest(http.MethodPut, "/v1/works/abc/parent", bytes.NewReader([]byte(`{"parentId": "200"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+	})
+
+	t.Run("should failed when service failed", func(t *testing.T) {
+		beforeEach()
+
+		work.UpdateWorkParentFunc = func(id types.ID, u *domain.WorkParentUpdating, s *session.Session) error {
+			return &bizerror.ErrWorkParentInvalid{Reason: "work is an ancestor of its parent"}
+		}
+		req := httptest.NewRequest(http.MethodPut, "/v1/works/100/parent", bytes.NewReader([]byte(`{"parentId": "200"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"work.parent_invalid",
+			"message":"parent of work is invalid: work is an ancestor of its parent","data":"work is an ancestor of its parent"}`))
+	})
+
+	t.Run("should be able to update parent of work", func(t *testing.T) {
+		beforeEach()
+
+		var workId types.ID
+		var updating *domain.WorkParentUpdating
+		work.UpdateWorkParentFunc = func(id types.ID, u *domain.WorkParentUpdating, s *session.Session) error {
+			workId, updating = id, u
+			return nil
+		}
+		req := httptest.NewRequest(http.MethodPut, "/v1/works/100/parent", bytes.NewReader([]byte(`{"parentId": "200"}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(workId).To(Equal(types.ID(100)))
+		Expect(*updating).To(Equal(domain.WorkParentUpdating{ParentID: 200}))
+	})
+}
+
+func TestQueryWorkHierarchyAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should failed when id is invalid", func(t *testing.T) {
+		beforeEach()
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/works/abc/children", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+
+		req = httptest.NewRequest(http.MethodGet, "/v1/works/abc/ancestors", nil)
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+	})
+
+	t.Run("should failed when service failed", func(t *testing.T) {
+		beforeEach()
+
+		work.QueryWorkChildrenFunc = func(id types.ID, s *session.Session) ([]work.WorkDetail, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		work.QueryWorkAncestorsFunc = func(id types.ID, s *session.Session) ([]domain.Work, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/works/100/children", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+
+		req = httptest.NewRequest(http.MethodGet, "/v1/works/100/ancestors", nil)
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+
+	t.Run("should be able to query children and ancestors of work", func(t *testing.T) {
+		beforeEach()
+
+		var childrenOf, ancestorsOf types.ID
+		work.QueryWorkChildrenFunc = func(id types.ID, s *session.Session) ([]work.WorkDetail, error) {
+			childrenOf = id
+			return []work.WorkDetail{{Work: domain.Work{ID: 200, Identifier: "W-2", Name: "child", ParentID: id}}}, nil
+		}
+		work.QueryWorkAncestorsFunc = func(id types.ID, s *session.Session) ([]domain.Work, error) {
+			ancestorsOf = id
+			return []domain.Work{{ID: 50, Identifier: "W-0", Name: "epic"}}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/works/100/children", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(childrenOf).To(Equal(types.ID(100)))
+		Expect(body).To(ContainSubstring(`"total":1`))
+		Expect(body).To(ContainSubstring(`"id":"200","identifier":"W-2","name":"child"`))
+		Expect(body).To(ContainSubstring(`"parentId":"100"`))
+
+		req = httptest.NewRequest(http.MethodGet, "/v1/works/100/ancestors", nil)
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(ancestorsOf).To(Equal(types.ID(100)))
+		Expect(body).To(ContainSubstring(`[{"id":"50","identifier":"W-0","name":"epic"`))
+	})
+}
+
 func TestCreateArchivedWorksAPI(t *testing.T) {
 	RegisterTestingT(t)
 
@@ -420,3 +683,49 @@ func TestUpdateWorkOrdersAPI(t *testing.T) {
 		Expect(body).To(BeEmpty())
 	})
 }
+
+func TestUpdateWorkParticipantsAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	t.Run("should failed when id is invalid", func(t *testing.T) {
+		beforeEach()
+
+		req := httptest.NewRequest(http.MethodPut, "/v1/works/abc/assignees", bytes.NewReader([]byte(`{"userIds": ["1"]}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+	})
+
+	t.Run("should failed when service failed", func(t *testing.T) {
+		beforeEach()
+
+		work.UpdateWorkParticipantsFunc = func(id types.ID, role string, u *work.WorkParticipantsUpdating, s *session.Session) error {
+			return bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodPut, "/v1/works/100/watchers", bytes.NewReader([]byte(`{"userIds": ["1"]}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+
+	t.Run("should be able to update assignees and watchers of work", func(t *testing.T) {
+		beforeEach()
+
+		var workIds []types.ID
+		var roles []string
+		var updatings []work.WorkParticipantsUpdating
+		work.UpdateWorkParticipantsFunc = func(id types.ID, role string, u *work.WorkParticipantsUpdating, s *session.Session) error {
+			workIds, roles, updatings = append(workIds, id), append(roles, role), append(updatings, *u)
+			return nil
+		}
+		req := httptest.NewRequest(http.MethodPut, "/v1/works/100/assignees", bytes.NewReader([]byte(`{"userIds": ["1", "2"]}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		req = httptest.NewRequest(http.MethodPut, "/v1/works/100/watchers", bytes.NewReader([]byte(`{"userIds": []}`)))
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+
+		Expect(workIds).To(Equal([]types.ID{100, 100}))
+		Expect(roles).To(Equal([]string{work.ParticipantAssignee, work.ParticipantWatcher}))
+		Expect(updatings).To(Equal([]work.WorkParticipantsUpdating{{UserIDs: []types.ID{1, 2}}, {UserIDs: []types.ID{}}}))
+	})
+}
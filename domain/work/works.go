@@ -45,6 +45,22 @@ type WorkDetail struct {
 	Type      *domain.Workflow      `json:"type"`
 	Labels    []label.LabelBrief    `json:"labels"`
 	CheckList []checklist.CheckItem `json:"checklist"`
+
+	Assignees []WorkParticipant `json:"assignees,omitempty"`
+	Watchers  []WorkParticipant `json:"watchers,omitempty"`
+
+	// ParentStates are the ancestors of State, from its parent to the top level one
+	ParentStates []state.State `json:"parentStates,omitempty"`
+
+	// Rollup summarizes the children of work, it is nil when work has no child
+	Rollup *WorkRollup `json:"rollup,omitempty"`
+
+	// Transitions are the transitions from current state of work, only appended in the detail of work
+	Transitions []flow.TransitionPermission `json:"transitions,omitempty"`
+	// Links are the links between work and other works, only appended in the detail of work
+	Links []WorkLinkBrief `json:"links,omitempty"`
+	// Warnings are only appended in the response of creating work, e.g. the soft wip limit of initial state is reached
+	Warnings state.Findings `json:"warnings,omitempty"`
 }
 
 func CreateWork(c *domain.WorkCreation, s *session.Session) (*WorkDetail, error) {
@@ -64,6 +80,13 @@ func CreateWork(c *domain.WorkCreation, s *session.Session) (*WorkDetail, error)
 		if !found {
 			return bizerror.ErrUnknownState
 		}
+		if !workflowDetail.StateMachine.IsLeaf(initialState.Name) {
+			return bizerror.ErrStateInvalid
+		}
+		wipWarning, err := flow.CheckWipLimit(tx, workflowDetail.ID, initialState)
+		if err != nil {
+			return err
+		}
 
 		now := types.CurrentTimestamp()
 		workDetail = &WorkDetail{
@@ -72,8 +95,10 @@ func CreateWork(c *domain.WorkCreation, s *session.Session) (*WorkDetail, error)
 				Name:       c.Name,
 				ProjectID:  c.ProjectID,
 				CreateTime: now,
+				ParentID:   c.ParentID,
 
 				FlowID:         workflowDetail.ID,
+				FlowVersion:    workflowDetail.Version,
 				OrderInState:   now.Time().UnixNano() / 1e6, // oldest
 				StateName:      initialState.Name,
 				StateCategory:  initialState.Category,
@@ -82,6 +107,9 @@ func CreateWork(c *domain.WorkCreation, s *session.Session) (*WorkDetail, error)
 			State: initialState,
 			Type:  &workflowDetail.Workflow,
 		}
+		if wipWarning != nil {
+			workDetail.Warnings = state.Findings{*wipWarning}
+		}
 		if c.PriorityLevel < 0 { // Highest: -1, lowest： 1
 			var highestPriorityWork domain.Work
 			err := tx.Model(&domain.Work{}).Where(&domain.Work{ProjectID: c.ProjectID, StateName: initialState.Name}).
@@ -94,6 +122,10 @@ func CreateWork(c *domain.WorkCreation, s *session.Session) (*WorkDetail, error)
 			}
 		}
 
+		if _, err := checkWorkParent(tx, &workDetail.Work, c.ParentID); err != nil {
+			return err
+		}
+
 		identifier, err := namespace.NextWorkIdentifier(c.ProjectID, tx)
 		if err != nil {
 			return err
@@ -104,13 +136,17 @@ func CreateWork(c *domain.WorkCreation, s *session.Session) (*WorkDetail, error)
 			return err
 		}
 
-		initProcessStep := domain.WorkProcessStep{WorkID: workDetail.ID, FlowID: workDetail.FlowID,
+		initProcessStep := domain.WorkProcessStep{WorkID: workDetail.ID, FlowID: workDetail.FlowID, FlowVersion: workDetail.FlowVersion,
 			CreatorID: s.Identity.ID, CreatorName: s.Identity.Nickname,
 			StateName: workDetail.State.Name, StateCategory: workDetail.State.Category, BeginTime: workDetail.CreateTime}
 		if err := tx.Create(initProcessStep).Error; err != nil {
 			return err
 		}
 
+		if err := assignDefaultPropertyValues(tx, &workDetail.Work); err != nil {
+			return err
+		}
+
 		ev, err = CreateWorkCreatedEvent(&workDetail.Work, &s.Identity, workDetail.CreateTime, tx)
 		if err != nil {
 			return err
@@ -148,6 +184,26 @@ func DetailWork(identifier string, s *session.Session) (*WorkDetail, error) {
 	if err := InnerAppendChecklistsFunc(ws, s); err != nil {
 		return nil, err
 	}
+	if err := InnerAppendRollupsFunc(ws, s); err != nil {
+		return nil, err
+	}
+
+	if ws[0].Type != nil {
+		workflow, err := flow.DetailWorkflowFunc(w.FlowID, s)
+		if err != nil {
+			return nil, err
+		}
+		if workflow.Version != w.FlowVersion {
+			if workflow, err = flow.DetailWorkflowVersionFunc(w.FlowID, w.FlowVersion, s); err != nil {
+				return nil, err
+			}
+		}
+		ws[0].Transitions = flow.PermitTransitions(w.ProjectID, workflow.StateMachine.AvailableTransitions(w.StateName, ""), s)
+	}
+
+	if ws[0].Links, err = queryWorkLinks(persistence.ActiveDataSourceManager.GormDB(s.Context), w.ID); err != nil {
+		return nil, err
+	}
 
 	return &ws[0], nil
 }
@@ -182,7 +238,7 @@ func InnerAppendChecklists(works []WorkDetail, c *session.Session) error {
 	return nil
 }
 
-// ExtendWorks append Work.state type and labels
+// ExtendWorks append Work.state type, labels, assignees and watchers
 func ExtendWorks(workDetails []WorkDetail, s *session.Session) ([]WorkDetail, error) {
 	var err error
 	c := len(workDetails)
@@ -207,12 +263,34 @@ func ExtendWorks(workDetails []WorkDetail, s *session.Session) ([]WorkDetail, er
 			workflowMap[flowId] = workflow
 		}
 	}
+	// load the versions of workflow which works are pinned to
+	versionCache := map[types.ID]map[int]*domain.WorkflowDetail{}
+	for i := 0; i < c; i++ {
+		w := workDetails[i]
+		workflow := workflowCache[w.FlowID]
+		if workflow == nil || workflow.Version == w.FlowVersion || versionCache[w.FlowID][w.FlowVersion] != nil {
+			continue
+		}
+		pinned, err := flow.DetailWorkflowVersionFunc(w.FlowID, w.FlowVersion, s)
+		if err != nil {
+			return nil, err
+		}
+		if versionCache[w.FlowID] == nil {
+			versionCache[w.FlowID] = map[int]*domain.WorkflowDetail{}
+		}
+		versionCache[w.FlowID][w.FlowVersion] = pinned
+	}
 
 	// load labels
 	wls, err := QueryLabelBriefsOfWorkFunc(workIds, s)
 	if err != nil {
 		return nil, err
 	}
+	// load assignees and watchers
+	wps, err := QueryParticipantsOfWorksFunc(workIds, s)
+	if err != nil {
+		return nil, err
+	}
 
 	for i := 0; i < c; i++ {
 		w := workDetails[i] // w is a copy, not a reference
@@ -221,11 +299,16 @@ func ExtendWorks(workDetails []WorkDetail, s *session.Session) ([]WorkDetail, er
 		workflow := workflowCache[w.FlowID]
 		if workflow != nil {
 			w.Type = &workflow.Workflow
-			stateFound, found := workflow.FindState(w.StateName)
+			definition := workflow
+			if pinned := versionCache[w.FlowID][w.FlowVersion]; pinned != nil {
+				definition = pinned
+			}
+			stateFound, found := definition.FindState(w.StateName)
 			if !found {
 				return nil, bizerror.ErrStateInvalid
 			}
 			w.State = stateFound
+			w.ParentStates = definition.StateMachine.Ancestors(w.StateName)
 			w.StateCategory = stateFound.Category
 		}
 
@@ -238,6 +321,20 @@ func ExtendWorks(workDetails []WorkDetail, s *session.Session) ([]WorkDetail, er
 		}
 		w.Labels = ls
 
+		// append assignees and watchers
+		var assignees, watchers []WorkParticipant
+		for _, p := range wps {
+			if p.WorkID != w.ID {
+				continue
+			}
+			if p.Role == ParticipantAssignee {
+				assignees = append(assignees, p.WorkParticipant)
+			} else if p.Role == ParticipantWatcher {
+				watchers = append(watchers, p.WorkParticipant)
+			}
+		}
+		w.Assignees, w.Watchers = assignees, watchers
+
 		// at last, put the copy w into slice
 		workDetails[i] = w
 	}
@@ -253,7 +350,7 @@ func ArchiveWorks(ids []types.ID, s *session.Session) error {
 			if err != nil {
 				return err
 			}
-			if work.StateCategory != state.Done && work.StateCategory != state.Rejected {
+			if base := state.BaseCategory(work.StateCategory); base != state.Done && base != state.Rejected {
 				return bizerror.ErrStateCategoryInvalid
 			}
 			if !work.ArchiveTime.IsZero() {
@@ -353,6 +450,8 @@ func UpdateWork(id types.ID, u *domain.WorkUpdating, s *session.Session) (*domai
 
 func DeleteWork(id types.ID, s *session.Session) error {
 	var ev *event.EventRecord
+	var relatedEvents []*event.EventRecord
+	var attachmentKeys []string
 	err1 := persistence.ActiveDataSourceManager.GormDB(s.Context).Transaction(func(tx *gorm.DB) error {
 		_, err := findWorkAndCheckPerms(tx, id, s)
 		if err != nil {
@@ -381,13 +480,36 @@ func DeleteWork(id types.ID, s *session.Session) error {
 		if err := ClearWorkLabelRelationsFunc(work.ID, tx); err != nil {
 			return err
 		}
+		if err := ClearWorkParticipantsFunc(work.ID, tx); err != nil {
+			return err
+		}
+		if err := ClearWorkCommentsFunc(work.ID, tx); err != nil {
+			return err
+		}
+		attachmentKeys, err = ClearWorkAttachmentsFunc(work.ID, tx)
+		if err != nil {
+			return err
+		}
+		childEvents, err := detachWorkChildren(tx, &work, s)
+		if err != nil {
+			return err
+		}
+		linkEvents, err := clearWorkLinks(tx, &work, s)
+		if err != nil {
+			return err
+		}
+		relatedEvents = append(childEvents, linkEvents...)
 		return nil
 	})
 	if err1 != nil {
 		return err1
 	}
+	removeAttachmentObjects(attachmentKeys, s)
 	if event.InvokeHandlersFunc != nil {
 		event.InvokeHandlersFunc(ev)
+		for _, relatedEvent := range relatedEvents {
+			event.InvokeHandlersFunc(relatedEvent)
+		}
 	}
 	return err1
 }
@@ -34,7 +34,8 @@ func setup(t *testing.T, testDatabase **testinfra.TestDatabase) (*domain.Workflo
 	db := testinfra.StartMysqlTestDatabase("flywheel")
 	*testDatabase = db
 	Expect(db.DS.GormDB(context.Background()).AutoMigrate(&domain.Project{}, &domain.ProjectMember{}, &domain.Work{}, &domain.WorkProcessStep{},
-		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &checklist.CheckItem{}, &work.WorkLabelRelation{}).Error).To(BeNil())
+		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &domain.WorkflowVersion{}, &checklist.CheckItem{}, &work.WorkLabelRelation{}, &work.WorkLink{}, &work.WorkParticipantRecord{}, &work.WorkComment{}, &work.WorkCommentRevision{}, &work.WorkAttachment{}, &account.User{},
+		&flow.WorkflowPropertyDefinition{}, &work.WorkPropertyValueRecord{}, &event.EventRecord{}).Error).To(BeNil())
 
 	persistence.ActiveDataSourceManager = db.DS
 	var err error
@@ -68,7 +69,11 @@ func setup(t *testing.T, testDatabase **testinfra.TestDatabase) (*domain.Workflo
 	work.QueryLabelBriefsOfWorkFunc = func(workIds []types.ID, s *session.Session) ([]work.WorkLabelBrief, error) {
 		return nil, nil
 	}
+	work.QueryParticipantsOfWorksFunc = func(workIds []types.ID, s *session.Session) ([]work.WorkParticipantBrief, error) {
+		return nil, nil
+	}
 	flow.DetailWorkflowFunc = flow.DetailWorkflow
+	flow.DetailWorkflowVersionFunc = flow.DetailWorkflowVersion
 
 	return flowDetail, flowDetail2, project1, project2, &persistedEvents, &handedEvents
 }
@@ -201,7 +206,7 @@ func TestCreateWork(t *testing.T) {
 
 		// should be visible to system permissions
 		detail, err = work.DetailWork(w.ID.String(), &session.Session{
-			Identity: session.Identity{ID: 10, Name: "index-robot"}, Perms: authority.Permissions{account.SystemViewPermission.ID}})
+			Identity: session.IndexRobot, Perms: authority.Permissions{account.SystemViewPermission.ID}})
 		Expect(err).To(BeNil())
 		Expect(detail).ToNot(BeNil())
 	})
@@ -564,6 +569,132 @@ func TestUpdateWork(t *testing.T) {
 	})
 }
 
+func TestChangeWorkflow(t *testing.T) {
+	RegisterTestingT(t)
+	var testDatabase *testinfra.TestDatabase
+
+	t.Run("should move work to another workflow of the same project", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		flowDetail, _, project1, _, persistedEvents, handedEvents := setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(1, domain.ProjectRoleManager+"_"+project1.ID.String())
+		target, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "target", ProjectID: project1.ID, ThemeColor: "blue", ThemeIcon: "task",
+			StateMachine: state.StateMachine{
+				States:      []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1}, {Name: "CLOSED", Category: state.Done, Order: 2}},
+				Transitions: []state.Transition{{Name: "close", From: "OPEN", To: "CLOSED"}},
+			},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "priority", Type: domain.PropTypeText}, {Name: "estimate", Type: domain.PropTypeText}},
+		}, sec)
+		Expect(err).To(BeNil())
+		_, err = flow.CreatePropertyDefinition(flowDetail.ID, domain.PropertyDefinition{Name: "priority", Type: domain.PropTypeText}, sec)
+		Expect(err).To(BeNil())
+		_, err = flow.CreatePropertyDefinition(flowDetail.ID, domain.PropertyDefinition{Name: "estimate", Type: domain.PropTypeNumber}, sec)
+		Expect(err).To(BeNil())
+
+		detail, err := work.CreateWork(&domain.WorkCreation{Name: "test work1", ProjectID: project1.ID, FlowID: flowDetail.ID,
+			InitialStateName: domain.StatePending.Name}, sec)
+		Expect(err).To(BeNil())
+		_, err = work.AssignWorkPropertyValue(work.WorkPropertyAssign{WorkId: detail.ID, Name: "priority", Value: "high"}, sec)
+		Expect(err).To(BeNil())
+		_, err = work.AssignWorkPropertyValue(work.WorkPropertyAssign{WorkId: detail.ID, Name: "estimate", Value: "3"}, sec)
+		Expect(err).To(BeNil())
+
+		_, err = work.ChangeWorkflow(detail.ID, &domain.WorkflowChanging{FlowID: target.ID}, sec)
+		Expect(err).To(Equal(&bizerror.ErrStatesUnmapped{States: []string{domain.StatePending.Name}}))
+		_, err = work.ChangeWorkflow(detail.ID, &domain.WorkflowChanging{FlowID: flowDetail.ID}, sec)
+		Expect(err).To(BeAssignableToTypeOf(&bizerror.ErrBadParam{}))
+
+		result, err := work.ChangeWorkflow(detail.ID,
+			&domain.WorkflowChanging{FlowID: target.ID, StateMapping: map[string]string{domain.StatePending.Name: "OPEN"}}, sec)
+		Expect(err).To(BeNil())
+		Expect(result.Work.FlowID).To(Equal(target.ID))
+		Expect(result.Work.FlowVersion).To(Equal(target.Version))
+		Expect(result.Work.StateName).To(Equal("OPEN"))
+		Expect(result.CarriedProperties).To(Equal([]string{"priority"}))
+		Expect(result.DroppedProperties).To(Equal([]string{"estimate"}))
+
+		values, err := work.QueryWorkPropertyValues([]types.ID{detail.ID}, sec)
+		Expect(err).To(BeNil())
+		Expect(values).To(HaveLen(1))
+		Expect(values[0].PropertyValues).To(HaveLen(2))
+		for _, v := range values[0].PropertyValues {
+			if v.Name == "priority" {
+				Expect(v.Value).To(Equal("high"))
+			} else {
+				Expect(v.Value).To(BeEmpty())
+			}
+		}
+
+		var steps []domain.WorkProcessStep
+		Expect(testDatabase.DS.GormDB(context.Background()).Where("work_id = ?", detail.ID).Order("begin_time ASC").Find(&steps).Error).To(BeNil())
+		Expect(steps).To(HaveLen(2))
+		Expect(steps[0].EndTime.IsZero()).To(BeFalse())
+		Expect(steps[0].NextStateName).To(Equal("OPEN"))
+		Expect(steps[1].FlowID).To(Equal(target.ID))
+		Expect(steps[1].StateName).To(Equal("OPEN"))
+		Expect(steps[1].EndTime.IsZero()).To(BeTrue())
+
+		Expect(len(*persistedEvents)).To(Equal(2))
+		Expect((*persistedEvents)[1].EventCategory).To(Equal(event.EventCategoryPropertyUpdated))
+		Expect((*persistedEvents)[1].UpdatedProperties[0].NewValue).To(Equal(target.ID.String()))
+		Expect(*handedEvents).To(Equal(*persistedEvents))
+	})
+
+	t.Run("should check required properties and wip limit of target state", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		flowDetail, _, project1, _, _, _ := setup(t, &testDatabase)
+
+		sec := testinfra.BuildSecCtx(1, domain.ProjectRoleManager+"_"+project1.ID.String())
+		target, err := flow.CreateWorkflow(&flow.WorkflowCreation{Name: "target", ProjectID: project1.ID, ThemeColor: "blue", ThemeIcon: "task",
+			StateMachine: state.StateMachine{
+				States: []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1, WipLimit: 1},
+					{Name: "CLOSED", Category: state.Done, Order: 2}},
+				Transitions: []state.Transition{{Name: "close", From: "OPEN", To: "CLOSED"}},
+			},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "resolution", Type: domain.PropTypeText, RequiredStates: []string{"CLOSED"}}},
+		}, sec)
+		Expect(err).To(BeNil())
+
+		work1, err := work.CreateWork(&domain.WorkCreation{Name: "test work1", ProjectID: project1.ID, FlowID: flowDetail.ID,
+			InitialStateName: domain.StatePending.Name}, sec)
+		Expect(err).To(BeNil())
+		work2, err := work.CreateWork(&domain.WorkCreation{Name: "test work2", ProjectID: project1.ID, FlowID: flowDetail.ID,
+			InitialStateName: domain.StatePending.Name}, sec)
+		Expect(err).To(BeNil())
+
+		_, err = work.ChangeWorkflow(work1.ID,
+			&domain.WorkflowChanging{FlowID: target.ID, StateMapping: map[string]string{domain.StatePending.Name: "CLOSED"}}, sec)
+		Expect(err).To(Equal(&bizerror.ErrPropertiesRequired{State: "CLOSED", Properties: []string{"resolution"}}))
+
+		_, err = work.ChangeWorkflow(work1.ID,
+			&domain.WorkflowChanging{FlowID: target.ID, StateMapping: map[string]string{domain.StatePending.Name: "OPEN"}}, sec)
+		Expect(err).To(BeNil())
+		_, err = work.ChangeWorkflow(work2.ID,
+			&domain.WorkflowChanging{FlowID: target.ID, StateMapping: map[string]string{domain.StatePending.Name: "OPEN"}}, sec)
+		Expect(err).To(Equal(&bizerror.ErrWipLimitExceeded{State: "OPEN", WipLimit: 1, Works: 1}))
+
+		unchanged, err := work.DetailWork(work2.ID.String(), sec)
+		Expect(err).To(BeNil())
+		Expect(unchanged.FlowID).To(Equal(flowDetail.ID))
+	})
+
+	t.Run("should forbid to move work to workflow of other project", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		flowDetail, flowDetail2, project1, project2, _, _ := setup(t, &testDatabase)
+
+		detail, err := work.CreateWork(&domain.WorkCreation{Name: "test work1", ProjectID: project1.ID, FlowID: flowDetail.ID,
+			InitialStateName: domain.StatePending.Name}, testinfra.BuildSecCtx(1, domain.ProjectRoleManager+"_"+project1.ID.String()))
+		Expect(err).To(BeNil())
+
+		_, err = work.ChangeWorkflow(detail.ID, &domain.WorkflowChanging{FlowID: flowDetail2.ID},
+			testinfra.BuildSecCtx(1, domain.ProjectRoleManager+"_"+project1.ID.String(), domain.ProjectRoleManager+"_"+project2.ID.String()))
+		Expect(err).To(BeAssignableToTypeOf(&bizerror.ErrBadParam{}))
+		_, err = work.ChangeWorkflow(detail.ID, &domain.WorkflowChanging{FlowID: flowDetail2.ID},
+			testinfra.BuildSecCtx(1, domain.ProjectRoleManager+"_"+project2.ID.String()))
+		Expect(err).To(Equal(bizerror.ErrForbidden))
+	})
+}
+
 func TestDeleteWork(t *testing.T) {
 	RegisterTestingT(t)
 	var testDatabase *testinfra.TestDatabase
@@ -798,6 +929,42 @@ func TestExtendWorks(t *testing.T) {
 			},
 		}))
 	})
+
+	t.Run("should resolve state by the version of workflow which work is pinned to", func(t *testing.T) {
+		defer teardown(t, testDatabase)
+		setup(t, &testDatabase)
+
+		flow.DetailWorkflowFunc = func(id types.ID, s *session.Session) (*domain.WorkflowDetail, error) {
+			return &domain.WorkflowDetail{
+				Workflow:     domain.Workflow{ID: id, Name: "flow-" + id.String(), Version: 2},
+				StateMachine: state.StateMachine{States: []state.State{{Name: "QUEUED", Category: state.InBacklog}}},
+			}, nil
+		}
+		var requestedVersions []int
+		flow.DetailWorkflowVersionFunc = func(id types.ID, version int, s *session.Session) (*domain.WorkflowDetail, error) {
+			requestedVersions = append(requestedVersions, version)
+			return &domain.WorkflowDetail{
+				Workflow:     domain.Workflow{ID: id, Name: "flow-" + id.String(), Version: version},
+				StateMachine: state.StateMachine{States: []state.State{{Name: "PENDING", Category: state.InBacklog}}},
+			}, nil
+		}
+		work.QueryLabelBriefsOfWorkFunc = func(workIds []types.ID, s *session.Session) ([]work.WorkLabelBrief, error) {
+			return nil, nil
+		}
+
+		ws := []work.WorkDetail{
+			{Work: domain.Work{ID: 100, FlowID: 2, FlowVersion: 1, StateName: "PENDING"}},
+			{Work: domain.Work{ID: 200, FlowID: 2, FlowVersion: 2, StateName: "QUEUED"}},
+			{Work: domain.Work{ID: 300, FlowID: 2, FlowVersion: 1, StateName: "PENDING"}},
+		}
+		ds, err := work.ExtendWorks(ws, nil)
+		Expect(err).To(BeNil())
+		Expect(requestedVersions).To(Equal([]int{1}))
+		Expect(ds[0].State).To(Equal(state.State{Name: "PENDING", Category: state.InBacklog}))
+		Expect(ds[1].State).To(Equal(state.State{Name: "QUEUED", Category: state.InBacklog}))
+		Expect(ds[2].State).To(Equal(state.State{Name: "PENDING", Category: state.InBacklog}))
+		Expect(ds[0].Type.Version).To(Equal(2))
+	})
 }
 
 func TestInnerLoadWorks(t *testing.T) {
@@ -10,6 +10,7 @@ import (
 type WorkProcessStep struct {
 	WorkID        types.ID       `json:"workId"`
 	FlowID        types.ID       `json:"flowId"`
+	FlowVersion   int            `json:"flowVersion"`
 	StateName     string         `json:"stateName"`
 	StateCategory state.Category `json:"stateCategory"`
 
@@ -17,6 +18,11 @@ type WorkProcessStep struct {
 	CreatorName string          `json:"creatorName"`
 	BeginTime   types.Timestamp `json:"beginTime" sql:"type:DATETIME(6) NOT NULL"`
 
+	// Comment and Reason are given by the creator when the work enters the state by a transition,
+	// Reason is one of the reasons of the state which is of category Rejected
+	Comment string `json:"comment" sql:"type:TEXT"`
+	Reason  string `json:"reason"`
+
 	EndTime types.Timestamp `json:"endTime" sql:"type:DATETIME(6)"`
 
 	NextStateName     string         `json:"nextStateName"`
@@ -28,6 +34,9 @@ type WorkProcessStepCreation struct {
 	WorkID    types.ID `json:"workId" validate:"required"`
 	FromState string   `json:"fromState" validate:"required"`
 	ToState   string   `json:"toState" validate:"required"`
+
+	Comment string `json:"comment"`
+	Reason  string `json:"reason"`
 }
 
 type WorkProcessStepQuery struct {
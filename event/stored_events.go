@@ -10,11 +10,14 @@ var (
 	EventPersistCreateFunc = eventPersistCreate
 )
 
+// eventPersistCreate saves the event, an index log is created along with it if the source of event is indexed
 func eventPersistCreate(record *EventRecord, tx *gorm.DB) error {
-	_, err := indexlog.CreateIndexLogFunc(record.ID, record.SourceType, record.SourceId, record.SourceDesc,
-		record.EventCategory == EventCategoryDeleted, record.Timestamp, tx)
-	if err != nil {
-		return err
+	if record.SourceType == indexlog.IndexedSourceType {
+		_, err := indexlog.CreateIndexLogFunc(record.ID, record.SourceType, record.SourceId, record.SourceDesc,
+			record.EventCategory == EventCategoryDeleted, record.Timestamp, tx)
+		if err != nil {
+			return err
+		}
 	}
 
 	return tx.Create(record).Error
@@ -68,4 +68,27 @@ func TestEventPersistCreate(t *testing.T) {
 		Expect(len(records)).To(Equal(1))
 		Expect(records[0]).To(Equal(event))
 	})
+
+	t.Run("should not create index log for event of source which is not indexed", func(t *testing.T) {
+		setup(t)
+		defer teardown(t)
+
+		indexLogCreated := false
+		indexlog.CreateIndexLogFunc = func(id types.ID, sourceType string, sourceId types.ID, sourceDesc string,
+			deletion bool, timestamp types.Timestamp, tx *gorm.DB) (*indexlog.IndexLogRecord, error) {
+			indexLogCreated = true
+			return nil, nil
+		}
+
+		event := EventRecord{
+			Event:     Event{SourceType: "WORKFLOW", SourceId: 1234, SourceDesc: "workflow1234", EventCategory: EventCategoryCreated},
+			Timestamp: types.TimestampOfDate(2021, 1, 1, 12, 12, 12, 0, time.Local),
+		}
+		Expect(eventPersistCreate(&event, testDatabase.DS.GormDB(context.Background()))).To(BeNil())
+		Expect(indexLogCreated).To(BeFalse())
+
+		records := []EventRecord{}
+		Expect(testDatabase.DS.GormDB(context.Background()).Model(&EventRecord{}).Find(&records).Error).To(BeNil())
+		Expect(len(records)).To(Equal(1))
+	})
 }
@@ -30,6 +30,9 @@ func (r *IndexLogRecord) TableName() string {
 	return "index_logs"
 }
 
+// IndexedSourceType is the source type of events which are indexed, only events of this type have index logs
+const IndexedSourceType = "WORK"
+
 var (
 	CreateIndexLogFunc        = CreateIndexLog
 	FinishIndexLogFunc        = FinishIndexLog
@@ -81,7 +84,7 @@ func LoadPendingIndexLog(page, size int) ([]IndexLogRecord, error) {
 	if offset < 0 {
 		offset = 0
 	}
-	if err := db.Where("indexed_time <= ? AND obsolete != ?", types.Timestamp{}, true).
+	if err := db.Where("source_type = ? AND indexed_time <= ? AND obsolete != ?", IndexedSourceType, types.Timestamp{}, true).
 		Offset(offset).Limit(size).Find(&indexLogs).Error; err != nil {
 		return nil, err
 	}
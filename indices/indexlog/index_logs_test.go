@@ -247,6 +247,14 @@ func TestLoadPendingIndexLog(t *testing.T) {
 		}
 		Expect(indexLogPersistCreate(&indexlog5, testDatabase.DS.GormDB(context.Background()))).To(BeNil())
 
+		// logs of the sources which are not indexed are never loaded
+		indexlog6 := IndexLogRecord{
+			IndexLog:  IndexLog{SourceType: "WORKFLOW", SourceId: 10006, SourceDesc: "workflow10006", Deletion: false},
+			ID:        106,
+			Timestamp: types.TimestampOfDate(2021, 1, 1, 12, 12, 12, 0, time.Local),
+		}
+		Expect(indexLogPersistCreate(&indexlog6, testDatabase.DS.GormDB(context.Background()))).To(BeNil())
+
 		ret, err := LoadPendingIndexLog(1, 2)
 		Expect(err).To(BeNil())
 		Expect(len(ret)).To(Equal(2))
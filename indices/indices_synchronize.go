@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/fundwit/go-commons/types"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 )
@@ -21,11 +22,11 @@ import (
 var (
 	WorkIndexEventHandlerName = "workIndexr"
 	indexRobot                = &session.Session{
-		Identity: session.Identity{ID: 10, Name: "index-robot"},
+		Identity: session.IndexRobot,
 		Perms:    authority.Permissions{account.SystemViewPermission.ID},
 	}
 	anonymousRecoveryInvoker = &session.Session{
-		Identity: session.Identity{ID: 11, Name: "anonymous-invoker"},
+		Identity: session.AnonymousInvoker,
 		Perms:    authority.Permissions{account.SystemRecoveryPermission.ID},
 	}
 
@@ -159,6 +160,12 @@ func IndicesFullSync() (err error) {
 			continue
 		}
 
+		if err := work.InnerAppendRollupsFunc(workDetails, indexRobot); err != nil {
+			logrus.Warnf("indices fully sync: error on append rollup(page = %d, pageSize = %d): %v", page, SyncBatchSize, err)
+			page++
+			continue
+		}
+
 		details, err := work.ExtendWorksFunc(workDetails, indexRobot)
 		if err != nil {
 			logrus.Warnf("indices fully sync: error on detail works(page = %d, pageSize = %d): %v", page, SyncBatchSize, err)
@@ -202,6 +209,7 @@ func IndexWorkEventHandle(e *event.EventRecord) *event.EventHandleResult {
 				HandlerIdentifier: WorkIndexEventHandlerName,
 			}
 		}
+		reindexParentWorks(e, w)
 	}
 
 	if err := indexlog.FinishIndexLogFunc(e.ID); err != nil {
@@ -212,3 +220,30 @@ func IndexWorkEventHandle(e *event.EventRecord) *event.EventHandleResult {
 	}
 	return &event.EventHandleResult{Success: true, HandlerIdentifier: WorkIndexEventHandlerName}
 }
+
+// reindexParentWorks refreshes the rollup of the parents which the work is moved under or out of
+func reindexParentWorks(e *event.EventRecord, w *work.WorkDetail) {
+	parentIds := map[types.ID]bool{}
+	if w.ParentID != 0 {
+		parentIds[w.ParentID] = true
+	}
+	for _, r := range e.UpdatedRelations {
+		if r.PropertyName != "Parent" || r.OldTargetId == "" {
+			continue
+		}
+		if id, err := types.ParseID(r.OldTargetId); err == nil {
+			parentIds[id] = true
+		}
+	}
+
+	for id := range parentIds {
+		parent, err := work.DetailWorkFunc(id.String(), indexRobot)
+		if err != nil {
+			logrus.Warnf("detail parent work %d which will be indexed, %v", id, err)
+			continue
+		}
+		if err := IndexWorks([]work.WorkDetail{*parent}, &session.Session{Context: context.Background()}); err != nil {
+			logrus.Warnf("index parent work %d, %v", id, err)
+		}
+	}
+}
@@ -57,6 +57,9 @@ func TestScheduleNewSyncRun(t *testing.T) {
 
 func TestIndexWorkEventHandle(t *testing.T) {
 	RegisterTestingT(t)
+	work.QueryCommentTextsOfWorksFunc = func(workIds []types.ID, s *session.Session) (map[types.ID][]string, error) {
+		return nil, nil
+	}
 
 	t.Run("only accept event of Work", func(t *testing.T) {
 		Expect(indices.IndexWorkEventHandle(&event.EventRecord{Event: event.Event{SourceType: "NOT_WORK"}})).To(BeNil())
@@ -89,12 +92,22 @@ func TestIndexWorkEventHandle(t *testing.T) {
 	})
 
 	t.Run("work create or update event handle success", func(t *testing.T) {
+		var indexedDoc interface{}
 		es.IndexFunc = func(index string, id types.ID, doc interface{}, s *session.Session) error {
+			indexedDoc = doc
 			return nil
 		}
 		work.DetailWorkFunc = func(identifier string, s *session.Session) (*work.WorkDetail, error) {
-			return &work.WorkDetail{}, nil
+			return &work.WorkDetail{Work: domain.Work{ID: 100}}, nil
+		}
+		work.QueryCommentTextsOfWorksFunc = func(workIds []types.ID, s *session.Session) (map[types.ID][]string, error) {
+			return map[types.ID][]string{100: {"comment 1", "comment 2"}}, nil
 		}
+		defer func() {
+			work.QueryCommentTextsOfWorksFunc = func(workIds []types.ID, s *session.Session) (map[types.ID][]string, error) {
+				return nil, nil
+			}
+		}()
 		var finishedIndexLogId types.ID
 		indexlog.FinishIndexLogFunc = func(id types.ID) error {
 			finishedIndexLogId = id
@@ -105,6 +118,36 @@ func TestIndexWorkEventHandle(t *testing.T) {
 		expectedResult := event.EventHandleResult{Success: true, HandlerIdentifier: indices.WorkIndexEventHandlerName}
 		Expect(*indices.IndexWorkEventHandle(&ev)).To(Equal(expectedResult))
 		Expect(finishedIndexLogId).To(Equal(types.ID(123)))
+		Expect(indexedDoc).To(Equal(indices.WorkDocument{WorkDetail: work.WorkDetail{Work: domain.Work{ID: 100}},
+			Comments: []string{"comment 1", "comment 2"}}))
+	})
+
+	t.Run("should reindex the old and new parents of work", func(t *testing.T) {
+		var indexedIds []types.ID
+		es.IndexFunc = func(index string, id types.ID, doc interface{}, s *session.Session) error {
+			indexedIds = append(indexedIds, id)
+			return nil
+		}
+		work.DetailWorkFunc = func(identifier string, s *session.Session) (*work.WorkDetail, error) {
+			id, err := types.ParseID(identifier)
+			if err != nil {
+				return nil, err
+			}
+			if id == 100 {
+				return &work.WorkDetail{Work: domain.Work{ID: id, ParentID: 200}}, nil
+			}
+			return &work.WorkDetail{Work: domain.Work{ID: id}}, nil
+		}
+		indexlog.FinishIndexLogFunc = func(id types.ID) error {
+			return nil
+		}
+		ev := event.EventRecord{ID: 123, Event: event.Event{SourceType: "WORK", SourceId: 100, EventCategory: event.EventCategoryRelationUpdated,
+			UpdatedRelations: event.UpdatedRelations{{PropertyName: "Parent", OldTargetId: "300", NewTargetId: "200"}}}}
+
+		expectedResult := event.EventHandleResult{Success: true, HandlerIdentifier: indices.WorkIndexEventHandlerName}
+		Expect(*indices.IndexWorkEventHandle(&ev)).To(Equal(expectedResult))
+		Expect(indexedIds[0]).To(Equal(types.ID(100)))
+		Expect(indexedIds[1:]).To(ConsistOf(types.ID(200), types.ID(300)))
 	})
 
 	t.Run("failed in detail work progress for work creation event or work updating event", func(t *testing.T) {
@@ -180,6 +223,13 @@ func TestIndicesFullSync(t *testing.T) {
 		doc   interface{}
 	}
 
+	work.InnerAppendRollupsFunc = func(details []work.WorkDetail, s *session.Session) error {
+		return nil
+	}
+	work.QueryCommentTextsOfWorksFunc = func(workIds []types.ID, s *session.Session) (map[types.ID][]string, error) {
+		return nil, nil
+	}
+
 	t.Run("should recover panic to error", func(t *testing.T) {
 		raisedErr := errors.New("error on load works")
 		work.InnerLoadWorksFunc = func(page, size int) ([]domain.Work, error) {
@@ -228,6 +278,18 @@ func TestIndicesFullSync(t *testing.T) {
 			}
 			return nil
 		}
+		rollup := &work.WorkRollup{Children: 1, StateCategories: map[state.Category]int{state.InProcess: 1}, CheckItems: 2, DoneCheckItems: 1}
+		work.InnerAppendRollupsFunc = func(details []work.WorkDetail, s *session.Session) error {
+			for i := range details {
+				if details[i].ID == 1 {
+					details[i].Rollup = rollup
+				}
+			}
+			return nil
+		}
+		defer func() {
+			work.InnerAppendRollupsFunc = func(details []work.WorkDetail, s *session.Session) error { return nil }
+		}()
 
 		indices.SyncBatchSize = 2
 		Expect(indices.IndicesFullSync()).To(BeNil())
@@ -236,8 +298,11 @@ func TestIndicesFullSync(t *testing.T) {
 		for i := 0; i < total; i++ {
 			d := work.WorkDetail{Work: domain.Work{ID: types.ID(i + 1)}, State: state.State{Name: "test"},
 				CheckList: []checklist.CheckItem{{Name: "checkitem"}}}
+			if i == 0 {
+				d.Rollup = rollup
+			}
 			wantedDocs = append(wantedDocs, indexResult{indices.WorkIndexName, types.ID(i + 1),
-				indices.WorkDocument{d},
+				indices.WorkDocument{WorkDetail: d},
 			})
 		}
 		Expect(len(docs)).To(Equal(5))
@@ -292,7 +357,7 @@ func TestIndicesFullSync(t *testing.T) {
 			d := work.WorkDetail{Work: domain.Work{ID: types.ID(i + 1)}, State: state.State{Name: "test"},
 				CheckList: []checklist.CheckItem{{Name: "checkitem"}}}
 			wantedDocs = append(wantedDocs, indexResult{indices.WorkIndexName, types.ID(i + 1),
-				indices.WorkDocument{d},
+				indices.WorkDocument{WorkDetail: d},
 			})
 		}
 		Expect(len(docs)).To(Equal(3))
@@ -347,7 +412,7 @@ func TestIndicesFullSync(t *testing.T) {
 			d := work.WorkDetail{Work: domain.Work{ID: types.ID(i + 1)}, State: state.State{Name: "test"},
 				CheckList: []checklist.CheckItem{{Name: "checkitem"}}}
 			wantedDocs = append(wantedDocs, indexResult{indices.WorkIndexName, types.ID(i + 1),
-				indices.WorkDocument{d},
+				indices.WorkDocument{WorkDetail: d},
 			})
 		}
 		Expect(len(docs)).To(Equal(3))
@@ -402,7 +467,7 @@ func TestIndicesFullSync(t *testing.T) {
 			d := work.WorkDetail{Work: domain.Work{ID: types.ID(i + 1)}, State: state.State{Name: "test"},
 				CheckList: []checklist.CheckItem{{Name: "checkitem"}}}
 			wantedDocs = append(wantedDocs, indexResult{indices.WorkIndexName, types.ID(i + 1),
-				indices.WorkDocument{d},
+				indices.WorkDocument{WorkDetail: d},
 			})
 		}
 		Expect(len(docs)).To(Equal(3))
@@ -457,7 +522,7 @@ func TestIndicesFullSync(t *testing.T) {
 			d := work.WorkDetail{Work: domain.Work{ID: types.ID(i + 1)}, State: state.State{Name: "test"},
 				CheckList: []checklist.CheckItem{{Name: "checkitem"}}}
 			wantedDocs = append(wantedDocs, indexResult{indices.WorkIndexName, types.ID(i + 1),
-				indices.WorkDocument{d},
+				indices.WorkDocument{WorkDetail: d},
 			})
 		}
 		Expect(len(docs)).To(Equal(3))
@@ -468,6 +533,9 @@ func TestIndicesFullSync(t *testing.T) {
 func TestIndexlogRecoverRoutine(t *testing.T) {
 	RegisterTestingT(t)
 	c := &session.Session{Perms: authority.Permissions{account.SystemRecoveryPermission.ID}}
+	work.QueryCommentTextsOfWorksFunc = func(workIds []types.ID, s *session.Session) (map[types.ID][]string, error) {
+		return nil, nil
+	}
 
 	type indexResult struct {
 		index string
@@ -544,7 +612,7 @@ func TestIndexlogRecoverRoutine(t *testing.T) {
 			}
 			d := work.WorkDetail{Work: domain.Work{ID: types.ID(i + 1)}, State: state.State{Name: "test"}}
 			wantedDocs = append(wantedDocs, indexResult{indices.WorkIndexName, types.ID(i + 1),
-				indices.WorkDocument{d},
+				indices.WorkDocument{WorkDetail: d},
 			})
 		}
 		Expect(len(docs)).To(Equal(4))
@@ -605,7 +673,7 @@ func TestIndexlogRecoverRoutine(t *testing.T) {
 		wantedDocs := []indexResult{}
 		d := work.WorkDetail{Work: domain.Work{ID: types.ID(7)}, State: state.State{Name: "test"}}
 		wantedDocs = append(wantedDocs, indexResult{indices.WorkIndexName, types.ID(7),
-			indices.WorkDocument{d},
+			indices.WorkDocument{WorkDetail: d},
 		})
 
 		Expect(len(docs)).To(Equal(1))
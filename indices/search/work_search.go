@@ -30,7 +30,10 @@ func SearchWorks(q domain.WorkQuery, s *session.Session) ([]work.WorkDetail, err
 						{"terms": {"projectId": [111, 222]}},
 
 						{"match": {"name": {"query": "xxx", "operator": "AND"}}},
+						{"multi_match": {"query": "xxx", "fields": ["name", "comments"], "operator": "AND"}},
 						{"terms": {"stateCategory": ["xxx"]}},
+						{"term": {"parentId": 333}},
+						{"term": {"assignees.id": 444}},
 
 						{"exists": {"field": "archiveTime"}},
 						{"bool": {"must_not": {"exists": {"field": "archiveTime"}}}}
@@ -54,10 +57,20 @@ func SearchWorks(q domain.WorkQuery, s *session.Session) ([]work.WorkDetail, err
 	if q.Name != "" {
 		filters = append(filters, es.H{"match": es.H{"name": es.H{"query": q.Name, "operator": "AND"}}})
 	}
+	if q.Keyword != "" {
+		filters = append(filters, es.H{"multi_match": es.H{"query": q.Keyword, "fields": []string{"name", "comments"}, "operator": "AND"}})
+	}
 	if len(q.StateCategories) > 0 {
 		filters = append(filters, es.H{"terms": es.H{"stateCategory": q.StateCategories}})
 	}
 
+	if q.ParentID != 0 {
+		filters = append(filters, es.H{"term": es.H{"parentId": q.ParentID}})
+	}
+	if q.AssignedToMe {
+		filters = append(filters, es.H{"term": es.H{"assignees.id": s.Identity.ID}})
+	}
+
 	if q.ArchiveState == domain.StatusOn {
 		filters = append(filters, es.H{"exists": es.H{"field": "archivedTime"}})
 	} else if q.ArchiveState == domain.StatusAll {
@@ -84,7 +97,8 @@ func SearchWorks(q domain.WorkQuery, s *session.Session) ([]work.WorkDetail, err
 		workDetails = append(workDetails, r)
 	}
 
-	// non indexed properties:  type, state, stateCategory, labels
+	// extended properties: type, state, stateCategory, labels, assignees, watchers. They are indexed as well (the filter
+	// assignedToMe matches assignees.id), and reloaded here since the index is updated after the database
 	worksExts, err := work.ExtendWorksFunc(workDetails, s)
 	if err != nil {
 		return nil, err
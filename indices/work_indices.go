@@ -16,6 +16,8 @@ var (
 
 type WorkDocument struct {
 	work.WorkDetail
+	// Comments is the content of comments on the work, it is indexed for full-text search only
+	Comments []string `json:"comments,omitempty"`
 }
 
 type BatchActionError map[types.ID]error
@@ -25,10 +27,18 @@ func (e BatchActionError) Error() string {
 }
 
 func IndexWorks(works []work.WorkDetail, s *session.Session) error {
-	docs := make([]WorkDocument, 0, len(works))
-	for _, work := range works {
+	workIds := make([]types.ID, 0, len(works))
+	for _, w := range works {
+		workIds = append(workIds, w.ID)
+	}
+	comments, err := work.QueryCommentTextsOfWorksFunc(workIds, s)
+	if err != nil {
+		return err
+	}
 
-		docs = append(docs, WorkDocument{WorkDetail: work})
+	docs := make([]WorkDocument, 0, len(works))
+	for _, w := range works {
+		docs = append(docs, WorkDocument{WorkDetail: w, Comments: comments[w.ID]})
 	}
 
 	if err := saveWorkDocuments(docs, s); err != nil {
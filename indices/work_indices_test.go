@@ -60,6 +60,9 @@ func TestIndexWorks(t *testing.T) {
 func beforeEach(t *testing.T) {
 	es.CreateClientFromEnv()
 	es.IndexFunc = es.Index
+	work.QueryCommentTextsOfWorksFunc = func(workIds []types.ID, s *session.Session) (map[types.ID][]string, error) {
+		return nil, nil
+	}
 
 	work.ExtendWorksFunc = func(works []work.WorkDetail, s *session.Session) ([]work.WorkDetail, error) {
 		return nil, nil
@@ -69,6 +72,7 @@ func beforeEach(t *testing.T) {
 
 func afterEach(t *testing.T) {
 	work.ExtendWorksFunc = work.ExtendWorks
+	work.QueryCommentTextsOfWorksFunc = work.QueryCommentTextsOfWorks
 	if strings.Contains(indices.WorkIndexName, "_test_") {
 		Expect(es.DropIndex(indices.WorkIndexName, &session.Session{Context: context.Background()})).To(BeNil())
 	}
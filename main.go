@@ -62,12 +62,12 @@ func main() {
 
 	// database migration (race condition)
 	err = ds.GormDB(context.Background()).AutoMigrate(&domain.Work{}, &domain.WorkProcessStep{}, &checklist.CheckItem{},
-		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{},
-		&flow.WorkflowPropertyDefinition{}, &work.WorkPropertyValueRecord{},
+		&domain.Workflow{}, &domain.WorkflowState{}, &domain.WorkflowStateTransition{}, &domain.WorkflowVersion{},
+		&flow.WorkflowPropertyDefinition{}, &flow.WorkflowTemplateRecord{}, &flow.StateCategoryRecord{}, &work.WorkPropertyValueRecord{},
 		&workcontribution.WorkContributionRecord{}, &event.EventRecord{}, &indexlog.IndexLogRecord{},
 		&account.User{}, &domain.Project{}, &domain.ProjectMember{},
-		&account.Role{}, &account.Permission{}, &label.Label{}, &work.WorkLabelRelation{},
-		&account.UserRoleBinding{}, &account.RolePermissionBinding{}).Error
+		&account.Role{}, &account.Permission{}, &label.Label{}, &work.WorkLabelRelation{}, &work.WorkLink{}, &work.WorkParticipantRecord{},
+		&work.WorkComment{}, &work.WorkCommentRevision{}, &work.WorkAttachment{}, &account.UserRoleBinding{}, &account.RolePermissionBinding{}).Error
 	if err != nil {
 		logrus.Fatalf("database migration failed %v\n", err)
 	}
@@ -75,6 +75,9 @@ func main() {
 	if err := account.DefaultSecurityConfiguration(); err != nil {
 		logrus.Fatalf("failed to prepare default security configuration %v\n", err)
 	}
+	if err := flow.LoadStateCategories(ds.GormDB(context.Background())); err != nil {
+		logrus.Fatalf("failed to load state categories %v\n", err)
+	}
 
 	es.CreateClientFromEnv()
 
@@ -97,6 +100,9 @@ func main() {
 
 	label.RegisterLabelsRestAPI(engine, securityMiddle)
 	work.RegisterWorkLabelRelationsRestAPI(engine, securityMiddle)
+	work.RegisterWorkLinksRestAPI(engine, securityMiddle)
+	work.RegisterWorkCommentsRestAPI(engine, securityMiddle)
+	work.RegisterWorkAttachmentsRestAPI(engine, securityMiddle)
 	work.RegisterWorkPropertiesRestAPI(engine, securityMiddle)
 	label.LabelDeleteCheckFuncs = append(label.LabelDeleteCheckFuncs, work.IsLabelReferencedByWork)
 	workrest.RegisterWorksRestAPI(engine, securityMiddle)
@@ -107,11 +113,19 @@ func main() {
 	event.EventHandlers = append(event.EventHandlers, indices.IndexWorkEventHandle)
 
 	servehttp.RegisterWorkflowHandler(engine, securityMiddle)
+	servehttp.RegisterWorkflowTemplateHandler(engine, securityMiddle)
+	servehttp.RegisterStateCategoryHandler(engine, securityMiddle)
 
 	servehttp.RegisterWorkProcessStepHandler(engine, securityMiddle)
 	workcontribution.RegisterWorkContributionsHandlers(engine, securityMiddle)
 
 	avatar.RegisterAvatarAPI(engine, securityMiddle)
+
+	stopTriggerEvaluation := work.StartTriggerEvaluation()
+	defer stopTriggerEvaluation()
+	stopStateCategoriesRefresh := flow.StartStateCategoriesRefresh()
+	defer stopStateCategoriesRefresh()
+
 	s3.Bootstrap()
 
 	err = engine.Run(":80")
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"flywheel/bizerror"
 	"flywheel/domain"
+	"flywheel/domain/state"
 	"flywheel/domain/work"
 	"flywheel/servehttp"
 	"flywheel/session"
@@ -47,8 +48,8 @@ func TestWorkProcessStepRestAPI(t *testing.T) {
 
 	t.Run("should be able to handle service error", func(t *testing.T) {
 		work.CreateWorkStateTransitionFunc =
-			func(c *domain.WorkProcessStepCreation, s *session.Session) error {
-				return errors.New("a mocked error")
+			func(c *domain.WorkProcessStepCreation, s *session.Session) (state.Findings, error) {
+				return nil, errors.New("a mocked error")
 			}
 		req := httptest.NewRequest(http.MethodPost, "/v1/transitions", bytes.NewReader([]byte(
 			`{"flowId":1, "workId": "1", "fromState": "DONE", "toState": "DOING"}`)))
@@ -59,8 +60,8 @@ func TestWorkProcessStepRestAPI(t *testing.T) {
 
 	t.Run("should be able to create transition", func(t *testing.T) {
 		work.CreateWorkStateTransitionFunc =
-			func(c *domain.WorkProcessStepCreation, s *session.Session) error {
-				return nil
+			func(c *domain.WorkProcessStepCreation, s *session.Session) (state.Findings, error) {
+				return nil, nil
 			}
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/transitions", bytes.NewReader([]byte(
@@ -68,6 +69,18 @@ func TestWorkProcessStepRestAPI(t *testing.T) {
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusCreated))
 		Expect(body).To(BeZero())
+
+		work.CreateWorkStateTransitionFunc =
+			func(c *domain.WorkProcessStepCreation, s *session.Session) (state.Findings, error) {
+				return state.Findings{{Level: state.FindingLevelWarning, Code: "state.wip_limit_exceeded",
+					Message: "wip limit of state DOING is exceeded: 3 works, limit 3", State: "DOING"}}, nil
+			}
+		req = httptest.NewRequest(http.MethodPost, "/v1/transitions", bytes.NewReader([]byte(
+			`{"flowId":1, "workId": "100", "fromState": "PENDING", "toState": "DOING"}`)))
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(body).To(MatchJSON(`{"warnings": [{"level": "warning", "code": "state.wip_limit_exceeded",
+			"message": "wip limit of state DOING is exceeded: 3 works, limit 3", "state": "DOING"}]}`))
 	})
 }
 
@@ -114,7 +127,8 @@ func TestQueryWorkProcessStepRestAPI(t *testing.T) {
 					{WorkID: 100, FlowID: 1, StateName: domain.StatePending.Name, StateCategory: domain.StatePending.Category,
 						NextStateName: domain.StateDoing.Name, NextStateCategory: domain.StateDoing.Category, CreatorID: 200, CreatorName: "user200",
 						BeginTime: types.Timestamp(ts), EndTime: types.Timestamp(ts)},
-					{WorkID: 100, FlowID: 1, StateName: domain.StateDoing.Name, StateCategory: domain.StateDoing.Category, BeginTime: types.Timestamp(ts)},
+					{WorkID: 100, FlowID: 1, StateName: domain.StateDoing.Name, StateCategory: domain.StateDoing.Category, BeginTime: types.Timestamp(ts),
+						Comment: "start now"},
 				}, nil
 			}
 
@@ -122,10 +136,10 @@ func TestQueryWorkProcessStepRestAPI(t *testing.T) {
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusOK))
 		Expect(body).To(MatchJSON(`{"total": 2, "data": [
-			{"workId": "100", "flowId": "1", "stateName": "PENDING", "stateCategory": 1, "nextStateName": "DOING", "nextStateCategory": 2, 
-				"beginTime": "` + timeString + `", "endTime":"` + timeString + `", "creatorId": "200", "creatorName": "user200"},
-			{"workId": "100", "flowId": "1", "stateName": "DOING", "stateCategory": 2, "beginTime": "` + timeString + `", "endTime": null,
-				"nextStateName": "", "nextStateCategory": 0, "creatorId": "0", "creatorName": ""}
+			{"workId": "100", "flowId": "1", "flowVersion": 0, "stateName": "PENDING", "stateCategory": 1, "nextStateName": "DOING", "nextStateCategory": 2, 
+				"beginTime": "` + timeString + `", "endTime":"` + timeString + `", "creatorId": "200", "creatorName": "user200", "comment": "", "reason": ""},
+			{"workId": "100", "flowId": "1", "flowVersion": 0, "stateName": "DOING", "stateCategory": 2, "beginTime": "` + timeString + `", "endTime": null,
+				"nextStateName": "", "nextStateCategory": 0, "creatorId": "0", "creatorName": "", "comment": "start now", "reason": ""}
 		]}`))
 	})
 }
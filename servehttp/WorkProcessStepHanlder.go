@@ -39,10 +39,14 @@ func (h *workProcessStepHandler) handleCreate(c *gin.Context) {
 		panic(&bizerror.ErrBadParam{Cause: err})
 	}
 
-	err = work.CreateWorkStateTransitionFunc(&creation, session.ExtractSessionFromGinContext(c))
+	warnings, err := work.CreateWorkStateTransitionFunc(&creation, session.ExtractSessionFromGinContext(c))
 	if err != nil {
 		panic(err)
 	}
+	if len(warnings) > 0 {
+		c.JSON(http.StatusCreated, gin.H{"warnings": warnings})
+		return
+	}
 	c.Status(http.StatusCreated)
 }
 
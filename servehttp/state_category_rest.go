@@ -0,0 +1,83 @@
+package servehttp
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/misc"
+	"flywheel/session"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+func RegisterStateCategoryHandler(r *gin.Engine, middleWares ...gin.HandlerFunc) {
+	g := r.Group("/v1/state-categories", middleWares...)
+
+	g.GET("", queryStateCategoriesRestAPI)
+	g.POST("", createStateCategoryRestAPI)
+	g.PUT(":category", updateStateCategoryRestAPI)
+	g.DELETE(":category", deleteStateCategoryRestAPI)
+}
+
+func queryStateCategoriesRestAPI(c *gin.Context) {
+	categories, err := flow.QueryStateCategoriesFunc(session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, categories)
+}
+
+func createStateCategoryRestAPI(c *gin.Context) {
+	creation := flow.StateCategoryCreation{}
+	if err := c.ShouldBindBodyWith(&creation, binding.JSON); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	category, err := flow.CreateStateCategoryFunc(&creation, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusCreated, category)
+}
+
+func updateStateCategoryRestAPI(c *gin.Context) {
+	category, err := strconv.ParseUint(c.Param("category"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid category '" + c.Param("category") + "'"})
+		return
+	}
+
+	updating := flow.StateCategoryUpdating{}
+	if err := c.ShouldBindBodyWith(&updating, binding.JSON); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	if err := flow.UpdateStateCategoryFunc(state.Category(category), &updating, session.ExtractSessionFromGinContext(c)); err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func deleteStateCategoryRestAPI(c *gin.Context) {
+	category, err := strconv.ParseUint(c.Param("category"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid category '" + c.Param("category") + "'"})
+		return
+	}
+
+	if err := flow.DeleteStateCategoryFunc(state.Category(category), session.ExtractSessionFromGinContext(c)); err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
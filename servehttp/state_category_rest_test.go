@@ -0,0 +1,90 @@
+package servehttp_test
+
+import (
+	"bytes"
+	"flywheel/bizerror"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/servehttp"
+	"flywheel/session"
+	"flywheel/testinfra"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+)
+
+func TestStateCategoryRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterStateCategoryHandler(router)
+
+	t.Run("should query categories", func(t *testing.T) {
+		flow.QueryStateCategoriesFunc = func(s *session.Session) ([]state.CategoryDefinition, error) {
+			return []state.CategoryDefinition{{Category: 100, Name: "Blocked", ThemeColor: "#FFCC80", Base: state.InProcess}}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/state-categories", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`[{"category": 100, "name": "Blocked", "themeColor": "#FFCC80", "base": 2}]`))
+	})
+
+	t.Run("should create category", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/state-categories", bytes.NewReader([]byte(`{"name": "Blocked"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message":"Key: 'StateCategoryCreation.Base' Error:Field validation for 'Base' failed on the 'required' tag","data":null}`))
+
+		var creation *flow.StateCategoryCreation
+		flow.CreateStateCategoryFunc = func(c *flow.StateCategoryCreation, s *session.Session) (*state.CategoryDefinition, error) {
+			creation = c
+			return &state.CategoryDefinition{Category: 100, Name: c.Name, ThemeColor: c.ThemeColor, Base: c.Base}, nil
+		}
+		req = httptest.NewRequest(http.MethodPost, "/v1/state-categories", bytes.NewReader([]byte(`{"name": "Blocked", "base": 2}`)))
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(*creation).To(Equal(flow.StateCategoryCreation{Name: "Blocked", Base: state.InProcess}))
+		Expect(body).To(MatchJSON(`{"category": 100, "name": "Blocked", "themeColor": "", "base": 2}`))
+	})
+
+	t.Run("should update category", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/v1/state-categories/abc", bytes.NewReader([]byte(`{"name": "Blocked"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid category 'abc'","data":null}`))
+
+		var category state.Category
+		var updating *flow.StateCategoryUpdating
+		flow.UpdateStateCategoryFunc = func(c state.Category, u *flow.StateCategoryUpdating, s *session.Session) error {
+			category, updating = c, u
+			return nil
+		}
+		req = httptest.NewRequest(http.MethodPut, "/v1/state-categories/100", bytes.NewReader([]byte(`{"name": "Blocked", "themeColor": "red"}`)))
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(category).To(Equal(state.Category(100)))
+		Expect(*updating).To(Equal(flow.StateCategoryUpdating{Name: "Blocked", ThemeColor: "red"}))
+	})
+
+	t.Run("should delete category", func(t *testing.T) {
+		flow.DeleteStateCategoryFunc = func(c state.Category, s *session.Session) error {
+			return &bizerror.ErrStateCategoryReferenced{Category: "Blocked", States: 2}
+		}
+		req := httptest.NewRequest(http.MethodDelete, "/v1/state-categories/100", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusConflict))
+		Expect(body).To(MatchJSON(`{"code":"workflow.state_category_referenced",
+			"message":"state category Blocked is referenced by 2 states and 0 works","data":{"states": 2, "works": 0}}`))
+
+		flow.DeleteStateCategoryFunc = func(c state.Category, s *session.Session) error {
+			return nil
+		}
+		status, _, _ = testinfra.ExecuteRequest(httptest.NewRequest(http.MethodDelete, "/v1/state-categories/100", nil), router)
+		Expect(status).To(Equal(http.StatusNoContent))
+	})
+}
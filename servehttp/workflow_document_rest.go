@@ -0,0 +1,60 @@
+package servehttp
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain/flow"
+	"flywheel/misc"
+	"flywheel/session"
+	"net/http"
+	"strings"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// exportWorkflowRestAPI responds the workflow document as JSON, or as YAML when query parameter format is yaml
+func exportWorkflowRestAPI(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+
+	doc, err := flow.ExportWorkflowFunc(id, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	if c.Query("format") == "yaml" {
+		c.YAML(http.StatusOK, doc)
+		return
+	}
+	c.JSON(http.StatusOK, doc)
+}
+
+// importWorkflowRestAPI accepts the workflow document as JSON, or as YAML when the content type is a yaml one
+func importWorkflowRestAPI(c *gin.Context) {
+	query := flow.WorkflowImportQuery{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	var b binding.BindingBody = binding.JSON
+	if strings.Contains(c.ContentType(), "yaml") {
+		b = binding.YAML
+	}
+	doc := flow.WorkflowDocument{}
+	if err := c.ShouldBindBodyWith(&doc, b); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	result, err := flow.ImportWorkflowFunc(&query, &doc, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
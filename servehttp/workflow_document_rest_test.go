@@ -0,0 +1,146 @@
+package servehttp_test
+
+import (
+	"bytes"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/servehttp"
+	"flywheel/session"
+	"flywheel/testinfra"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+)
+
+var demoDocument = flow.WorkflowDocument{Format: flow.WorkflowDocumentFormat, Name: "demo", ThemeColor: "blue", ThemeIcon: "task",
+	States:      []state.State{{Name: "OPEN", Category: state.InBacklog, Order: 1}, {Name: "CLOSED", Category: state.Done, Order: 2}},
+	Transitions: []state.Transition{{Name: "close", From: "OPEN", To: "CLOSED", Guards: state.Guards{{Type: state.GuardChecklistDone}}}},
+	PropertyDefinitions: []domain.PropertyDefinition{{Name: "priority", Type: domain.PropTypeSelect, Title: "Priority",
+		Options: domain.PropertyOptions{domain.OptionKeySelectEnum: []interface{}{"high", "low"}}}},
+}
+
+const demoDocumentYAML = `format: flywheel.workflow/v1
+name: demo
+themeColor: blue
+themeIcon: task
+states:
+    - name: OPEN
+      category: 1
+      order: 1
+    - name: CLOSED
+      category: 3
+      order: 2
+transitions:
+    - name: close
+      from: OPEN
+      to: CLOSED
+      guards:
+        - type: checklistDone
+propertyDefinitions:
+    - name: priority
+      type: select
+      title: Priority
+      options:
+        selectEnums:
+            - high
+            - low
+`
+
+func TestExportWorkflowRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when id is invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/abc/export", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+
+	t.Run("should export document as json and yaml", func(t *testing.T) {
+		var exportedID types.ID
+		flow.ExportWorkflowFunc = func(id types.ID, s *session.Session) (*flow.WorkflowDocument, error) {
+			exportedID = id
+			doc := demoDocument
+			return &doc, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/export", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(exportedID).To(Equal(types.ID(10)))
+		Expect(body).To(MatchJSON(`{"format": "flywheel.workflow/v1", "name": "demo", "themeColor": "blue", "themeIcon": "task",
+			"states": [{"name": "OPEN", "category": 1, "order": 1}, {"name": "CLOSED", "category": 3, "order": 2}],
+			"transitions": [{"name": "close", "from": "OPEN", "to": "CLOSED", "guards": [{"type": "checklistDone"}]}],
+			"propertyDefinitions": [{"name": "priority", "type": "select", "title": "Priority", "options": {"selectEnums": ["high", "low"]}}]}`))
+
+		req = httptest.NewRequest(http.MethodGet, "/v1/workflows/10/export?format=yaml", nil)
+		status, body, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchYAML(demoDocumentYAML))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.ExportWorkflowFunc = func(id types.ID, s *session.Session) (*flow.WorkflowDocument, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/export", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+}
+
+func TestImportWorkflowRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	var query *flow.WorkflowImportQuery
+	var doc *flow.WorkflowDocument
+	flow.ImportWorkflowFunc = func(q *flow.WorkflowImportQuery, d *flow.WorkflowDocument, s *session.Session) (*flow.WorkflowImportResult, error) {
+		query, doc = q, d
+		return &flow.WorkflowImportResult{DryRun: q.DryRun, Changes: []flow.DefinitionChange{
+			{Action: flow.ChangeModified, Kind: flow.ChangeKindWorkflow, Name: "name", Old: "old", New: d.Name}}}, nil
+	}
+
+	t.Run("should import yaml document", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/import?workflowId=10&dryRun=true", bytes.NewReader([]byte(demoDocumentYAML)))
+		req.Header.Set("Content-Type", "application/x-yaml")
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(*query).To(Equal(flow.WorkflowImportQuery{WorkflowID: 10, DryRun: true}))
+		Expect(*doc).To(Equal(demoDocument))
+		Expect(body).To(MatchJSON(`{"dryRun": true, "changes": [{"action": "modified", "kind": "workflow", "name": "name", "old": "old", "new": "demo"}]}`))
+	})
+
+	t.Run("should import json document", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/import?projectId=1", bytes.NewReader([]byte(
+			`{"format": "flywheel.workflow/v1", "name": "demo", "themeColor": "blue", "themeIcon": "task",
+			"states": [{"name": "OPEN", "category": 1, "order": 1}, {"name": "CLOSED", "category": 3, "order": 2}],
+			"transitions": [{"name": "close", "from": "OPEN", "to": "CLOSED", "guards": [{"type": "checklistDone"}]}],
+			"propertyDefinitions": [{"name": "priority", "type": "select", "title": "Priority", "options": {"selectEnums": ["high", "low"]}}]}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(*query).To(Equal(flow.WorkflowImportQuery{ProjectID: 1}))
+		Expect(*doc).To(Equal(demoDocument))
+	})
+
+	t.Run("should return 400 when format is unknown", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/import?projectId=1", bytes.NewReader([]byte(
+			`{"format": "v0", "name": "demo", "themeColor": "blue", "themeIcon": "task"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message":"Key: 'WorkflowDocument.Format' Error:Field validation for 'Format' failed on the 'eq' tag","data":null}`))
+	})
+}
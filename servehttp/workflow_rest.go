@@ -31,21 +31,35 @@ func RegisterWorkflowHandler(r *gin.Engine, middleWares ...gin.HandlerFunc) {
 
 	g.POST("", handler.handleCreateWorkflow)
 	g.GET("", handler.handleQueryWorkflows)
+	g.POST("validate", handler.handleValidateStateMachine)
+	g.POST("import", importWorkflowRestAPI)
 	g.GET(":flowId", handler.handleDetailWorkflows)
 	g.PUT(":flowId", handler.handleUpdateWorkflowsBase)
 	g.DELETE(":flowId", handler.handleDeleteWorkflow)
+	g.GET(":flowId/export", exportWorkflowRestAPI)
+	g.POST(":flowId/clone", handler.handleCloneWorkflow)
+	g.GET(":flowId/diagram", handler.handleRenderWorkflowDiagram)
+	g.GET(":flowId/comparison", handler.handleCompareWorkflow)
+	g.GET(":flowId/events", handler.handleQueryWorkflowEvents)
 
 	g.POST(":flowId/states", handler.handleCreateStateMachineState)
 	g.PUT(":flowId/states", handler.handleUpdateStateMachineState)
+	g.DELETE(":flowId/states/:stateName", handler.handleDeleteStateMachineState)
 	g.PUT(":flowId/state-orders", handler.handleUpdateStateMachineStateOrders)
 
 	g.GET(":flowId/transitions", handler.handleQueryTransitions)
 	g.POST(":flowId/transitions", handler.handleCreateStateMachineTransitions)
 	g.DELETE(":flowId/transitions", handler.handleDeleteStateMachineTransitions)
 
+	g.GET(":flowId/versions/:version", handler.handleDetailWorkflowVersion)
+	g.POST(":flowId/migrations", handler.handleMigrateWorks)
+	g.POST(":flowId/simulations", simulateTransitionsRestAPI)
+
 	g.GET(":flowId/properties", queryWorkflowPropertyRestAPI)
 	g.POST(":flowId/properties", createWorkflowPropertyRestAPI)
 	g.DELETE("properties/:id", deleteWorkflowPropertyRestAPI)
+
+	g.POST(":flowId/templates", saveWorkflowAsTemplateRestAPI)
 }
 
 type workflowHandler struct {
@@ -82,6 +96,16 @@ func (h *workflowHandler) handleCreateWorkflow(c *gin.Context) {
 	c.JSON(http.StatusCreated, workflow)
 }
 
+// handleValidateStateMachine lints a state machine definition without saving it
+func (h *workflowHandler) handleValidateStateMachine(c *gin.Context) {
+	stateMachine := state.StateMachine{}
+	err := c.ShouldBindBodyWith(&stateMachine, binding.JSON)
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	c.JSON(http.StatusOK, stateMachine.Validate())
+}
+
 func (h *workflowHandler) handleDetailWorkflows(c *gin.Context) {
 	id, err := types.ParseID(c.Param("flowId"))
 	if err != nil {
@@ -95,6 +119,12 @@ func (h *workflowHandler) handleDetailWorkflows(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	workflowDetail.StateLoads, err = flow.QueryStateLoadsFunc(workflowDetail, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
 	c.JSON(http.StatusOK, workflowDetail)
 }
 
@@ -159,7 +189,7 @@ func (h *workflowHandler) handleQueryTransitions(c *gin.Context) {
 	}
 
 	availableTransitions := workflow.StateMachine.AvailableTransitions(query.FromState, query.ToState)
-	c.JSON(http.StatusOK, availableTransitions)
+	c.JSON(http.StatusOK, flow.PermitTransitions(workflow.ProjectID, availableTransitions, session.ExtractSessionFromGinContext(c)))
 }
 
 func (h *workflowHandler) handleCreateStateMachineTransitions(c *gin.Context) {
@@ -241,6 +271,28 @@ func (h *workflowHandler) handleUpdateStateMachineState(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+func (h *workflowHandler) handleDeleteStateMachineState(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+
+	deleting := flow.StateDeleting{}
+	if err := c.ShouldBindQuery(&deleting); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	deleting.Name = c.Param("stateName")
+
+	result, err := flow.DeleteStateFunc(id, &deleting, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 func (h *workflowHandler) handleUpdateStateMachineStateOrders(c *gin.Context) {
 	id, err := types.ParseID(c.Param("flowId"))
 	if err != nil {
@@ -289,3 +341,126 @@ func (h *workflowHandler) handleCreateStateMachineState(c *gin.Context) {
 	}
 	c.Status(http.StatusNoContent)
 }
+
+func (h *workflowHandler) handleDetailWorkflowVersion(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 0 {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid version '" + c.Param("version") + "'"})
+		return
+	}
+
+	workflowDetail, err := flow.DetailWorkflowVersionFunc(id, version, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, workflowDetail)
+}
+
+func (h *workflowHandler) handleMigrateWorks(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+
+	migration := flow.WorkMigration{}
+	err = c.ShouldBindBodyWith(&migration, binding.JSON)
+	if err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+	if err = h.validator.Struct(migration); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	result, err := flow.MigrateWorksFunc(id, &migration, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *workflowHandler) handleCloneWorkflow(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+
+	cloning := flow.WorkflowCloning{}
+	if err := c.ShouldBindBodyWith(&cloning, binding.JSON); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	workflow, err := flow.CloneWorkflowFunc(id, &cloning, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusCreated, workflow)
+}
+
+func (h *workflowHandler) handleRenderWorkflowDiagram(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+	query := flow.WorkflowDiagramQuery{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	diagram, err := flow.RenderWorkflowDiagramFunc(id, &query, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.String(http.StatusOK, diagram)
+}
+
+func (h *workflowHandler) handleCompareWorkflow(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+	query := flow.WorkflowComparisonQuery{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	changes, err := flow.CompareWorkflowFunc(id, &query, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+func (h *workflowHandler) handleQueryWorkflowEvents(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+
+	records, err := flow.QueryWorkflowEventsFunc(id, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
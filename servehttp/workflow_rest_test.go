@@ -8,6 +8,7 @@ import (
 	"flywheel/domain"
 	"flywheel/domain/flow"
 	"flywheel/domain/state"
+	"flywheel/event"
 	"flywheel/servehttp"
 	"flywheel/session"
 	"flywheel/testinfra"
@@ -44,7 +45,7 @@ func TestQueryWorkflowsRestAPI(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/v1/workflows", nil)
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusOK))
-		Expect(body).To(MatchJSON(`[{"id": "10", "name": "test workflow", "themeColor":"blue", "themeIcon": "some-icon", "projectId": "100", "createTime": "` +
+		Expect(body).To(MatchJSON(`[{"id": "10", "name": "test workflow", "themeColor":"blue", "themeIcon": "some-icon", "projectId": "100", "version": 0, "createTime": "` +
 			timeString + `"}]`))
 	})
 
@@ -119,7 +120,7 @@ func TestCreateWorkflowsRestAPI(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/v1/workflows", bytes.NewReader(reqBody))
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusCreated))
-		Expect(body).To(MatchJSON(`{"id": "123", "name": "test workflow", "themeColor": "blue", "themeIcon": "some-icon", "projectId": "333", "createTime": "` + timeString + `",
+		Expect(body).To(MatchJSON(`{"id": "123", "name": "test workflow", "themeColor": "blue", "themeIcon": "some-icon", "projectId": "333", "version": 0, "createTime": "` + timeString + `",
 			"propertyDefinitions": null,
 			"stateMachine": {
 				"states": [{"name":"OPEN", "category": 2, "order": 10}, {"name":"CLOSED", "category": 3, "order": 20}],
@@ -131,6 +132,57 @@ func TestCreateWorkflowsRestAPI(t *testing.T) {
 	})
 }
 
+func TestValidateWorkflowRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when failed to bind", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/validate", bytes.NewReader([]byte(`bbb`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid character 'b' looking for beginning of value","data":null}`))
+	})
+
+	t.Run("should return empty findings for valid state machine", func(t *testing.T) {
+		reqBody, err := json.Marshal(buildDemoWorkflowCreation().StateMachine)
+		Expect(err).To(BeNil())
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/validate", bytes.NewReader(reqBody))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`[]`))
+	})
+
+	t.Run("should return findings of invalid state machine", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/validate", bytes.NewReader([]byte(`{
+			"states": [{"name": "OPEN", "category": 2}, {"name": "CLOSED", "category": 3}],
+			"transitions": [{"name": "done", "from": "OPEN", "to": "UNKNOWN"}]}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`[
+			{"level": "error", "code": "transition.unknown_state", "message": "transition done is to unknown state UNKNOWN", "state": "UNKNOWN", "transition": "done"},
+			{"level": "warning", "code": "state.dead_end", "message": "there is no transition out of state OPEN", "state": "OPEN"},
+			{"level": "warning", "code": "state.unreachable", "message": "state CLOSED can not be reached", "state": "CLOSED"}
+		]`))
+	})
+
+	t.Run("should reject workflow creation with invalid state machine", func(t *testing.T) {
+		flow.CreateWorkflowFunc = func(creation *flow.WorkflowCreation, s *session.Session) (*domain.WorkflowDetail, error) {
+			return nil, &state.ErrStateMachineInvalid{Findings: state.Findings{
+				{Level: state.FindingLevelError, Code: "state.duplicated", Message: "state OPEN is duplicated", State: "OPEN"}}}
+		}
+		reqBody, err := json.Marshal(buildDemoWorkflowCreation())
+		Expect(err).To(BeNil())
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows", bytes.NewReader(reqBody))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"workflow.state_machine_invalid","message":"state machine is invalid: state OPEN is duplicated",
+			"data":[{"level": "error", "code": "state.duplicated", "message": "state OPEN is duplicated", "state": "OPEN"}]}`))
+	})
+}
+
 func TestDetailWorkflowsRestAPI(t *testing.T) {
 	RegisterTestingT(t)
 
@@ -150,11 +202,15 @@ func TestDetailWorkflowsRestAPI(t *testing.T) {
 				StateMachine:        domain.GenericWorkflowTemplate.StateMachine,
 			}, nil
 		}
+		flow.QueryStateLoadsFunc = func(workflow *domain.WorkflowDetail, s *session.Session) ([]domain.StateLoad, error) {
+			return []domain.StateLoad{{State: "PENDING", Works: 3}, {State: "DOING", Works: 4, WipLimit: 3, Exceeded: true},
+				{State: "DONE", Works: 0}}, nil
+		}
 
 		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/1", nil)
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusOK))
-		Expect(body).To(MatchJSON(`{"id": "10", "name": "test workflow", "themeColor": "blue", "themeIcon": "some-icon", "projectId": "100", "createTime": "` + timeString + `",
+		Expect(body).To(MatchJSON(`{"id": "10", "name": "test workflow", "themeColor": "blue", "themeIcon": "some-icon", "projectId": "100", "version": 0, "createTime": "` + timeString + `",
 			"propertyDefinitions":[
 				{"name": "description", "type":"", "title":"", "options":null},
 				{"name": "creatorId",   "type":"", "title":"", "options":null}
@@ -169,7 +225,19 @@ func TestDetailWorkflowsRestAPI(t *testing.T) {
 					{"name": "finish", "from": "DOING", "to": "DONE"},
 					{"name": "reopen", "from": "DONE", "to": "PENDING"}
 				]
-			}}`))
+			},
+			"stateLoads": [{"state": "PENDING", "works": 3, "wipLimit": 0, "exceeded": false},
+				{"state": "DOING", "works": 4, "wipLimit": 3, "exceeded": true},
+				{"state": "DONE", "works": 0, "wipLimit": 0, "exceeded": false}]}`))
+	})
+
+	t.Run("should be able to handle error when query state loads", func(t *testing.T) {
+		flow.QueryStateLoadsFunc = func(workflow *domain.WorkflowDetail, s *session.Session) ([]domain.StateLoad, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/1", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
 	})
 
 	t.Run("should return 400 when id is invalid", func(t *testing.T) {
@@ -275,7 +343,7 @@ func TestUpdateWorkflowBaseRestAPI(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPut, "/v1/workflows/1", bytes.NewReader(reqBody))
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusOK))
-		Expect(body).To(MatchJSON(`{"id": "10", "name": "updated works", "themeColor": "yellow", "themeIcon": "arrow",` +
+		Expect(body).To(MatchJSON(`{"id": "10", "name": "updated works", "themeColor": "yellow", "themeIcon": "arrow", "version": 0,` +
 			`"projectId": "100", "createTime": "` + timeString + `"}`))
 	})
 }
@@ -334,8 +402,28 @@ func TestQueryWorkflowTransitionsRestAPI(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/transitions?fromState=PENDING", nil)
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusOK))
-		Expect(body).To(MatchJSON(`[{"name":"begin","from":"PENDING","to":"DOING"},
-			{"name":"close","from":"PENDING","to":"DONE"}]`))
+		Expect(body).To(MatchJSON(`[{"name":"begin","from":"PENDING","to":"DOING","permitted":true},
+			{"name":"close","from":"PENDING","to":"DONE","permitted":true}]`))
+	})
+
+	t.Run("should tell whether transitions are permitted to caller", func(t *testing.T) {
+		flow.DetailWorkflowFunc = func(ID types.ID, s *session.Session) (*domain.WorkflowDetail, error) {
+			return &domain.WorkflowDetail{
+				Workflow: domain.Workflow{ID: types.ID(10), Name: "test workflow", ProjectID: types.ID(100), CreateTime: time.Now()},
+				StateMachine: state.StateMachine{
+					States: []state.State{{Name: "OPEN", Category: state.InBacklog}, {Name: "DOING", Category: state.InProcess},
+						{Name: "CLOSED", Category: state.Done}},
+					Transitions: []state.Transition{{Name: "begin", From: "OPEN", To: "DOING"},
+						{Name: "close", From: "OPEN", To: "CLOSED", Guards: state.Guards{{Type: state.GuardProjectRole, Name: domain.ProjectRoleManager}}}},
+				},
+			}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/transitions?fromState=OPEN", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`[{"name":"begin","from":"OPEN","to":"DOING","permitted":true},
+			{"name":"close","from":"OPEN","to":"CLOSED","guards":[{"type":"projectRole","name":"manager"}],"permitted":false}]`))
 	})
 
 	t.Run("should be able to query transitions with query: fromState and toState", func(t *testing.T) {
@@ -350,7 +438,7 @@ func TestQueryWorkflowTransitionsRestAPI(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/transitions?fromState=PENDING&toState=DONE", nil)
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusOK))
-		Expect(body).To(MatchJSON(`[{"name":"close","from":"PENDING","to":"DONE"}]`))
+		Expect(body).To(MatchJSON(`[{"name":"close","from":"PENDING","to":"DONE","permitted":true}]`))
 	})
 
 	t.Run("should be able to query transitions with unknown state", func(t *testing.T) {
@@ -443,6 +531,34 @@ func TestCreateWorkflowTransitionsRestAPI(t *testing.T) {
 		}`))
 	})
 
+	t.Run("should return 400 when guards or hooks are invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/1/transitions", bytes.NewReader([]byte(
+			`[{"name": "test", "from": "PENDING", "to": "DOING", "guards": [{"type": "propertySet"}], "hooks": [{"type": "unknown", "name": "x"}]}]`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{
+			"code":"common.bad_param",
+			"message":"[0]: Key: 'Transition.Guards[0].Name' Error:Field validation for 'Name' failed on the 'required_unless' tag\n` +
+			`Key: 'Transition.Hooks[0].Type' Error:Field validation for 'Type' failed on the 'oneof' tag",
+			"data":null
+		}`))
+	})
+
+	t.Run("should save transitions with guards and hooks", func(t *testing.T) {
+		var saved []state.Transition
+		flow.CreateWorkflowStateTransitionsFunc = func(id types.ID, transitions []state.Transition, s *session.Session) error {
+			saved = transitions
+			return nil
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/1/transitions", bytes.NewReader([]byte(
+			`[{"name": "finish", "from": "DOING", "to": "DONE", "guards": [{"type": "checklistDone"}], "hooks": [{"type": "addLabel", "name": "finished"}]}]`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusNoContent))
+		Expect(saved).To(Equal([]state.Transition{{Name: "finish", From: "DOING", To: "DONE",
+			Guards: state.Guards{{Type: state.GuardChecklistDone}}, Hooks: state.Hooks{{Type: state.HookAddLabel, Name: "finished"}}}}))
+	})
+
 	t.Run("should return 404 when workflow is not exist", func(t *testing.T) {
 		flow.CreateWorkflowStateTransitionsFunc = func(id types.ID, transitions []state.Transition, s *session.Session) error {
 			return bizerror.ErrNotFound
@@ -650,7 +766,9 @@ func TestUpdateWorkflowStateRestAPI(t *testing.T) {
 	})
 
 	t.Run("should return 2xx when everything is ok", func(t *testing.T) {
+		var captured flow.WorkflowStateUpdating
 		flow.UpdateWorkflowStateFunc = func(id types.ID, updating flow.WorkflowStateUpdating, s *session.Session) error {
+			captured = updating
 			return nil
 		}
 
@@ -660,6 +778,22 @@ func TestUpdateWorkflowStateRestAPI(t *testing.T) {
 		status, body, _ := testinfra.ExecuteRequest(req, router)
 		Expect(status).To(Equal(http.StatusNoContent))
 		Expect(body).To(BeEmpty())
+		Expect(captured.WipLimit).To(BeNil())
+		Expect(captured.WipSoft).To(BeNil())
+		Expect(captured.Reasons).To(BeNil())
+
+		req = httptest.NewRequest(http.MethodPut, "/v1/workflows/1/states", bytes.NewReader([]byte(
+			`{"originName": "PENDING", "name": "QUEUED", "order": 2000, "wipLimit": 0, "wipSoft": false, "reasons": []}`)))
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusNoContent))
+		Expect(*captured.WipLimit).To(BeZero())
+		Expect(*captured.WipSoft).To(BeFalse())
+		Expect(*captured.Reasons).To(BeEmpty())
+
+		req = httptest.NewRequest(http.MethodPut, "/v1/workflows/1/states", bytes.NewReader([]byte(
+			`{"originName": "PENDING", "name": "QUEUED", "order": 2000, "wipLimit": -1}`)))
+		status, _, _ = testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
 	})
 }
 
@@ -840,5 +974,301 @@ func buildDemoWorkflowCreation() *flow.WorkflowCreation {
 }
 
 type workflowManagerMock struct {
-	CreateWorkStateTransitionFunc func(t *domain.WorkProcessStepCreation, s *session.Session) error
+	CreateWorkStateTransitionFunc func(t *domain.WorkProcessStepCreation, s *session.Session) (state.Findings, error)
+}
+
+func TestDetailWorkflowVersionRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when version is invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/versions/abc", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid version 'abc'","data":null}`))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.DetailWorkflowVersionFunc = func(id types.ID, version int, s *session.Session) (*domain.WorkflowDetail, error) {
+			return nil, bizerror.ErrWorkflowVersionInvalid
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/versions/3", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"workflow.version_invalid","message":"workflow version is invalid","data":null}`))
+	})
+
+	t.Run("should return workflow definition of version", func(t *testing.T) {
+		var paramId types.ID
+		var paramVersion int
+		flow.DetailWorkflowVersionFunc = func(id types.ID, version int, s *session.Session) (*domain.WorkflowDetail, error) {
+			paramId, paramVersion = id, version
+			return &domain.WorkflowDetail{
+				Workflow:     domain.Workflow{ID: id, Name: "test workflow", ProjectID: 100, Version: version},
+				StateMachine: state.StateMachine{States: []state.State{{Name: "OPEN", Category: state.InProcess, Order: 1}}},
+			}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/versions/2", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(paramId).To(Equal(types.ID(10)))
+		Expect(paramVersion).To(Equal(2))
+		Expect(body).To(MatchJSON(`{"id": "10", "name": "test workflow", "themeColor": "", "themeIcon": "", "projectId": "100", "version": 2,
+			"createTime": "0001-01-01T00:00:00Z", "propertyDefinitions": null,
+			"stateMachine": {"states": [{"name": "OPEN", "category": 2, "order": 1}], "transitions": null}}`))
+	})
+}
+
+func TestMigrateWorksRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when failed to validate", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/10/migrations", bytes.NewReader([]byte(`{"fromVersion": 2, "toVersion": 1}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message":"Key: 'WorkMigration.ToVersion' Error:Field validation for 'ToVersion' failed on the 'gtfield' tag","data":null}`))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.MigrateWorksFunc = func(id types.ID, m *flow.WorkMigration, s *session.Session) (*flow.WorkMigrationResult, error) {
+			return nil, &bizerror.ErrStatesUnmapped{States: []string{"PENDING"}}
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/10/migrations", bytes.NewReader([]byte(`{"fromVersion": 1, "toVersion": 2}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"workflow.states_unmapped","message":"states are not mapped: PENDING","data":["PENDING"]}`))
+	})
+
+	t.Run("should migrate works", func(t *testing.T) {
+		var paramId types.ID
+		var paramMigration *flow.WorkMigration
+		flow.MigrateWorksFunc = func(id types.ID, m *flow.WorkMigration, s *session.Session) (*flow.WorkMigrationResult, error) {
+			paramId, paramMigration = id, m
+			return &flow.WorkMigrationResult{MigratedWorks: 3}, nil
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/10/migrations",
+			bytes.NewReader([]byte(`{"fromVersion": 1, "toVersion": 2, "stateMapping": {"PENDING": "QUEUED"}}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`{"migratedWorks": 3}`))
+		Expect(paramId).To(Equal(types.ID(10)))
+		Expect(*paramMigration).To(Equal(flow.WorkMigration{FromVersion: 1, ToVersion: 2, StateMapping: map[string]string{"PENDING": "QUEUED"}}))
+	})
+}
+
+func TestCloneWorkflowRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when failed to bind", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/10/clone", bytes.NewReader([]byte(`{"projectId": "200"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message":"Key: 'WorkflowCloning.Name' Error:Field validation for 'Name' failed on the 'required' tag","data":null}`))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.CloneWorkflowFunc = func(id types.ID, c *flow.WorkflowCloning, s *session.Session) (*domain.WorkflowDetail, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/10/clone", bytes.NewReader([]byte(`{"name": "copy", "projectId": "200"}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+
+	t.Run("should clone workflow", func(t *testing.T) {
+		var paramId types.ID
+		var paramCloning *flow.WorkflowCloning
+		flow.CloneWorkflowFunc = func(id types.ID, c *flow.WorkflowCloning, s *session.Session) (*domain.WorkflowDetail, error) {
+			paramId, paramCloning = id, c
+			return &domain.WorkflowDetail{
+				Workflow:     domain.Workflow{ID: 20, Name: c.Name, ProjectID: c.ProjectID, Version: 1},
+				StateMachine: state.StateMachine{States: []state.State{{Name: "OPEN", Category: state.InProcess, Order: 10001}}},
+			}, nil
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/10/clone", bytes.NewReader([]byte(`{"name": "copy", "projectId": "200"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(paramId).To(Equal(types.ID(10)))
+		Expect(*paramCloning).To(Equal(flow.WorkflowCloning{Name: "copy", ProjectID: 200}))
+		Expect(body).To(MatchJSON(`{"id": "20", "name": "copy", "themeColor": "", "themeIcon": "", "projectId": "200", "version": 1,
+			"createTime": "0001-01-01T00:00:00Z", "propertyDefinitions": null,
+			"stateMachine": {"states": [{"name": "OPEN", "category": 2, "order": 10001}], "transitions": null}}`))
+	})
+}
+
+func TestRenderWorkflowDiagramRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when format is invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/diagram?format=svg", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message":"Key: 'WorkflowDiagramQuery.Format' Error:Field validation for 'Format' failed on the 'oneof' tag","data":null}`))
+	})
+
+	t.Run("should return rendered diagram", func(t *testing.T) {
+		var paramId types.ID
+		var query *flow.WorkflowDiagramQuery
+		flow.RenderWorkflowDiagramFunc = func(id types.ID, q *flow.WorkflowDiagramQuery, s *session.Session) (string, error) {
+			paramId, query = id, q
+			return "digraph workflow {\n}\n", nil
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/diagram?format=dot&withCounts=true", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(paramId).To(Equal(types.ID(10)))
+		Expect(*query).To(Equal(flow.WorkflowDiagramQuery{Format: state.DiagramDot, WithCounts: true}))
+		Expect(body).To(Equal("digraph workflow {\n}\n"))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.RenderWorkflowDiagramFunc = func(id types.ID, q *flow.WorkflowDiagramQuery, s *session.Session) (string, error) {
+			return "", bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/diagram", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+}
+
+func TestDeleteStateMachineStateRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when replacement is missing", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/v1/workflows/10/states/REVIEW", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message":"Key: 'StateDeleting.Replacement' Error:Field validation for 'Replacement' failed on the 'required' tag","data":null}`))
+	})
+
+	t.Run("should delete state", func(t *testing.T) {
+		var paramId types.ID
+		var deleting *flow.StateDeleting
+		flow.DeleteStateFunc = func(id types.ID, c *flow.StateDeleting, s *session.Session) (*flow.StateDeletionResult, error) {
+			paramId, deleting = id, c
+			return &flow.StateDeletionResult{MovedWorks: 2}, nil
+		}
+		req := httptest.NewRequest(http.MethodDelete, "/v1/workflows/10/states/REVIEW?replacement=DOING&force=true", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(paramId).To(Equal(types.ID(10)))
+		Expect(*deleting).To(Equal(flow.StateDeleting{Name: "REVIEW", Replacement: "DOING", Force: true}))
+		Expect(body).To(MatchJSON(`{"movedWorks": 2}`))
+	})
+
+	t.Run("should respond references of state", func(t *testing.T) {
+		flow.DeleteStateFunc = func(id types.ID, c *flow.StateDeleting, s *session.Session) (*flow.StateDeletionResult, error) {
+			return nil, &bizerror.ErrStateReferenced{State: c.Name, ProcessSteps: 3}
+		}
+		req := httptest.NewRequest(http.MethodDelete, "/v1/workflows/10/states/REVIEW?replacement=DOING", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusConflict))
+		Expect(body).To(MatchJSON(`{"code":"workflow.state_referenced","message":"state REVIEW is referenced by 3 process steps and 0 archived works",
+			"data":{"processSteps":3,"archivedWorks":0}}`))
+	})
+}
+
+func TestCompareWorkflowRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when id is invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/abc/comparison?workflowId=20", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.CompareWorkflowFunc = func(id types.ID, q *flow.WorkflowComparisonQuery, s *session.Session) ([]flow.DefinitionChange, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/comparison?workflowId=20", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+
+	t.Run("should compare workflow", func(t *testing.T) {
+		var paramId types.ID
+		var paramQuery *flow.WorkflowComparisonQuery
+		flow.CompareWorkflowFunc = func(id types.ID, q *flow.WorkflowComparisonQuery, s *session.Session) ([]flow.DefinitionChange, error) {
+			paramId, paramQuery = id, q
+			return []flow.DefinitionChange{{Action: flow.ChangeRenamed, Kind: flow.ChangeKindState, Name: "DONE",
+				Old: state.State{Name: "CLOSED", Category: state.Done, Order: 2}, New: state.State{Name: "DONE", Category: state.Done, Order: 2},
+				Fields: []string{"name"}}}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/comparison?templateId=30", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(paramId).To(Equal(types.ID(10)))
+		Expect(*paramQuery).To(Equal(flow.WorkflowComparisonQuery{TemplateID: 30}))
+		Expect(body).To(MatchJSON(`[{"action": "renamed", "kind": "state", "name": "DONE",
+			"old": {"name": "CLOSED", "category": 3, "order": 2}, "new": {"name": "DONE", "category": 3, "order": 2}, "fields": ["name"]}]`))
+	})
+}
+
+func TestQueryWorkflowEventsRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when id is invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/abc/events", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.QueryWorkflowEventsFunc = func(id types.ID, s *session.Session) ([]event.EventRecord, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/events", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+
+	t.Run("should query workflow events", func(t *testing.T) {
+		var paramId types.ID
+		flow.QueryWorkflowEventsFunc = func(id types.ID, s *session.Session) ([]event.EventRecord, error) {
+			paramId = id
+			return []event.EventRecord{{ID: 100, Event: event.Event{SourceId: 10, SourceType: flow.WorkflowEventSourceType,
+				SourceDesc: "test", CreatorId: 1, CreatorName: "user1", EventCategory: event.EventCategoryPropertyUpdated,
+				UpdatedProperties: event.UpdatedProperties{{PropertyName: "Name", OldValue: "old", NewValue: "test"}}}}}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflows/10/events", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(paramId).To(Equal(types.ID(10)))
+		Expect(body).To(MatchJSON(`[{"id": "100", "sourceId": "10", "sourceType": "WORKFLOW", "sourceDesc": "test",
+			"creatorId": "1", "creatorName": "user1", "eventCategory": "PROPERTY_UPDATED",
+			"updatedProperties": [{"propertyName": "Name", "propertyDesc": "", "oldValue": "old", "oldValueDesc": "", "newValue": "test", "newValueDesc": ""}],
+			"updatedRelations": null, "timestamp": null}]`))
+	})
 }
@@ -0,0 +1,35 @@
+package servehttp
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain/work"
+	"flywheel/misc"
+	"flywheel/session"
+	"net/http"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// simulateTransitionsRestAPI responds what happens when each transition is performed on the simulated work, nothing is written
+func simulateTransitionsRestAPI(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+
+	simulation := work.TransitionSimulation{}
+	if err := c.ShouldBindBodyWith(&simulation, binding.JSON); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	outcomes, err := work.SimulateTransitionsFunc(id, &simulation, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, outcomes)
+}
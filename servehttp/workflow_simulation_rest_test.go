@@ -0,0 +1,64 @@
+package servehttp_test
+
+import (
+	"bytes"
+	"flywheel/bizerror"
+	"flywheel/domain/state"
+	"flywheel/domain/work"
+	"flywheel/servehttp"
+	"flywheel/session"
+	"flywheel/testinfra"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+)
+
+func TestSimulateTransitionsRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when id is invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/abc/simulations", bytes.NewReader([]byte(`{}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+
+	t.Run("should simulate transitions successfully", func(t *testing.T) {
+		var flowID types.ID
+		var simulation *work.TransitionSimulation
+		work.SimulateTransitionsFunc = func(id types.ID, sim *work.TransitionSimulation, s *session.Session) ([]work.TransitionOutcome, error) {
+			flowID, simulation = id, sim
+			return []work.TransitionOutcome{{Transition: state.Transition{Name: "finish", From: "DOING", To: "DONE"},
+				Permitted: true, Refusals: []string{"property resolution is not set"}, StateCategory: state.Done,
+				ProcessBeginTime: types.Timestamp(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+				ProcessEndTime:   types.Timestamp(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC))}}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/10/simulations", bytes.NewReader([]byte(
+			`{"stateName": "DOING", "propertyValues": {"resolution": ""}}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(flowID).To(Equal(types.ID(10)))
+		Expect(*simulation).To(Equal(work.TransitionSimulation{StateName: "DOING", PropertyValues: map[string]string{"resolution": ""}}))
+		Expect(body).To(MatchJSON(`[{"name": "finish", "from": "DOING", "to": "DONE", "permitted": true,
+			"refusals": ["property resolution is not set"], "stateCategory": 3,
+			"processBeginTime": "2021-01-01T00:00:00Z", "processEndTime": "2021-01-02T00:00:00Z"}]`))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		work.SimulateTransitionsFunc = func(id types.ID, sim *work.TransitionSimulation, s *session.Session) ([]work.TransitionOutcome, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/10/simulations", bytes.NewReader([]byte(`{"workId": "100"}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+}
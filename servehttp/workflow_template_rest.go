@@ -0,0 +1,147 @@
+package servehttp
+
+import (
+	"flywheel/bizerror"
+	"flywheel/domain/flow"
+	"flywheel/misc"
+	"flywheel/session"
+	"net/http"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+func RegisterWorkflowTemplateHandler(r *gin.Engine, middleWares ...gin.HandlerFunc) {
+	g := r.Group("/v1/workflow-templates", middleWares...)
+
+	g.POST("", createWorkflowTemplateRestAPI)
+	g.GET("", queryWorkflowTemplatesRestAPI)
+	g.GET(":id", detailWorkflowTemplateRestAPI)
+	g.PUT(":id", updateWorkflowTemplateRestAPI)
+	g.DELETE(":id", deleteWorkflowTemplateRestAPI)
+	g.POST(":id/workflows", createWorkflowFromTemplateRestAPI)
+}
+
+func createWorkflowTemplateRestAPI(c *gin.Context) {
+	creation := flow.WorkflowTemplateCreation{}
+	if err := c.ShouldBindBodyWith(&creation, binding.JSON); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	t, err := flow.CreateWorkflowTemplateFunc(&creation, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+func queryWorkflowTemplatesRestAPI(c *gin.Context) {
+	query := flow.WorkflowTemplateQuery{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	templates, err := flow.QueryWorkflowTemplatesFunc(&query, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func detailWorkflowTemplateRestAPI(c *gin.Context) {
+	id, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("id") + "'"})
+		return
+	}
+
+	t, err := flow.DetailWorkflowTemplateFunc(id, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+func updateWorkflowTemplateRestAPI(c *gin.Context) {
+	id, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("id") + "'"})
+		return
+	}
+
+	updating := flow.WorkflowTemplateUpdating{}
+	if err := c.ShouldBindBodyWith(&updating, binding.JSON); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	if err := flow.UpdateWorkflowTemplateFunc(id, &updating, session.ExtractSessionFromGinContext(c)); err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func deleteWorkflowTemplateRestAPI(c *gin.Context) {
+	id, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("id") + "'"})
+		return
+	}
+
+	if err := flow.DeleteWorkflowTemplateFunc(id, session.ExtractSessionFromGinContext(c)); err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func createWorkflowFromTemplateRestAPI(c *gin.Context) {
+	id, err := types.ParseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("id") + "'"})
+		return
+	}
+
+	instantiation := flow.WorkflowInstantiation{}
+	if err := c.ShouldBindBodyWith(&instantiation, binding.JSON); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	workflow, err := flow.CreateWorkflowFromTemplateFunc(id, &instantiation, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusCreated, workflow)
+}
+
+func saveWorkflowAsTemplateRestAPI(c *gin.Context) {
+	id, err := types.ParseID(c.Param("flowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &misc.ErrorBody{Code: "common.bad_param", Message: "invalid id '" + c.Param("flowId") + "'"})
+		return
+	}
+
+	saving := flow.WorkflowTemplateSaving{}
+	if err := c.ShouldBindBodyWith(&saving, binding.JSON); err != nil {
+		panic(&bizerror.ErrBadParam{Cause: err})
+	}
+
+	t, err := flow.SaveWorkflowAsTemplateFunc(id, &saving, session.ExtractSessionFromGinContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
@@ -0,0 +1,245 @@
+package servehttp_test
+
+import (
+	"bytes"
+	"errors"
+	"flywheel/bizerror"
+	"flywheel/domain"
+	"flywheel/domain/flow"
+	"flywheel/domain/state"
+	"flywheel/servehttp"
+	"flywheel/session"
+	"flywheel/testinfra"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fundwit/go-commons/types"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+)
+
+func TestCreateWorkflowTemplateRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowTemplateHandler(router)
+
+	t.Run("should return 400 when failed to bind", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflow-templates", bytes.NewReader([]byte(`{}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param",
+			"message":"Key: 'WorkflowTemplateCreation.Name' Error:Field validation for 'Name' failed on the 'required' tag","data":null}`))
+	})
+
+	t.Run("should create template successfully", func(t *testing.T) {
+		var creation *flow.WorkflowTemplateCreation
+		flow.CreateWorkflowTemplateFunc = func(c *flow.WorkflowTemplateCreation, s *session.Session) (*domain.WorkflowTemplateDetail, error) {
+			creation = c
+			return &domain.WorkflowTemplateDetail{WorkflowTemplate: domain.WorkflowTemplate{ID: 10, Name: c.Name, ProjectID: c.ProjectID,
+				CreatorID: 100, CreateTime: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+				StateMachine: c.StateMachine, PropertyDefinitions: c.PropertyDefinitions}, nil
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflow-templates", bytes.NewReader([]byte(
+			`{"name": "test", "projectId": "1", "stateMachine": {"states": [{"name": "OPEN", "category": 1}]},
+			"propertyDefinitions": [{"name": "resolution", "type": "text"}]}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(*creation).To(Equal(flow.WorkflowTemplateCreation{Name: "test", ProjectID: 1,
+			StateMachine:        state.StateMachine{States: []state.State{{Name: "OPEN", Category: state.InBacklog}}},
+			PropertyDefinitions: []domain.PropertyDefinition{{Name: "resolution", Type: domain.PropTypeText}}}))
+		Expect(body).To(MatchJSON(`{"id": "10", "name": "test", "projectId": "1", "creatorId": "100", "createTime": "2021-01-01T00:00:00Z",
+			"stateMachine": {"states": [{"name": "OPEN", "category": 1, "order": 0}], "transitions": null},
+			"propertyDefinitions": [{"name": "resolution", "type": "text", "title": "", "options": null}]}`))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.CreateWorkflowTemplateFunc = func(c *flow.WorkflowTemplateCreation, s *session.Session) (*domain.WorkflowTemplateDetail, error) {
+			return nil, bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflow-templates", bytes.NewReader([]byte(`{"name": "test"}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+}
+
+func TestQueryWorkflowTemplatesRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowTemplateHandler(router)
+
+	t.Run("should return templates", func(t *testing.T) {
+		var query *flow.WorkflowTemplateQuery
+		flow.QueryWorkflowTemplatesFunc = func(q *flow.WorkflowTemplateQuery, s *session.Session) ([]domain.WorkflowTemplate, error) {
+			query = q
+			return []domain.WorkflowTemplate{domain.GenericWorkflowTemplate.WorkflowTemplate}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflow-templates?projectId=1", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(*query).To(Equal(flow.WorkflowTemplateQuery{ProjectID: 1}))
+		Expect(body).To(MatchJSON(`[{"id": "1", "name": "GenericTask", "projectId": "0", "creatorId": "0", "createTime": "2020-01-01T00:00:00Z"}]`))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.QueryWorkflowTemplatesFunc = func(q *flow.WorkflowTemplateQuery, s *session.Session) ([]domain.WorkflowTemplate, error) {
+			return nil, errors.New("a mocked error")
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflow-templates", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusInternalServerError))
+		Expect(body).To(MatchJSON(`{"code":"common.internal_server_error","message":"a mocked error","data":null}`))
+	})
+}
+
+func TestDetailWorkflowTemplateRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowTemplateHandler(router)
+
+	t.Run("should return 400 when id is invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflow-templates/abc", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(body).To(MatchJSON(`{"code":"common.bad_param","message":"invalid id 'abc'","data":null}`))
+	})
+
+	t.Run("should return template detail", func(t *testing.T) {
+		flow.DetailWorkflowTemplateFunc = func(id types.ID, s *session.Session) (*domain.WorkflowTemplateDetail, error) {
+			return &domain.WorkflowTemplateDetail{WorkflowTemplate: domain.WorkflowTemplate{ID: id, Name: "test",
+				CreateTime: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}}, nil
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/workflow-templates/10", nil)
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`{"id": "10", "name": "test", "projectId": "0", "creatorId": "0", "createTime": "2021-01-01T00:00:00Z",
+			"stateMachine": {"states": null, "transitions": null}, "propertyDefinitions": null}`))
+	})
+}
+
+func TestUpdateWorkflowTemplateRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowTemplateHandler(router)
+
+	t.Run("should return 400 when id is invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/v1/workflow-templates/abc", bytes.NewReader([]byte(`{"name": "test"}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+
+	t.Run("should update template", func(t *testing.T) {
+		var updatedID types.ID
+		var updating *flow.WorkflowTemplateUpdating
+		flow.UpdateWorkflowTemplateFunc = func(id types.ID, c *flow.WorkflowTemplateUpdating, s *session.Session) error {
+			updatedID, updating = id, c
+			return nil
+		}
+		req := httptest.NewRequest(http.MethodPut, "/v1/workflow-templates/10", bytes.NewReader([]byte(`{"name": "test"}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(updatedID).To(Equal(types.ID(10)))
+		Expect(*updating).To(Equal(flow.WorkflowTemplateUpdating{Name: "test"}))
+	})
+}
+
+func TestDeleteWorkflowTemplateRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowTemplateHandler(router)
+
+	t.Run("should delete template", func(t *testing.T) {
+		var deletedID types.ID
+		flow.DeleteWorkflowTemplateFunc = func(id types.ID, s *session.Session) error {
+			deletedID = id
+			return nil
+		}
+		req := httptest.NewRequest(http.MethodDelete, "/v1/workflow-templates/10", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusNoContent))
+		Expect(deletedID).To(Equal(types.ID(10)))
+	})
+
+	t.Run("should be able to handle error", func(t *testing.T) {
+		flow.DeleteWorkflowTemplateFunc = func(id types.ID, s *session.Session) error {
+			return bizerror.ErrForbidden
+		}
+		req := httptest.NewRequest(http.MethodDelete, "/v1/workflow-templates/1", nil)
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+}
+
+func TestCreateWorkflowFromTemplateRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowTemplateHandler(router)
+
+	t.Run("should return 400 when failed to bind", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflow-templates/1/workflows", bytes.NewReader([]byte(`{"name": "test"}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+
+	t.Run("should create workflow from template", func(t *testing.T) {
+		var templateID types.ID
+		var instantiation *flow.WorkflowInstantiation
+		flow.CreateWorkflowFromTemplateFunc = func(id types.ID, c *flow.WorkflowInstantiation, s *session.Session) (*domain.WorkflowDetail, error) {
+			templateID, instantiation = id, c
+			return &domain.WorkflowDetail{Workflow: domain.Workflow{ID: 20, Name: c.Name, ProjectID: c.ProjectID, ThemeColor: c.ThemeColor,
+				ThemeIcon: c.ThemeIcon, Version: 1, CreateTime: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}}, nil
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflow-templates/1/workflows", bytes.NewReader([]byte(
+			`{"name": "test", "projectId": "2", "themeColor": "blue", "themeIcon": "foo"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(templateID).To(Equal(types.ID(1)))
+		Expect(*instantiation).To(Equal(flow.WorkflowInstantiation{Name: "test", ProjectID: 2, ThemeColor: "blue", ThemeIcon: "foo"}))
+		Expect(body).To(MatchJSON(`{"id": "20", "name": "test", "projectId": "2", "themeColor": "blue", "themeIcon": "foo", "version": 1,
+			"createTime": "2021-01-01T00:00:00Z", "stateMachine": {"states": null, "transitions": null}, "propertyDefinitions": null}`))
+	})
+}
+
+func TestSaveWorkflowAsTemplateRestAPI(t *testing.T) {
+	RegisterTestingT(t)
+
+	router := gin.Default()
+	router.Use(bizerror.ErrorHandling())
+	servehttp.RegisterWorkflowHandler(router)
+
+	t.Run("should return 400 when id is invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/abc/templates", bytes.NewReader([]byte(`{"name": "test"}`)))
+		status, _, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+
+	t.Run("should save workflow as template", func(t *testing.T) {
+		var workflowID types.ID
+		var saving *flow.WorkflowTemplateSaving
+		flow.SaveWorkflowAsTemplateFunc = func(id types.ID, c *flow.WorkflowTemplateSaving, s *session.Session) (*domain.WorkflowTemplateDetail, error) {
+			workflowID, saving = id, c
+			return &domain.WorkflowTemplateDetail{WorkflowTemplate: domain.WorkflowTemplate{ID: 30, Name: c.Name, ProjectID: c.ProjectID,
+				CreateTime: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}}, nil
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/workflows/10/templates", bytes.NewReader([]byte(`{"name": "test", "projectId": "1"}`)))
+		status, body, _ := testinfra.ExecuteRequest(req, router)
+		Expect(status).To(Equal(http.StatusCreated))
+		Expect(workflowID).To(Equal(types.ID(10)))
+		Expect(*saving).To(Equal(flow.WorkflowTemplateSaving{Name: "test", ProjectID: 1}))
+		Expect(body).To(MatchJSON(`{"id": "30", "name": "test", "projectId": "1", "creatorId": "0", "createTime": "2021-01-01T00:00:00Z",
+			"stateMachine": {"states": null, "transitions": null}, "propertyDefinitions": null}`))
+	})
+}
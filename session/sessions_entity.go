@@ -26,6 +26,13 @@ type Identity struct {
 	Nickname string   `json:"nickname"`
 }
 
+// the reserved identities which the system acts as, ids of users are generated by id worker and never fall in this range
+var (
+	IndexRobot       = Identity{ID: 10, Name: "index-robot"}
+	AnonymousInvoker = Identity{ID: 11, Name: "anonymous-invoker"}
+	TriggerRobot     = Identity{ID: 12, Name: "trigger-robot", Nickname: "Trigger Robot"}
+)
+
 func (c *Session) Clone() Session {
 	return Session{
 		Token:        c.Token,